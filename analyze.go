@@ -0,0 +1,254 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultAnalyzeTopFiles = 10
+
+	// maxImageMetadata is the size limit of the JSON files of a saved
+	// image, like manifest.json, the configuration and the attestations.
+	maxImageMetadata = 64 << 20
+)
+
+// ErrImageLayersMismatch is returned by AnalyzeImage when the layers of the
+// saved image don't match its history.
+var ErrImageLayersMismatch = errors.New("the layers of the saved image don't match its history")
+
+// AnalyzeImageOptions is the set of options that can be used when analyzing
+// an image.
+type AnalyzeImageOptions struct {
+	Name string
+
+	// Files, when set, downloads the image, like ExportImage, to find the
+	// largest files of each layer. The whole image is streamed, which may
+	// take a while for large images.
+	Files bool
+
+	// TopFiles is the number of files reported for each layer. It
+	// defaults to 10.
+	TopFiles int
+}
+
+// ImageAnalysis is the result of AnalyzeImage.
+type ImageAnalysis struct {
+	// Size is the sum of the sizes of the layers.
+	Size int64
+
+	// Layers lists the steps of the history of the image, the oldest
+	// first, including the ones which didn't change the filesystem.
+	Layers []LayerAnalysis
+}
+
+// LayerAnalysis is a step of the history of an image, with the size of the
+// layer it created and, when the files were analyzed, its largest files.
+type LayerAnalysis struct {
+	ID        string
+	CreatedBy string
+	Size      int64
+	Files     []LayerFile
+}
+
+// LayerFile is a regular file added or modified by a layer.
+type LayerFile struct {
+	Path string
+	Size int64
+}
+
+// AnalyzeImage reports the size of each layer of the given image, from its
+// history, and optionally its largest files, from the saved image, to find
+// what to slim down.
+func (c *Client) AnalyzeImage(opts AnalyzeImageOptions) (*ImageAnalysis, error) {
+	if opts.TopFiles <= 0 {
+		opts.TopFiles = defaultAnalyzeTopFiles
+	}
+	history, err := c.ImageHistory(opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	var analysis ImageAnalysis
+	for i := len(history) - 1; i >= 0; i-- {
+		analysis.Size += history[i].Size
+		analysis.Layers = append(analysis.Layers, LayerAnalysis{
+			ID:        history[i].ID,
+			CreatedBy: history[i].CreatedBy,
+			Size:      history[i].Size,
+		})
+	}
+	if !opts.Files {
+		return &analysis, nil
+	}
+	saved, err := c.readSavedImage(opts.Name, opts.TopFiles)
+	if err != nil {
+		return nil, err
+	}
+	if err := saved.attach(analysis.Layers); err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+// savedImage holds what AnalyzeImage reads from a saved image: the JSON files,
+// by name, and the largest files of the layers, by the name of their tar
+// archive.
+type savedImage struct {
+	metadata map[string][]byte
+	files    map[string][]LayerFile
+}
+
+func (c *Client) readSavedImage(name string, top int) (*savedImage, error) {
+	r, w := io.Pipe()
+	errs := make(chan error, 1)
+	go func() {
+		err := c.ExportImage(ExportImageOptions{Name: name, OutputStream: w})
+		w.CloseWithError(err)
+		errs <- err
+	}()
+	saved, err := parseSavedImage(r, top)
+	if err == nil {
+		// the archive may be padded after its end
+		_, err = io.Copy(ioutil.Discard, r)
+	}
+	// unblocks the export when the parsing failed
+	r.CloseWithError(errors.New("analysis of the saved image interrupted"))
+	exportErr := <-errs
+	if err != nil {
+		return nil, err
+	}
+	return saved, exportErr
+}
+
+// parseSavedImage reads the tar archive written by ExportImage, in either the
+// legacy format, with a directory per layer, or the OCI layout, with the
+// layers in blobs/sha256. As the manifest may come last, every entry is read
+// either as JSON or as the tar archive of a layer. The layers are skipped when
+// top is zero.
+func parseSavedImage(r io.Reader, top int) (*savedImage, error) {
+	saved := savedImage{metadata: make(map[string][]byte), files: make(map[string][]LayerFile)}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return &saved, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		br := bufio.NewReader(tr)
+		head, _ := br.Peek(2)
+		switch {
+		case len(head) > 0 && (head[0] == '{' || head[0] == '['):
+			if hdr.Size <= maxImageMetadata {
+				if saved.metadata[hdr.Name], err = ioutil.ReadAll(br); err != nil {
+					return nil, err
+				}
+			}
+		case top == 0:
+		case len(head) == 2 && head[0] == 0x1f && head[1] == 0x8b:
+			gz, err := gzip.NewReader(br)
+			if err != nil {
+				return nil, err
+			}
+			saved.files[hdr.Name], _ = largestFiles(gz, top)
+		default:
+			// the entries which are not tar archives, like VERSION, are
+			// skipped
+			saved.files[hdr.Name], _ = largestFiles(br, top)
+		}
+	}
+}
+
+// largestFiles returns the largest regular files of the tar archive of a
+// layer, the largest first.
+func largestFiles(r io.Reader, top int) ([]LayerFile, error) {
+	var files layerFiles
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		path := "/" + strings.TrimPrefix(hdr.Name, "./")
+		if len(files) < top {
+			files = append(files, LayerFile{Path: path, Size: hdr.Size})
+		} else if hdr.Size > files[top-1].Size {
+			files[top-1] = LayerFile{Path: path, Size: hdr.Size}
+		} else {
+			continue
+		}
+		sort.Stable(files)
+	}
+}
+
+type layerFiles []LayerFile
+
+func (files layerFiles) Len() int           { return len(files) }
+func (files layerFiles) Less(i, j int) bool { return files[i].Size > files[j].Size }
+func (files layerFiles) Swap(i, j int)      { files[i], files[j] = files[j], files[i] }
+
+// attach sets the files of the given layers, the oldest first. The layers of
+// the saved image are matched to the steps of the history which created one,
+// according to the configuration of the image.
+func (saved *savedImage) attach(layers []LayerAnalysis) error {
+	var manifest []struct {
+		Config string
+		Layers []string
+	}
+	if err := json.Unmarshal(saved.metadata["manifest.json"], &manifest); err != nil {
+		return err
+	}
+	if len(manifest) == 0 {
+		return ErrImageLayersMismatch
+	}
+	var config struct {
+		History []struct {
+			EmptyLayer bool `json:"empty_layer"`
+		} `json:"history"`
+	}
+	if data, ok := saved.metadata[manifest[0].Config]; ok {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return err
+		}
+	}
+	tarballs := manifest[0].Layers
+	for i := range layers {
+		empty := layers[i].Size == 0
+		if len(config.History) == len(layers) {
+			empty = config.History[i].EmptyLayer
+		}
+		if empty {
+			continue
+		}
+		if len(tarballs) == 0 {
+			return ErrImageLayersMismatch
+		}
+		layers[i].Files = saved.files[tarballs[0]]
+		tarballs = tarballs[1:]
+	}
+	if len(tarballs) > 0 {
+		return ErrImageLayersMismatch
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func tarArchive(t *testing.T, files map[string][]byte, order ...string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range order {
+		content := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAnalyzeImage(t *testing.T) {
+	base := tarArchive(t, map[string][]byte{
+		"bin/sh":    bytes.Repeat([]byte("x"), 300),
+		"etc/hosts": []byte("127.0.0.1 localhost"),
+		"lib/libc":  bytes.Repeat([]byte("x"), 1000),
+	}, "bin/sh", "etc/hosts", "lib/libc")
+	app := tarArchive(t, map[string][]byte{
+		"app/main": bytes.Repeat([]byte("x"), 500),
+	}, "app/main")
+	saved := tarArchive(t, map[string][]byte{
+		"blobs/sha256/base":   base,
+		"blobs/sha256/app":    app,
+		"blobs/sha256/config": []byte(`{"history":[{},{"empty_layer":true},{}]}`),
+		"manifest.json":       []byte(`[{"Config":"blobs/sha256/config","Layers":["blobs/sha256/base","blobs/sha256/app"]}]`),
+	}, "blobs/sha256/base", "blobs/sha256/app", "blobs/sha256/config", "manifest.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images/app/history":
+			w.Write([]byte(`[
+	{"Id":"app","CreatedBy":"COPY main /app/main","Size":500},
+	{"Id":"<missing>","CreatedBy":"WORKDIR /app","Size":0},
+	{"Id":"<missing>","CreatedBy":"ADD rootfs /","Size":1319}
+]`))
+		case "/images/app/get":
+			w.Write(saved)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := client.AnalyzeImage(AnalyzeImageOptions{Name: "app", Files: true, TopFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ImageAnalysis{
+		Size: 1819,
+		Layers: []LayerAnalysis{
+			{ID: "<missing>", CreatedBy: "ADD rootfs /", Size: 1319, Files: []LayerFile{
+				{Path: "/lib/libc", Size: 1000},
+				{Path: "/bin/sh", Size: 300},
+			}},
+			{ID: "<missing>", CreatedBy: "WORKDIR /app"},
+			{ID: "app", CreatedBy: "COPY main /app/main", Size: 500, Files: []LayerFile{{Path: "/app/main", Size: 500}}},
+		},
+	}
+	if !reflect.DeepEqual(analysis, expected) {
+		t.Errorf("AnalyzeImage: wrong analysis. Want %#v. Got %#v.", expected, analysis)
+	}
+}
+
+func TestAnalyzeImageLayersMismatch(t *testing.T) {
+	saved := tarArchive(t, map[string][]byte{
+		"manifest.json": []byte(`[{"Config":"config.json","Layers":["a/layer.tar","b/layer.tar"]}]`),
+	}, "manifest.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/history") {
+			w.Write([]byte(`[{"Id":"app","Size":10}]`))
+			return
+		}
+		w.Write(saved)
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.AnalyzeImage(AnalyzeImageOptions{Name: "app", Files: true})
+	if err != ErrImageLayersMismatch {
+		t.Errorf("AnalyzeImage: wrong error. Want %#v. Got %#v.", ErrImageLayersMismatch, err)
+	}
+}
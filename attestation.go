@@ -0,0 +1,119 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Predicate types of the attestations created by BuildKit.
+const (
+	PredicateSPDX             = "https://spdx.dev/Document"
+	PredicateSLSAProvenance   = "https://slsa.dev/provenance/v0.2"
+	PredicateSLSAProvenanceV1 = "https://slsa.dev/provenance/v1"
+)
+
+const (
+	mediaTypeOCIIndex    = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerIndex = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	annotationReferenceType   = "vnd.docker.reference.type"
+	annotationReferenceDigest = "vnd.docker.reference.digest"
+	annotationPredicateType   = "in-toto.io/predicate-type"
+)
+
+// Attestation is an in-toto statement attached to an image by BuildKit, like
+// its SBOM or its provenance.
+type Attestation struct {
+	// For is the digest of the manifest of the image, for a platform,
+	// described by the attestation.
+	For string
+
+	PredicateType string
+	MediaType     string
+	Digest        string
+
+	// Content is the in-toto statement, in JSON.
+	Content []byte
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// ImageAttestations returns the attestations of the given image, like the
+// SBOM and the provenance requested with docker buildx build --sbom and
+// --provenance, read from the saved image.
+//
+// Only the daemons using the containerd image store keep the attestations,
+// and only when they were built or pulled along with the image: the other
+// daemons return no attestation. The remote API can't request attestations
+// from the builder, as it requires a BuildKit session.
+func (c *Client) ImageAttestations(name string) ([]Attestation, error) {
+	saved, err := c.readSavedImage(name, 0)
+	if err != nil {
+		return nil, err
+	}
+	var index ociManifest
+	if data, ok := saved.metadata["index.json"]; ok {
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, err
+		}
+	}
+	return saved.attestations(index.Manifests, nil)
+}
+
+// attestations walks the given descriptors, and the indexes they point to,
+// to collect the layers of the attestation manifests. The blobs missing from
+// the saved image are skipped.
+func (saved *savedImage) attestations(descriptors []ociDescriptor, attestations []Attestation) ([]Attestation, error) {
+	for _, descriptor := range descriptors {
+		data, ok := saved.metadata[blobPath(descriptor.Digest)]
+		if !ok {
+			continue
+		}
+		var manifest ociManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, err
+		}
+		switch {
+		case descriptor.MediaType == mediaTypeOCIIndex || descriptor.MediaType == mediaTypeDockerIndex:
+			var err error
+			if attestations, err = saved.attestations(manifest.Manifests, attestations); err != nil {
+				return nil, err
+			}
+		case descriptor.Annotations[annotationReferenceType] == "attestation-manifest":
+			for _, layer := range manifest.Layers {
+				content, ok := saved.metadata[blobPath(layer.Digest)]
+				if !ok {
+					continue
+				}
+				attestations = append(attestations, Attestation{
+					For:           descriptor.Annotations[annotationReferenceDigest],
+					PredicateType: layer.Annotations[annotationPredicateType],
+					MediaType:     layer.MediaType,
+					Digest:        layer.Digest,
+					Content:       content,
+				})
+			}
+		}
+	}
+	return attestations, nil
+}
+
+// blobPath returns the path of a blob in the OCI layout, like
+// blobs/sha256/<hex>.
+func blobPath(digest string) string {
+	return "blobs/" + strings.Replace(digest, ":", "/", 1)
+}
@@ -0,0 +1,56 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestImageAttestations(t *testing.T) {
+	sbom := []byte(`{"_type":"https://in-toto.io/Statement/v0.1","predicateType":"https://spdx.dev/Document"}`)
+	saved := tarArchive(t, map[string][]byte{
+		"index.json": []byte(`{"manifests":[{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"sha256:index"}]}`),
+		"blobs/sha256/index": []byte(`{"manifests":[
+	{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:image"},
+	{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:attest",
+		"annotations":{"vnd.docker.reference.type":"attestation-manifest","vnd.docker.reference.digest":"sha256:image"}}
+]}`),
+		"blobs/sha256/image": []byte(`{"layers":[{"mediaType":"application/vnd.oci.image.layer.v1.tar","digest":"sha256:layer"}]}`),
+		"blobs/sha256/attest": []byte(`{"layers":[
+	{"mediaType":"application/vnd.in-toto+json","digest":"sha256:sbom","annotations":{"in-toto.io/predicate-type":"https://spdx.dev/Document"}},
+	{"mediaType":"application/vnd.in-toto+json","digest":"sha256:provenance","annotations":{"in-toto.io/predicate-type":"https://slsa.dev/provenance/v0.2"}}
+]}`),
+		"blobs/sha256/sbom": sbom,
+	}, "blobs/sha256/sbom", "blobs/sha256/attest", "blobs/sha256/image", "blobs/sha256/index", "index.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/images/app/get" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Write(saved)
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attestations, err := client.ImageAttestations("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Attestation{{
+		For:           "sha256:image",
+		PredicateType: PredicateSPDX,
+		MediaType:     "application/vnd.in-toto+json",
+		Digest:        "sha256:sbom",
+		Content:       sbom,
+	}}
+	if !reflect.DeepEqual(attestations, expected) {
+		t.Errorf("ImageAttestations: wrong attestations. Want %#v. Got %#v.", expected, attestations)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedValue replaces the values of the headers carrying credentials in
+// the audit records.
+const redactedValue = "<redacted>"
+
+// redactedHeaders lists the headers carrying credentials, in their canonical
+// form.
+var redactedHeaders = []string{"Authorization", "X-Registry-Auth", "X-Registry-Config"}
+
+// AuditRecord describes a request sent to the daemon by the client, or denied
+// by the client before being sent.
+type AuditRecord struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+
+	// Path is the path of the request, without the query string, which
+	// may hold secrets, like the build arguments.
+	Path string `json:"path"`
+
+	// Headers are the headers set by the call, like the credentials of the
+	// registry, whose values are redacted.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Labels are the AuditLabels of the client.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Status is the status code of the response. It's zero when no
+	// response was received, and for the calls hijacking the connection,
+	// like attaching to containers, whose records are made once the
+	// session is over.
+	Status   int           `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+
+	// DryRun is set for the requests skipped by the DryRun of the client.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// AuditRecorder receives the records of the requests of a client, for
+// auditing the use of the socket of the daemon. RecordRequest may be called
+// concurrently.
+type AuditRecorder interface {
+	RecordRequest(record AuditRecord)
+}
+
+// AuditRecorderFunc is an adapter allowing the use of ordinary functions as
+// an AuditRecorder.
+type AuditRecorderFunc func(record AuditRecord)
+
+// RecordRequest calls f(record).
+func (f AuditRecorderFunc) RecordRequest(record AuditRecord) {
+	f(record)
+}
+
+// NewAuditLog returns an AuditRecorder writing the records to w, one JSON
+// object per line.
+func NewAuditLog(w io.Writer) AuditRecorder {
+	return &auditLog{encoder: json.NewEncoder(w)}
+}
+
+type auditLog struct {
+	mut     sync.Mutex
+	encoder *json.Encoder
+}
+
+func (l *auditLog) RecordRequest(record AuditRecord) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.encoder.Encode(record)
+}
+
+// audit records a request, when the client has an AuditRecorder. The response
+// may be nil.
+func (c *Client) audit(method, path string, headers map[string]string, start time.Time, resp *http.Response, err error) {
+	if c.AuditRecorder == nil {
+		return
+	}
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	record := AuditRecord{
+		Time:     start,
+		Method:   method,
+		Path:     path,
+		Labels:   c.AuditLabels,
+		Duration: time.Since(start),
+		DryRun:   c.dryRun(method) && err == nil,
+	}
+	if len(headers) > 0 {
+		record.Headers = make(map[string]string, len(headers))
+		for key, value := range headers {
+			if containsString(redactedHeaders, http.CanonicalHeaderKey(key)) {
+				value = redactedValue
+			}
+			record.Headers[key] = value
+		}
+	}
+	if resp != nil {
+		record.Status = resp.StatusCode
+	}
+	if err != nil {
+		record.Error = err.Error()
+		if e, ok := err.(*Error); ok {
+			record.Status = e.Status
+		}
+	}
+	c.AuditRecorder.RecordRequest(record)
+}
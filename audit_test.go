@@ -0,0 +1,74 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestClientAuditRecorder(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "no such image", status: http.StatusNotFound}
+	client := newTestClient(fakeRT)
+	var records []AuditRecord
+	client.AuditRecorder = AuditRecorderFunc(func(record AuditRecord) {
+		records = append(records, record)
+	})
+	client.AuditLabels = map[string]string{"component": "deployer"}
+	client.ReadOnly = true
+	client.InspectImage("busybox")
+	auth := AuthConfiguration{Username: "user", Password: "secret"}
+	client.InspectDistribution("busybox", auth)
+	client.ListImages(ListImagesOptions{All: true})
+	client.RemoveImage("busybox")
+	if len(records) != 4 {
+		t.Fatalf("AuditRecorder: wrong number of records. Want 4. Got %d.", len(records))
+	}
+	for _, record := range records {
+		if !reflect.DeepEqual(record.Labels, client.AuditLabels) {
+			t.Errorf("AuditRecorder: wrong labels. Want %#v. Got %#v.", client.AuditLabels, record.Labels)
+		}
+	}
+	if r := records[0]; r.Method != "GET" || r.Path != "/images/busybox/json" || r.Status != http.StatusNotFound {
+		t.Errorf("AuditRecorder: wrong record of InspectImage: %#v.", r)
+	}
+	if r := records[1]; r.Path != "/distribution/busybox/json" || r.Headers["X-Registry-Auth"] != "<redacted>" {
+		t.Errorf("AuditRecorder: wrong record of InspectDistribution: %#v.", r)
+	}
+	if r := records[2]; r.Path != "/images/json" {
+		t.Errorf("AuditRecorder: the query string should be removed. Got %q.", r.Path)
+	}
+	if r := records[3]; r.Method != "DELETE" || r.Status != 0 || r.Error == "" {
+		t.Errorf("AuditRecorder: wrong record of the denied RemoveImage: %#v.", r)
+	}
+}
+
+func TestNewAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewAuditLog(&buf)
+	log.RecordRequest(AuditRecord{Method: "GET", Path: "/_ping", Status: 200})
+	log.RecordRequest(AuditRecord{Method: "POST", Path: "/containers/create", Status: 201})
+	decoder := json.NewDecoder(&buf)
+	var paths []string
+	for {
+		var record AuditRecord
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, record.Path)
+	}
+	expected := []string{"/_ping", "/containers/create"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("NewAuditLog: wrong records. Want %#v. Got %#v.", expected, paths)
+	}
+}
@@ -7,7 +7,9 @@ package docker
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
+	"net/http"
 	"os"
 	"path"
 	"strings"
@@ -20,6 +22,10 @@ type AuthConfiguration struct {
 	Password      string `json:"password,omitempty"`
 	Email         string `json:"email,omitempty"`
 	ServerAddress string `json:"serveraddress,omitempty"`
+
+	// IdentityToken is a token returned by the registry on login, used in
+	// place of the password.
+	IdentityToken string `json:"identitytoken,omitempty"`
 }
 
 // AuthConfigurations represents authentication options to use for the
@@ -28,6 +34,72 @@ type AuthConfigurations struct {
 	Configs map[string]AuthConfiguration `json:"configs"`
 }
 
+// ForImage returns the credentials for the registry of the given image
+// reference, like quay.io/coreos/etcd:v2.0.9. Images without a registry, and
+// the index.docker.io and registry-1.docker.io aliases, are looked up as
+// Docker Hub images. Configurations are matched by host, so keys like
+// https://index.docker.io/v1/ work as expected.
+func (c *AuthConfigurations) ForImage(image string) (AuthConfiguration, bool) {
+	if c == nil {
+		return AuthConfiguration{}, false
+	}
+	registry := registryOf(image)
+	for key, auth := range c.Configs {
+		if normalizeRegistryHost(key) == registry {
+			return auth, true
+		}
+	}
+	return AuthConfiguration{}, false
+}
+
+// registryOf returns the normalized host of the registry of the given image
+// reference.
+func registryOf(image string) string {
+	registry := DefaultRegistry
+	if ref, err := ParseReference(image); err == nil {
+		registry = ref.Registry
+	} else if i := strings.Index(image, "/"); i > -1 && strings.ContainsAny(image[:i], ".:") {
+		registry = image[:i]
+	}
+	return normalizeRegistryHost(registry)
+}
+
+// normalizeRegistryHost returns the host of the given registry address,
+// which may be a URL, mapping the aliases of Docker Hub to docker.io.
+func normalizeRegistryHost(address string) string {
+	if i := strings.Index(address, "://"); i > -1 {
+		address = address[i+3:]
+	}
+	if i := strings.Index(address, "/"); i > -1 {
+		address = address[:i]
+	}
+	address = strings.ToLower(address)
+	switch address {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return DefaultRegistry
+	}
+	return address
+}
+
+// authFor returns the credentials to use for the given image: the given
+// ones, unless they're empty, in which case they're obtained from the
+// AuthProvider of the client or looked up in its AuthConfigs.
+func (c *Client) authFor(image string, auth AuthConfiguration) (AuthConfiguration, error) {
+	if auth != (AuthConfiguration{}) {
+		return auth, nil
+	}
+	if c.AuthProvider != nil {
+		provided, ok, err := c.AuthProvider.Credentials(registryOf(image))
+		if ok || err != nil {
+			return provided, err
+		}
+	}
+	if found, ok := c.AuthConfigs.ForImage(image); ok {
+		return found, nil
+	}
+	return auth, nil
+}
+
 // dockerConfig represents a registry authentation configuration from the
 // .dockercfg file.
 type dockerConfig struct {
@@ -81,3 +153,95 @@ func authConfigs(confs map[string]dockerConfig) (*AuthConfigurations, error) {
 	}
 	return c, nil
 }
+
+// AuthStatus is the result of an authentication check.
+type AuthStatus struct {
+	Status        string `json:"Status,omitempty" yaml:"Status,omitempty"`
+	IdentityToken string `json:"IdentityToken,omitempty" yaml:"IdentityToken,omitempty"`
+}
+
+// AuthCheck validates the given credentials against the registry in their
+// ServerAddress, through the daemon.
+//
+// When the registry returns an identity token, the client caches it and uses
+// it in place of the password in later pulls and pushes using the same
+// credentials. If the token is rejected, it's refreshed by checking the
+// credentials again, and the operation is retried once.
+func (c *Client) AuthCheck(conf *AuthConfiguration) (AuthStatus, error) {
+	var status AuthStatus
+	if conf == nil {
+		return status, errors.New("conf is nil")
+	}
+	body, _, err := c.do("POST", "/auth", conf, false)
+	if err != nil {
+		return status, err
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &status); err != nil {
+			return status, err
+		}
+	}
+	if status.IdentityToken != "" {
+		c.setIdentityToken(*conf, status.IdentityToken)
+	}
+	return status, nil
+}
+
+func identityTokenKey(auth AuthConfiguration) string {
+	return auth.ServerAddress + "\x00" + auth.Username
+}
+
+func (c *Client) setIdentityToken(auth AuthConfiguration, token string) {
+	c.authMut.Lock()
+	defer c.authMut.Unlock()
+	if c.identityTokens == nil {
+		c.identityTokens = make(map[string]string)
+	}
+	if token == "" {
+		delete(c.identityTokens, identityTokenKey(auth))
+	} else {
+		c.identityTokens[identityTokenKey(auth)] = token
+	}
+}
+
+// resolveAuth returns the credentials to send to the daemon in place of the
+// given ones, using the cached identity token, if any.
+func (c *Client) resolveAuth(auth AuthConfiguration) AuthConfiguration {
+	if auth.IdentityToken != "" {
+		return auth
+	}
+	c.authMut.Lock()
+	token := c.identityTokens[identityTokenKey(auth)]
+	c.authMut.Unlock()
+	if token != "" {
+		auth.IdentityToken = token
+		auth.Password = ""
+	}
+	return auth
+}
+
+// withAuth calls fn with the credentials resolved from the given ones. If
+// fn fails because a cached identity token was rejected, the token is
+// refreshed and fn is called again.
+func (c *Client) withAuth(auth AuthConfiguration, fn func(AuthConfiguration) error) error {
+	resolved := c.resolveAuth(auth)
+	err := fn(resolved)
+	if err == nil || resolved.IdentityToken == auth.IdentityToken || auth.Password == "" || !isUnauthorized(err) {
+		return err
+	}
+	c.setIdentityToken(auth, "")
+	if _, err := c.AuthCheck(&auth); err != nil {
+		return err
+	}
+	return fn(c.resolveAuth(auth))
+}
+
+// isUnauthorized reports whether the given error was caused by the registry
+// rejecting the credentials.
+func isUnauthorized(err error) bool {
+	if e, ok := err.(*Error); ok && e.Status == http.StatusUnauthorized {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unauthorized") || strings.Contains(msg, "authentication required")
+}
@@ -5,8 +5,12 @@
 package docker
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -35,3 +39,169 @@ func TestAuthConfig(t *testing.T) {
 		t.Errorf(`AuthConfigurations.Configs["docker.io"].ServerAddress: wrong result. Want %q. Got %q`, want, got)
 	}
 }
+
+func TestAuthCheck(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Status":"Login Succeeded","IdentityToken":"9cbaf023"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	auth := AuthConfiguration{Username: "user", Password: "pass", ServerAddress: "registry.example.com"}
+	status, err := client.AuthCheck(&auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AuthStatus{Status: "Login Succeeded", IdentityToken: "9cbaf023"}
+	if status != expected {
+		t.Errorf("AuthCheck: wrong status. Want %#v. Got %#v.", expected, status)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != "POST" || req.URL.Path != "/auth" {
+		t.Errorf("AuthCheck: wrong request. Want POST /auth. Got %s %s.", req.Method, req.URL.Path)
+	}
+	resolved := client.resolveAuth(auth)
+	if resolved.IdentityToken != "9cbaf023" || resolved.Password != "" {
+		t.Errorf("AuthCheck: identity token not cached. Got %#v.", resolved)
+	}
+	other := client.resolveAuth(AuthConfiguration{Username: "other", ServerAddress: "registry.example.com"})
+	if other.IdentityToken != "" {
+		t.Errorf("AuthCheck: identity token used for other credentials. Got %#v.", other)
+	}
+}
+
+func TestAuthCheckNil(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{status: http.StatusOK})
+	if _, err := client.AuthCheck(nil); err == nil {
+		t.Error("AuthCheck: expected error on nil conf, got <nil>")
+	}
+}
+
+func TestAuthCheckFailure(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "wrong login/password", status: http.StatusUnauthorized})
+	_, err := client.AuthCheck(&AuthConfiguration{Username: "user", Password: "wrong"})
+	if e, ok := err.(*Error); !ok || e.Status != http.StatusUnauthorized {
+		t.Errorf("AuthCheck: wrong error. Want *Error with status 401. Got %#v.", err)
+	}
+}
+
+func TestPullImageRefreshesIdentityToken(t *testing.T) {
+	var tokens []string
+	var authCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			authCalls++
+			fmt.Fprintf(w, `{"Status":"Login Succeeded","IdentityToken":"token-%d"}`, authCalls)
+		case "/images/create":
+			data, _ := base64.URLEncoding.DecodeString(r.Header.Get("X-Registry-Auth"))
+			var auth AuthConfiguration
+			json.Unmarshal(data, &auth)
+			tokens = append(tokens, auth.IdentityToken)
+			w.Header().Set("Content-Type", "application/json")
+			if auth.IdentityToken == "token-1" {
+				w.Write([]byte(`{"error":"unauthorized: authentication required"}`))
+				return
+			}
+			w.Write([]byte(`{"status":"Downloaded newer image"}`))
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := AuthConfiguration{Username: "user", Password: "pass", ServerAddress: "registry.example.com"}
+	if _, err := client.AuthCheck(&auth); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = client.PullImage(PullImageOptions{Repository: "registry.example.com/app", OutputStream: &buf}, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"token-1", "token-2"}; strings.Join(tokens, ",") != strings.Join(expected, ",") {
+		t.Errorf("PullImage: wrong identity tokens sent. Want %q. Got %q.", expected, tokens)
+	}
+	if authCalls != 2 {
+		t.Errorf("PullImage: wrong number of auth checks. Want 2. Got %d.", authCalls)
+	}
+}
+
+func TestPullImageUnauthorizedWithoutIdentityToken(t *testing.T) {
+	fakeRT := &FakeRoundTripper{
+		message: `{"error":"unauthorized: authentication required"}`,
+		status:  http.StatusOK,
+		header:  map[string]string{"Content-Type": "application/json"},
+	}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	auth := AuthConfiguration{Username: "user", Password: "pass"}
+	err := client.PullImage(PullImageOptions{Repository: "app", OutputStream: &buf}, auth)
+	if err == nil {
+		t.Fatal("PullImage: expected error, got <nil>")
+	}
+	if len(fakeRT.requests) != 1 {
+		t.Errorf("PullImage: should not retry without an identity token. Got %d requests.", len(fakeRT.requests))
+	}
+}
+
+func TestAuthConfigurationsForImage(t *testing.T) {
+	hub := AuthConfiguration{Username: "hub", ServerAddress: "https://index.docker.io/v1/"}
+	quay := AuthConfiguration{Username: "quay", ServerAddress: "quay.io"}
+	local := AuthConfiguration{Username: "local", ServerAddress: "localhost:5000"}
+	configs := &AuthConfigurations{Configs: map[string]AuthConfiguration{
+		"https://index.docker.io/v1/": hub,
+		"https://quay.io":             quay,
+		"localhost:5000":              local,
+	}}
+	var tests = []struct {
+		image    string
+		expected AuthConfiguration
+		found    bool
+	}{
+		{"busybox", hub, true},
+		{"tsuru/python:2.7", hub, true},
+		{"docker.io/tsuru/python", hub, true},
+		{"index.docker.io/tsuru/python", hub, true},
+		{"registry-1.docker.io/tsuru/python", hub, true},
+		{"quay.io/coreos/etcd:v2.0.9", quay, true},
+		{"QUAY.IO/coreos/etcd", quay, true},
+		{"localhost:5000/app", local, true},
+		{"gcr.io/project/app", AuthConfiguration{}, false},
+	}
+	for _, tt := range tests {
+		auth, found := configs.ForImage(tt.image)
+		if auth != tt.expected || found != tt.found {
+			t.Errorf("AuthConfigurations.ForImage(%q): wrong result. Want %#v, %v. Got %#v, %v.", tt.image, tt.expected, tt.found, auth, found)
+		}
+	}
+	var nilConfigs *AuthConfigurations
+	if _, found := nilConfigs.ForImage("busybox"); found {
+		t.Error("AuthConfigurations.ForImage: nil configurations should not find credentials")
+	}
+}
+
+func TestPullImageUsesClientAuthConfigs(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "Pulling 1/100", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	quay := AuthConfiguration{Username: "quay", Password: "secret", ServerAddress: "quay.io"}
+	client.AuthConfigs = &AuthConfigurations{Configs: map[string]AuthConfiguration{"quay.io": quay}}
+	var buf bytes.Buffer
+	if err := client.PullImage(PullImageOptions{Repository: "quay.io/coreos/etcd", OutputStream: &buf}, AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	explicit := AuthConfiguration{Username: "explicit"}
+	if err := client.PullImage(PullImageOptions{Repository: "quay.io/coreos/etcd", OutputStream: &buf}, explicit); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []AuthConfiguration{quay, explicit} {
+		data, err := base64.URLEncoding.DecodeString(fakeRT.requests[i].Header.Get("X-Registry-Auth"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got AuthConfiguration
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != expected {
+			t.Errorf("PullImage: wrong credentials sent. Want %#v. Got %#v.", expected, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"io"
+	"sync"
+)
+
+// defaultBufferSize is the size of the buffers used to copy streams, when
+// not specified.
+const defaultBufferSize = 32 * 1024
+
+// BufferPool is a pool of the buffers used to copy the streams of the API,
+// like the output of attach, exec and logs, or archives. Assigned to the
+// BufferPool field of a Client, it saves the allocation of a buffer for each
+// stream, which adds up with many concurrent streams.
+type BufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+// NewBufferPool returns a pool of buffers of the given size, which is also
+// the size of the chunks read from the streams. It defaults to 32KB.
+func NewBufferPool(size int) *BufferPool {
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+	p := BufferPool{size: size}
+	p.pool.New = func() interface{} {
+		return make([]byte, p.size)
+	}
+	return &p
+}
+
+// Get returns a buffer from the pool, allocating it if the pool is empty.
+func (p *BufferPool) Get() []byte {
+	return p.pool.Get().([]byte)
+}
+
+// Put returns a buffer to the pool. Buffers of another size are dropped.
+func (p *BufferPool) Put(buf []byte) {
+	if len(buf) == p.size {
+		p.pool.Put(buf)
+	}
+}
+
+// getBuffer returns a buffer for copying a stream, from the pool of the
+// client if it has one.
+func (c *Client) getBuffer() []byte {
+	if c.BufferPool != nil {
+		return c.BufferPool.Get()
+	}
+	return make([]byte, defaultBufferSize)
+}
+
+func (c *Client) putBuffer(buf []byte) {
+	if c.BufferPool != nil {
+		c.BufferPool.Put(buf)
+	}
+}
+
+// copyStream copies src to dst, using a buffer of the client.
+func (c *Client) copyStream(dst io.Writer, src io.Reader) (int64, error) {
+	buf := c.getBuffer()
+	defer c.putBuffer(buf)
+	return copyBuffer(dst, src, buf)
+}
+
+// stdCopyStream demultiplexes src into stdout and stderr, like stdCopy, using
+// a buffer of the client.
+func (c *Client) stdCopyStream(stdout, stderr io.Writer, src io.Reader) (int64, error) {
+	buf := c.getBuffer()
+	defer c.putBuffer(buf)
+	return stdCopyBuffer(stdout, stderr, src, buf)
+}
+
+// copyBuffer copies src to dst through the given buffer. Unlike io.Copy, it
+// never allocates.
+func copyBuffer(dst io.Writer, src io.Reader, buf []byte) (written int64, err error) {
+	for {
+		nr, er := src.Read(buf)
+		if nr > 0 {
+			nw, ew := dst.Write(buf[:nr])
+			written += int64(nw)
+			if ew != nil {
+				return written, ew
+			}
+			if nw != nr {
+				return written, io.ErrShortWrite
+			}
+		}
+		if er == io.EOF {
+			return written, nil
+		}
+		if er != nil {
+			return written, er
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNewBufferPool(t *testing.T) {
+	var tests = []struct {
+		size     int
+		expected int
+	}{
+		{0, defaultBufferSize},
+		{-1, defaultBufferSize},
+		{4096, 4096},
+	}
+	for _, tt := range tests {
+		pool := NewBufferPool(tt.size)
+		if got := len(pool.Get()); got != tt.expected {
+			t.Errorf("NewBufferPool(%d): wrong buffer size. Want %d. Got %d.", tt.size, tt.expected, got)
+		}
+	}
+}
+
+func TestBufferPoolPutDropsOtherSizes(t *testing.T) {
+	pool := NewBufferPool(16)
+	pool.Put(make([]byte, 32))
+	for i := 0; i < 10; i++ {
+		if got := len(pool.Get()); got != 16 {
+			t.Fatalf("BufferPool.Get: wrong buffer size. Want 16. Got %d.", got)
+		}
+	}
+}
+
+func TestCopyBuffer(t *testing.T) {
+	input := strings.Repeat("docker", 100)
+	var out bytes.Buffer
+	n, err := copyBuffer(&out, iotest.OneByteReader(strings.NewReader(input)), make([]byte, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(input)) || out.String() != input {
+		t.Errorf("copyBuffer: wrong output. Want %d bytes. Got %d.", len(input), n)
+	}
+}
+
+func TestStdCopyBufferGrowsForLargeFrames(t *testing.T) {
+	var input, stdout, stderr bytes.Buffer
+	input.Write([]byte{1, 0, 0, 0, 0, 0, 0, 19})
+	input.Write([]byte("something happened!"))
+	n, err := stdCopyBuffer(&stdout, &stderr, &input, make([]byte, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 19 || stdout.String() != "something happened!" {
+		t.Errorf("stdCopyBuffer: wrong stdout. Want %q. Got %q.", "something happened!", stdout.String())
+	}
+}
+
+func TestLogsWithBufferPool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 19})
+		w.Write([]byte("something happened!"))
+		w.Write([]byte{2, 0, 0, 0, 0, 0, 0, 12})
+		w.Write([]byte("just kidding"))
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.BufferPool = NewBufferPool(64)
+	var stdout, stderr bytes.Buffer
+	for i := 0; i < 3; i++ {
+		stdout.Reset()
+		stderr.Reset()
+		opts := LogsOptions{Container: "a123456", OutputStream: &stdout, ErrorStream: &stderr, Stdout: true, Stderr: true}
+		if err := client.Logs(opts); err != nil {
+			t.Fatal(err)
+		}
+		if stdout.String() != "something happened!" || stderr.String() != "just kidding" {
+			t.Errorf("Logs: wrong output. Got %q and %q.", stdout.String(), stderr.String())
+		}
+	}
+}
@@ -1,11 +1,13 @@
 package docker
 
 import (
+	"archive/tar"
 	"bytes"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -131,6 +133,236 @@ func TestBuildImageSendXRegistryConfig(t *testing.T) {
 	}
 }
 
+func TestBuildImageFromDockerfile(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	opts := BuildImageOptions{
+		Name:         "testImage",
+		Dockerfile:   "Dockerfile.test",
+		OutputStream: &buf,
+	}
+	dockerfile := "FROM busybox\nADD app/run.sh /run.sh\n"
+	files := map[string][]byte{"app/run.sh": []byte("#!/bin/sh\necho hi\n")}
+	if err := client.BuildImageFromDockerfile(dockerfile, files, opts); err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	if contentType := req.Header.Get("Content-Type"); contentType != "application/tar" {
+		t.Errorf("BuildImageFromDockerfile: wrong Content-Type. Want %q. Got %q.", "application/tar", contentType)
+	}
+	if got := req.URL.Query().Get("dockerfile"); got != "Dockerfile.test" {
+		t.Errorf("BuildImageFromDockerfile: wrong dockerfile parameter. Want %q. Got %q.", "Dockerfile.test", got)
+	}
+	got := make(map[string]string)
+	tr := tar.NewReader(req.Body)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[header.Name] = string(data)
+	}
+	expected := map[string]string{
+		"Dockerfile.test": dockerfile,
+		"app/run.sh":      "#!/bin/sh\necho hi\n",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("BuildImageFromDockerfile: wrong context. Want %#v. Got %#v.", expected, got)
+	}
+}
+
+func TestBuildImageFromDockerfileMultipleContextsError(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "", status: http.StatusOK})
+	var buf bytes.Buffer
+	opts := BuildImageOptions{OutputStream: &buf, ContextDir: "testing/data"}
+	err := client.BuildImageFromDockerfile("FROM busybox", nil, opts)
+	if err != ErrMultipleContexts {
+		t.Errorf("BuildImageFromDockerfile: wrong error. Want %#v. Got %#v.", ErrMultipleContexts, err)
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	var tests = []struct {
+		patterns []string
+		path     string
+		expected bool
+	}{
+		{[]string{"foofile"}, "foofile", true},
+		{[]string{"foofile"}, "barfile", false},
+		{[]string{"*.tar"}, "container.tar", true},
+		{[]string{"*.tar"}, "dir/container.tar", false},
+		{[]string{"*/*.tar"}, "dir/container.tar", true},
+		{[]string{"node_modules"}, "node_modules/lib/index.js", true},
+		{[]string{"/node_modules/"}, "node_modules", true},
+		{[]string{"**/node_modules"}, "app/web/node_modules/x", true},
+		{[]string{"**/node_modules"}, "node_modules", true},
+		{[]string{"docs/**/*.md"}, "docs/a/b/README.md", true},
+		{[]string{"docs/**/*.md"}, "docs/README.md", true},
+		{[]string{"*.md", "!README.md"}, "README.md", false},
+		{[]string{"*.md", "!README.md"}, "CHANGES.md", true},
+		{[]string{"*.md", "!README.md", "README*"}, "README.md", true},
+		{[]string{"# comment", "", "  tmp  "}, "tmp/file", true},
+		{[]string{"# comment"}, "# comment", false},
+		{[]string{"file?.txt"}, "file1.txt", true},
+		{[]string{"file[0-9].txt"}, "filea.txt", false},
+		{[]string{"file[^0-9].txt"}, "filea.txt", true},
+	}
+	for _, tt := range tests {
+		m, err := newIgnoreMatcher(tt.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.matches(tt.path); got != tt.expected {
+			t.Errorf("ignoreMatcher(%q).matches(%q): wrong result. Want %v. Got %v.", tt.patterns, tt.path, tt.expected, got)
+		}
+	}
+}
+
+func TestIgnoreMatcherBadPattern(t *testing.T) {
+	for _, pattern := range []string{"[", "!"} {
+		if _, err := newIgnoreMatcher([]string{pattern}); err == nil {
+			t.Errorf("newIgnoreMatcher(%q): expected error, got <nil>", pattern)
+		}
+	}
+}
+
+func TestBuildImageContextDirDockerignoreExceptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		".dockerignore":             "*\n!src\nsrc/*.tmp\n",
+		"Dockerfile.dev":            "FROM busybox",
+		"README.md":                 "readme",
+		"node_modules/lib/index.js": "module",
+		"src/main.go":               "package main",
+		"src/build.tmp":             "tmp",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	opts := BuildImageOptions{
+		Name:         "testImage",
+		Dockerfile:   "Dockerfile.dev",
+		OutputStream: &buf,
+		ContextDir:   dir,
+	}
+	if err := client.BuildImage(opts); err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	tr := tar.NewReader(fakeRT.requests[0].Body)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		found = append(found, header.Name)
+	}
+	expected := []string{".dockerignore", "Dockerfile.dev", "src/", "src/main.go"}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("BuildImage: incorrect files sent in tarball to docker server\nexpected %+v, found %+v", expected, found)
+	}
+}
+
+func TestBuildImageCacheOptions(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	buildArgs := map[string]string{"VERSION": "1.0"}
+	opts := BuildImageOptions{
+		Name:         "testImage",
+		InputStream:  &buf,
+		OutputStream: &buf,
+		BuildArgs:    buildArgs,
+		CacheFrom:    []string{"registry.example.com/app:cache", "app:latest"},
+		InlineCache:  true,
+		Version:      BuilderBuildKit,
+	}
+	if err := client.BuildImage(opts); err != nil {
+		t.Fatal(err)
+	}
+	query := fakeRT.requests[0].URL.Query()
+	if got, expected := query.Get("cachefrom"), `["registry.example.com/app:cache","app:latest"]`; got != expected {
+		t.Errorf("BuildImage: wrong cachefrom. Want %q. Got %q.", expected, got)
+	}
+	if got, expected := query.Get("buildargs"), `{"BUILDKIT_INLINE_CACHE":"1","VERSION":"1.0"}`; got != expected {
+		t.Errorf("BuildImage: wrong buildargs. Want %q. Got %q.", expected, got)
+	}
+	if got := query.Get("version"); got != "2" {
+		t.Errorf("BuildImage: wrong version. Want %q. Got %q.", "2", got)
+	}
+	if len(buildArgs) != 1 {
+		t.Errorf("BuildImage: should not modify the given BuildArgs. Got %v.", buildArgs)
+	}
+}
+
+func TestBuildImageBuildID(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	opts := BuildImageOptions{
+		Name:         "testImage",
+		InputStream:  &buf,
+		OutputStream: &buf,
+		BuildID:      "build-123",
+	}
+	if err := client.BuildImage(opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := fakeRT.requests[0].URL.Query().Get("buildid"); got != "build-123" {
+		t.Errorf("BuildImage: wrong buildid. Want %q. Got %q.", "build-123", got)
+	}
+}
+
+func TestCancelBuild(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	if err := client.CancelBuild("build-123"); err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != "POST" {
+		t.Errorf("CancelBuild: wrong HTTP method. Want %q. Got %q.", "POST", req.Method)
+	}
+	if req.URL.Path != "/build/cancel" {
+		t.Errorf("CancelBuild: wrong path. Want %q. Got %q.", "/build/cancel", req.URL.Path)
+	}
+	if got := req.URL.Query().Get("id"); got != "build-123" {
+		t.Errorf("CancelBuild: wrong id. Want %q. Got %q.", "build-123", got)
+	}
+}
+
+func TestCancelBuildFailure(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such build", status: http.StatusNotFound})
+	err := client.CancelBuild("build-123")
+	if e, ok := err.(*Error); !ok || e.Status != http.StatusNotFound {
+		t.Errorf("CancelBuild: wrong error. Want *Error with status 404. Got %#v.", err)
+	}
+}
+
 func unpackBodyTarball(req io.ReadCloser) (tmpdir string, err error) {
 	tmpdir, err = ioutil.TempDir("", "go-dockerclient-test")
 	if err != nil {
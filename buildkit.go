@@ -0,0 +1,296 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// buildkitTraceID is the ID of the messages carrying BuildKit status updates
+// in the output of a build.
+const buildkitTraceID = "moby.buildkit.trace"
+
+var errInvalidProtobuf = errors.New("invalid BuildKit status message")
+
+// BuildStatus is a status update sent by the BuildKit builder while an image
+// is being built.
+type BuildStatus struct {
+	Vertexes []*Vertex
+	Statuses []*VertexStatus
+	Logs     []*VertexLog
+}
+
+// Vertex is a step of a BuildKit build.
+type Vertex struct {
+	Digest    string
+	Inputs    []string
+	Name      string
+	Cached    bool
+	Started   *time.Time
+	Completed *time.Time
+	Error     string
+}
+
+// VertexStatus reports the progress of a task within a step of a BuildKit
+// build, like the download of a layer.
+type VertexStatus struct {
+	ID        string
+	Vertex    string
+	Name      string
+	Current   int64
+	Total     int64
+	Timestamp time.Time
+	Started   *time.Time
+	Completed *time.Time
+}
+
+// VertexLog is a chunk of output of a step of a BuildKit build. Stream is 1
+// for the standard output and 2 for the standard error.
+type VertexLog struct {
+	Vertex    string
+	Timestamp time.Time
+	Stream    int
+	Data      []byte
+}
+
+// decodeBuildStatus decodes the aux field of a BuildKit trace message, which
+// holds a protobuf-encoded StatusResponse.
+func decodeBuildStatus(aux json.RawMessage) (*BuildStatus, error) {
+	var data []byte
+	if err := json.Unmarshal(aux, &data); err != nil {
+		return nil, err
+	}
+	var status BuildStatus
+	err := decodeProtobuf(data, func(field int, b *protobufBuffer) error {
+		switch field {
+		case 1:
+			var v Vertex
+			if err := b.message(v.decode); err != nil {
+				return err
+			}
+			status.Vertexes = append(status.Vertexes, &v)
+		case 2:
+			var s VertexStatus
+			if err := b.message(s.decode); err != nil {
+				return err
+			}
+			status.Statuses = append(status.Statuses, &s)
+		case 3:
+			var l VertexLog
+			if err := b.message(l.decode); err != nil {
+				return err
+			}
+			status.Logs = append(status.Logs, &l)
+		default:
+			return b.skip()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (v *Vertex) decode(field int, b *protobufBuffer) error {
+	var err error
+	switch field {
+	case 1:
+		v.Digest, err = b.string()
+	case 2:
+		var input string
+		input, err = b.string()
+		v.Inputs = append(v.Inputs, input)
+	case 3:
+		v.Name, err = b.string()
+	case 4:
+		var cached uint64
+		cached, err = b.varint()
+		v.Cached = cached != 0
+	case 5:
+		v.Started, err = b.timestamp()
+	case 6:
+		v.Completed, err = b.timestamp()
+	case 7:
+		v.Error, err = b.string()
+	default:
+		err = b.skip()
+	}
+	return err
+}
+
+func (s *VertexStatus) decode(field int, b *protobufBuffer) error {
+	var err error
+	switch field {
+	case 1:
+		s.ID, err = b.string()
+	case 2:
+		s.Vertex, err = b.string()
+	case 3:
+		s.Name, err = b.string()
+	case 4:
+		var current uint64
+		current, err = b.varint()
+		s.Current = int64(current)
+	case 5:
+		var total uint64
+		total, err = b.varint()
+		s.Total = int64(total)
+	case 6:
+		var ts *time.Time
+		if ts, err = b.timestamp(); ts != nil {
+			s.Timestamp = *ts
+		}
+	case 7:
+		s.Started, err = b.timestamp()
+	case 8:
+		s.Completed, err = b.timestamp()
+	default:
+		err = b.skip()
+	}
+	return err
+}
+
+func (l *VertexLog) decode(field int, b *protobufBuffer) error {
+	var err error
+	switch field {
+	case 1:
+		l.Vertex, err = b.string()
+	case 2:
+		var ts *time.Time
+		if ts, err = b.timestamp(); ts != nil {
+			l.Timestamp = *ts
+		}
+	case 3:
+		var stream uint64
+		stream, err = b.varint()
+		l.Stream = int(stream)
+	case 4:
+		l.Data, err = b.bytes()
+	default:
+		err = b.skip()
+	}
+	return err
+}
+
+// protobufBuffer is a minimal decoder for the protobuf wire format, enough to
+// read BuildKit status messages without depending on a protobuf library.
+type protobufBuffer struct {
+	data     []byte
+	wireType int
+}
+
+// decodeProtobuf calls fn for each field of the given message. fn must
+// consume the value of the field, using the methods of the buffer.
+func decodeProtobuf(data []byte, fn func(field int, b *protobufBuffer) error) error {
+	b := protobufBuffer{data: data}
+	for len(b.data) > 0 {
+		key, err := b.readVarint()
+		if err != nil {
+			return err
+		}
+		b.wireType = int(key & 7)
+		if err := fn(int(key>>3), &b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *protobufBuffer) readVarint() (uint64, error) {
+	var v uint64
+	for i := uint(0); i < 10 && i < uint(len(b.data)); i++ {
+		c := b.data[i]
+		v |= uint64(c&0x7f) << (7 * i)
+		if c < 0x80 {
+			b.data = b.data[i+1:]
+			return v, nil
+		}
+	}
+	return 0, errInvalidProtobuf
+}
+
+func (b *protobufBuffer) varint() (uint64, error) {
+	if b.wireType != 0 {
+		return 0, errInvalidProtobuf
+	}
+	return b.readVarint()
+}
+
+func (b *protobufBuffer) bytes() ([]byte, error) {
+	if b.wireType != 2 {
+		return nil, errInvalidProtobuf
+	}
+	n, err := b.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(b.data)) {
+		return nil, errInvalidProtobuf
+	}
+	v := b.data[:n]
+	b.data = b.data[n:]
+	return v, nil
+}
+
+func (b *protobufBuffer) string() (string, error) {
+	v, err := b.bytes()
+	return string(v), err
+}
+
+func (b *protobufBuffer) message(fn func(field int, b *protobufBuffer) error) error {
+	v, err := b.bytes()
+	if err != nil {
+		return err
+	}
+	return decodeProtobuf(v, fn)
+}
+
+// timestamp reads a google.protobuf.Timestamp message.
+func (b *protobufBuffer) timestamp() (*time.Time, error) {
+	var seconds, nanos uint64
+	err := b.message(func(field int, b *protobufBuffer) error {
+		var err error
+		switch field {
+		case 1:
+			seconds, err = b.varint()
+		case 2:
+			nanos, err = b.varint()
+		default:
+			err = b.skip()
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	t := time.Unix(int64(seconds), int64(int32(nanos))).UTC()
+	return &t, nil
+}
+
+func (b *protobufBuffer) skip() error {
+	var n int
+	switch b.wireType {
+	case 0:
+		_, err := b.readVarint()
+		return err
+	case 1:
+		n = 8
+	case 2:
+		_, err := b.bytes()
+		return err
+	case 5:
+		n = 4
+	default:
+		return errInvalidProtobuf
+	}
+	if n > len(b.data) {
+		return errInvalidProtobuf
+	}
+	b.data = b.data[n:]
+	return nil
+}
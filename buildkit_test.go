@@ -0,0 +1,178 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// protobufMessage is a minimal protobuf encoder, used to build BuildKit
+// status messages in tests.
+type protobufMessage struct {
+	bytes.Buffer
+}
+
+func (m *protobufMessage) varint(v uint64) {
+	for v >= 0x80 {
+		m.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	m.WriteByte(byte(v))
+}
+
+func (m *protobufMessage) uintField(field int, v uint64) *protobufMessage {
+	m.varint(uint64(field<<3 | 0))
+	m.varint(v)
+	return m
+}
+
+func (m *protobufMessage) bytesField(field int, v []byte) *protobufMessage {
+	m.varint(uint64(field<<3 | 2))
+	m.varint(uint64(len(v)))
+	m.Write(v)
+	return m
+}
+
+func (m *protobufMessage) stringField(field int, v string) *protobufMessage {
+	return m.bytesField(field, []byte(v))
+}
+
+func (m *protobufMessage) messageField(field int, v *protobufMessage) *protobufMessage {
+	return m.bytesField(field, v.Bytes())
+}
+
+func (m *protobufMessage) timestampField(field int, t time.Time) *protobufMessage {
+	ts := new(protobufMessage).uintField(1, uint64(t.Unix())).uintField(2, uint64(t.Nanosecond()))
+	return m.messageField(field, ts)
+}
+
+func buildkitTraceMessage(t *testing.T, status *protobufMessage) string {
+	aux, err := json.Marshal(status.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := json.Marshal(map[string]interface{}{"id": buildkitTraceID, "aux": json.RawMessage(aux)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(msg)
+}
+
+func TestDecodeBuildStatus(t *testing.T) {
+	started := time.Date(2015, 4, 23, 10, 0, 0, 500, time.UTC)
+	completed := started.Add(2 * time.Second)
+	vertex := new(protobufMessage).
+		stringField(1, "sha256:abc").
+		stringField(2, "sha256:in1").
+		stringField(2, "sha256:in2").
+		stringField(3, "[1/2] FROM busybox").
+		uintField(4, 1).
+		timestampField(5, started).
+		timestampField(6, completed).
+		stringField(7, "failed").
+		stringField(8, "unknown field")
+	vertexStatus := new(protobufMessage).
+		stringField(1, "extracting").
+		stringField(2, "sha256:abc").
+		stringField(3, "layer").
+		uintField(4, 512).
+		uintField(5, 1024).
+		timestampField(6, started).
+		timestampField(7, started)
+	vertexLog := new(protobufMessage).
+		stringField(1, "sha256:abc").
+		timestampField(2, completed).
+		uintField(3, 2).
+		bytesField(4, []byte("oops\n"))
+	status := new(protobufMessage).
+		messageField(1, vertex).
+		messageField(2, vertexStatus).
+		messageField(3, vertexLog).
+		uintField(9, 42)
+	aux, _ := json.Marshal(status.Bytes())
+	got, err := decodeBuildStatus(aux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &BuildStatus{
+		Vertexes: []*Vertex{{
+			Digest:    "sha256:abc",
+			Inputs:    []string{"sha256:in1", "sha256:in2"},
+			Name:      "[1/2] FROM busybox",
+			Cached:    true,
+			Started:   &started,
+			Completed: &completed,
+			Error:     "failed",
+		}},
+		Statuses: []*VertexStatus{{
+			ID:        "extracting",
+			Vertex:    "sha256:abc",
+			Name:      "layer",
+			Current:   512,
+			Total:     1024,
+			Timestamp: started,
+			Started:   &started,
+		}},
+		Logs: []*VertexLog{{
+			Vertex:    "sha256:abc",
+			Timestamp: completed,
+			Stream:    2,
+			Data:      []byte("oops\n"),
+		}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("decodeBuildStatus: wrong result.\nWant %#v.\nGot %#v.", expected, got)
+	}
+}
+
+func TestDecodeBuildStatusInvalid(t *testing.T) {
+	truncated := new(protobufMessage).stringField(1, "sha256:abc").Bytes()
+	status := new(protobufMessage).bytesField(1, truncated[:len(truncated)-2])
+	aux, _ := json.Marshal(status.Bytes()[:status.Len()-1])
+	if _, err := decodeBuildStatus(aux); err != errInvalidProtobuf {
+		t.Errorf("decodeBuildStatus: wrong error. Want %#v. Got %#v.", errInvalidProtobuf, err)
+	}
+}
+
+func TestBuildImageStatusChan(t *testing.T) {
+	vertex := new(protobufMessage).stringField(1, "sha256:abc").stringField(3, "RUN make")
+	body := `{"stream":"Step 1"}` + "\n" +
+		buildkitTraceMessage(t, new(protobufMessage).messageField(1, vertex)) + "\n" +
+		`{"id":"moby.image.id","aux":{"ID":"sha256:def"}}` + "\n"
+	fakeRT := &FakeRoundTripper{
+		message: body,
+		status:  http.StatusOK,
+		header:  map[string]string{"Content-Type": "application/json"},
+	}
+	client := newTestClient(fakeRT)
+	statusChan := make(chan *BuildStatus, 10)
+	var buf bytes.Buffer
+	opts := BuildImageOptions{
+		Name:         "testImage",
+		InputStream:  &buf,
+		OutputStream: &buf,
+		Version:      BuilderBuildKit,
+		StatusChan:   statusChan,
+	}
+	if err := client.BuildImage(opts); err != nil {
+		t.Fatal(err)
+	}
+	var statuses []*BuildStatus
+	for status := range statusChan {
+		statuses = append(statuses, status)
+	}
+	expected := []*BuildStatus{{Vertexes: []*Vertex{{Digest: "sha256:abc", Name: "RUN make"}}}}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("BuildImage: wrong statuses.\nWant %#v.\nGot %#v.", expected, statuses)
+	}
+	if got := buf.String(); got != "Step 1" {
+		t.Errorf("BuildImage: wrong output. Want %q. Got %q.", "Step 1", got)
+	}
+}
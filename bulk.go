@@ -0,0 +1,147 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultBulkConcurrency is the number of containers handled at the same time
+// by the bulk operations, when not specified.
+const defaultBulkConcurrency = 4
+
+// MultiError is returned by the bulk operations when some of the resources
+// could not be handled. Errors maps the ID or name of each of these resources
+// to the error of its operation.
+type MultiError struct {
+	Errors map[string]error
+}
+
+func (e *MultiError) Error() string {
+	ids := make([]string, 0, len(e.Errors))
+	for id := range e.Errors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	msgs := make([]string, len(ids))
+	for i, id := range ids {
+		msgs[i] = id + ": " + e.Errors[id].Error()
+	}
+	return fmt.Sprintf("%d operations failed: %s", len(ids), strings.Join(msgs, "; "))
+}
+
+// StopAllOptions is the set of options that can be used in a call to StopAll.
+type StopAllOptions struct {
+	// Filters selects the containers to stop, as in ListContainersOptions,
+	// like {"label": {"ci=true"}}. All running containers are stopped when
+	// it's empty.
+	Filters map[string][]string
+
+	// Timeout is the number of seconds to wait for each container to stop
+	// before killing it.
+	Timeout uint
+
+	// Concurrency is the number of containers stopped at the same time. It
+	// defaults to 4.
+	Concurrency int
+}
+
+// StopAll stops the running containers matching the given filters, returning
+// the IDs of the containers that are stopped, including the ones that stopped
+// on their own meanwhile. When some of them can't be stopped, the error is a
+// *MultiError, and the other containers are still stopped.
+func (c *Client) StopAll(opts StopAllOptions) ([]string, error) {
+	containers, err := c.ListContainers(ListContainersOptions{Filters: opts.Filters})
+	if err != nil {
+		return nil, err
+	}
+	return forEachContainer(containers, opts.Concurrency, func(id string) error {
+		err := c.StopContainer(id, opts.Timeout)
+		if _, ok := err.(*ContainerNotRunning); ok {
+			return nil
+		}
+		return err
+	})
+}
+
+// RemoveAllOptions is the set of options that can be used in a call to
+// RemoveAll.
+type RemoveAllOptions struct {
+	// Filters selects the containers to remove, as in
+	// ListContainersOptions, like {"label": {"ci=true"}}. All containers
+	// are removed when it's empty.
+	Filters map[string][]string
+
+	// RemoveVolumes removes the volumes associated to the containers.
+	RemoveVolumes bool
+
+	// Force removes the containers that are running. Otherwise, removing
+	// them fails.
+	Force bool
+
+	// Concurrency is the number of containers removed at the same time. It
+	// defaults to 4.
+	Concurrency int
+}
+
+// RemoveAll removes the containers, running or not, matching the given
+// filters, returning the IDs of the containers that are gone, including the
+// ones removed by someone else meanwhile. When some of them can't be removed,
+// the error is a *MultiError, and the other containers are still removed.
+func (c *Client) RemoveAll(opts RemoveAllOptions) ([]string, error) {
+	containers, err := c.ListContainers(ListContainersOptions{All: true, Filters: opts.Filters})
+	if err != nil {
+		return nil, err
+	}
+	return forEachContainer(containers, opts.Concurrency, func(id string) error {
+		err := c.RemoveContainer(RemoveContainerOptions{ID: id, RemoveVolumes: opts.RemoveVolumes, Force: opts.Force})
+		if _, ok := err.(*NoSuchContainer); ok {
+			return nil
+		}
+		return err
+	})
+}
+
+// forEachContainer calls fn for each container, using up to concurrency
+// goroutines, and returns the IDs of the containers for which fn succeeded.
+func forEachContainer(containers []APIContainers, concurrency int, fn func(id string) error) ([]string, error) {
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+	ids := make(chan string)
+	var mut sync.Mutex
+	var wg sync.WaitGroup
+	var done []string
+	errs := make(map[string]error)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				err := fn(id)
+				mut.Lock()
+				if err != nil {
+					errs[id] = err
+				} else {
+					done = append(done, id)
+				}
+				mut.Unlock()
+			}
+		}()
+	}
+	for _, container := range containers {
+		ids <- container.ID
+	}
+	close(ids)
+	wg.Wait()
+	sort.Strings(done)
+	if len(errs) > 0 {
+		return done, &MultiError{Errors: errs}
+	}
+	return done, nil
+}
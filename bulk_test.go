@@ -0,0 +1,110 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bulkServer lists the given containers and answers the operations on them
+// with the status in statuses, 204 by default. It records the list query and
+// the maximum number of concurrent operations.
+func bulkServer(list string, statuses map[string]int) (*httptest.Server, *string, *int) {
+	var mut sync.Mutex
+	var query string
+	var inFlight, maxInFlight int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/containers/json" {
+			query = r.URL.RawQuery
+			w.Write([]byte(list))
+			return
+		}
+		mut.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mut.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mut.Lock()
+		inFlight--
+		mut.Unlock()
+		id := strings.Split(strings.TrimPrefix(r.URL.Path, "/containers/"), "/")[0]
+		status, ok := statuses[id]
+		if !ok {
+			status = http.StatusNoContent
+		}
+		w.WriteHeader(status)
+	}))
+	return server, &query, &maxInFlight
+}
+
+const bulkContainers = `[{"Id":"c1"},{"Id":"c2"},{"Id":"c3"},{"Id":"c4"},{"Id":"c5"}]`
+
+func TestStopAll(t *testing.T) {
+	statuses := map[string]int{"c2": http.StatusNotModified, "c4": http.StatusInternalServerError}
+	server, query, maxInFlight := bulkServer(bulkContainers, statuses)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped, err := client.StopAll(StopAllOptions{Filters: map[string][]string{"label": {"ci=true"}}, Concurrency: 2})
+	multi, ok := err.(*MultiError)
+	if !ok {
+		t.Fatalf("StopAll: wrong error. Want a *MultiError. Got %#v.", err)
+	}
+	if len(multi.Errors) != 1 || multi.Errors["c4"] == nil {
+		t.Errorf("StopAll: wrong errors. Got %#v.", multi.Errors)
+	}
+	expected := []string{"c1", "c2", "c3", "c5"}
+	if !reflect.DeepEqual(stopped, expected) {
+		t.Errorf("StopAll: wrong stopped containers. Want %#v. Got %#v.", expected, stopped)
+	}
+	if *query != "filters=%7B%22label%22%3A%5B%22ci%3Dtrue%22%5D%7D" {
+		t.Errorf("StopAll: wrong list query. Got %q.", *query)
+	}
+	if *maxInFlight > 2 {
+		t.Errorf("StopAll: concurrency not bounded. Want at most 2. Got %d.", *maxInFlight)
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	statuses := map[string]int{"c3": http.StatusNotFound}
+	server, query, _ := bulkServer(bulkContainers, statuses)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := client.RemoveAll(RemoveAllOptions{Force: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"c1", "c2", "c3", "c4", "c5"}
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("RemoveAll: wrong removed containers. Want %#v. Got %#v.", expected, removed)
+	}
+	if *query != "all=1" {
+		t.Errorf("RemoveAll: wrong list query. Want %q. Got %q.", "all=1", *query)
+	}
+}
+
+func TestMultiErrorMessage(t *testing.T) {
+	err := &MultiError{Errors: map[string]error{
+		"c2": &NoSuchContainer{ID: "c2"},
+		"c1": &ContainerNotRunning{ID: "c1"},
+	}}
+	expected := "2 operations failed: c1: Container not running: c1; c2: No such container: c2"
+	if err.Error() != expected {
+		t.Errorf("MultiError: wrong message. Want %q. Got %q.", expected, err.Error())
+	}
+}
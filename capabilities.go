@@ -0,0 +1,133 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import "strings"
+
+// Linux capabilities, set in HostConfig.CapAdd and HostConfig.CapDrop. CapAll
+// stands for all of them.
+const (
+	CapAll = "ALL"
+
+	CapChown             = "CHOWN"
+	CapDACOverride       = "DAC_OVERRIDE"
+	CapDACReadSearch     = "DAC_READ_SEARCH"
+	CapFowner            = "FOWNER"
+	CapFsetid            = "FSETID"
+	CapKill              = "KILL"
+	CapSetgid            = "SETGID"
+	CapSetuid            = "SETUID"
+	CapSetpcap           = "SETPCAP"
+	CapLinuxImmutable    = "LINUX_IMMUTABLE"
+	CapNetBindService    = "NET_BIND_SERVICE"
+	CapNetBroadcast      = "NET_BROADCAST"
+	CapNetAdmin          = "NET_ADMIN"
+	CapNetRaw            = "NET_RAW"
+	CapIPCLock           = "IPC_LOCK"
+	CapIPCOwner          = "IPC_OWNER"
+	CapSysModule         = "SYS_MODULE"
+	CapSysRawio          = "SYS_RAWIO"
+	CapSysChroot         = "SYS_CHROOT"
+	CapSysPtrace         = "SYS_PTRACE"
+	CapSysPacct          = "SYS_PACCT"
+	CapSysAdmin          = "SYS_ADMIN"
+	CapSysBoot           = "SYS_BOOT"
+	CapSysNice           = "SYS_NICE"
+	CapSysResource       = "SYS_RESOURCE"
+	CapSysTime           = "SYS_TIME"
+	CapSysTTYConfig      = "SYS_TTY_CONFIG"
+	CapMknod             = "MKNOD"
+	CapLease             = "LEASE"
+	CapAuditWrite        = "AUDIT_WRITE"
+	CapAuditControl      = "AUDIT_CONTROL"
+	CapSetfcap           = "SETFCAP"
+	CapMACOverride       = "MAC_OVERRIDE"
+	CapMACAdmin          = "MAC_ADMIN"
+	CapSyslog            = "SYSLOG"
+	CapWakeAlarm         = "WAKE_ALARM"
+	CapBlockSuspend      = "BLOCK_SUSPEND"
+	CapAuditRead         = "AUDIT_READ"
+	CapPerfmon           = "PERFMON"
+	CapBPF               = "BPF"
+	CapCheckpointRestore = "CHECKPOINT_RESTORE"
+)
+
+var capabilities = []string{
+	CapChown,
+	CapDACOverride,
+	CapDACReadSearch,
+	CapFowner,
+	CapFsetid,
+	CapKill,
+	CapSetgid,
+	CapSetuid,
+	CapSetpcap,
+	CapLinuxImmutable,
+	CapNetBindService,
+	CapNetBroadcast,
+	CapNetAdmin,
+	CapNetRaw,
+	CapIPCLock,
+	CapIPCOwner,
+	CapSysModule,
+	CapSysRawio,
+	CapSysChroot,
+	CapSysPtrace,
+	CapSysPacct,
+	CapSysAdmin,
+	CapSysBoot,
+	CapSysNice,
+	CapSysResource,
+	CapSysTime,
+	CapSysTTYConfig,
+	CapMknod,
+	CapLease,
+	CapAuditWrite,
+	CapAuditControl,
+	CapSetfcap,
+	CapMACOverride,
+	CapMACAdmin,
+	CapSyslog,
+	CapWakeAlarm,
+	CapBlockSuspend,
+	CapAuditRead,
+	CapPerfmon,
+	CapBPF,
+	CapCheckpointRestore,
+}
+
+// UnknownCapability is the error returned by ValidateCapabilities and
+// CreateContainer for the names that are not Linux capabilities.
+type UnknownCapability struct {
+	Name string
+}
+
+func (err *UnknownCapability) Error() string {
+	return "Unknown capability: " + err.Name
+}
+
+// ValidateCapabilities checks that the given names are Linux capabilities,
+// or ALL. Like the daemon, it accepts the names in any case, with or without
+// the CAP_ prefix.
+func ValidateCapabilities(names ...string) error {
+	for _, name := range names {
+		normalized := strings.TrimPrefix(strings.ToUpper(name), "CAP_")
+		if !containsString(capabilities, normalized) && normalized != CapAll {
+			return &UnknownCapability{Name: name}
+		}
+	}
+	return nil
+}
+
+// DropAllExcept drops all the capabilities of the container, except the given
+// ones.
+func (hostConfig *HostConfig) DropAllExcept(names ...string) error {
+	if err := ValidateCapabilities(names...); err != nil {
+		return err
+	}
+	hostConfig.CapDrop = []string{CapAll}
+	hostConfig.CapAdd = names
+	return nil
+}
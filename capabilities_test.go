@@ -0,0 +1,49 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestValidateCapabilities(t *testing.T) {
+	if err := ValidateCapabilities(CapNetAdmin, "cap_sys_ptrace", "ALL", "CAP_BPF"); err != nil {
+		t.Error(err)
+	}
+	expected := &UnknownCapability{Name: "NET_ADMIM"}
+	if err := ValidateCapabilities(CapChown, "NET_ADMIM"); !reflect.DeepEqual(err, expected) {
+		t.Errorf("ValidateCapabilities: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+}
+
+func TestDropAllExcept(t *testing.T) {
+	var hostConfig HostConfig
+	if err := hostConfig.DropAllExcept(CapChown, CapNetBindService); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hostConfig.CapDrop, []string{"ALL"}) || !reflect.DeepEqual(hostConfig.CapAdd, []string{"CHOWN", "NET_BIND_SERVICE"}) {
+		t.Errorf("DropAllExcept: wrong capabilities. Got %#v and %#v.", hostConfig.CapAdd, hostConfig.CapDrop)
+	}
+	if err := hostConfig.DropAllExcept("SETUIDD"); err == nil {
+		t.Error("DropAllExcept: expected an error")
+	}
+}
+
+func TestCreateContainerUnknownCapability(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	_, err := client.CreateContainer(CreateContainerOptions{
+		Config:     &Config{Image: "base"},
+		HostConfig: &HostConfig{CapDrop: []string{"NET_RAWW"}},
+	})
+	if _, ok := err.(*UnknownCapability); !ok {
+		t.Errorf("CreateContainer: wrong error. Want a *UnknownCapability. Got %#v.", err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("CreateContainer: the container should not be created. Got %d requests.", len(fakeRT.requests))
+	}
+}
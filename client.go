@@ -23,19 +23,25 @@ import (
 	"net/url"
 	"os"
 	gosignal "os/signal"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/docker/docker/pkg/promise"
-	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/docker/pkg/term"
 	"github.com/docker/docker/utils"
 )
 
-const userAgent = "go-dockerclient"
+const (
+	userAgent = "go-dockerclient"
+
+	defaultDockerSocket = "/var/run/docker.sock"
+)
 
 var (
 	// ErrInvalidEndpoint is returned when the endpoint is not a valid HTTP URL.
@@ -45,8 +51,75 @@ var (
 	ErrConnectionRefused = errors.New("cannot connect to Docker endpoint")
 
 	apiVersion112, _ = NewAPIVersion("1.12")
+	apiVersion125, _ = NewAPIVersion("1.25")
+	apiVersion138, _ = NewAPIVersion("1.38")
+	apiVersion141, _ = NewAPIVersion("1.41")
+	apiVersion142, _ = NewAPIVersion("1.42")
+	apiVersion144, _ = NewAPIVersion("1.44")
+	apiVersion147, _ = NewAPIVersion("1.47")
+	apiVersion148, _ = NewAPIVersion("1.48")
 )
 
+// UnsupportedField is returned when a request uses a field that is not
+// supported by the API version used by the client, instead of sending it to
+// a daemon that would silently ignore it.
+type UnsupportedField struct {
+	Field      string
+	MinVersion APIVersion
+	Version    APIVersion
+}
+
+func (err *UnsupportedField) Error() string {
+	return fmt.Sprintf("%s requires Docker API %s or newer, the client uses API %s", err.Field, err.MinVersion, err.Version)
+}
+
+// ErrConnectionFailed is returned when the client cannot connect to the unix
+// socket of the daemon, because the socket doesn't exist or because the user
+// can't access it. Err is the underlying syscall error.
+type ErrConnectionFailed struct {
+	Path string
+	UID  int
+	Err  error
+}
+
+func (err *ErrConnectionFailed) Error() string {
+	msg := fmt.Sprintf("cannot connect to the Docker daemon at unix://%s: %s", err.Path, err.Err)
+	switch err.Err {
+	case syscall.EACCES:
+		return fmt.Sprintf("%s (uid %d): add the user to the docker group, or run as root", msg, err.UID)
+	case syscall.ENOENT:
+		return msg + ": is the docker daemon running?"
+	}
+	return msg
+}
+
+// ErrReadOnly is returned by the calls of a read-only client that may change
+// the state of the daemon, which are not sent.
+type ErrReadOnly struct {
+	Method string
+	Path   string
+}
+
+func (err *ErrReadOnly) Error() string {
+	return fmt.Sprintf("read-only client: %s %s denied", err.Method, err.Path)
+}
+
+// socketError wraps the errors of dialing the unix socket at path that
+// have a known cause in an *ErrConnectionFailed.
+func socketError(path string, err error) error {
+	errno := err
+	if e, ok := errno.(*net.OpError); ok {
+		errno = e.Err
+	}
+	if e, ok := errno.(*os.SyscallError); ok {
+		errno = e.Err
+	}
+	if errno == syscall.EACCES || errno == syscall.ENOENT {
+		return &ErrConnectionFailed{Path: path, UID: os.Getuid(), Err: errno}
+	}
+	return err
+}
+
 // APIVersion is an internal representation of a version of the Remote API.
 type APIVersion []int
 
@@ -124,17 +197,83 @@ func (version APIVersion) compare(other APIVersion) int {
 
 // Client is the basic type of this package. It provides methods for
 // interaction with the API.
+//
+// A Client is safe for concurrent use by multiple goroutines, as long as its
+// exported fields are not modified after the first call to the API.
 type Client struct {
 	SkipServerVersionCheck bool
 	HTTPClient             *http.Client
 	TLSConfig              *tls.Config
 
+	// RateLimiter, when set, throttles calls to the API. Streaming
+	// endpoints are not subject to it.
+	RateLimiter *RateLimiter
+
+	// BufferPool, when set, provides the buffers used to copy streams.
+	// Otherwise, a buffer is allocated for each stream.
+	BufferPool *BufferPool
+
+	// AuthConfigs, when set, provides the credentials used by the methods
+	// that talk to a registry, like PullImage and PushImage, when they're
+	// given an empty AuthConfiguration. Credentials are selected based on
+	// the registry of the image.
+	AuthConfigs *AuthConfigurations
+
+	// AuthProvider, when set, is asked for credentials before AuthConfigs.
+	// See ECRAuthProvider.
+	AuthProvider AuthProvider
+
+	// Compat selects the adjustments made for daemons that implement the
+	// Docker API with some differences, like Podman. By default, the daemon
+	// is detected from the /version endpoint. See IsPodman.
+	Compat CompatMode
+
+	// StrictAPIVersion, when set, makes the requests setting fields newer
+	// than the API version in use fail with an *UnsupportedField, instead
+	// of leaving these fields out of the request.
+	StrictAPIVersion bool
+
+	// ReadOnly, when set, restricts the client to the GET and HEAD
+	// requests, like the ones of the inspect, list, logs, stats and
+	// events methods. The other calls fail with an *ErrReadOnly, including
+	// attaching to containers, which may write to their input.
+	ReadOnly bool
+
+	// DryRun, when set, doesn't send the requests that may change the
+	// state of the daemon, like the ones denied by ReadOnly, once they're
+	// validated: the calls succeed with an empty response, so the
+	// resources created in a dry run have no ID, and the hijacked calls,
+	// like attaching to containers, return right away. The requests are
+	// recorded by the AuditRecorder, if any, with DryRun set.
+	DryRun bool
+
+	// RequestPolicy, when set, is asked whether each request may be sent
+	// to the daemon. See EndpointPolicy.
+	RequestPolicy RequestPolicy
+
+	// AuditRecorder, when set, receives a record of each request, including
+	// the ones denied by ReadOnly and RequestPolicy. AuditLabels are added
+	// to the records, like the name of the component using the client.
+	AuditRecorder AuditRecorder
+	AuditLabels   map[string]string
+
+	// ImageVerifier, when set, verifies the images pulled by PullImage and
+	// the images of the containers created by CreateContainer.
+	ImageVerifier ImageVerifier
+
 	endpoint            string
 	endpointURL         *url.URL
 	eventMonitor        *eventMonitoringState
 	requestedAPIVersion APIVersion
 	serverAPIVersion    APIVersion
 	expectedAPIVersion  APIVersion
+	versionMut          sync.RWMutex
+	identityTokens      map[string]string
+	authMut             sync.Mutex
+	podman              *bool
+	compatMut           sync.Mutex
+	proxy               func(*http.Request) (*url.URL, error)
+	dialer              func(network, address string) (net.Conn, error)
 }
 
 // NewClient returns a Client instance ready for communication with the given
@@ -225,6 +364,7 @@ func NewVersionedTLSClient(endpoint string, cert, key, ca, apiVersionString stri
 		tlsConfig.RootCAs = caPool
 	}
 	tr := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
 		TLSClientConfig: tlsConfig,
 	}
 	if err != nil {
@@ -240,6 +380,186 @@ func NewVersionedTLSClient(endpoint string, cert, key, ca, apiVersionString stri
 	}, nil
 }
 
+// NewClientFromEnv returns a Client instance ready for communication created
+// from Docker's default logic for the environment variables DOCKER_HOST,
+// DOCKER_TLS_VERIFY and DOCKER_CERT_PATH.
+//
+// When DOCKER_HOST is not set, the client connects to the first existing
+// socket among the ones used by rootless Docker, Docker Desktop and the
+// system daemon. See DefaultDockerHost.
+func NewClientFromEnv() (*Client, error) {
+	client, err := NewVersionedClientFromEnv("")
+	if err != nil {
+		return nil, err
+	}
+	client.SkipServerVersionCheck = true
+	return client, nil
+}
+
+// NewVersionedClientFromEnv returns a Client instance ready for communication
+// created from Docker's default logic for the environment variables
+// DOCKER_HOST, DOCKER_TLS_VERIFY and DOCKER_CERT_PATH, using a specific remote
+// API version.
+func NewVersionedClientFromEnv(apiVersionString string) (*Client, error) {
+	dockerHost := os.Getenv("DOCKER_HOST")
+	if dockerHost == "" {
+		dockerHost = DefaultDockerHost()
+	}
+	if os.Getenv("DOCKER_TLS_VERIFY") == "" {
+		return NewVersionedClient(dockerHost, apiVersionString)
+	}
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if certPath == "" {
+		certPath = filepath.Join(os.Getenv("HOME"), ".docker")
+	}
+	if strings.HasPrefix(dockerHost, "tcp://") {
+		dockerHost = "https://" + strings.TrimPrefix(dockerHost, "tcp://")
+	}
+	cert := filepath.Join(certPath, "cert.pem")
+	key := filepath.Join(certPath, "key.pem")
+	ca := filepath.Join(certPath, "ca.pem")
+	return NewVersionedTLSClient(dockerHost, cert, key, ca, apiVersionString)
+}
+
+// DefaultDockerHost returns the endpoint of the local Docker daemon. It's the
+// first existing socket among:
+//
+//	$XDG_RUNTIME_DIR/docker.sock (rootless Docker)
+//	$HOME/.docker/run/docker.sock (Docker Desktop)
+//	$HOME/.docker/desktop/docker.sock (older versions of Docker Desktop)
+//	/var/run/docker.sock
+//
+// When none of them exists, unix:///var/run/docker.sock is returned.
+func DefaultDockerHost() string {
+	for _, candidate := range dockerSocketCandidates() {
+		if info, err := os.Stat(candidate); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return "unix://" + candidate
+		}
+	}
+	return "unix://" + defaultDockerSocket
+}
+
+func dockerSocketCandidates() []string {
+	var candidates []string
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "docker.sock"))
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		candidates = append(candidates,
+			filepath.Join(home, ".docker", "run", "docker.sock"),
+			filepath.Join(home, ".docker", "desktop", "docker.sock"),
+		)
+	}
+	return append(candidates, defaultDockerSocket)
+}
+
+// Endpoint returns the endpoint of the Docker daemon used by the client.
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// ensureAPIVersion negotiates the API version with the server before the
+// first call to the given path, unless the check is disabled.
+func (c *Client) ensureAPIVersion(path string) error {
+	if path == "/version" || c.SkipServerVersionCheck {
+		return nil
+	}
+	c.versionMut.Lock()
+	defer c.versionMut.Unlock()
+	if c.expectedAPIVersion != nil {
+		return nil
+	}
+	return c.checkAPIVersion()
+}
+
+// isMutating tells whether the requests of the given method may change the
+// state of the daemon.
+func isMutating(method string) bool {
+	return method != "GET" && method != "HEAD"
+}
+
+// dryRun tells whether the requests of the given method are skipped by a dry
+// run.
+func (c *Client) dryRun(method string) bool {
+	return c.DryRun && isMutating(method)
+}
+
+// dryRunResponse is the response to the requests skipped by a dry run. The
+// body of the request is consumed, as a stream may be waiting to be sent.
+func dryRunResponse(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+		if _, err := io.Copy(ioutil.Discard, req.Body); err != nil {
+			return nil, err
+		}
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+// checkRequest tells whether the client may send the given request.
+func (c *Client) checkRequest(method, path string) error {
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	if c.ReadOnly && isMutating(method) {
+		return &ErrReadOnly{Method: method, Path: path}
+	}
+	if c.RequestPolicy != nil {
+		return c.RequestPolicy.CheckRequest(method, path)
+	}
+	return nil
+}
+
+// getExpectedAPIVersion returns the API version negotiated with the server,
+// or nil if it hasn't been negotiated yet.
+func (c *Client) getExpectedAPIVersion() APIVersion {
+	c.versionMut.RLock()
+	defer c.versionMut.RUnlock()
+	return c.expectedAPIVersion
+}
+
+// apiVersion returns the API version used by the client, negotiating it with
+// the server if needed. It returns nil when the version is unknown, because
+// the check is disabled and no version was requested.
+func (c *Client) apiVersion() (APIVersion, error) {
+	if err := c.ensureAPIVersion(""); err != nil {
+		return nil, err
+	}
+	return c.currentAPIVersion(), nil
+}
+
+// currentAPIVersion returns the API version used by the client, without
+// negotiating it, or nil when it's unknown.
+func (c *Client) currentAPIVersion() APIVersion {
+	if version := c.getExpectedAPIVersion(); version != nil {
+		return version
+	}
+	return c.requestedAPIVersion
+}
+
+// requireAPIVersion returns an *UnsupportedField error when the API version
+// used by the client is older than min, the version introducing the given
+// field.
+func (c *Client) requireAPIVersion(field string, min APIVersion) error {
+	version, err := c.apiVersion()
+	if err != nil {
+		return err
+	}
+	if version != nil && version.LessThan(min) {
+		return &UnsupportedField{Field: field, MinVersion: min, Version: version}
+	}
+	return nil
+}
+
 func (c *Client) checkAPIVersion() error {
 	serverAPIVersionString, err := c.getServerAPIVersionString()
 	if err != nil {
@@ -272,6 +592,41 @@ func (c *Client) Ping() error {
 	return nil
 }
 
+const (
+	waitForDaemonMinDelay = 100 * time.Millisecond
+	waitForDaemonMaxDelay = 2 * time.Second
+)
+
+// WaitForDaemon pings the daemon until it answers, for programs that start
+// along with it. The delay between two attempts grows exponentially from 100
+// milliseconds to 2 seconds. When the daemon doesn't answer within the
+// given timeout, it returns the error of the last attempt.
+func (c *Client) WaitForDaemon(timeout time.Duration) error {
+	return waitForDaemon(c.Ping, timeout, time.Now, time.Sleep)
+}
+
+func waitForDaemon(ping func() error, timeout time.Duration, now func() time.Time, sleep func(time.Duration)) error {
+	deadline := now().Add(timeout)
+	delay := waitForDaemonMinDelay
+	for {
+		err := ping()
+		if err == nil {
+			return nil
+		}
+		left := deadline.Sub(now())
+		if left <= 0 {
+			return err
+		}
+		if delay > left {
+			delay = left
+		}
+		sleep(delay)
+		if delay *= 2; delay > waitForDaemonMaxDelay {
+			delay = waitForDaemonMaxDelay
+		}
+	}
+}
+
 func (c *Client) getServerAPIVersionString() (version string, err error) {
 	body, status, err := c.do("GET", "/version", nil, false)
 	if err != nil {
@@ -280,130 +635,191 @@ func (c *Client) getServerAPIVersionString() (version string, err error) {
 	if status != http.StatusOK {
 		return "", fmt.Errorf("Received unexpected status %d while trying to retrieve the server version", status)
 	}
-	var versionResponse map[string]string
+	var versionResponse DockerVersion
 	err = json.Unmarshal(body, &versionResponse)
 	if err != nil {
 		return "", err
 	}
-	version = versionResponse["ApiVersion"]
-	return version, nil
+	c.setPodman(isPodmanVersion(&versionResponse))
+	return versionResponse.APIVersion, nil
+}
+
+// DoOptions specify the parameters used in a call to the Do method.
+type DoOptions struct {
+	// Data is encoded as JSON and sent as the request body, when not nil.
+	Data interface{}
+
+	// ForceJSON makes Do send Data as JSON even when it's nil.
+	ForceJSON bool
+
+	// Headers contains additional headers to send along with the request.
+	Headers map[string]string
+
+	// InputStream is streamed as the request body, without being buffered
+	// in memory. It's ignored when Data is set.
+	InputStream io.Reader
+}
+
+// Do sends an arbitrary request to the Docker API, allowing callers to reach
+// endpoints that aren't wrapped by this package yet. The path may include a
+// query string and must not include the API version prefix, which is added
+// by the client.
+//
+// The caller is responsible for closing the body of the returned response.
+// Responses with a status code outside of the 2xx and 3xx ranges are
+// returned as an *Error, after the body has been consumed and closed.
+func (c *Client) Do(method, path string, opts DoOptions) (*http.Response, error) {
+	return c.doRequest(method, path, opts)
 }
 
 func (c *Client) do(method, path string, data interface{}, forceJSON bool) ([]byte, int, error) {
-	var params io.Reader
-	if data != nil || forceJSON {
-		buf, err := json.Marshal(data)
-		if err != nil {
-			return nil, -1, err
+	return c.doWithOptions(method, path, DoOptions{Data: data, ForceJSON: forceJSON})
+}
+
+// doWithOptions is like do, for requests that need more options, like
+// headers.
+func (c *Client) doWithOptions(method, path string, opts DoOptions) ([]byte, int, error) {
+	c.waitRateLimit(path)
+	resp, err := c.doRequest(method, path, opts)
+	if err != nil {
+		if e, ok := err.(*Error); ok {
+			return nil, e.Status, e
 		}
-		params = bytes.NewBuffer(buf)
+		return nil, -1, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, -1, err
+	}
+	return body, resp.StatusCode, nil
+}
+
+func (c *Client) doRequest(method, path string, opts DoOptions) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.sendRequest(method, path, opts)
+	c.audit(method, path, opts.Headers, start, resp, err)
+	return resp, err
+}
+
+func (c *Client) sendRequest(method, path string, opts DoOptions) (*http.Response, error) {
+	if err := c.checkRequest(method, path); err != nil {
+		return nil, err
+	}
+	if err := c.ensureAPIVersion(path); err != nil {
+		return nil, err
 	}
-	if path != "/version" && !c.SkipServerVersionCheck && c.expectedAPIVersion == nil {
-		err := c.checkAPIVersion()
+	params := opts.InputStream
+	if opts.Data != nil || opts.ForceJSON {
+		data := opts.Data
+		if c.StrictAPIVersion {
+			if err := checkNewerFields(data, c.currentAPIVersion()); err != nil {
+				return nil, err
+			}
+		} else {
+			data = omitNewerFields(data, c.currentAPIVersion())
+		}
+		buf, err := json.Marshal(data)
 		if err != nil {
-			return nil, -1, err
+			return nil, err
 		}
+		params = bytes.NewBuffer(buf)
 	}
 	req, err := http.NewRequest(method, c.getURL(path), params)
 	if err != nil {
-		return nil, -1, err
+		return nil, err
 	}
 	req.Header.Set("User-Agent", userAgent)
-	if data != nil {
+	if opts.Data != nil {
 		req.Header.Set("Content-Type", "application/json")
 	} else if method == "POST" {
 		req.Header.Set("Content-Type", "text/plain")
 	}
+	for key, val := range opts.Headers {
+		req.Header.Set(key, val)
+	}
+	if c.dryRun(method) {
+		return dryRunResponse(req)
+	}
 	var resp *http.Response
 	protocol := c.endpointURL.Scheme
 	address := c.endpointURL.Path
 	if protocol == "unix" {
-		dial, err := net.Dial(protocol, address)
+		dial, err := c.dial(protocol, address)
 		if err != nil {
-			return nil, -1, err
+			return nil, err
 		}
-		defer dial.Close()
 		clientconn := httputil.NewClientConn(dial, nil)
 		resp, err = clientconn.Do(req)
 		if err != nil {
-			return nil, -1, err
+			clientconn.Close()
+			return nil, err
 		}
-		defer clientconn.Close()
+		resp.Body = &clientConnBody{ReadCloser: resp.Body, conn: clientconn}
 	} else {
 		resp, err = c.HTTPClient.Do(req)
 	}
 	if err != nil {
 		if strings.Contains(err.Error(), "connection refused") {
-			return nil, -1, ErrConnectionRefused
+			return nil, ErrConnectionRefused
 		}
-		return nil, -1, err
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, -1, err
+		return nil, err
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-		return nil, resp.StatusCode, newError(resp.StatusCode, body)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, newError(resp.StatusCode, body)
 	}
-	return body, resp.StatusCode, nil
+	return resp, nil
+}
+
+// clientConnBody is the body of a response read from a connection that was
+// dialed for a single request. Closing it also closes the connection.
+type clientConnBody struct {
+	io.ReadCloser
+	conn *httputil.ClientConn
 }
 
-func (c *Client) stream(method, path string, setRawTerminal, rawJSONStream bool, headers map[string]string, in io.Reader, stdout, stderr io.Writer) error {
+func (b *clientConnBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.conn.Close()
+	return err
+}
+
+// streamOptions specify the parameters of a call to the stream method.
+type streamOptions struct {
+	setRawTerminal bool
+	rawJSONStream  bool
+	headers        map[string]string
+	in             io.Reader
+	stdout         io.Writer
+	stderr         io.Writer
+
+	// messages, when set, is called for each message of a JSON stream,
+	// before it's written to stdout. It's not called for raw JSON streams.
+	messages func(m *JSONMessage) error
+}
+
+func (c *Client) stream(method, path string, streamOpts streamOptions) error {
+	in := streamOpts.in
 	if (method == "POST" || method == "PUT") && in == nil {
 		in = bytes.NewReader(nil)
 	}
-	if path != "/version" && !c.SkipServerVersionCheck && c.expectedAPIVersion == nil {
-		err := c.checkAPIVersion()
-		if err != nil {
-			return err
-		}
-	}
-	req, err := http.NewRequest(method, c.getURL(path), in)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("User-Agent", userAgent)
-	if method == "POST" {
-		req.Header.Set("Content-Type", "text/plain")
-	}
-	for key, val := range headers {
-		req.Header.Set(key, val)
-	}
-	var resp *http.Response
-	protocol := c.endpointURL.Scheme
-	address := c.endpointURL.Path
+	stdout, stderr := streamOpts.stdout, streamOpts.stderr
 	if stdout == nil {
 		stdout = ioutil.Discard
 	}
 	if stderr == nil {
 		stderr = ioutil.Discard
 	}
-	if protocol == "unix" {
-		dial, err := net.Dial(protocol, address)
-		if err != nil {
-			return err
-		}
-		clientconn := httputil.NewClientConn(dial, nil)
-		resp, err = clientconn.Do(req)
-		defer clientconn.Close()
-	} else {
-		resp, err = c.HTTPClient.Do(req)
-	}
+	resp, err := c.doRequest(method, path, DoOptions{Headers: streamOpts.headers, InputStream: in})
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") {
-			return ErrConnectionRefused
-		}
 		return err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-		return newError(resp.StatusCode, body)
-	}
 	ct := resp.Header.Get("Content-Type")
 	if ct == "application/json" || ct == "application/x-json-stream" {
 		var (
@@ -414,23 +830,28 @@ func (c *Client) stream(method, path string, setRawTerminal, rawJSONStream bool,
 			outFd = file.Fd()
 			isTerminalOut = term.IsTerminal(outFd)
 		}
-		if isTerminalOut {
+		if isTerminalOut && streamOpts.messages == nil {
 			return utils.DisplayJSONMessagesStream(resp.Body, stdout, outFd, isTerminalOut)
 		}
 		// if we want to get raw json stream, just copy it back to output
 		// without decoding it
-		if rawJSONStream {
-			_, err = io.Copy(stdout, resp.Body)
+		if streamOpts.rawJSONStream {
+			_, err = c.copyStream(stdout, resp.Body)
 			return err
 		}
 		dec := json.NewDecoder(resp.Body)
 		for {
-			var m jsonMessage
+			var m JSONMessage
 			if err := dec.Decode(&m); err == io.EOF {
 				break
 			} else if err != nil {
 				return err
 			}
+			if streamOpts.messages != nil {
+				if err := streamOpts.messages(&m); err != nil {
+					return err
+				}
+			}
 			if m.Stream != "" {
 				fmt.Fprint(stdout, m.Stream)
 			} else if m.Progress != "" {
@@ -446,10 +867,10 @@ func (c *Client) stream(method, path string, setRawTerminal, rawJSONStream bool,
 
 		if stdout != nil || stderr != nil {
 			// When TTY is ON, use regular copy
-			if setRawTerminal {
-				_, err = io.Copy(stdout, resp.Body)
+			if streamOpts.setRawTerminal {
+				_, err = c.copyStream(stdout, resp.Body)
 			} else {
-				_, err = stdcopy.StdCopy(stdout, stderr, resp.Body)
+				_, err = c.stdCopyStream(stdout, stderr, resp.Body)
 			}
 			return err
 		}
@@ -457,12 +878,48 @@ func (c *Client) stream(method, path string, setRawTerminal, rawJSONStream bool,
 	return nil
 }
 
-func (c *Client) hijack(method, path string, success chan struct{}, setRawTerminal bool, in io.Reader, stderr, stdout io.Writer, data interface{}) error {
-	if path != "/version" && !c.SkipServerVersionCheck && c.expectedAPIVersion == nil {
-		err := c.checkAPIVersion()
-		if err != nil {
-			return err
+// progressWriter is an io.Writer that reports the number of bytes written
+// so far after every write. The counter may be shared between writers.
+type progressWriter struct {
+	w        io.Writer
+	written  *int64
+	progress func(int64)
+}
+
+// newProgressWriter wraps w in a progressWriter, or returns w untouched when
+// there's no progress function.
+func newProgressWriter(w io.Writer, progress func(int64)) io.Writer {
+	if progress == nil || w == nil {
+		return w
+	}
+	return &progressWriter{w: w, written: new(int64), progress: progress}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	if w.w == nil {
+		return len(p), nil
+	}
+	n, err := w.w.Write(p)
+	*w.written += int64(n)
+	w.progress(*w.written)
+	return n, err
+}
+
+func (c *Client) hijack(method, path string, success chan struct{}, setRawTerminal bool, in io.Reader, stderr, stdout io.Writer, data interface{}) (err error) {
+	start := time.Now()
+	defer func() { c.audit(method, path, nil, start, nil, err) }()
+	if err := c.checkRequest(method, path); err != nil {
+		return err
+	}
+	if err := c.ensureAPIVersion(path); err != nil {
+		return err
+	}
+	if c.dryRun(method) {
+		if success != nil {
+			success <- struct{}{}
+			<-success
 		}
+		return nil
 	}
 
 	var params io.Reader
@@ -485,23 +942,9 @@ func (c *Client) hijack(method, path string, success chan struct{}, setRawTermin
 		return err
 	}
 	req.Header.Set("Content-Type", "text/plain")
-	protocol := c.endpointURL.Scheme
-	address := c.endpointURL.Path
-	if protocol != "unix" {
-		protocol = "tcp"
-		address = c.endpointURL.Host
-	}
-	var dial net.Conn
-	if c.TLSConfig != nil && protocol != "unix" {
-		dial, err = tlsDial(protocol, address, c.TLSConfig)
-		if err != nil {
-			return err
-		}
-	} else {
-		dial, err = net.Dial(protocol, address)
-		if err != nil {
-			return err
-		}
+	dial, err := c.dialHijack(req)
+	if err != nil {
+		return err
 	}
 	clientconn := httputil.NewClientConn(dial, nil)
 	defer clientconn.Close()
@@ -512,22 +955,31 @@ func (c *Client) hijack(method, path string, success chan struct{}, setRawTermin
 	}
 	rwc, br := clientconn.Hijack()
 	defer rwc.Close()
+	if setRawTerminal && in != nil && os.Getenv("NORAW") == "" {
+		terminal, err := makeRawTerminal(in)
+		if err != nil && err != ErrNotTerminal {
+			return err
+		}
+		if terminal != nil {
+			defer terminal.Restore()
+		}
+	}
 	errs := make(chan error, 2)
 	exit := make(chan bool)
 	go func() {
 		defer close(exit)
 		var err error
 		if setRawTerminal {
-			_, err = io.Copy(stdout, br)
+			_, err = c.copyStream(stdout, br)
 		} else {
-			_, err = stdcopy.StdCopy(stdout, stderr, br)
+			_, err = c.stdCopyStream(stdout, stderr, br)
 		}
 		errs <- err
 	}()
 	go func() {
 		var err error
 		if in != nil {
-			_, err = io.Copy(rwc, in)
+			_, err = c.copyStream(rwc, in)
 		}
 		rwc.(interface {
 			CloseWrite() error
@@ -538,6 +990,30 @@ func (c *Client) hijack(method, path string, success chan struct{}, setRawTermin
 	return <-errs
 }
 
+// exists sends a GET request to the given path, reporting a 404 response as
+// false rather than as an error. The response body is not read.
+func (c *Client) exists(path string) (bool, error) {
+	c.waitRateLimit(path)
+	resp, err := c.doRequest("GET", path, DoOptions{})
+	if e, ok := err.(*Error); ok && e.Status == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// waitRateLimit blocks until the RateLimiter of the client, if any, allows a
+// call to the given path. The calls that use doRequest directly, rather than
+// doWithOptions, wait for it themselves.
+func (c *Client) waitRateLimit(path string) {
+	if c.RateLimiter != nil {
+		c.RateLimiter.Wait(path)
+	}
+}
+
 func (c *Client) getURL(path string) string {
 	urlStr := strings.TrimRight(c.endpointURL.String(), "/")
 	if c.endpointURL.Scheme == "unix" {
@@ -550,11 +1026,131 @@ func (c *Client) getURL(path string) string {
 	return fmt.Sprintf("%s%s", urlStr, path)
 }
 
-type jsonMessage struct {
-	Status   string `json:"status,omitempty"`
-	Progress string `json:"progress,omitempty"`
-	Error    string `json:"error,omitempty"`
-	Stream   string `json:"stream,omitempty"`
+// JSONMessage is a message of the JSON streams sent by the API, like the
+// progress of an image pull or the output of a build.
+type JSONMessage struct {
+	ID             string          `json:"id,omitempty"`
+	Status         string          `json:"status,omitempty"`
+	Progress       string          `json:"progress,omitempty"`
+	ProgressDetail *JSONProgress   `json:"progressDetail,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	Stream         string          `json:"stream,omitempty"`
+	Aux            json.RawMessage `json:"aux,omitempty"`
+}
+
+// JSONProgress is the progress of an operation on a layer, like its
+// download during a pull, in bytes.
+type JSONProgress struct {
+	Current int64 `json:"current,omitempty"`
+	Total   int64 `json:"total,omitempty"`
+}
+
+// Percent returns the completion of the operation, between 0 and 100, or -1
+// when the total is unknown.
+func (p *JSONProgress) Percent() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	if p.Current >= p.Total {
+		return 100
+	}
+	return float64(p.Current) * 100 / float64(p.Total)
+}
+
+// omitNewerFields returns a copy of data without the values of the fields
+// introduced after the given API version, so they're left out of the request
+// instead of being rejected by older daemons. The version of a field is
+// declared in its apiVersion tag, like `apiVersion:"1.25"`. Structs, pointers
+// to structs and embedded structs are copied; other values are returned as
+// is, as is data when the version is unknown.
+func omitNewerFields(data interface{}, version APIVersion) interface{} {
+	if data == nil || version == nil {
+		return data
+	}
+	return omitNewerValue(reflect.ValueOf(data), version).Interface()
+}
+
+func omitNewerValue(value reflect.Value, version APIVersion) reflect.Value {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() || value.Elem().Kind() != reflect.Struct {
+			return value
+		}
+		ptr := reflect.New(value.Elem().Type())
+		ptr.Elem().Set(omitNewerValue(value.Elem(), version))
+		return ptr
+	case reflect.Struct:
+		result := reflect.New(value.Type()).Elem()
+		result.Set(value)
+		for i := 0; i < result.NumField(); i++ {
+			field := result.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if tag := value.Type().Field(i).Tag.Get("apiVersion"); tag != "" {
+				min, err := NewAPIVersion(tag)
+				if err == nil && version.LessThan(min) {
+					field.Set(reflect.Zero(field.Type()))
+					continue
+				}
+			}
+			field.Set(omitNewerValue(field, version))
+		}
+		return result
+	}
+	return value
+}
+
+// checkNewerFields returns an *UnsupportedField error when data sets a field
+// introduced after the given API version, for the clients with
+// StrictAPIVersion. The field is set when it's not empty. Pointers to structs
+// and embedded structs are checked as well. Nothing is checked when the
+// version is unknown.
+func checkNewerFields(data interface{}, version APIVersion) error {
+	if data == nil || version == nil {
+		return nil
+	}
+	return checkNewerValue(reflect.ValueOf(data), version)
+}
+
+func checkNewerValue(value reflect.Value, version APIVersion) error {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return checkNewerValue(value.Elem(), version)
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if tag := field.Tag.Get("apiVersion"); tag != "" && !isEmptyValue(value.Field(i)) {
+				min, err := NewAPIVersion(tag)
+				if err == nil && version.LessThan(min) {
+					name := value.Type().Name() + "." + field.Name
+					return &UnsupportedField{Field: name, MinVersion: min, Version: version}
+				}
+			}
+			if err := checkNewerValue(value.Field(i), version); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isEmptyValue reports whether v is empty, as for the omitempty option of
+// encoding/json, or a zero struct.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Ptr, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
 }
 
 func queryString(opts interface{}) string {
@@ -604,6 +1200,12 @@ func queryString(opts interface{}) string {
 					items.Add(key, string(b))
 				}
 			}
+		case reflect.Slice:
+			if v.Len() > 0 {
+				if b, err := json.Marshal(v.Interface()); err == nil {
+					items.Add(key, string(b))
+				}
+			}
 		case reflect.Map:
 			if len(v.MapKeys()) > 0 {
 				if b, err := json.Marshal(v.Interface()); err == nil {
@@ -737,17 +1339,21 @@ func readBody(stream io.ReadCloser, statusCode int, err error) ([]byte, int, err
 	return body, statusCode, nil
 }
 
-func (c *Client) hijack2(method, path string, setRawTerminal bool, dialer func(string, string) (net.Conn, error), in io.Reader, stdout, stderr io.Writer, started chan io.Closer, data interface{}) error {
-	defer func() {
-		if started != nil {
-			close(started)
-		}
-	}()
-	if path != "/version" && !c.SkipServerVersionCheck && c.expectedAPIVersion == nil {
-		err := c.checkAPIVersion()
-		if err != nil {
-			return err
+func (c *Client) hijack2(method, path string, setRawTerminal bool, dialer func(string, string) (net.Conn, error), in io.Reader, stdout, stderr io.Writer, success chan struct{}, data interface{}) (err error) {
+	start := time.Now()
+	defer func() { c.audit(method, path, nil, start, nil, err) }()
+	if err := c.checkRequest(method, path); err != nil {
+		return err
+	}
+	if err := c.ensureAPIVersion(path); err != nil {
+		return err
+	}
+	if c.dryRun(method) {
+		if success != nil {
+			success <- struct{}{}
+			<-success
 		}
+		return nil
 	}
 
 	var params io.Reader
@@ -777,11 +1383,12 @@ func (c *Client) hijack2(method, path string, setRawTerminal bool, dialer func(s
 		address = c.endpointURL.Host
 	}
 	req.Host = address
-	realDialer := dialer
-	if realDialer == nil {
-		realDialer = net.Dial
+	var dial net.Conn
+	if dialer != nil {
+		dial, err = dialer(protocol, address)
+	} else {
+		dial, err = c.dialHijack(req)
 	}
-	dial, err := realDialer(protocol, address)
 	if err != nil {
 		return err
 	}
@@ -803,20 +1410,19 @@ func (c *Client) hijack2(method, path string, setRawTerminal bool, dialer func(s
 
 	defer rwc.Close()
 
-	if started != nil {
-		started <- rwc
+	if success != nil {
+		success <- struct{}{}
+		<-success
 	}
 
 	var (
 		stdin        io.ReadCloser
 		isTerminalIn bool
-		inFd         uintptr
 	)
 
 	if in != nil {
 		if file, ok := in.(*os.File); ok {
-			inFd = file.Fd()
-			isTerminalIn = term.IsTerminal(inFd)
+			isTerminalIn = IsTerminal(file)
 			stdin = file
 		} else {
 			if closer, ok := in.(io.ReadCloser); ok {
@@ -828,21 +1434,21 @@ func (c *Client) hijack2(method, path string, setRawTerminal bool, dialer func(s
 	}
 
 	var receiveStdout chan error
-	var oldState *term.State
+	var terminal rawTerminal
 
 	if in != nil && setRawTerminal && isTerminalIn && os.Getenv("NORAW") == "" {
-		oldState, err = term.SetRawTerminal(inFd)
+		terminal, err = makeRawTerminal(in)
 		if err != nil {
 			return err
 		}
-		defer term.RestoreTerminal(inFd, oldState)
+		defer terminal.Restore()
 	}
 	if stdout != nil || stderr != nil {
 		receiveStdout = promise.Go(func() (err error) {
 			defer func() {
 				if in != nil {
-					if setRawTerminal {
-						term.RestoreTerminal(inFd, oldState)
+					if terminal != nil {
+						terminal.Restore()
 					}
 					if runtime.GOOS != "darwin" {
 						stdin.Close()
@@ -851,9 +1457,9 @@ func (c *Client) hijack2(method, path string, setRawTerminal bool, dialer func(s
 			}()
 
 			if setRawTerminal && stdout != nil {
-				_, err = io.Copy(stdout, br)
+				_, err = c.copyStream(stdout, br)
 			} else {
-				_, err = stdcopy.StdCopy(stdout, stderr, br)
+				_, err = c.stdCopyStream(stdout, stderr, br)
 			}
 			return err
 		})
@@ -861,7 +1467,7 @@ func (c *Client) hijack2(method, path string, setRawTerminal bool, dialer func(s
 
 	sendStdin := promise.Go(func() error {
 		if in != nil {
-			io.Copy(rwc, in)
+			c.copyStream(rwc, in)
 		}
 		if tcpc, ok := rwc.(*net.TCPConn); ok {
 			tcpc.CloseWrite()
@@ -5,14 +5,22 @@
 package docker
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestNewAPIClient(t *testing.T) {
@@ -245,6 +253,77 @@ func TestPingFailingWrongStatus(t *testing.T) {
 	}
 }
 
+func TestDo(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Name":"plugin"}`, status: http.StatusOK, header: map[string]string{"X-Docker-Plugin": "yes"}}
+	client := newTestClient(fakeRT)
+	resp, err := client.Do("POST", "/plugins/pull?remote=plugin", DoOptions{
+		Data:    map[string]string{"Name": "plugin"},
+		Headers: map[string]string{"X-Registry-Auth": "token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Do: wrong status. Want %d. Got %d.", http.StatusOK, resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-Docker-Plugin"); got != "yes" {
+		t.Errorf("Do: wrong header. Want %q. Got %q.", "yes", got)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != fakeRT.message {
+		t.Errorf("Do: wrong body. Want %q. Got %q.", fakeRT.message, body)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != "POST" {
+		t.Errorf("Do: wrong HTTP method. Want %q. Got %q.", "POST", req.Method)
+	}
+	if req.URL.Path != "/plugins/pull" || req.URL.Query().Get("remote") != "plugin" {
+		t.Errorf("Do: wrong URL. Got %q.", req.URL)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Do: wrong Content-Type. Want %q. Got %q.", "application/json", got)
+	}
+	if got := req.Header.Get("X-Registry-Auth"); got != "token" {
+		t.Errorf("Do: wrong X-Registry-Auth header. Want %q. Got %q.", "token", got)
+	}
+}
+
+func TestDoInputStream(t *testing.T) {
+	var got []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte("loaded"))
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	content := strings.Repeat("layer", 1024)
+	resp, err := client.Do("POST", "/images/load", DoOptions{InputStream: strings.NewReader(content)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if string(got) != content {
+		t.Errorf("Do: wrong request body. Want %d bytes. Got %d.", len(content), len(got))
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "loaded" {
+		t.Errorf("Do: wrong response body. Want %q. Got %q.", "loaded", body)
+	}
+}
+
+func TestDoFailure(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such plugin", status: http.StatusNotFound})
+	resp, err := client.Do("GET", "/plugins/foo/json", DoOptions{})
+	if resp != nil {
+		t.Errorf("Do: expected <nil> response, got %#v.", resp)
+	}
+	expected := &Error{Status: http.StatusNotFound, Message: "no such plugin"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("Do: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+}
+
 type FakeRoundTripper struct {
 	message  string
 	status   int
@@ -288,3 +367,490 @@ type dumb struct {
 type fakeEndpointURL struct {
 	Scheme string
 }
+
+func TestClientConcurrentCalls(t *testing.T) {
+	var mut sync.Mutex
+	var versionCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/version"):
+			mut.Lock()
+			versionCalls++
+			mut.Unlock()
+			w.Write([]byte(`{"ApiVersion":"1.17"}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			w.Write([]byte(`[{"Id":"abc"}]`))
+		case strings.HasSuffix(r.URL.Path, "/images/create"):
+			w.Write([]byte(`{"status":"Pulling"}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/abc/exec"):
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"exec-1"}`))
+		case strings.HasSuffix(r.URL.Path, "/exec/exec-1/json"):
+			w.Write([]byte(`{"ID":"exec-1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client, err := NewVersionedClient(server.URL, "1.17")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, err := client.ListContainers(ListContainersOptions{})
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- client.PullImage(PullImageOptions{Repository: "base", OutputStream: ioutil.Discard}, AuthConfiguration{})
+		}()
+		go func() {
+			defer wg.Done()
+			exec, err := client.CreateExec(CreateExecOptions{Container: "abc", Cmd: []string{"ls"}})
+			if err == nil {
+				_, err = client.InspectExec(exec.ID)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if versionCalls != 1 {
+		t.Errorf("Concurrent calls: wrong number of version checks. Want 1. Got %d.", versionCalls)
+	}
+}
+
+// setEnv sets the given environment variables, returning a function that
+// restores their previous values.
+func setEnv(vars map[string]string) func() {
+	previous := make(map[string]string)
+	for k, v := range vars {
+		previous[k] = os.Getenv(k)
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range previous {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+func TestDefaultDockerHost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	runtimeDir := filepath.Join(dir, "run")
+	home := filepath.Join(dir, "home")
+	for _, d := range []string{runtimeDir, filepath.Join(home, ".docker", "run"), filepath.Join(home, ".docker", "desktop")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer setEnv(map[string]string{"XDG_RUNTIME_DIR": runtimeDir, "HOME": home})()
+	if _, err := os.Stat(defaultDockerSocket); err == nil {
+		t.Skip("a system docker socket exists")
+	}
+	if got := DefaultDockerHost(); got != "unix://"+defaultDockerSocket {
+		t.Errorf("DefaultDockerHost(): wrong result. Want %q. Got %q.", "unix://"+defaultDockerSocket, got)
+	}
+	sockets := []string{
+		filepath.Join(home, ".docker", "desktop", "docker.sock"),
+		filepath.Join(home, ".docker", "run", "docker.sock"),
+		filepath.Join(runtimeDir, "docker.sock"),
+	}
+	for _, socket := range sockets {
+		l, err := net.Listen("unix", socket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		if got := DefaultDockerHost(); got != "unix://"+socket {
+			t.Errorf("DefaultDockerHost(): wrong result. Want %q. Got %q.", "unix://"+socket, got)
+		}
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	defer setEnv(map[string]string{"DOCKER_HOST": "tcp://localhost:4243", "DOCKER_TLS_VERIFY": ""})()
+	client, err := NewClientFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Endpoint() != "tcp://localhost:4243" {
+		t.Errorf("NewClientFromEnv: wrong endpoint. Want %q. Got %q.", "tcp://localhost:4243", client.Endpoint())
+	}
+	if client.endpointURL.Scheme != "http" {
+		t.Errorf("NewClientFromEnv: wrong scheme. Want %q. Got %q.", "http", client.endpointURL.Scheme)
+	}
+	if !client.SkipServerVersionCheck {
+		t.Error("NewClientFromEnv: expected SkipServerVersionCheck to be true")
+	}
+}
+
+func TestNewClientFromEnvTLS(t *testing.T) {
+	defer setEnv(map[string]string{
+		"DOCKER_HOST":       "tcp://localhost:4243",
+		"DOCKER_TLS_VERIFY": "1",
+		"DOCKER_CERT_PATH":  "testing/data",
+	})()
+	client, err := NewVersionedClientFromEnv("1.17")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.endpointURL.Scheme != "https" {
+		t.Errorf("NewVersionedClientFromEnv: wrong scheme. Want %q. Got %q.", "https", client.endpointURL.Scheme)
+	}
+	if client.TLSConfig == nil || client.TLSConfig.InsecureSkipVerify || client.TLSConfig.RootCAs == nil {
+		t.Errorf("NewVersionedClientFromEnv: TLS should be verified with the CA. Got %#v.", client.TLSConfig)
+	}
+	if client.requestedAPIVersion.String() != "1.17" {
+		t.Errorf("NewVersionedClientFromEnv: wrong API version. Want %q. Got %q.", "1.17", client.requestedAPIVersion)
+	}
+}
+
+func TestRequireAPIVersion(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id":"abc"}`, status: http.StatusOK}
+	client, err := NewVersionedClient("http://localhost:4243", "1.40")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.HTTPClient = &http.Client{Transport: fakeRT}
+	_, err = client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "busybox"}, Platform: "linux/arm64"})
+	e, ok := err.(*UnsupportedField)
+	if !ok {
+		t.Fatalf("CreateContainer: wrong error. Want *UnsupportedField. Got %#v.", err)
+	}
+	if e.Field != "CreateContainerOptions.Platform" || e.MinVersion.String() != "1.41" || e.Version.String() != "1.40" {
+		t.Errorf("CreateContainer: wrong error: %#v.", e)
+	}
+	expectedMsg := "CreateContainerOptions.Platform requires Docker API 1.41 or newer, the client uses API 1.40"
+	if e.Error() != expectedMsg {
+		t.Errorf("UnsupportedField: wrong message. Want %q. Got %q.", expectedMsg, e.Error())
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("CreateContainer: unexpected requests: %d.", len(fakeRT.requests))
+	}
+	if _, err = client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "busybox"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequireAPIVersionNegotiated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/version" {
+			w.Write([]byte(`{"ApiVersion":"1.41"}`))
+			return
+		}
+		w.Write([]byte(`{"Id":"abc"}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = false
+	_, err = client.CreateExec(CreateExecOptions{Container: "abc", Cmd: []string{"sh"}, ConsoleSize: &[2]uint{24, 80}})
+	if e, ok := err.(*UnsupportedField); !ok || e.Field != "CreateExecOptions.ConsoleSize" {
+		t.Errorf("CreateExec: wrong error. Want *UnsupportedField. Got %#v.", err)
+	}
+	if _, err = client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "busybox"}, Platform: "linux/arm64"}); err != nil {
+		t.Errorf("CreateContainer: unexpected error: %s.", err)
+	}
+}
+
+func TestOmitNewerFields(t *testing.T) {
+	version, _ := NewAPIVersion("1.20")
+	data := struct {
+		*Config
+		HostConfig *HostConfig
+	}{&Config{Image: "busybox", StopSignal: "SIGINT"}, &HostConfig{Privileged: true}}
+	result := omitNewerFields(data, version)
+	b, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"Image":"busybox","HostConfig":{"Privileged":true,"RestartPolicy":{},"LogConfig":{}}}`
+	if string(b) != expected {
+		t.Errorf("omitNewerFields: wrong result. Want %s. Got %s.", expected, b)
+	}
+	if data.Config.StopSignal != "SIGINT" {
+		t.Errorf("omitNewerFields: should not modify the original value. Got %q.", data.Config.StopSignal)
+	}
+	if got := omitNewerFields(data, nil); !reflect.DeepEqual(got, data) {
+		t.Errorf("omitNewerFields: should not modify data with an unknown version. Got %#v.", got)
+	}
+}
+
+func TestDoOmitsNewerFields(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id":"abc"}`, status: http.StatusOK}
+	client, err := NewVersionedClient("http://localhost:4243", "1.30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.HTTPClient = &http.Client{Transport: fakeRT}
+	opts := CreateExecOptions{Container: "abc", Cmd: []string{"ls"}, Env: []string{"A=1"}, WorkingDir: "/tmp"}
+	if _, err := client.CreateExec(opts); err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["Env"]; !ok {
+		t.Error("CreateExec: Env should be sent to API 1.30.")
+	}
+	if _, ok := body["WorkingDir"]; ok {
+		t.Error("CreateExec: WorkingDir should not be sent to API 1.30.")
+	}
+}
+
+func TestDoSendsLongStandingFields(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id":"abc"}`, status: http.StatusOK}
+	client, err := NewVersionedClient("http://localhost:4243", "1.12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.StrictAPIVersion = true
+	client.HTTPClient = &http.Client{Transport: fakeRT}
+	hostConfig := HostConfig{CapAdd: []string{CapNetAdmin}, RestartPolicy: AlwaysRestart(), ReadonlyRootfs: true}
+	opts := CreateContainerOptions{Config: &Config{Image: "busybox", MacAddress: "02:42:ac:11:00:02"}, HostConfig: &hostConfig}
+	if _, err := client.CreateContainer(opts); err != nil {
+		t.Fatal(err)
+	}
+	var body struct{ HostConfig HostConfig }
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(body.HostConfig.CapAdd, hostConfig.CapAdd) || body.HostConfig.RestartPolicy != hostConfig.RestartPolicy {
+		t.Errorf("CreateContainer: wrong host config sent to API 1.12. Got %#v.", body.HostConfig)
+	}
+}
+
+func TestCreateExecConsoleSizeUnsupported(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id":"abc"}`, status: http.StatusOK}
+	client, err := NewVersionedClient("http://localhost:4243", "1.41")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.HTTPClient = &http.Client{Transport: fakeRT}
+	_, err = client.CreateExec(CreateExecOptions{Container: "abc", Cmd: []string{"ls"}, ConsoleSize: &[2]uint{24, 80}})
+	if e, ok := err.(*UnsupportedField); !ok || e.Field != "CreateExecOptions.ConsoleSize" {
+		t.Errorf("CreateExec: wrong error. Want *UnsupportedField. Got %#v.", err)
+	}
+	hostConfig := HostConfig{ConsoleSize: &[2]uint{24, 80}}
+	_, err = client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "busybox"}, HostConfig: &hostConfig})
+	if e, ok := err.(*UnsupportedField); !ok || e.Field != "HostConfig.ConsoleSize" {
+		t.Errorf("CreateContainer: wrong error. Want *UnsupportedField. Got %#v.", err)
+	}
+	if len(fakeRT.requests) > 0 {
+		t.Errorf("CreateExec: unsupported fields should not be sent. Got %d requests.", len(fakeRT.requests))
+	}
+}
+
+func TestCheckNewerFields(t *testing.T) {
+	version, _ := NewAPIVersion("1.20")
+	data := struct {
+		*Config
+		HostConfig *HostConfig
+	}{&Config{Image: "busybox"}, &HostConfig{Privileged: true, ReadonlyRootfs: true}}
+	if err := checkNewerFields(data, version); err != nil {
+		t.Errorf("checkNewerFields: unexpected error: %s", err)
+	}
+	data.Config.StopSignal = "SIGINT"
+	err := checkNewerFields(data, version)
+	expected := &UnsupportedField{Field: "Config.StopSignal", MinVersion: APIVersion{1, 21}, Version: version}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("checkNewerFields: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+	if err := checkNewerFields(data, nil); err != nil {
+		t.Errorf("checkNewerFields: should not check data with an unknown version. Got %#v.", err)
+	}
+}
+
+func TestDoRejectsNewerFields(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id":"abc"}`, status: http.StatusOK}
+	client, err := NewVersionedClient("http://localhost:4243", "1.30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.StrictAPIVersion = true
+	client.HTTPClient = &http.Client{Transport: fakeRT}
+	if _, err := client.CreateExec(CreateExecOptions{Container: "abc", Cmd: []string{"ls"}, Env: []string{"A=1"}}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.CreateExec(CreateExecOptions{Container: "abc", Cmd: []string{"ls"}, WorkingDir: "/tmp"})
+	if e, ok := err.(*UnsupportedField); !ok || e.Field != "CreateExecOptions.WorkingDir" {
+		t.Errorf("CreateExec: wrong error. Want *UnsupportedField. Got %#v.", err)
+	}
+	if len(fakeRT.requests) != 1 {
+		t.Errorf("CreateExec: unsupported fields should not be sent. Got %d requests.", len(fakeRT.requests))
+	}
+}
+
+func TestPingSocketNotFound(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "docker.sock")
+	client, err := NewClient("unix://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Ping()
+	e, ok := err.(*ErrConnectionFailed)
+	if !ok {
+		t.Fatalf("Ping: wrong error. Want *ErrConnectionFailed. Got %#v.", err)
+	}
+	if e.Path != path || e.Err != syscall.ENOENT || e.UID != os.Getuid() {
+		t.Errorf("Ping: wrong error. Got %#v.", e)
+	}
+	if !strings.Contains(e.Error(), "is the docker daemon running?") {
+		t.Errorf("Ping: wrong error message. Got %q.", e.Error())
+	}
+}
+
+func TestErrConnectionFailedPermissionDenied(t *testing.T) {
+	err := socketError("/var/run/docker.sock", &net.OpError{Op: "dial", Net: "unix", Err: &os.SyscallError{Syscall: "connect", Err: syscall.EACCES}})
+	e, ok := err.(*ErrConnectionFailed)
+	if !ok {
+		t.Fatalf("socketError: wrong error. Want *ErrConnectionFailed. Got %#v.", err)
+	}
+	expected := fmt.Sprintf("cannot connect to the Docker daemon at unix:///var/run/docker.sock: permission denied (uid %d): add the user to the docker group, or run as root", os.Getuid())
+	if e.Error() != expected {
+		t.Errorf("ErrConnectionFailed: wrong message. Want %q. Got %q.", expected, e.Error())
+	}
+	other := &net.OpError{Op: "dial", Net: "unix", Err: syscall.ECONNREFUSED}
+	if err := socketError("/var/run/docker.sock", other); err != other {
+		t.Errorf("socketError: wrong error. Want %#v. Got %#v.", other, err)
+	}
+}
+
+func TestWaitForDaemon(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1420070400, 0)}
+	attempts := 0
+	ping := func() error {
+		if attempts++; attempts < 5 {
+			return ErrConnectionRefused
+		}
+		return nil
+	}
+	if err := waitForDaemon(ping, time.Minute, clock.Now, clock.Sleep); err != nil {
+		t.Fatal(err)
+	}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond}
+	if !reflect.DeepEqual(clock.slept, expected) {
+		t.Errorf("WaitForDaemon: wrong delays. Want %v. Got %v.", expected, clock.slept)
+	}
+}
+
+func TestWaitForDaemonTimeout(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1420070400, 0)}
+	ping := func() error { return ErrConnectionRefused }
+	if err := waitForDaemon(ping, 5*time.Second, clock.Now, clock.Sleep); err != ErrConnectionRefused {
+		t.Errorf("WaitForDaemon: wrong error. Want %#v. Got %#v.", ErrConnectionRefused, err)
+	}
+	expected := []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		800 * time.Millisecond, 1600 * time.Millisecond, 1900 * time.Millisecond,
+	}
+	if !reflect.DeepEqual(clock.slept, expected) {
+		t.Errorf("WaitForDaemon: wrong delays. Want %v. Got %v.", expected, clock.slept)
+	}
+}
+
+func TestWaitForDaemonServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WaitForDaemon(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientReadOnly(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "[]", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	client.ReadOnly = true
+	if _, err := client.ListContainers(ListContainersOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err := client.RemoveContainer(RemoveContainerOptions{ID: "abc", Force: true})
+	expected := &ErrReadOnly{Method: "DELETE", Path: "/containers/abc"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("RemoveContainer: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+	err = client.AttachToContainer(AttachToContainerOptions{Container: "abc", Stdout: true, OutputStream: ioutil.Discard})
+	if _, ok := err.(*ErrReadOnly); !ok {
+		t.Errorf("AttachToContainer: wrong error. Want *ErrReadOnly. Got %#v.", err)
+	}
+	if len(fakeRT.requests) != 1 {
+		t.Errorf("ReadOnly: the denied calls should not be sent. Got %d requests.", len(fakeRT.requests))
+	}
+}
+
+func TestClientDryRun(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id":"abc"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	client.DryRun = true
+	var records []AuditRecord
+	client.AuditRecorder = AuditRecorderFunc(func(record AuditRecord) {
+		records = append(records, record)
+	})
+	container, err := client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "busybox"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.ID != "" {
+		t.Errorf("CreateContainer: wrong ID in a dry run. Want %q. Got %q.", "", container.ID)
+	}
+	if err := client.StartContainer("abc", nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.CreateContainer(CreateContainerOptions{
+		Config:     &Config{Image: "busybox"},
+		HostConfig: &HostConfig{CapAdd: []string{"CAP_UNKNOWN"}},
+	})
+	if _, ok := err.(*UnknownCapability); !ok {
+		t.Errorf("CreateContainer: the options should be validated in a dry run. Got %#v.", err)
+	}
+	if _, err := client.InspectContainer("abc"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fakeRT.requests) != 1 || fakeRT.requests[0].Method != "GET" {
+		t.Errorf("DryRun: only the GET requests should be sent. Got %d requests.", len(fakeRT.requests))
+	}
+	var dryRun []string
+	for _, record := range records {
+		if record.DryRun {
+			dryRun = append(dryRun, record.Method+" "+record.Path)
+		}
+	}
+	expected := []string{"POST /containers/create", "POST /containers/abc/start"}
+	if !reflect.DeepEqual(dryRun, expected) {
+		t.Errorf("DryRun: wrong records. Want %#v. Got %#v.", expected, dryRun)
+	}
+}
@@ -6,14 +6,20 @@ package docker
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -43,6 +49,7 @@ type APIPort struct {
 type APIContainers struct {
 	ID         string    `json:"Id" yaml:"Id"`
 	Image      string    `json:"Image,omitempty" yaml:"Image,omitempty"`
+	ImageID    string    `json:"ImageID,omitempty" yaml:"ImageID,omitempty"`
 	Command    string    `json:"Command,omitempty" yaml:"Command,omitempty"`
 	Created    int64     `json:"Created,omitempty" yaml:"Created,omitempty"`
 	Status     string    `json:"Status,omitempty" yaml:"Status,omitempty"`
@@ -56,19 +63,30 @@ type APIContainers struct {
 //
 // See http://goo.gl/6Y4Gz7 for more details.
 func (c *Client) ListContainers(opts ListContainersOptions) ([]APIContainers, error) {
-	path := "/containers/json?" + queryString(opts)
-	body, _, err := c.do("GET", path, nil, false)
-	if err != nil {
-		return nil, err
-	}
-	var containers []APIContainers
-	err = json.Unmarshal(body, &containers)
+	containers := []APIContainers{}
+	err := c.ListContainersFunc(opts, func(container APIContainers) error {
+		containers = append(containers, container)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	return containers, nil
 }
 
+// ListContainersFunc calls fn for each container matching the given
+// criteria, as the list is received, without holding it in memory. It stops
+// at the first error returned by fn, and returns it.
+func (c *Client) ListContainersFunc(opts ListContainersOptions, fn func(APIContainers) error) error {
+	return c.listJSON("/containers/json?"+queryString(opts), func(element []byte) error {
+		var container APIContainers
+		if err := json.Unmarshal(element, &container); err != nil {
+			return err
+		}
+		return fn(container)
+	})
+}
+
 // Port represents the port number and the protocol, in the form
 // <number>/<protocol>. For example: 80/tcp.
 type Port string
@@ -97,6 +115,25 @@ type State struct {
 	Error      string    `json:"Error,omitempty" yaml:"Error,omitempty"`
 	StartedAt  time.Time `json:"StartedAt,omitempty" yaml:"StartedAt,omitempty"`
 	FinishedAt time.Time `json:"FinishedAt,omitempty" yaml:"FinishedAt,omitempty"`
+	Health     *Health   `json:"Health,omitempty" yaml:"Health,omitempty"`
+}
+
+// Health is the state of the healthcheck of a container, reported by Docker
+// API 1.24 and newer for the containers that have a healthcheck. Status is
+// one of "starting", "healthy" and "unhealthy".
+type Health struct {
+	Status        string        `json:"Status,omitempty" yaml:"Status,omitempty"`
+	FailingStreak int           `json:"FailingStreak,omitempty" yaml:"FailingStreak,omitempty"`
+	Log           []HealthCheck `json:"Log,omitempty" yaml:"Log,omitempty"`
+}
+
+// HealthCheck is the result of a single run of the healthcheck of a
+// container.
+type HealthCheck struct {
+	Start    time.Time `json:"Start,omitempty" yaml:"Start,omitempty"`
+	End      time.Time `json:"End,omitempty" yaml:"End,omitempty"`
+	ExitCode int       `json:"ExitCode,omitempty" yaml:"ExitCode,omitempty"`
+	Output   string    `json:"Output,omitempty" yaml:"Output,omitempty"`
 }
 
 // String returns the string representation of a state.
@@ -197,6 +234,8 @@ type Config struct {
 	SecurityOpts    []string            `json:"SecurityOpts,omitempty" yaml:"SecurityOpts,omitempty"`
 	OnBuild         []string            `json:"OnBuild,omitempty" yaml:"OnBuild,omitempty"`
 	MacAddress      string              `json:"MacAddress,omitempty" yaml:"MacAddress,omitempty"`
+	StopSignal      string              `json:"StopSignal,omitempty" yaml:"StopSignal,omitempty" apiVersion:"1.21"`
+	Labels          map[string]string   `json:"Labels,omitempty" yaml:"Labels,omitempty" apiVersion:"1.18"`
 }
 
 // Container is the type encompasing everything about a container - its config,
@@ -248,6 +287,13 @@ func (c *Client) InspectContainer(id string) (*Container, error) {
 	return &container, nil
 }
 
+// ContainerExists reports whether the given container exists. Unlike
+// InspectContainer, it doesn't decode the container, and a missing container
+// is not an error.
+func (c *Client) ContainerExists(id string) (bool, error) {
+	return c.exists("/containers/" + id + "/json")
+}
+
 // ContainerChanges returns changes in the filesystem of the given container.
 //
 // See http://goo.gl/QkW9sH for more details.
@@ -268,6 +314,27 @@ func (c *Client) ContainerChanges(id string) ([]Change, error) {
 	return changes, nil
 }
 
+// NameConflictPolicy determines what CreateContainer does when the daemon
+// reports that the requested container name is already in use.
+type NameConflictPolicy int
+
+const (
+	// NameConflictFail makes CreateContainer return ErrContainerAlreadyExists.
+	// This is the default behavior.
+	NameConflictFail NameConflictPolicy = iota
+
+	// NameConflictRemove forcefully removes the container that owns the name
+	// and then creates the new container.
+	NameConflictRemove
+
+	// NameConflictSuffix appends a random suffix to the name and tries again.
+	NameConflictSuffix
+)
+
+// maxNameConflictRetries is the number of suffixed names tried by
+// CreateContainer before giving up when NameConflictSuffix is used.
+const maxNameConflictRetries = 5
+
 // CreateContainerOptions specify parameters to the CreateContainer function.
 //
 // See http://goo.gl/2xxQQK for more details.
@@ -275,6 +342,14 @@ type CreateContainerOptions struct {
 	Name       string
 	Config     *Config `qs:"-"`
 	HostConfig *HostConfig
+
+	// Platform is the platform of the image, in the form os[/arch[/variant]]
+	// (for example, linux/arm64). Requires Docker API 1.41 or newer.
+	Platform string
+
+	// OnNameConflict tells CreateContainer how to recover when another
+	// container already uses Name.
+	OnNameConflict NameConflictPolicy `qs:"-"`
 }
 
 // CreateContainer creates a new container, returning the container instance,
@@ -282,6 +357,81 @@ type CreateContainerOptions struct {
 //
 // See http://goo.gl/mErxNp for more details.
 func (c *Client) CreateContainer(opts CreateContainerOptions) (*Container, error) {
+	if opts.Platform != "" {
+		if err := c.requireAPIVersion("CreateContainerOptions.Platform", apiVersion141); err != nil {
+			return nil, err
+		}
+	}
+	if opts.HostConfig != nil {
+		if opts.HostConfig.ConsoleSize != nil {
+			if err := c.requireAPIVersion("HostConfig.ConsoleSize", apiVersion142); err != nil {
+				return nil, err
+			}
+		}
+		if err := opts.HostConfig.LogConfig.Validate(); err != nil {
+			return nil, err
+		}
+		if err := ValidateCapabilities(opts.HostConfig.CapAdd...); err != nil {
+			return nil, err
+		}
+		if err := ValidateCapabilities(opts.HostConfig.CapDrop...); err != nil {
+			return nil, err
+		}
+		if err := opts.HostConfig.validateLimits(); err != nil {
+			return nil, err
+		}
+		if err := opts.HostConfig.validateResolver(); err != nil {
+			return nil, err
+		}
+		if err := opts.HostConfig.validateResources(); err != nil {
+			return nil, err
+		}
+		for _, rule := range opts.HostConfig.DeviceCgroupRules {
+			if !deviceCgroupRule.MatchString(rule) {
+				return nil, &InvalidDeviceCgroupRule{Rule: rule}
+			}
+		}
+		if opts.HostConfig.requestsCDIDevices() {
+			if err := c.requireAPIVersion("DeviceRequest.Driver", apiVersion144); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c.ImageVerifier != nil && opts.Config != nil {
+		if err := c.verifyImage(opts.Config.Image); err != nil {
+			return nil, err
+		}
+	}
+	container, err := c.createContainer(opts)
+	if err != ErrContainerAlreadyExists || opts.Name == "" {
+		return container, err
+	}
+	switch opts.OnNameConflict {
+	case NameConflictRemove:
+		err = c.RemoveContainer(RemoveContainerOptions{ID: opts.Name, Force: true})
+		if err != nil {
+			if _, ok := err.(*NoSuchContainer); !ok {
+				return nil, err
+			}
+		}
+		return c.createContainer(opts)
+	case NameConflictSuffix:
+		name := opts.Name
+		for i := 0; i < maxNameConflictRetries && err == ErrContainerAlreadyExists; i++ {
+			opts.Name = name + "-" + randomSuffix()
+			container, err = c.createContainer(opts)
+		}
+		return container, err
+	}
+	return nil, err
+}
+
+func (c *Client) createContainer(opts CreateContainerOptions) (*Container, error) {
+	if opts.Config != nil && strings.Contains(opts.Config.Image, "@") {
+		config := *opts.Config
+		config.Image = joinRepositoryTag(ParseRepositoryTag(config.Image))
+		opts.Config = &config
+	}
 	path := "/containers/create?" + queryString(opts)
 	body, status, err := c.do("POST", path, struct {
 		*Config
@@ -294,6 +444,9 @@ func (c *Client) CreateContainer(opts CreateContainerOptions) (*Container, error
 	if status == http.StatusNotFound {
 		return nil, ErrNoSuchImage
 	}
+	if status == http.StatusConflict {
+		return nil, ErrContainerAlreadyExists
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -308,6 +461,12 @@ func (c *Client) CreateContainer(opts CreateContainerOptions) (*Container, error
 	return &container, nil
 }
 
+func randomSuffix() string {
+	var buf [4]byte
+	rand.Read(buf[:])
+	return fmt.Sprintf("%x", buf)
+}
+
 // KeyValuePair is a type for generic key/value pairs as used in the Lxc
 // configuration
 type KeyValuePair struct {
@@ -354,6 +513,34 @@ type Device struct {
 	CgroupPermissions string `json:"CgroupPermissions,omitempty" yaml:"CgroupPermissions,omitempty"`
 }
 
+// DeviceRequest requests devices from a device driver, like the GPUs of the
+// nvidia driver, or the devices described by the Container Device Interface
+// (CDI), requested with CDIDevices.
+type DeviceRequest struct {
+	Driver       string            `json:"Driver,omitempty" yaml:"Driver,omitempty"`
+	Count        int               `json:"Count,omitempty" yaml:"Count,omitempty"`
+	DeviceIDs    []string          `json:"DeviceIDs,omitempty" yaml:"DeviceIDs,omitempty"`
+	Capabilities [][]string        `json:"Capabilities,omitempty" yaml:"Capabilities,omitempty"`
+	Options      map[string]string `json:"Options,omitempty" yaml:"Options,omitempty"`
+}
+
+// cdiDriver is the driver of the device requests for CDI devices.
+const cdiDriver = "cdi"
+
+var cdiDeviceName = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?/[a-zA-Z0-9]([a-zA-Z0-9_-]*[a-zA-Z0-9])?=[a-zA-Z0-9]([a-zA-Z0-9_.:-]*[a-zA-Z0-9])?$`)
+
+// CDIDevices returns the request of the given CDI devices, by their fully
+// qualified names, in the form vendor/class=name, like nvidia.com/gpu=0 or
+// nvidia.com/gpu=all. CDI devices require Docker API 1.44 or newer.
+func CDIDevices(names ...string) (DeviceRequest, error) {
+	for _, name := range names {
+		if !cdiDeviceName.MatchString(name) {
+			return DeviceRequest{}, fmt.Errorf("invalid CDI device name: %s", name)
+		}
+	}
+	return DeviceRequest{Driver: cdiDriver, DeviceIDs: names}, nil
+}
+
 // HostConfig contains the container options related to starting a container on
 // a given host
 type HostConfig struct {
@@ -376,6 +563,189 @@ type HostConfig struct {
 	RestartPolicy   RestartPolicy          `json:"RestartPolicy,omitempty" yaml:"RestartPolicy,omitempty"`
 	Devices         []Device               `json:"Devices,omitempty" yaml:"Devices,omitempty"`
 	ReadonlyRootfs  bool                   `json:"ReadonlyRootfs,omitempty" yaml:"ReadonlyRootfs,omitempty"`
+	LogConfig       LogConfig              `json:"LogConfig,omitempty" yaml:"LogConfig,omitempty" apiVersion:"1.18"`
+
+	// ConsoleSize is the initial size of the TTY, as [height, width]. It
+	// requires Docker API 1.42 or newer.
+	ConsoleSize *[2]uint `json:"ConsoleSize,omitempty" yaml:"ConsoleSize,omitempty" apiVersion:"1.42"`
+
+	// Annotations are passed to the OCI runtime, as the annotations of
+	// the spec of the container. They require Docker API 1.43 or newer.
+	Annotations map[string]string `json:"Annotations,omitempty" yaml:"Annotations,omitempty" apiVersion:"1.43"`
+
+	// DeviceRequests requests devices from device drivers. It requires
+	// Docker API 1.40 or newer, and 1.44 for CDI devices.
+	DeviceRequests []DeviceRequest `json:"DeviceRequests,omitempty" yaml:"DeviceRequests,omitempty" apiVersion:"1.40"`
+
+	// Runtime is the OCI runtime of the container, among the ones listed
+	// by DockerInfo.Runtimes. The daemon uses its default runtime when
+	// it's empty. It requires Docker API 1.25 or newer.
+	Runtime string `json:"Runtime,omitempty" yaml:"Runtime,omitempty" apiVersion:"1.25"`
+
+	// SecurityOpt customizes the labels of SELinux, and the profiles of
+	// AppArmor and seccomp. Build its values with SeccompProfile,
+	// AppArmorProfile, SeccompUnconfined and NoNewPrivileges. It requires
+	// Docker API 1.17 or newer.
+	SecurityOpt []string `json:"SecurityOpt,omitempty" yaml:"SecurityOpt,omitempty" apiVersion:"1.17"`
+
+	// Ulimits are the resource limits of the processes of the container,
+	// and Sysctls the kernel parameters of its namespaces. They require
+	// Docker API 1.18 and 1.24 or newer, and are checked by
+	// CreateContainer.
+	Ulimits []ULimit          `json:"Ulimits,omitempty" yaml:"Ulimits,omitempty" apiVersion:"1.18"`
+	Sysctls map[string]string `json:"Sysctls,omitempty" yaml:"Sysctls,omitempty" apiVersion:"1.24"`
+
+	// Tmpfs mounts tmpfs file systems in the container, by path, with
+	// their mount options, as built by TmpfsOptions. ShmSize is the size
+	// of /dev/shm in bytes, 64 MiB when it's zero. They require Docker API
+	// 1.22 or newer.
+	Tmpfs   map[string]string `json:"Tmpfs,omitempty" yaml:"Tmpfs,omitempty" apiVersion:"1.22"`
+	ShmSize int64             `json:"ShmSize,omitempty" yaml:"ShmSize,omitempty" apiVersion:"1.22"`
+
+	// MaskedPaths and ReadonlyPaths replace the default lists of paths of
+	// the container that are masked, or mounted read-only, like
+	// /proc/kcore or /proc/sys. Empty lists keep the defaults. Along with
+	// ReadonlyRootfs, they harden the container. They require Docker API
+	// 1.39 or newer.
+	MaskedPaths   []string `json:"MaskedPaths,omitempty" yaml:"MaskedPaths,omitempty" apiVersion:"1.39"`
+	ReadonlyPaths []string `json:"ReadonlyPaths,omitempty" yaml:"ReadonlyPaths,omitempty" apiVersion:"1.39"`
+
+	// Init runs an init process, tini, as the PID 1 of the container,
+	// reaping zombies and forwarding signals. The daemon decides when
+	// it's nil. It requires Docker API 1.25 or newer.
+	Init *bool `json:"Init,omitempty" yaml:"Init,omitempty" apiVersion:"1.25"`
+
+	// UTSMode and CgroupnsMode, like IpcMode and PidMode, share the
+	// namespaces of the host, with NamespaceHost, or of another container,
+	// with NamespaceOfContainer. CgroupnsMode may also be
+	// NamespacePrivate. They require Docker API 1.25 and 1.41 or newer.
+	UTSMode      string `json:"UTSMode,omitempty" yaml:"UTSMode,omitempty" apiVersion:"1.25"`
+	CgroupnsMode string `json:"CgroupnsMode,omitempty" yaml:"CgroupnsMode,omitempty" apiVersion:"1.41"`
+
+	// DNSOptions are the options of the resolver of the container, like
+	// "ndots:2", along with the servers in DNS and the domains in
+	// DNSSearch. It requires Docker API 1.21 or newer.
+	DNSOptions []string `json:"DnsOptions,omitempty" yaml:"DnsOptions,omitempty" apiVersion:"1.21"`
+
+	// GroupAdd lists additional groups, by name or ID, of the processes of
+	// the container. It requires Docker API 1.20 or newer.
+	GroupAdd []string `json:"GroupAdd,omitempty" yaml:"GroupAdd,omitempty" apiVersion:"1.20"`
+
+	// StorageOpt are the options of the storage driver for the container,
+	// like its maximum size, StorageOptSize, with overlay2 on xfs. It
+	// requires Docker API 1.24 or newer.
+	StorageOpt map[string]string `json:"StorageOpt,omitempty" yaml:"StorageOpt,omitempty" apiVersion:"1.24"`
+
+	// DeviceCgroupRules allow the container to use devices, added later,
+	// in the format of the devices cgroup: type major:minor access, like
+	// "c 13:* rwm". It requires Docker API 1.28 or newer.
+	DeviceCgroupRules []string `json:"DeviceCgroupRules,omitempty" yaml:"DeviceCgroupRules,omitempty" apiVersion:"1.28"`
+
+	// OomKillDisable keeps the OOM killer from killing the processes of
+	// the container, and OomScoreAdj, from -1000 to 1000, makes them more
+	// or less likely to be killed. MemorySwappiness, from 0 to 100, is how
+	// likely the anonymous pages of the container are swapped, the daemon
+	// deciding when it's nil. KernelMemory limits the kernel memory of the
+	// container, in bytes. They require Docker API 1.19, 1.22, 1.20 and
+	// 1.21 or newer. KernelMemory is ignored since API 1.42.
+	OomKillDisable   bool   `json:"OomKillDisable,omitempty" yaml:"OomKillDisable,omitempty" apiVersion:"1.19"`
+	OomScoreAdj      int    `json:"OomScoreAdj,omitempty" yaml:"OomScoreAdj,omitempty" apiVersion:"1.22"`
+	MemorySwappiness *int64 `json:"MemorySwappiness,omitempty" yaml:"MemorySwappiness,omitempty" apiVersion:"1.20"`
+	KernelMemory     int64  `json:"KernelMemory,omitempty" yaml:"KernelMemory,omitempty" apiVersion:"1.21"`
+
+	// BlkioWeight, from 10 to 1000, is the share of the block IO of the
+	// container, and BlkioWeightDevice the ones on given devices. The
+	// other Blkio fields throttle the IO of the container on given
+	// devices, in bytes or operations per second. They require Docker API
+	// 1.19, and 1.22 for the devices, or newer.
+	BlkioWeight          uint16           `json:"BlkioWeight,omitempty" yaml:"BlkioWeight,omitempty" apiVersion:"1.19"`
+	BlkioWeightDevice    []WeightDevice   `json:"BlkioWeightDevice,omitempty" yaml:"BlkioWeightDevice,omitempty" apiVersion:"1.22"`
+	BlkioDeviceReadBps   []ThrottleDevice `json:"BlkioDeviceReadBps,omitempty" yaml:"BlkioDeviceReadBps,omitempty" apiVersion:"1.22"`
+	BlkioDeviceWriteBps  []ThrottleDevice `json:"BlkioDeviceWriteBps,omitempty" yaml:"BlkioDeviceWriteBps,omitempty" apiVersion:"1.22"`
+	BlkioDeviceReadIOps  []ThrottleDevice `json:"BlkioDeviceReadIOps,omitempty" yaml:"BlkioDeviceReadIOps,omitempty" apiVersion:"1.22"`
+	BlkioDeviceWriteIOps []ThrottleDevice `json:"BlkioDeviceWriteIOps,omitempty" yaml:"BlkioDeviceWriteIOps,omitempty" apiVersion:"1.22"`
+
+	// CpusetCpus and CpusetMems pin the container to the given CPUs and
+	// memory nodes, in the format of cpusets, like "0-3,6". NanoCpus is
+	// the number of CPUs of the container, in billionths, as returned by
+	// NanoCPUs. CPURealtimePeriod and CPURealtimeRuntime, in microseconds,
+	// give the container realtime scheduling. They require Docker API
+	// 1.19, and 1.25 for NanoCpus and realtime scheduling, or newer.
+	CpusetCpus         string `json:"CpusetCpus,omitempty" yaml:"CpusetCpus,omitempty" apiVersion:"1.19"`
+	CpusetMems         string `json:"CpusetMems,omitempty" yaml:"CpusetMems,omitempty" apiVersion:"1.19"`
+	NanoCpus           int64  `json:"NanoCpus,omitempty" yaml:"NanoCpus,omitempty" apiVersion:"1.25"`
+	CPURealtimePeriod  int64  `json:"CpuRealtimePeriod,omitempty" yaml:"CpuRealtimePeriod,omitempty" apiVersion:"1.25"`
+	CPURealtimeRuntime int64  `json:"CpuRealtimeRuntime,omitempty" yaml:"CpuRealtimeRuntime,omitempty" apiVersion:"1.25"`
+}
+
+// StorageOptSize is the option of HostConfig.StorageOpt limiting the size of
+// the writable layer of the container, like "10G".
+const StorageOptSize = "size"
+
+var deviceCgroupRule = regexp.MustCompile(`^[abc] ([0-9]+|\*):([0-9]+|\*) [rwm]{1,3}$`)
+
+// InvalidDeviceCgroupRule is the error returned by CreateContainer for the
+// rules of HostConfig.DeviceCgroupRules in the wrong format.
+type InvalidDeviceCgroupRule struct {
+	Rule string
+}
+
+func (err *InvalidDeviceCgroupRule) Error() string {
+	return "Invalid device cgroup rule: " + err.Rule
+}
+
+// Modes of the namespaces of a container, in HostConfig.
+const (
+	// NamespaceHost shares the namespace of the host.
+	NamespaceHost = "host"
+
+	// NamespacePrivate gives the container its own namespace.
+	NamespacePrivate = "private"
+
+	// NamespaceShareable gives the container its own IPC namespace,
+	// which other containers may share.
+	NamespaceShareable = "shareable"
+)
+
+// NamespaceOfContainer returns the mode sharing the namespace of the given
+// container, by name or ID, like a sidecar or a debugging container does.
+func NamespaceOfContainer(id string) string {
+	return "container:" + id
+}
+
+// TmpfsOptions returns the mount options of a tmpfs file system of the given
+// size in bytes, with the given permissions, for HostConfig.Tmpfs. A zero size
+// or mode keeps the default of the daemon: half of the memory of the host,
+// and 1777.
+func TmpfsOptions(size int64, mode os.FileMode) string {
+	var options []string
+	if size > 0 {
+		options = append(options, "size="+strconv.FormatInt(size, 10))
+	}
+	if mode != 0 {
+		perm := uint32(mode.Perm())
+		if mode&os.ModeSetuid != 0 {
+			perm |= 04000
+		}
+		if mode&os.ModeSetgid != 0 {
+			perm |= 02000
+		}
+		if mode&os.ModeSticky != 0 {
+			perm |= 01000
+		}
+		options = append(options, "mode="+strconv.FormatUint(uint64(perm), 8))
+	}
+	return strings.Join(options, ",")
+}
+
+// requestsCDIDevices tells whether the host config requests CDI devices.
+func (hostConfig *HostConfig) requestsCDIDevices() bool {
+	for _, request := range hostConfig.DeviceRequests {
+		if request.Driver == cdiDriver {
+			return true
+		}
+	}
+	return false
 }
 
 // StartContainer starts a container, returning an error in case of failure.
@@ -415,6 +785,69 @@ func (c *Client) StopContainer(id string, timeout uint) error {
 	return nil
 }
 
+// StopWithSignal stops a container by sending it the given signal, and kills
+// it with SIGKILL when it doesn't stop within the given timeout (in seconds).
+// It's like StopContainer, but the signal is chosen by the caller and the
+// escalation happens on the client side, so it works with any daemon.
+func (c *Client) StopWithSignal(id string, signal Signal, timeout uint) error {
+	container, err := c.InspectContainer(id)
+	if err != nil {
+		return err
+	}
+	if !container.State.Running {
+		return &ContainerNotRunning{ID: id}
+	}
+	return c.stopWithSignal(id, signal, timeout)
+}
+
+// StopOrKill stops a container with the signal configured in its StopSignal
+// setting, or SIGTERM when it's not set, and kills it with SIGKILL when it
+// doesn't stop within the given timeout (in seconds). See StopWithSignal.
+func (c *Client) StopOrKill(id string, timeout uint) error {
+	container, err := c.InspectContainer(id)
+	if err != nil {
+		return err
+	}
+	if !container.State.Running {
+		return &ContainerNotRunning{ID: id}
+	}
+	signal := SIGTERM
+	if container.Config != nil && container.Config.StopSignal != "" {
+		if signal, err = ParseSignal(container.Config.StopSignal); err != nil {
+			return err
+		}
+	}
+	return c.stopWithSignal(id, signal, timeout)
+}
+
+func (c *Client) stopWithSignal(id string, signal Signal, timeout uint) error {
+	// waiting concurrently is safe even if the container stops before the
+	// wait request is sent, as the wait returns right away in that case.
+	waitErrs := make(chan error, 1)
+	go func() {
+		_, err := c.WaitContainer(id)
+		waitErrs <- err
+	}()
+	if err := c.KillContainer(KillContainerOptions{ID: id, Signal: signal}); err != nil {
+		return err
+	}
+	select {
+	case err := <-waitErrs:
+		return err
+	case <-time.After(time.Duration(timeout) * time.Second):
+	}
+	if err := c.KillContainer(KillContainerOptions{ID: id, Signal: SIGKILL}); err != nil {
+		select {
+		case waitErr := <-waitErrs:
+			// the container stopped before being killed
+			return waitErr
+		default:
+			return err
+		}
+	}
+	return <-waitErrs
+}
+
 // RestartContainer stops a container, killing it after the given timeout (in
 // seconds), during the stop process.
 //
@@ -505,12 +938,27 @@ type KillContainerOptions struct {
 	// The signal to send to the container. When omitted, Docker server
 	// will assume SIGKILL.
 	Signal Signal
+
+	// SignalName is the signal to send to the container, in the formats
+	// accepted by ParseSignal, like "SIGHUP", "HUP" or "1". It overrides
+	// Signal when set.
+	SignalName string `qs:"-"`
 }
 
 // KillContainer kills a container, returning an error in case of failure.
 //
 // See http://goo.gl/TFkECx for more details.
 func (c *Client) KillContainer(opts KillContainerOptions) error {
+	if opts.SignalName != "" {
+		signal, err := ParseSignal(opts.SignalName)
+		if err != nil {
+			return err
+		}
+		opts.Signal = signal
+	}
+	if opts.Signal < 0 || opts.Signal > 64 {
+		return fmt.Errorf("invalid signal: %d", opts.Signal)
+	}
 	path := "/containers/" + opts.ID + "/kill" + "?" + queryString(opts)
 	_, status, err := c.do("POST", path, nil, false)
 	if status == http.StatusNotFound {
@@ -522,6 +970,12 @@ func (c *Client) KillContainer(opts KillContainerOptions) error {
 	return nil
 }
 
+// SendSignal sends the given signal to a container, in the formats accepted
+// by ParseSignal. It's KillContainer, for the signals that don't kill.
+func (c *Client) SendSignal(id, signal string) error {
+	return c.KillContainer(KillContainerOptions{ID: id, SignalName: signal})
+}
+
 // RemoveContainerOptions encapsulates options to remove a container.
 //
 // See http://goo.gl/ZB83ji for more details.
@@ -561,6 +1015,10 @@ type CopyFromContainerOptions struct {
 	OutputStream io.Writer `json:"-"`
 	Container    string    `json:"-"`
 	Resource     string
+
+	// TransferProgress, if set, is called with the total number of bytes
+	// written to OutputStream after every write.
+	TransferProgress func(written int64) `json:"-"`
 }
 
 // CopyFromContainer copy files or folders from a container, using a given
@@ -571,16 +1029,95 @@ func (c *Client) CopyFromContainer(opts CopyFromContainerOptions) error {
 	if opts.Container == "" {
 		return &NoSuchContainer{ID: opts.Container}
 	}
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
 	url := fmt.Sprintf("/containers/%s/copy", opts.Container)
-	body, status, err := c.do("POST", url, opts, false)
-	if status == http.StatusNotFound {
+	err = c.stream("POST", url, streamOptions{
+		setRawTerminal: true,
+		headers:        map[string]string{"Content-Type": "application/json"},
+		in:             bytes.NewReader(data),
+		stdout:         newProgressWriter(opts.OutputStream, opts.TransferProgress),
+	})
+	if e, ok := err.(*Error); ok && e.Status == http.StatusNotFound {
 		return &NoSuchContainer{ID: opts.Container}
 	}
-	if err != nil {
+	return err
+}
+
+// DownloadFromContainerOptions is the set of options that can be used when
+// downloading resources from a container.
+type DownloadFromContainerOptions struct {
+	OutputStream io.Writer `json:"-" qs:"-"`
+
+	// Path of the file or directory inside the container. The resource is
+	// written to OutputStream as a tar archive.
+	Path string `qs:"path"`
+
+	// TransferProgress, if set, is called with the total number of bytes
+	// written to OutputStream after every write.
+	TransferProgress func(written int64) `qs:"-"`
+
+	// BandwidthLimit, if positive, caps the rate of the download, in bytes
+	// per second.
+	BandwidthLimit int64 `qs:"-"`
+}
+
+// DownloadFromContainer downloads a tar archive of files or folders in a
+// container, writing it to the given output stream as it's received. It
+// requires Docker API 1.20 or newer.
+func (c *Client) DownloadFromContainer(id string, opts DownloadFromContainerOptions) error {
+	url := fmt.Sprintf("/containers/%s/archive?", id) + queryString(opts)
+	err := c.stream("GET", url, streamOptions{
+		setRawTerminal: true,
+		stdout:         newProgressWriter(newThrottledWriter(opts.OutputStream, opts.BandwidthLimit), opts.TransferProgress),
+	})
+	return archiveError(id, opts.Path, err)
+}
+
+// UploadToContainerOptions is the set of options that can be used when
+// uploading files or folders to a container.
+type UploadToContainerOptions struct {
+	// InputStream is a tar archive, extracted in the container.
+	InputStream io.Reader `json:"-" qs:"-"`
+
+	// Path of the directory inside the container where the archive is
+	// extracted. It must exist.
+	Path string `qs:"path"`
+
+	// NoOverwriteDirNonDir makes the upload fail when it would replace a
+	// directory with a file, or the other way around.
+	NoOverwriteDirNonDir bool `qs:"noOverwriteDirNonDir"`
+
+	// BandwidthLimit, if positive, caps the rate of the upload, in bytes
+	// per second.
+	BandwidthLimit int64 `qs:"-"`
+}
+
+// UploadToContainer extracts a tar archive into a directory of a container. It
+// requires Docker API 1.20 or newer.
+func (c *Client) UploadToContainer(id string, opts UploadToContainerOptions) error {
+	url := fmt.Sprintf("/containers/%s/archive?", id) + queryString(opts)
+	err := c.stream("PUT", url, streamOptions{
+		headers: map[string]string{"Content-Type": "application/x-tar"},
+		in:      newThrottledReader(opts.InputStream, opts.BandwidthLimit),
+	})
+	return archiveError(id, opts.Path, err)
+}
+
+// archiveError translates the errors of the archive endpoint, which answers
+// 404 both when the container and when the path in the container are
+// missing. The message of the daemon tells which one it is.
+func archiveError(id, path string, err error) error {
+	e, ok := err.(*Error)
+	if !ok || e.Status != http.StatusNotFound {
 		return err
 	}
-	io.Copy(opts.OutputStream, bytes.NewBuffer(body))
-	return nil
+	if strings.Contains(strings.ToLower(e.Message), "no such container") {
+		return &NoSuchContainer{ID: id}
+	}
+	return &NoSuchPath{Container: id, Path: path}
 }
 
 // WaitContainer blocks until the given container stops, return the exit code
@@ -588,7 +1125,12 @@ func (c *Client) CopyFromContainer(opts CopyFromContainerOptions) error {
 //
 // See http://goo.gl/J88DHU for more details.
 func (c *Client) WaitContainer(id string) (int, error) {
-	body, status, err := c.do("POST", "/containers/"+id+"/wait", nil, false)
+	path := "/containers/" + id + "/wait"
+	if c.isPodman() {
+		// Podman's default wait condition differs among versions.
+		path += "?condition=not-running"
+	}
+	body, status, err := c.do("POST", path, nil, false)
 	if status == http.StatusNotFound {
 		return 0, &NoSuchContainer{ID: id}
 	}
@@ -603,6 +1145,73 @@ func (c *Client) WaitContainer(id string) (int, error) {
 	return r.StatusCode, nil
 }
 
+// ErrEventStreamClosed is returned by StartAndWaitContainer when the event
+// stream ends before the container exits.
+var ErrEventStreamClosed = errors.New("event stream closed before the container exited")
+
+// ErrExitCodeUnknown is returned by StartAndWaitContainer when the container
+// was removed before its exit code could be read.
+var ErrExitCodeUnknown = errors.New("container removed before its exit code was known")
+
+// StartAndWaitContainer starts the given container and blocks until it
+// exits, returning its exit code.
+//
+// Unlike calling StartContainer and then WaitContainer, it subscribes to the
+// events of the container before starting it, so the exit of a container
+// that stops right away is never missed.
+//
+// Daemons older than Docker 1.10 don't report the exit code in the events,
+// so it's read by inspecting the container. With them, StartAndWaitContainer
+// returns ErrExitCodeUnknown when the container is removed as soon as it
+// exits, as with the AutoRemove option.
+func (c *Client) StartAndWaitContainer(id string, hostConfig *HostConfig) (int, error) {
+	container, err := c.InspectContainer(id)
+	if err != nil {
+		return 0, err
+	}
+	filters, err := json.Marshal(map[string][]string{"container": {container.ID}, "event": {"die"}})
+	if err != nil {
+		return 0, err
+	}
+	path := "/events?filters=" + url.QueryEscape(string(filters))
+	c.waitRateLimit(path)
+	resp, err := c.doRequest("GET", path, DoOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := c.StartContainer(id, hostConfig); err != nil {
+		return 0, err
+	}
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event APIEvents
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return 0, ErrEventStreamClosed
+			}
+			return 0, err
+		}
+		if event.ID != container.ID && event.Actor.ID != container.ID {
+			continue
+		}
+		if event.Status != "die" && event.Action != "die" {
+			continue
+		}
+		if code, err := strconv.Atoi(event.Actor.Attributes["exitCode"]); err == nil {
+			return code, nil
+		}
+		// older daemons don't report the exit code in the event
+		container, err := c.InspectContainer(id)
+		if _, ok := err.(*NoSuchContainer); ok {
+			return 0, ErrExitCodeUnknown
+		} else if err != nil {
+			return 0, err
+		}
+		return container.State.ExitCode, nil
+	}
+}
+
 // CommitContainerOptions aggregates parameters to the CommitContainer method.
 //
 // See http://goo.gl/Jn8pe8 for more details.
@@ -665,13 +1274,29 @@ type AttachToContainerOptions struct {
 	//
 	// It must be an unbuffered channel. Using a buffered channel can lead
 	// to unexpected behavior.
-	Success chan io.Closer
+	//
+	// It allows starting the container once the attach is established, so
+	// the early output of the container is not lost. AttachAndStartContainer
+	// does it.
+	Success chan struct{}
 
 	// Use raw terminal? Usually true when the container contains a TTY.
 	RawTerminal bool `qs:"-"`
 
 	// Dialer a function to use when dialing instead of net.Dial
 	Dialer func(string, string) (net.Conn, error) `qs:"-"`
+
+	// SigProxy forwards the signals received by the local process to the
+	// container while attached, like the --sig-proxy flag of docker attach.
+	// SIGINT and SIGTERM are sent to the container, and SIGWINCH resizes
+	// its TTY to the size of the terminal of OutputStream.
+	SigProxy bool `qs:"-"`
+
+	// ClassifyStreamEnd, with Stream, checks why the stream broke when it
+	// ends with an error, pinging the daemon and inspecting the container.
+	// The error is then a *StreamError if the daemon or the container is
+	// gone.
+	ClassifyStreamEnd bool `qs:"-"`
 }
 
 // AttachToContainer attaches to a container, using the given options.
@@ -682,7 +1307,56 @@ func (c *Client) AttachToContainer(opts AttachToContainerOptions) error {
 		return &NoSuchContainer{ID: opts.Container}
 	}
 	path := "/containers/" + opts.Container + "/attach?" + queryString(opts)
-	return c.hijack2("POST", path, opts.RawTerminal, opts.Dialer, opts.InputStream, opts.OutputStream, opts.ErrorStream, opts.Success, nil)
+	if opts.SigProxy {
+		defer c.proxySignals(opts.Container, opts.OutputStream)()
+	}
+	err := c.hijack2("POST", path, opts.RawTerminal, opts.Dialer, opts.InputStream, opts.OutputStream, opts.ErrorStream, opts.Success, nil)
+	if err != nil && opts.Stream && opts.ClassifyStreamEnd {
+		return c.classifyStreamEnd(opts.Container, err)
+	}
+	return err
+}
+
+// AttachAndStartContainer attaches to the given container and starts it once
+// the attach is established, so none of its output is lost, and blocks until
+// the attach ends, like AttachToContainer. The Success field of the options
+// is ignored.
+func (c *Client) AttachAndStartContainer(opts AttachToContainerOptions, hostConfig *HostConfig) error {
+	dial := opts.Dialer
+	if dial == nil {
+		dial = net.Dial
+	}
+	var mut sync.Mutex
+	var conn net.Conn
+	opts.Dialer = func(network, address string) (net.Conn, error) {
+		c, err := dial(network, address)
+		mut.Lock()
+		conn = c
+		mut.Unlock()
+		return c, err
+	}
+	success := make(chan struct{})
+	opts.Success = success
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.AttachToContainer(opts)
+	}()
+	select {
+	case <-success:
+	case err := <-errs:
+		return err
+	}
+	if err := c.StartContainer(opts.Container, hostConfig); err != nil {
+		// the container won't produce any output, abort the attach
+		mut.Lock()
+		conn.Close()
+		mut.Unlock()
+		success <- struct{}{}
+		<-errs
+		return err
+	}
+	success <- struct{}{}
+	return <-errs
 }
 
 // LogsOptions represents the set of options used when getting logs from a
@@ -701,9 +1375,28 @@ type LogsOptions struct {
 
 	// Use raw terminal? Usually true when the container contains a TTY.
 	RawTerminal bool `qs:"-"`
+
+	// TransferProgress, if set, is called with the total number of bytes
+	// written to OutputStream and ErrorStream after every write.
+	TransferProgress func(written int64) `qs:"-"`
+
+	// Since is a UNIX timestamp. Only the logs written since then are
+	// returned. Requires Docker API 1.19 or newer.
+	Since int64
+
+	// Reconnect, with Follow, reconnects the stream when it breaks while
+	// the container is still running, waiting for the daemon when it
+	// restarts. The logs are resumed from the timestamp of the last line
+	// received, so no line is lost or received twice. When the daemon is
+	// not back within ReconnectTimeout, which defaults to one minute, or
+	// when the container is removed, Logs returns a *StreamError.
+	Reconnect        bool          `qs:"-"`
+	ReconnectTimeout time.Duration `qs:"-"`
 }
 
-// Logs gets stdout and stderr logs from the specified container.
+// Logs gets stdout and stderr logs from the specified container. It fails
+// with ErrLogsNotSupported when the logging driver of the container doesn't
+// support reading the logs.
 //
 // See http://goo.gl/rLhKSU for more details.
 func (c *Client) Logs(opts LogsOptions) error {
@@ -713,8 +1406,133 @@ func (c *Client) Logs(opts LogsOptions) error {
 	if opts.Tail == "" {
 		opts.Tail = "all"
 	}
+	stdout, stderr := opts.OutputStream, opts.ErrorStream
+	if opts.TransferProgress != nil {
+		var written int64
+		stdout = &progressWriter{w: stdout, written: &written, progress: opts.TransferProgress}
+		stderr = &progressWriter{w: stderr, written: &written, progress: opts.TransferProgress}
+	}
+	var err error
+	if opts.Follow && opts.Reconnect {
+		err = c.followLogs(opts, stdout, stderr)
+	} else {
+		err = c.logs(opts, stdout, stderr)
+	}
+	if e, ok := err.(*Error); ok && strings.Contains(e.Message, "does not support reading") {
+		// the daemon doesn't tell which driver, the container does
+		if container, inspectErr := c.InspectContainer(opts.Container); inspectErr == nil && container.HostConfig != nil {
+			return &ErrLogsNotSupported{Container: opts.Container, Driver: container.HostConfig.LogConfig.Type}
+		}
+	}
+	return err
+}
+
+// followLogs follows the logs of the container, reconnecting the stream
+// until it ends with the container stopped. The logs are requested with
+// timestamps, which tell where to resume them from.
+func (c *Client) followLogs(opts LogsOptions, stdout, stderr io.Writer) error {
+	if opts.ReconnectTimeout <= 0 {
+		opts.ReconnectTimeout = defaultReconnectTimeout
+	}
+	resumer := &logResumer{keep: opts.Timestamps}
+	opts.Timestamps = true
+	out, errOut := resumer.writer(stdout), resumer.writer(stderr)
+	for {
+		err := c.logs(opts, out, errOut)
+		if _, ok := err.(*Error); ok {
+			return err
+		}
+		if pingErr := c.Ping(); pingErr != nil {
+			if waitErr := c.WaitForDaemon(opts.ReconnectTimeout); waitErr != nil {
+				if err == nil {
+					err = io.ErrUnexpectedEOF
+				}
+				return &StreamError{Cause: StreamDaemonGone, Err: err}
+			}
+		}
+		container, inspectErr := c.InspectContainer(opts.Container)
+		if _, ok := inspectErr.(*NoSuchContainer); ok {
+			return &StreamError{Cause: StreamContainerGone, Err: err}
+		} else if inspectErr != nil {
+			return inspectErr
+		}
+		if err == nil && !container.State.Running {
+			if err := out.flush(); err != nil {
+				return err
+			}
+			return errOut.flush()
+		}
+		out.discard()
+		errOut.discard()
+		resumer.resume()
+		if !resumer.last.IsZero() {
+			opts.Since = resumer.last.Unix()
+			opts.Tail = "all"
+		}
+	}
+}
+
+func (c *Client) logs(opts LogsOptions, stdout, stderr io.Writer) error {
 	path := "/containers/" + opts.Container + "/logs?" + queryString(opts)
-	return c.stream("GET", path, opts.RawTerminal, false, nil, nil, opts.OutputStream, opts.ErrorStream)
+	return c.stream("GET", path, streamOptions{
+		setRawTerminal: opts.RawTerminal,
+		stdout:         stdout,
+		stderr:         stderr,
+	})
+}
+
+// LogsTruncatedMarker is appended by CaptureLogs to the logs truncated to
+// the maximum size.
+const LogsTruncatedMarker = "\n[logs truncated]\n"
+
+const defaultCaptureLogsMax = 1 << 20
+
+// errLogsCaptured stops the logs once CaptureLogs reaches the maximum size.
+var errLogsCaptured = errors.New("logs captured")
+
+// CaptureLogs returns the logs of the container, stdout and stderr
+// interleaved, up to maxBytes, which defaults to 1 MiB. Longer logs are
+// truncated, followed by LogsTruncatedMarker, and the rest of the logs is
+// not downloaded, so a chatty container can't exhaust the memory.
+func (c *Client) CaptureLogs(id string, maxBytes int) ([]byte, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultCaptureLogsMax
+	}
+	container, err := c.InspectContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	w := &logCapture{max: maxBytes}
+	err = c.Logs(LogsOptions{
+		Container:    id,
+		OutputStream: w,
+		ErrorStream:  w,
+		Stdout:       true,
+		Stderr:       true,
+		RawTerminal:  container.Config != nil && container.Config.Tty,
+	})
+	if err == errLogsCaptured {
+		w.buf.WriteString(LogsTruncatedMarker)
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+// logCapture buffers the logs up to max bytes.
+type logCapture struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (w *logCapture) Write(p []byte) (int, error) {
+	if room := w.max - w.buf.Len(); len(p) > room {
+		w.buf.Write(p[:room])
+		return room, errLogsCaptured
+	}
+	return w.buf.Write(p)
 }
 
 // ResizeContainerTTY resizes the terminal to the given height and width.
@@ -733,6 +1551,10 @@ func (c *Client) ResizeContainerTTY(id string, height, width int) error {
 type ExportContainerOptions struct {
 	ID           string
 	OutputStream io.Writer
+
+	// TransferProgress, if set, is called with the total number of bytes
+	// written to OutputStream after every write.
+	TransferProgress func(written int64)
 }
 
 // ExportContainer export the contents of container id as tar archive
@@ -744,9 +1566,69 @@ func (c *Client) ExportContainer(opts ExportContainerOptions) error {
 		return &NoSuchContainer{ID: opts.ID}
 	}
 	url := fmt.Sprintf("/containers/%s/export", opts.ID)
-	return c.stream("GET", url, true, false, nil, nil, opts.OutputStream, nil)
+	return c.stream("GET", url, streamOptions{
+		setRawTerminal: true,
+		stdout:         newProgressWriter(opts.OutputStream, opts.TransferProgress),
+	})
 }
 
+var errImportAborted = errors.New("import aborted")
+
+// FlattenContainerOptions is the set of parameters to the FlattenContainer
+// method.
+type FlattenContainerOptions struct {
+	Container  string
+	Repository string
+	Tag        string
+
+	// OutputStream, when set, receives the progress of the import.
+	OutputStream io.Writer
+}
+
+// FlattenContainer creates an image with a single layer from the filesystem
+// of the given container, by exporting the container and importing the
+// result. The image doesn't keep the configuration of the container, like
+// its command or its environment, nor its history.
+func (c *Client) FlattenContainer(opts FlattenContainerOptions) (*Image, error) {
+	if opts.Container == "" {
+		return nil, &NoSuchContainer{ID: opts.Container}
+	}
+	if opts.Repository == "" {
+		return nil, ErrNoSuchImage
+	}
+	output := opts.OutputStream
+	if output == nil {
+		output = ioutil.Discard
+	}
+	pr, pw := io.Pipe()
+	exportErrs := make(chan error, 1)
+	go func() {
+		err := c.ExportContainer(ExportContainerOptions{ID: opts.Container, OutputStream: pw})
+		pw.CloseWithError(err)
+		exportErrs <- err
+	}()
+	err := c.ImportImage(ImportImageOptions{
+		Repository:   opts.Repository,
+		Tag:          opts.Tag,
+		Source:       "-",
+		InputStream:  pr,
+		OutputStream: output,
+	})
+	// unblocks the export when the import fails before consuming it
+	pr.CloseWithError(errImportAborted)
+	if exportErr := <-exportErrs; exportErr != nil && exportErr != errImportAborted {
+		return nil, exportErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.InspectImage(joinRepositoryTag(opts.Repository, opts.Tag))
+}
+
+// ErrContainerAlreadyExists is the error returned by CreateContainer when the
+// container name is already in use.
+var ErrContainerAlreadyExists = errors.New("container already exists")
+
 // NoSuchContainer is the error returned when a given container does not exist.
 type NoSuchContainer struct {
 	ID string
@@ -756,6 +1638,28 @@ func (err *NoSuchContainer) Error() string {
 	return "No such container: " + err.ID
 }
 
+// NoSuchPath is the error returned when a given path does not exist in a
+// container.
+type NoSuchPath struct {
+	Container string
+	Path      string
+}
+
+func (err *NoSuchPath) Error() string {
+	return "No such path in container " + err.Container + ": " + err.Path
+}
+
+// ErrLogsNotSupported is the error returned by Logs when the logging driver
+// of the container doesn't support reading the logs.
+type ErrLogsNotSupported struct {
+	Container string
+	Driver    string
+}
+
+func (err *ErrLogsNotSupported) Error() string {
+	return "Logging driver " + err.Driver + " of container " + err.Container + " does not support reading"
+}
+
 // ContainerAlreadyRunning is the error returned when a given container is
 // already running.
 type ContainerAlreadyRunning struct {
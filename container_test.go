@@ -18,6 +18,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -436,6 +437,179 @@ func TestCreateContainer(t *testing.T) {
 	}
 }
 
+func TestCreateContainerNewerHostConfig(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client, err := NewVersionedClient("http://localhost:4243", "1.41")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.HTTPClient = &http.Client{Transport: fakeRT}
+	hostConfig := HostConfig{ConsoleSize: &[2]uint{24, 80}, Annotations: map[string]string{"io.kubernetes.cri.container-type": "container"}}
+	opts := CreateContainerOptions{Config: &Config{Image: "base"}, HostConfig: &hostConfig}
+	_, err = client.CreateContainer(opts)
+	if e, ok := err.(*UnsupportedField); !ok || e.Field != "HostConfig.ConsoleSize" {
+		t.Errorf("CreateContainer: wrong error. Want *UnsupportedField. Got %#v.", err)
+	}
+	client, err = NewVersionedClient("http://localhost:4243", "1.43")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.HTTPClient = &http.Client{Transport: fakeRT}
+	if _, err := client.CreateContainer(opts); err != nil {
+		t.Fatal(err)
+	}
+	var gotBody map[string]interface{}
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	sent := gotBody["HostConfig"].(map[string]interface{})
+	if !reflect.DeepEqual(sent["ConsoleSize"], []interface{}{24.0, 80.0}) || sent["Annotations"] == nil {
+		t.Errorf("CreateContainer: wrong HostConfig. Got %#v.", sent)
+	}
+}
+
+func TestCDIDevices(t *testing.T) {
+	request, err := CDIDevices("nvidia.com/gpu=0", "vendor.example.com/net-card=eth_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DeviceRequest{Driver: "cdi", DeviceIDs: []string{"nvidia.com/gpu=0", "vendor.example.com/net-card=eth_1"}}
+	if !reflect.DeepEqual(request, expected) {
+		t.Errorf("CDIDevices: wrong request. Want %#v. Got %#v.", expected, request)
+	}
+	for _, name := range []string{"gpu=0", "nvidia.com/gpu", "nvidia.com/=0", "/dev/nvidia0"} {
+		if _, err := CDIDevices(name); err == nil {
+			t.Errorf("CDIDevices(%q): expected an error", name)
+		}
+	}
+}
+
+func TestCreateContainerCDIDevices(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client, err := NewVersionedClient("http://localhost:4243", "1.43")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.HTTPClient = &http.Client{Transport: fakeRT}
+	request, _ := CDIDevices("nvidia.com/gpu=all")
+	_, err = client.CreateContainer(CreateContainerOptions{
+		Config:     &Config{Image: "base"},
+		HostConfig: &HostConfig{DeviceRequests: []DeviceRequest{request}},
+	})
+	if e, ok := err.(*UnsupportedField); !ok || e.Field != "DeviceRequest.Driver" {
+		t.Errorf("CreateContainer: wrong error. Want *UnsupportedField. Got %#v.", err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("CreateContainer: CDI devices should not be sent. Got %d requests.", len(fakeRT.requests))
+	}
+}
+
+func TestCreateContainerHardened(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	hostConfig := HostConfig{
+		ReadonlyRootfs: true,
+		MaskedPaths:    []string{"/proc/kcore", "/proc/keys"},
+		ReadonlyPaths:  []string{"/proc/sys"},
+	}
+	if _, err := client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "base"}, HostConfig: &hostConfig}); err != nil {
+		t.Fatal(err)
+	}
+	var gotBody struct{ HostConfig HostConfig }
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotBody.HostConfig, hostConfig) {
+		t.Errorf("CreateContainer: wrong HostConfig. Want %#v. Got %#v.", hostConfig, gotBody.HostConfig)
+	}
+}
+
+func TestCreateContainerNamespaces(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	init := true
+	hostConfig := HostConfig{
+		Init:         &init,
+		PidMode:      NamespaceOfContainer("app"),
+		IpcMode:      NamespaceOfContainer("app"),
+		UTSMode:      NamespaceHost,
+		CgroupnsMode: NamespacePrivate,
+	}
+	if _, err := client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "base"}, HostConfig: &hostConfig}); err != nil {
+		t.Fatal(err)
+	}
+	var gotBody struct{ HostConfig HostConfig }
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotBody.HostConfig, hostConfig) {
+		t.Errorf("CreateContainer: wrong HostConfig. Want %#v. Got %#v.", hostConfig, gotBody.HostConfig)
+	}
+}
+
+func TestCreateContainerDeviceCgroupRules(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	hostConfig := HostConfig{
+		StorageOpt:        map[string]string{StorageOptSize: "10G"},
+		DeviceCgroupRules: []string{"c 13:* rwm", "b 8:0 r"},
+	}
+	if _, err := client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "base"}, HostConfig: &hostConfig}); err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range []string{"c 13:* rwx", "d 1:1 r", "c 13 rwm"} {
+		hostConfig.DeviceCgroupRules = []string{rule}
+		_, err := client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "base"}, HostConfig: &hostConfig})
+		expected := &InvalidDeviceCgroupRule{Rule: rule}
+		if !reflect.DeepEqual(err, expected) {
+			t.Errorf("CreateContainer: wrong error. Want %#v. Got %#v.", expected, err)
+		}
+	}
+	if len(fakeRT.requests) != 1 {
+		t.Errorf("CreateContainer: invalid rules should not be sent. Got %d requests.", len(fakeRT.requests))
+	}
+}
+
+func TestTmpfsOptions(t *testing.T) {
+	var tests = []struct {
+		size     int64
+		mode     os.FileMode
+		expected string
+	}{
+		{64 << 20, 0755, "size=67108864,mode=755"},
+		{0, os.ModeSticky | 0777, "mode=1777"},
+		{1024, 0, "size=1024"},
+		{0, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := TmpfsOptions(tt.size, tt.mode); got != tt.expected {
+			t.Errorf("TmpfsOptions(%d, %v): wrong options. Want %q. Got %q.", tt.size, tt.mode, tt.expected, got)
+		}
+	}
+}
+
+func TestCreateContainerImageDigest(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id":"4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	config := Config{Image: "busybox:1.0@sha256:4b82b6ae"}
+	if _, err := client.CreateContainer(CreateContainerOptions{Config: &config}); err != nil {
+		t.Fatal(err)
+	}
+	var gotBody Config
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	if gotBody.Image != "busybox@sha256:4b82b6ae" {
+		t.Errorf("CreateContainer: wrong image. Want %q. Got %q.", "busybox@sha256:4b82b6ae", gotBody.Image)
+	}
+	if config.Image != "busybox:1.0@sha256:4b82b6ae" {
+		t.Errorf("CreateContainer: the config of the caller should not change. Got %q.", config.Image)
+	}
+}
+
 func TestCreateContainerImageNotFound(t *testing.T) {
 	client := newTestClient(&FakeRoundTripper{message: "No such image", status: http.StatusNotFound})
 	config := Config{AttachStdout: true, AttachStdin: true}
@@ -448,6 +622,89 @@ func TestCreateContainerImageNotFound(t *testing.T) {
 	}
 }
 
+func TestCreateContainerPlatform(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "{}", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := CreateContainerOptions{Name: "web", Platform: "linux/arm64", Config: &Config{}}
+	_, err := client.CreateContainer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	expected := map[string][]string{"name": {"web"}, "platform": {"linux/arm64"}}
+	if got := map[string][]string(req.URL.Query()); !reflect.DeepEqual(got, expected) {
+		t.Errorf("CreateContainer: wrong query string. Want %#v. Got %#v.", expected, got)
+	}
+}
+
+func TestCreateContainerNameConflict(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "Conflict", status: http.StatusConflict})
+	container, err := client.CreateContainer(CreateContainerOptions{Name: "web", Config: &Config{}})
+	if container != nil {
+		t.Errorf("CreateContainer: expected <nil> container, got %#v.", container)
+	}
+	if err != ErrContainerAlreadyExists {
+		t.Errorf("CreateContainer: Wrong error. Want %#v. Got %#v.", ErrContainerAlreadyExists, err)
+	}
+}
+
+func TestCreateContainerNameConflictRemove(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		case len(paths) == 1:
+			http.Error(w, "Conflict", http.StatusConflict)
+		default:
+			w.Write([]byte(`{"Id":"abc123"}`))
+		}
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	opts := CreateContainerOptions{Name: "web", Config: &Config{}, OnNameConflict: NameConflictRemove}
+	container, err := client.CreateContainer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.ID != "abc123" || container.Name != "web" {
+		t.Errorf("CreateContainer: wrong container. Got %#v.", container)
+	}
+	expected := []string{"POST /containers/create", "DELETE /containers/web", "POST /containers/create"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("CreateContainer: wrong requests. Want %#v. Got %#v.", expected, paths)
+	}
+}
+
+func TestCreateContainerNameConflictSuffix(t *testing.T) {
+	var names []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names = append(names, r.URL.Query().Get("name"))
+		if len(names) < 3 {
+			http.Error(w, "Conflict", http.StatusConflict)
+			return
+		}
+		w.Write([]byte(`{"Id":"abc123"}`))
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	opts := CreateContainerOptions{Name: "web", Config: &Config{}, OnNameConflict: NameConflictSuffix}
+	container, err := client.CreateContainer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 {
+		t.Fatalf("CreateContainer: wrong number of attempts. Want 3. Got %d.", len(names))
+	}
+	if names[0] != "web" || !strings.HasPrefix(names[2], "web-") || names[1] == names[2] {
+		t.Errorf("CreateContainer: wrong names tried: %#v.", names)
+	}
+	if container.Name != names[2] {
+		t.Errorf("CreateContainer: wrong name. Want %q. Got %q.", names[2], container.Name)
+	}
+}
+
 func TestCreateContainerWithHostConfig(t *testing.T) {
 	fakeRT := &FakeRoundTripper{message: "{}", status: http.StatusOK}
 	client := newTestClient(fakeRT)
@@ -688,6 +945,40 @@ func TestKillContainerSignal(t *testing.T) {
 	}
 }
 
+func TestKillContainerSignalName(t *testing.T) {
+	var tests = []struct {
+		name   string
+		signal string
+	}{
+		{"SIGHUP", "1"},
+		{"usr1", "10"},
+		{"15", "15"},
+	}
+	for _, tt := range tests {
+		fakeRT := &FakeRoundTripper{message: "", status: http.StatusNoContent}
+		client := newTestClient(fakeRT)
+		if err := client.SendSignal("a2334", tt.name); err != nil {
+			t.Fatal(err)
+		}
+		if signal := fakeRT.requests[0].URL.Query().Get("signal"); signal != tt.signal {
+			t.Errorf("SendSignal(%q): Wrong query string in request. Want %q. Got %q.", tt.name, tt.signal, signal)
+		}
+	}
+}
+
+func TestKillContainerInvalidSignal(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusNoContent}
+	client := newTestClient(fakeRT)
+	for _, opts := range []KillContainerOptions{{ID: "a2334", SignalName: "SIGFOO"}, {ID: "a2334", Signal: 65}} {
+		if err := client.KillContainer(opts); err == nil {
+			t.Errorf("KillContainer(%#v): expected an error", opts)
+		}
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("KillContainer: invalid signals should not be sent. Got %d requests.", len(fakeRT.requests))
+	}
+}
+
 func TestKillContainerNotFound(t *testing.T) {
 	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
 	err := client.KillContainer(KillContainerOptions{ID: "a2334"})
@@ -1148,6 +1439,37 @@ func TestLogs(t *testing.T) {
 	}
 }
 
+func TestLogsTransferProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 5})
+		w.Write([]byte("hello"))
+		w.Write([]byte{2, 0, 0, 0, 0, 0, 0, 6})
+		w.Write([]byte("world!"))
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	var stdout, stderr bytes.Buffer
+	var written int64
+	opts := LogsOptions{
+		Container:        "a123456",
+		OutputStream:     &stdout,
+		ErrorStream:      &stderr,
+		Stdout:           true,
+		Stderr:           true,
+		TransferProgress: func(n int64) { written = n },
+	}
+	err := client.Logs(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "hello" || stderr.String() != "world!" {
+		t.Errorf("Logs: wrong output. Got stdout %q and stderr %q.", stdout.String(), stderr.String())
+	}
+	if written != 11 {
+		t.Errorf("Logs: wrong progress. Want %d. Got %d.", 11, written)
+	}
+}
+
 func TestLogsNilStdoutDoesntFail(t *testing.T) {
 	var req http.Request
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1275,6 +1597,58 @@ func TestLogsRawTerminal(t *testing.T) {
 	}
 }
 
+func TestLogsNotSupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/containers/a123456/json" {
+			w.Write([]byte(`{"Id":"a123456","HostConfig":{"LogConfig":{"Type":"syslog"}}}`))
+			return
+		}
+		http.Error(w, "configured logging driver does not support reading", http.StatusNotImplemented)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	var buf bytes.Buffer
+	err := client.Logs(LogsOptions{Container: "a123456", OutputStream: &buf, Stdout: true})
+	expected := &ErrLogsNotSupported{Container: "a123456", Driver: "syslog"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("Logs: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+}
+
+func TestCaptureLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/containers/a123456/json" {
+			w.Write([]byte(`{"Id":"a123456","Config":{"Tty":false}}`))
+			return
+		}
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 19})
+		w.Write([]byte("something happened!"))
+		w.Write([]byte{2, 0, 0, 0, 0, 0, 0, 12})
+		w.Write([]byte("then failed!"))
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	var tests = []struct {
+		max      int
+		expected string
+	}{
+		{1024, "something happened!then failed!"},
+		{31, "something happened!then failed!"},
+		{23, "something happened!then" + LogsTruncatedMarker},
+	}
+	for _, tt := range tests {
+		logs, err := client.CaptureLogs("a123456", tt.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(logs) != tt.expected {
+			t.Errorf("CaptureLogs(%d): wrong logs. Want %q. Got %q.", tt.max, tt.expected, logs)
+		}
+	}
+}
+
 func TestLogsNoContainer(t *testing.T) {
 	var client Client
 	err := client.Logs(LogsOptions{})
@@ -1338,6 +1712,72 @@ func TestExportContainerViaUnixSocket(t *testing.T) {
 	}
 }
 
+func TestFlattenContainer(t *testing.T) {
+	content := "exported container tar content"
+	var imported []byte
+	var importQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/containers/4fa6e0f0c678/export":
+			w.Write([]byte(content))
+		case r.URL.Path == "/images/create":
+			importQuery = r.URL.Query()
+			imported, _ = ioutil.ReadAll(r.Body)
+			w.Write([]byte(`{"status":"sha256:b750fe79"}`))
+		case r.URL.Path == "/images/tsuru/flat:1.0/json":
+			w.Write([]byte(`{"Id":"b750fe79269d2ec9a3c593ef05b4332b1d1a02a62b4accb2c21d589ff2f5f2dc"}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := client.FlattenContainer(FlattenContainerOptions{Container: "4fa6e0f0c678", Repository: "tsuru/flat", Tag: "1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(imported) != content {
+		t.Errorf("FlattenContainer: wrong imported content. Want %q. Got %q.", content, string(imported))
+	}
+	expectedQuery := url.Values{"fromSrc": {"-"}, "repo": {"tsuru/flat"}, "tag": {"1.0"}}
+	if !reflect.DeepEqual(importQuery, expectedQuery) {
+		t.Errorf("FlattenContainer: wrong import query. Want %#v. Got %#v.", expectedQuery, importQuery)
+	}
+	if image.ID != "b750fe79269d2ec9a3c593ef05b4332b1d1a02a62b4accb2c21d589ff2f5f2dc" {
+		t.Errorf("FlattenContainer: wrong image ID. Got %q.", image.ID)
+	}
+}
+
+func TestFlattenContainerExportFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/images/create" {
+			ioutil.ReadAll(r.Body)
+			return
+		}
+		http.Error(w, "no such container", http.StatusNotFound)
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.FlattenContainer(FlattenContainerOptions{Container: "4fa6e0f0c678", Repository: "tsuru/flat"})
+	if e, ok := err.(*Error); !ok || e.Status != http.StatusNotFound {
+		t.Errorf("FlattenContainer: wrong error. Want a 404 *Error. Got %#v.", err)
+	}
+}
+
+func TestFlattenContainerRequiresRepository(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{status: http.StatusOK})
+	_, err := client.FlattenContainer(FlattenContainerOptions{Container: "4fa6e0f0c678"})
+	if err != ErrNoSuchImage {
+		t.Errorf("FlattenContainer: wrong error. Want %#v. Got %#v.", ErrNoSuchImage, err)
+	}
+}
+
 func runStreamConnServer(t *testing.T, network, laddr string, listening chan<- string, done chan<- int) {
 	defer close(done)
 	l, err := net.Listen(network, laddr)
@@ -1391,6 +1831,68 @@ func TestCopyFromContainer(t *testing.T) {
 	}
 }
 
+func TestCopyFromContainerNotFound(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	opts := CopyFromContainerOptions{Container: "a123456", Resource: "/etc/hosts", OutputStream: &bytes.Buffer{}}
+	err := client.CopyFromContainer(opts)
+	expected := &NoSuchContainer{ID: "a123456"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("CopyFromContainer: Wrong error returned. Want %#v. Got %#v.", expected, err)
+	}
+}
+
+func TestDownloadFromContainer(t *testing.T) {
+	content := "tar content"
+	fakeRT := &FakeRoundTripper{message: content, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	var out bytes.Buffer
+	var progress []int64
+	opts := DownloadFromContainerOptions{
+		Path:             "/etc/hosts",
+		OutputStream:     &out,
+		TransferProgress: func(n int64) { progress = append(progress, n) },
+	}
+	err := client.DownloadFromContainer("a123456", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != content {
+		t.Errorf("DownloadFromContainer: wrong output. Want %q. Got %q.", content, out.String())
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != int64(len(content)) {
+		t.Errorf("DownloadFromContainer: wrong progress. Want final value %d. Got %v.", len(content), progress)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != "GET" {
+		t.Errorf("DownloadFromContainer: wrong HTTP method. Want %q. Got %q.", "GET", req.Method)
+	}
+	if req.URL.Path != "/containers/a123456/archive" {
+		t.Errorf("DownloadFromContainer: wrong path. Want %q. Got %q.", "/containers/a123456/archive", req.URL.Path)
+	}
+	if got := req.URL.Query().Get("path"); got != "/etc/hosts" {
+		t.Errorf("DownloadFromContainer: wrong path parameter. Want %q. Got %q.", "/etc/hosts", got)
+	}
+}
+
+func TestDownloadFromContainerNotFound(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	err := client.DownloadFromContainer("a123456", DownloadFromContainerOptions{Path: "/", OutputStream: &bytes.Buffer{}})
+	expected := &NoSuchContainer{ID: "a123456"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("DownloadFromContainer: Wrong error returned. Want %#v. Got %#v.", expected, err)
+	}
+}
+
+func TestDownloadFromContainerPathNotFound(t *testing.T) {
+	message := "Could not find the file /missing in container a123456"
+	client := newTestClient(&FakeRoundTripper{message: message, status: http.StatusNotFound})
+	err := client.DownloadFromContainer("a123456", DownloadFromContainerOptions{Path: "/missing", OutputStream: &bytes.Buffer{}})
+	expected := &NoSuchPath{Container: "a123456", Path: "/missing"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("DownloadFromContainer: Wrong error returned. Want %#v. Got %#v.", expected, err)
+	}
+}
+
 func TestCopyFromContainerEmptyContainer(t *testing.T) {
 	client := newTestClient(&FakeRoundTripper{status: http.StatusOK})
 	err := client.CopyFromContainer(CopyFromContainerOptions{})
@@ -1527,3 +2029,291 @@ func TestTopContainerWithPsArgs(t *testing.T) {
 		t.Errorf("TopContainer: Expected URI to have %q. Got %q.", expectedURI, fakeRT.requests[0].URL.String())
 	}
 }
+
+func TestContainerExists(t *testing.T) {
+	var tests = []struct {
+		status   int
+		exists   bool
+		hasError bool
+	}{
+		{http.StatusOK, true, false},
+		{http.StatusNotFound, false, false},
+		{http.StatusInternalServerError, false, true},
+	}
+	for _, tt := range tests {
+		fakeRT := &FakeRoundTripper{message: `{"Id":"4fa6e0f0c678"}`, status: tt.status}
+		client := newTestClient(fakeRT)
+		exists, err := client.ContainerExists("4fa6e0f0c678")
+		if exists != tt.exists || (err != nil) != tt.hasError {
+			t.Errorf("ContainerExists (status %d): wrong result. Want %v, error: %v. Got %v, %v.", tt.status, tt.exists, tt.hasError, exists, err)
+		}
+		req := fakeRT.requests[0]
+		if req.Method != "GET" || req.URL.Path != "/containers/4fa6e0f0c678/json" {
+			t.Errorf("ContainerExists: wrong request. Got %s %s.", req.Method, req.URL.Path)
+		}
+	}
+}
+
+// stopServer simulates a running container that stops when it receives one
+// of the given signals, recording the signals it gets.
+func stopServer(stopSignals []string, inspect string) (*httptest.Server, func() []string) {
+	var mut sync.Mutex
+	var signals []string
+	stopped := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/4fa6e0f0c678/json":
+			w.Write([]byte(inspect))
+		case "/containers/4fa6e0f0c678/kill":
+			signal := r.URL.Query().Get("signal")
+			mut.Lock()
+			signals = append(signals, signal)
+			mut.Unlock()
+			for _, s := range stopSignals {
+				if s == signal {
+					close(stopped)
+				}
+			}
+		case "/containers/4fa6e0f0c678/wait":
+			<-stopped
+			w.Write([]byte(`{"StatusCode":137}`))
+		}
+	}))
+	return server, func() []string {
+		mut.Lock()
+		defer mut.Unlock()
+		return signals
+	}
+}
+
+func TestStopWithSignal(t *testing.T) {
+	server, signals := stopServer([]string{"2"}, `{"Id":"4fa6e0f0c678","State":{"Running":true}}`)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.StopWithSignal("4fa6e0f0c678", SIGINT, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := signals(); !reflect.DeepEqual(got, []string{"2"}) {
+		t.Errorf("StopWithSignal: wrong signals. Want %#v. Got %#v.", []string{"2"}, got)
+	}
+}
+
+func TestStopWithSignalEscalates(t *testing.T) {
+	server, signals := stopServer([]string{"9"}, `{"Id":"4fa6e0f0c678","State":{"Running":true}}`)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.StopWithSignal("4fa6e0f0c678", SIGINT, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := signals(); !reflect.DeepEqual(got, []string{"2", "9"}) {
+		t.Errorf("StopWithSignal: wrong signals. Want %#v. Got %#v.", []string{"2", "9"}, got)
+	}
+}
+
+func TestStopWithSignalNotRunning(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: `{"Id":"4fa6e0f0c678","State":{"Running":false}}`, status: http.StatusOK})
+	err := client.StopWithSignal("4fa6e0f0c678", SIGINT, 10)
+	if _, ok := err.(*ContainerNotRunning); !ok {
+		t.Errorf("StopWithSignal: wrong error. Want a *ContainerNotRunning. Got %#v.", err)
+	}
+}
+
+func TestStopOrKillUsesStopSignal(t *testing.T) {
+	var tests = []struct {
+		inspect  string
+		expected string
+	}{
+		{`{"Id":"4fa6e0f0c678","Config":{"StopSignal":"SIGQUIT"},"State":{"Running":true}}`, "3"},
+		{`{"Id":"4fa6e0f0c678","Config":{},"State":{"Running":true}}`, "15"},
+	}
+	for _, tt := range tests {
+		server, signals := stopServer([]string{tt.expected}, tt.inspect)
+		client, err := NewClient(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.StopOrKill("4fa6e0f0c678", 10); err != nil {
+			t.Fatal(err)
+		}
+		if got := signals(); !reflect.DeepEqual(got, []string{tt.expected}) {
+			t.Errorf("StopOrKill: wrong signals. Want %#v. Got %#v.", []string{tt.expected}, got)
+		}
+		server.Close()
+	}
+}
+
+// startAndWaitServer simulates a container that exits as soon as it starts,
+// sending the given die event.
+func startAndWaitServer(t *testing.T, dieEvent string) (*httptest.Server, *string) {
+	started := make(chan struct{})
+	var eventsQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/web/json":
+			w.Write([]byte(`{"Id":"4fa6e0f0c678","State":{"ExitCode":5}}`))
+		case "/events":
+			eventsQuery = r.URL.Query().Get("filters")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-started
+			w.Write([]byte(`{"status":"start","id":"4fa6e0f0c678","time":1429790400}` + "\n"))
+			w.Write([]byte(`{"status":"die","id":"a2344f0c0000","time":1429790401}` + "\n"))
+			w.Write([]byte(dieEvent + "\n"))
+		case "/containers/web/start":
+			close(started)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("StartAndWaitContainer: unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	return server, &eventsQuery
+}
+
+func TestStartAndWaitContainer(t *testing.T) {
+	dieEvent := `{"status":"die","id":"4fa6e0f0c678","Type":"container","Action":"die","Actor":{"ID":"4fa6e0f0c678","Attributes":{"exitCode":"3"}},"time":1429790401}`
+	server, eventsQuery := startAndWaitServer(t, dieEvent)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := client.StartAndWaitContainer("web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 {
+		t.Errorf("StartAndWaitContainer: wrong exit code. Want 3. Got %d.", code)
+	}
+	expected := `{"container":["4fa6e0f0c678"],"event":["die"]}`
+	if *eventsQuery != expected {
+		t.Errorf("StartAndWaitContainer: wrong event filters. Want %q. Got %q.", expected, *eventsQuery)
+	}
+}
+
+func TestStartAndWaitContainerWithoutExitCodeInEvent(t *testing.T) {
+	server, _ := startAndWaitServer(t, `{"status":"die","id":"4fa6e0f0c678","time":1429790401}`)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := client.StartAndWaitContainer("web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 5 {
+		t.Errorf("StartAndWaitContainer: wrong exit code. Want 5. Got %d.", code)
+	}
+}
+
+func TestStartAndWaitContainerRemovedWithoutExitCode(t *testing.T) {
+	inspects := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/web/json":
+			if inspects++; inspects > 1 {
+				http.Error(w, "no such container", http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"Id":"4fa6e0f0c678"}`))
+		case "/events":
+			w.Write([]byte(`{"status":"die","id":"4fa6e0f0c678","time":1429790401}` + "\n"))
+		case "/containers/web/start":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.StartAndWaitContainer("web", nil); err != ErrExitCodeUnknown {
+		t.Errorf("StartAndWaitContainer: wrong error. Want %#v. Got %#v.", ErrExitCodeUnknown, err)
+	}
+}
+
+func TestStartAndWaitContainerStreamClosed(t *testing.T) {
+	server, _ := startAndWaitServer(t, "")
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.StartAndWaitContainer("web", nil); err != ErrEventStreamClosed {
+		t.Errorf("StartAndWaitContainer: wrong error. Want %#v. Got %#v.", ErrEventStreamClosed, err)
+	}
+}
+
+func TestAttachAndStartContainer(t *testing.T) {
+	var mut sync.Mutex
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		calls = append(calls, r.URL.Path)
+		mut.Unlock()
+		switch r.URL.Path {
+		case "/containers/a123456/attach":
+			w.Header().Set("Connection", "close")
+			w.Write([]byte("hello"))
+		case "/containers/a123456/start":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	var stdout bytes.Buffer
+	opts := AttachToContainerOptions{
+		Container:    "a123456",
+		OutputStream: &stdout,
+		Stdout:       true,
+		Stream:       true,
+		RawTerminal:  true,
+	}
+	if err := client.AttachAndStartContainer(opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/containers/a123456/attach", "/containers/a123456/start"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("AttachAndStartContainer: wrong calls. Want %#v. Got %#v.", expected, calls)
+	}
+	if stdout.String() != "hello" {
+		t.Errorf("AttachAndStartContainer: wrong stdout. Want %q. Got %q.", "hello", stdout.String())
+	}
+}
+
+func TestAttachAndStartContainerStartFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/a123456/attach":
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Type: application/vnd.docker.raw-stream\r\n\r\n"))
+			// blocks until the client aborts the attach
+			ioutil.ReadAll(conn)
+		case "/containers/a123456/start":
+			http.Error(w, "no such container", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	opts := AttachToContainerOptions{
+		Container:    "a123456",
+		OutputStream: ioutil.Discard,
+		Stdout:       true,
+		Stream:       true,
+		RawTerminal:  true,
+	}
+	err := client.AttachAndStartContainer(opts, nil)
+	if _, ok := err.(*NoSuchContainer); !ok {
+		t.Errorf("AttachAndStartContainer: wrong error. Want a *NoSuchContainer. Got %#v.", err)
+	}
+}
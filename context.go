@@ -0,0 +1,187 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultContext is the name of the context that targets the daemon
+// described by the environment variables DOCKER_HOST, DOCKER_TLS_VERIFY and
+// DOCKER_CERT_PATH.
+const DefaultContext = "default"
+
+// ErrNoSuchContext is returned by NewClientFromContext when the context
+// doesn't exist.
+var ErrNoSuchContext = errors.New("no such docker context")
+
+// contextMetadata is the content of the meta.json file of a context, stored
+// by the docker CLI in ~/.docker/contexts/meta/<sha256 of the name>.
+type contextMetadata struct {
+	Name      string
+	Endpoints map[string]contextEndpoint
+}
+
+type contextEndpoint struct {
+	Host          string
+	SkipTLSVerify bool
+}
+
+// NewClientFromContext returns a Client instance ready for communication with
+// the daemon of the given docker context, as created by the `docker context`
+// command. The client uses the endpoint and the TLS material stored in the
+// context.
+//
+// When name is empty, the current context is used: the one named by the
+// DOCKER_CONTEXT environment variable or, when DOCKER_HOST isn't set either,
+// the currentContext of the docker config.json file. The default context
+// behaves like NewClientFromEnv.
+//
+// Contexts are read from the directory named by the DOCKER_CONFIG environment
+// variable, or from ~/.docker.
+func NewClientFromContext(name string) (*Client, error) {
+	if name == "" {
+		var err error
+		if name, err = currentContext(); err != nil {
+			return nil, err
+		}
+	}
+	if name == DefaultContext {
+		return NewClientFromEnv()
+	}
+	dir := filepath.Join(dockerConfigDir(), "contexts")
+	id := contextID(name)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "meta", id, "meta.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoSuchContext
+		}
+		return nil, err
+	}
+	var meta contextMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	endpoint, ok := meta.Endpoints["docker"]
+	if !ok || endpoint.Host == "" {
+		return nil, ErrInvalidEndpoint
+	}
+	tlsConfig, err := contextTLSConfig(filepath.Join(dir, "tls", id, "docker"), endpoint.SkipTLSVerify)
+	if err != nil {
+		return nil, err
+	}
+	host := endpoint.Host
+	if tlsConfig != nil && strings.HasPrefix(host, "tcp://") {
+		host = "https://" + strings.TrimPrefix(host, "tcp://")
+	}
+	client, err := NewClient(host)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		client.TLSConfig = tlsConfig
+		client.HTTPClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
+	return client, nil
+}
+
+// currentContext returns the name of the context selected by the
+// environment, or by the docker config.json file.
+func currentContext() (string, error) {
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" {
+		return name, nil
+	}
+	if os.Getenv("DOCKER_HOST") != "" {
+		return DefaultContext, nil
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dockerConfigDir(), "config.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return DefaultContext, nil
+		}
+		return "", err
+	}
+	var config struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", err
+	}
+	if config.CurrentContext == "" {
+		return DefaultContext, nil
+	}
+	return config.CurrentContext, nil
+}
+
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.Getenv("HOME"), ".docker")
+}
+
+// contextID returns the name of the directories storing the given context.
+func contextID(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// contextTLSConfig loads the TLS material of a context endpoint, stored in
+// the ca.pem, cert.pem and key.pem files of the given directory. It returns
+// nil when the context has no TLS material.
+func contextTLSConfig(dir string, skipVerify bool) (*tls.Config, error) {
+	ca, err := readOptionalFile(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		return nil, err
+	}
+	cert, err := readOptionalFile(filepath.Join(dir, "cert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	key, err := readOptionalFile(filepath.Join(dir, "key.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if ca == nil && cert == nil && key == nil && !skipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: skipVerify}
+	if ca != nil {
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("Could not add RootCA pem")
+		}
+		tlsConfig.RootCAs = caPool
+	}
+	if cert != nil || key != nil {
+		if cert == nil || key == nil {
+			return nil, errors.New("Both cert and key path are required")
+		}
+		tlsCert, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{tlsCert}
+	}
+	return tlsConfig, nil
+}
+
+func readOptionalFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
@@ -0,0 +1,143 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeContext stores a context in the given docker config directory, the
+// way the docker CLI does, copying the TLS material from testing/data.
+func writeContext(t *testing.T, configDir, name, host string, tlsFiles ...string) {
+	id := contextID(name)
+	metaDir := filepath.Join(configDir, "contexts", "meta", id)
+	if err := os.MkdirAll(metaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	meta := `{"Name":"` + name + `","Metadata":{},"Endpoints":{"docker":{"Host":"` + host + `","SkipTLSVerify":false}}}`
+	if err := ioutil.WriteFile(filepath.Join(metaDir, "meta.json"), []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if len(tlsFiles) == 0 {
+		return
+	}
+	tlsDir := filepath.Join(configDir, "contexts", "tls", id, "docker")
+	if err := os.MkdirAll(tlsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range tlsFiles {
+		data, err := ioutil.ReadFile(filepath.Join("testing", "data", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tlsDir, file), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNewClientFromContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setEnv(map[string]string{"DOCKER_CONFIG": dir, "DOCKER_CONTEXT": "", "DOCKER_HOST": ""})()
+	writeContext(t, dir, "remote", "tcp://remote:2375")
+	client, err := NewClientFromContext("remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Endpoint() != "tcp://remote:2375" {
+		t.Errorf("NewClientFromContext: wrong endpoint. Want %q. Got %q.", "tcp://remote:2375", client.Endpoint())
+	}
+	if client.TLSConfig != nil {
+		t.Errorf("NewClientFromContext: unexpected TLS config: %#v.", client.TLSConfig)
+	}
+}
+
+func TestNewClientFromContextTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setEnv(map[string]string{"DOCKER_CONFIG": dir, "DOCKER_CONTEXT": "", "DOCKER_HOST": ""})()
+	writeContext(t, dir, "secure", "tcp://remote:2376", "ca.pem", "cert.pem", "key.pem")
+	client, err := NewClientFromContext("secure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Endpoint() != "https://remote:2376" {
+		t.Errorf("NewClientFromContext: wrong endpoint. Want %q. Got %q.", "https://remote:2376", client.Endpoint())
+	}
+	if client.TLSConfig == nil || client.TLSConfig.RootCAs == nil || len(client.TLSConfig.Certificates) != 1 {
+		t.Errorf("NewClientFromContext: wrong TLS config: %#v.", client.TLSConfig)
+	}
+}
+
+func TestNewClientFromContextIncompleteKeyPair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setEnv(map[string]string{"DOCKER_CONFIG": dir})()
+	writeContext(t, dir, "broken", "tcp://remote:2376", "cert.pem")
+	if _, err := NewClientFromContext("broken"); err == nil {
+		t.Error("NewClientFromContext: expected an error for a certificate without key")
+	}
+}
+
+func TestNewClientFromContextNotFound(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setEnv(map[string]string{"DOCKER_CONFIG": dir})()
+	if _, err := NewClientFromContext("missing"); err != ErrNoSuchContext {
+		t.Errorf("NewClientFromContext: wrong error. Want %#v. Got %#v.", ErrNoSuchContext, err)
+	}
+}
+
+func TestNewClientFromContextCurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setEnv(map[string]string{"DOCKER_CONFIG": dir, "DOCKER_CONTEXT": "", "DOCKER_HOST": ""})()
+	writeContext(t, dir, "fromconfig", "tcp://config:2375")
+	writeContext(t, dir, "fromenv", "tcp://env:2375")
+	config := `{"auths":{},"currentContext":"fromconfig"}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		context  string
+		host     string
+		expected string
+	}{
+		{"", "", "tcp://config:2375"},
+		{"fromenv", "", "tcp://env:2375"},
+		{"fromenv", "tcp://host:2375", "tcp://env:2375"},
+		{"", "tcp://host:2375", "tcp://host:2375"},
+	}
+	for _, tt := range tests {
+		os.Setenv("DOCKER_CONTEXT", tt.context)
+		os.Setenv("DOCKER_HOST", tt.host)
+		client, err := NewClientFromContext("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.Endpoint() != tt.expected {
+			t.Errorf("NewClientFromContext(%q, %q): wrong endpoint. Want %q. Got %q.", tt.context, tt.host, tt.expected, client.Endpoint())
+		}
+	}
+}
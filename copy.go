@@ -0,0 +1,214 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// ErrNotRegularFile is returned by CopyFileToContainer and
+	// CopyFileFromContainer when the source is not a regular file.
+	ErrNotRegularFile = errors.New("not a regular file")
+
+	// ErrMissingDestination is returned by CopyFileToContainer and
+	// CopyFileFromContainer when the destination is not specified.
+	ErrMissingDestination = errors.New("missing copy destination")
+)
+
+// CopyFileToContainerOptions is the set of options that can be used when
+// copying a file to a container.
+type CopyFileToContainerOptions struct {
+	// Path of the file inside the container. Missing parent directories
+	// are created, owned by root and with mode 0755.
+	Path string
+
+	// Source is the path of the local file to copy. It's ignored when
+	// Content is set.
+	Source string
+
+	// Content is the content of the file. When Size is not set, Content is
+	// read into memory to find out its size.
+	Content io.Reader
+	Size    int64
+
+	// Mode, UID and GID set the permissions and the owner of the file in
+	// the container. Mode defaults to the mode of the source file, or 0644
+	// for Content, and the file is owned by root by default.
+	Mode os.FileMode
+	UID  int
+	GID  int
+
+	// ModTime defaults to the modification time of the source file, or to
+	// the current time for Content.
+	ModTime time.Time
+}
+
+// CopyFileToContainer copies a single file to a container, taking care of
+// packing it in a tar archive as expected by UploadToContainer. It requires
+// Docker API 1.20 or newer.
+func (c *Client) CopyFileToContainer(id string, opts CopyFileToContainerOptions) error {
+	if opts.Path == "" {
+		return ErrMissingDestination
+	}
+	content, hdr, err := copySource(opts)
+	if err != nil {
+		return err
+	}
+	if closer, ok := content.(io.Closer); ok {
+		defer closer.Close()
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(hdr)
+		if err == nil {
+			_, err = io.CopyN(tw, content, hdr.Size)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	err = c.UploadToContainer(id, UploadToContainerOptions{InputStream: pr, Path: "/"})
+	pr.Close()
+	return err
+}
+
+// copySource returns the content of the file described by opts, along with
+// its tar header. The name in the header is relative to the root of the
+// container.
+func copySource(opts CopyFileToContainerOptions) (io.Reader, *tar.Header, error) {
+	hdr := tar.Header{
+		Name:     path.Clean("/" + opts.Path)[1:],
+		Typeflag: tar.TypeReg,
+		Mode:     int64(opts.Mode.Perm()),
+		Uid:      opts.UID,
+		Gid:      opts.GID,
+		ModTime:  opts.ModTime,
+		Size:     opts.Size,
+	}
+	if hdr.Name == "" {
+		return nil, nil, ErrMissingDestination
+	}
+	var content io.Reader
+	if opts.Content != nil {
+		content = opts.Content
+		if opts.Size <= 0 {
+			data, err := ioutil.ReadAll(opts.Content)
+			if err != nil {
+				return nil, nil, err
+			}
+			content = bytes.NewReader(data)
+			hdr.Size = int64(len(data))
+		}
+		if opts.Mode == 0 {
+			hdr.Mode = 0644
+		}
+		if hdr.ModTime.IsZero() {
+			hdr.ModTime = time.Now()
+		}
+	} else {
+		info, err := os.Stat(opts.Source)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return nil, nil, ErrNotRegularFile
+		}
+		f, err := os.Open(opts.Source)
+		if err != nil {
+			return nil, nil, err
+		}
+		content = f
+		hdr.Size = info.Size()
+		if opts.Mode == 0 {
+			hdr.Mode = int64(info.Mode().Perm())
+		}
+		if hdr.ModTime.IsZero() {
+			hdr.ModTime = info.ModTime()
+		}
+	}
+	return content, &hdr, nil
+}
+
+// CopyFileFromContainerOptions is the set of options that can be used when
+// copying a file from a container.
+type CopyFileFromContainerOptions struct {
+	// Path of the file inside the container.
+	Path string
+
+	// OutputStream receives the content of the file.
+	OutputStream io.Writer
+
+	// Destination is the path of the local file where the content is
+	// written, with the mode of the file in the container. Missing parent
+	// directories are created. It's ignored when OutputStream is set.
+	Destination string
+}
+
+// CopyFileFromContainer copies a single file from a container, taking care of
+// unpacking it from the tar archive returned by DownloadFromContainer. It
+// returns the header of the file in the archive, which holds its mode, owner
+// and modification time. It requires Docker API 1.20 or newer.
+func (c *Client) CopyFileFromContainer(id string, opts CopyFileFromContainerOptions) (*tar.Header, error) {
+	if opts.OutputStream == nil && opts.Destination == "" {
+		return nil, ErrMissingDestination
+	}
+	pr, pw := io.Pipe()
+	downloadErrs := make(chan error, 1)
+	go func() {
+		err := c.DownloadFromContainer(id, DownloadFromContainerOptions{Path: opts.Path, OutputStream: pw})
+		pw.CloseWithError(err)
+		downloadErrs <- err
+	}()
+	hdr, err := copyFromArchive(pr, opts)
+	// unblocks the download when the archive is not fully read
+	pr.CloseWithError(errCopyDone)
+	if downloadErr := <-downloadErrs; downloadErr != nil && downloadErr != errCopyDone {
+		return nil, downloadErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hdr, nil
+}
+
+var errCopyDone = errors.New("copy done")
+
+func copyFromArchive(r io.Reader, opts CopyFileFromContainerOptions) (*tar.Header, error) {
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+		return nil, ErrNotRegularFile
+	}
+	if opts.OutputStream != nil {
+		_, err = io.Copy(opts.OutputStream, tr)
+		return hdr, err
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.Destination), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(opts.Destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(f, tr); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return hdr, f.Close()
+}
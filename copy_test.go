@@ -0,0 +1,246 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// uploadServer returns a server accepting archive uploads, which stores the
+// query and the archive of the last upload.
+func uploadServer(query *string, archive *bytes.Buffer) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/containers/a2344/archive" {
+			http.Error(w, "no such container", http.StatusNotFound)
+			return
+		}
+		*query = r.URL.RawQuery
+		archive.ReadFrom(r.Body)
+	}))
+}
+
+func readArchive(t *testing.T, archive *bytes.Buffer) (*tar.Header, string) {
+	tr := tar.NewReader(archive)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hdr, string(content)
+}
+
+func TestUploadToContainer(t *testing.T) {
+	fakeRT := &FakeRoundTripper{status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	input := bytes.NewBufferString("tar content")
+	opts := UploadToContainerOptions{InputStream: input, Path: "/tmp", NoOverwriteDirNonDir: true}
+	if err := client.UploadToContainer("a2344", opts); err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != "PUT" {
+		t.Errorf("UploadToContainer: wrong method. Want %q. Got %q.", "PUT", req.Method)
+	}
+	if req.URL.Path != "/containers/a2344/archive" {
+		t.Errorf("UploadToContainer: wrong path. Want %q. Got %q.", "/containers/a2344/archive", req.URL.Path)
+	}
+	if got := req.URL.RawQuery; got != "noOverwriteDirNonDir=1&path=%2Ftmp" {
+		t.Errorf("UploadToContainer: wrong query string. Got %q.", got)
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "application/x-tar" {
+		t.Errorf("UploadToContainer: wrong content type. Want %q. Got %q.", "application/x-tar", ct)
+	}
+}
+
+func TestUploadToContainerNotFound(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	err := client.UploadToContainer("a2344", UploadToContainerOptions{InputStream: &bytes.Buffer{}, Path: "/"})
+	expected := &NoSuchContainer{ID: "a2344"}
+	if e, ok := err.(*NoSuchContainer); !ok || e.ID != expected.ID {
+		t.Errorf("UploadToContainer: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+}
+
+func TestCopyFileToContainerContent(t *testing.T) {
+	var query string
+	var archive bytes.Buffer
+	server := uploadServer(&query, &archive)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2015, 4, 23, 10, 0, 0, 0, time.UTC)
+	opts := CopyFileToContainerOptions{
+		Path:    "/etc/app/config.yml",
+		Content: strings.NewReader("debug: true\n"),
+		Mode:    0600,
+		UID:     1000,
+		GID:     1001,
+		ModTime: modTime,
+	}
+	if err := client.CopyFileToContainer("a2344", opts); err != nil {
+		t.Fatal(err)
+	}
+	if query != "path=%2F" {
+		t.Errorf("CopyFileToContainer: wrong query string. Want %q. Got %q.", "path=%2F", query)
+	}
+	hdr, content := readArchive(t, &archive)
+	if hdr.Name != "etc/app/config.yml" {
+		t.Errorf("CopyFileToContainer: wrong name. Want %q. Got %q.", "etc/app/config.yml", hdr.Name)
+	}
+	if hdr.Mode != 0600 || hdr.Uid != 1000 || hdr.Gid != 1001 || !hdr.ModTime.Equal(modTime) {
+		t.Errorf("CopyFileToContainer: wrong header: %#v.", hdr)
+	}
+	if content != "debug: true\n" {
+		t.Errorf("CopyFileToContainer: wrong content. Want %q. Got %q.", "debug: true\n", content)
+	}
+}
+
+func TestCopyFileToContainerSource(t *testing.T) {
+	var query string
+	var archive bytes.Buffer
+	server := uploadServer(&query, &archive)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.TempFile("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("#!/bin/sh\n")
+	f.Close()
+	if err := os.Chmod(f.Name(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	opts := CopyFileToContainerOptions{Path: "usr/local/bin/run", Source: f.Name()}
+	if err := client.CopyFileToContainer("a2344", opts); err != nil {
+		t.Fatal(err)
+	}
+	hdr, content := readArchive(t, &archive)
+	if hdr.Name != "usr/local/bin/run" || hdr.Mode != 0755 || hdr.Size != 10 {
+		t.Errorf("CopyFileToContainer: wrong header: %#v.", hdr)
+	}
+	if content != "#!/bin/sh\n" {
+		t.Errorf("CopyFileToContainer: wrong content. Want %q. Got %q.", "#!/bin/sh\n", content)
+	}
+}
+
+func TestCopyFileToContainerErrors(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{status: http.StatusOK})
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var tests = []struct {
+		opts     CopyFileToContainerOptions
+		expected error
+	}{
+		{CopyFileToContainerOptions{Content: strings.NewReader("")}, ErrMissingDestination},
+		{CopyFileToContainerOptions{Path: "/", Content: strings.NewReader("")}, ErrMissingDestination},
+		{CopyFileToContainerOptions{Path: "/tmp/dir", Source: dir}, ErrNotRegularFile},
+	}
+	for _, tt := range tests {
+		if err := client.CopyFileToContainer("a2344", tt.opts); err != tt.expected {
+			t.Errorf("CopyFileToContainer(%#v): wrong error. Want %#v. Got %#v.", tt.opts, tt.expected, err)
+		}
+	}
+	if len(client.HTTPClient.Transport.(*FakeRoundTripper).requests) != 0 {
+		t.Error("CopyFileToContainer: unexpected request")
+	}
+}
+
+func archiveWith(t *testing.T, hdr *tar.Header, content string) string {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte(content))
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestCopyFileFromContainer(t *testing.T) {
+	archive := archiveWith(t, &tar.Header{Name: "config.yml", Mode: 0640, Uid: 1000, Size: 12}, "debug: true\n")
+	fakeRT := &FakeRoundTripper{message: archive, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	var out bytes.Buffer
+	hdr, err := client.CopyFileFromContainer("a2344", CopyFileFromContainerOptions{Path: "/etc/app/config.yml", OutputStream: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "debug: true\n" {
+		t.Errorf("CopyFileFromContainer: wrong content. Want %q. Got %q.", "debug: true\n", out.String())
+	}
+	if hdr.Mode != 0640 || hdr.Uid != 1000 {
+		t.Errorf("CopyFileFromContainer: wrong header: %#v.", hdr)
+	}
+	if got := fakeRT.requests[0].URL.Query().Get("path"); got != "/etc/app/config.yml" {
+		t.Errorf("CopyFileFromContainer: wrong path. Want %q. Got %q.", "/etc/app/config.yml", got)
+	}
+}
+
+func TestCopyFileFromContainerDestination(t *testing.T) {
+	archive := archiveWith(t, &tar.Header{Name: "run", Mode: 0750, Size: 10}, "#!/bin/sh\n")
+	client := newTestClient(&FakeRoundTripper{message: archive, status: http.StatusOK})
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "bin", "run")
+	if _, err := client.CopyFileFromContainer("a2344", CopyFileFromContainerOptions{Path: "/run", Destination: dest}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("CopyFileFromContainer: wrong mode. Want %v. Got %v.", os.FileMode(0750), info.Mode().Perm())
+	}
+	content, _ := ioutil.ReadFile(dest)
+	if string(content) != "#!/bin/sh\n" {
+		t.Errorf("CopyFileFromContainer: wrong content. Want %q. Got %q.", "#!/bin/sh\n", string(content))
+	}
+}
+
+func TestCopyFileFromContainerNotRegularFile(t *testing.T) {
+	archive := archiveWith(t, &tar.Header{Name: "app", Typeflag: tar.TypeDir, Mode: 0755}, "")
+	client := newTestClient(&FakeRoundTripper{message: archive, status: http.StatusOK})
+	var out bytes.Buffer
+	_, err := client.CopyFileFromContainer("a2344", CopyFileFromContainerOptions{Path: "/app", OutputStream: &out})
+	if err != ErrNotRegularFile {
+		t.Errorf("CopyFileFromContainer: wrong error. Want %#v. Got %#v.", ErrNotRegularFile, err)
+	}
+}
+
+func TestCopyFileFromContainerNotFound(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	var out bytes.Buffer
+	_, err := client.CopyFileFromContainer("a2344", CopyFileFromContainerOptions{Path: "/app", OutputStream: &out})
+	if _, ok := err.(*NoSuchContainer); !ok {
+		t.Errorf("CopyFileFromContainer: wrong error. Want a *NoSuchContainer. Got %#v.", err)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldDiff is a difference between the spec of a container and the
+// container, as reported by Diff. Field is the path of the field, like
+// Config.Image, with the key for the fields holding a list of variables or a
+// map, like Config.Env[PORT] or HostConfig.PortBindings[80/tcp]. Desired and
+// Actual are nil when the key is missing.
+type FieldDiff struct {
+	Field   string
+	Desired interface{}
+	Actual  interface{}
+}
+
+// Diff compares the spec of a container with the container, as inspected,
+// reporting the differences in the image, the command, the environment, the
+// labels, the mounts, the ports and the settings of the host.
+//
+// The values filled in by the daemon, or by the image, are ignored: the
+// fields left empty in the spec, like the command or the restart policy, are
+// not compared, the environment variables, labels and exposed ports added by
+// the image are allowed, and the equivalent values, like the implicit latest
+// tag or the default network mode, are considered equal.
+func Diff(desired CreateContainerOptions, actual Container) []FieldDiff {
+	var d differ
+	config, actualConfig := desired.Config, actual.Config
+	if config == nil {
+		config = &Config{}
+	}
+	if actualConfig == nil {
+		actualConfig = &Config{}
+	}
+	if normalizeImage(config.Image) != normalizeImage(actualConfig.Image) {
+		d.add("Config.Image", config.Image, actualConfig.Image)
+	}
+	if len(config.Cmd) > 0 {
+		d.compare("Config.Cmd", config.Cmd, actualConfig.Cmd)
+	}
+	if len(config.Entrypoint) > 0 {
+		d.compare("Config.Entrypoint", config.Entrypoint, actualConfig.Entrypoint)
+	}
+	if config.User != "" {
+		d.compare("Config.User", config.User, actualConfig.User)
+	}
+	if config.WorkingDir != "" {
+		d.compare("Config.WorkingDir", config.WorkingDir, actualConfig.WorkingDir)
+	}
+	d.compareSubset("Config.Env", envMap(config.Env), envMap(actualConfig.Env))
+	d.compareSubset("Config.Labels", config.Labels, actualConfig.Labels)
+	for port := range config.ExposedPorts {
+		if _, ok := actualConfig.ExposedPorts[port]; !ok {
+			d.add("Config.ExposedPorts["+string(port)+"]", port, nil)
+		}
+	}
+
+	hostConfig, actualHostConfig := desired.HostConfig, actual.HostConfig
+	if hostConfig == nil {
+		hostConfig = &HostConfig{}
+	}
+	if actualHostConfig == nil {
+		actualHostConfig = &HostConfig{}
+	}
+	d.compareSets("HostConfig.Binds", hostConfig.Binds, actualHostConfig.Binds)
+	d.compareMaps("HostConfig.Tmpfs", hostConfig.Tmpfs, actualHostConfig.Tmpfs)
+	d.comparePortBindings(hostConfig.PortBindings, actualHostConfig.PortBindings)
+	if hostConfig.RestartPolicy.Name != "" || hostConfig.RestartPolicy.MaximumRetryCount != 0 {
+		desiredPolicy, actualPolicy := hostConfig.RestartPolicy, actualHostConfig.RestartPolicy
+		if actualPolicy.Name == "" {
+			actualPolicy.Name = "no"
+		}
+		d.compare("HostConfig.RestartPolicy", desiredPolicy, actualPolicy)
+	}
+	if hostConfig.NetworkMode != "" {
+		d.compare("HostConfig.NetworkMode", normalizeNetworkMode(hostConfig.NetworkMode), normalizeNetworkMode(actualHostConfig.NetworkMode))
+	}
+	d.compare("HostConfig.Privileged", hostConfig.Privileged, actualHostConfig.Privileged)
+	d.compare("HostConfig.ReadonlyRootfs", hostConfig.ReadonlyRootfs, actualHostConfig.ReadonlyRootfs)
+	d.compareSets("HostConfig.CapAdd", hostConfig.CapAdd, actualHostConfig.CapAdd)
+	d.compareSets("HostConfig.CapDrop", hostConfig.CapDrop, actualHostConfig.CapDrop)
+	return d.diffs
+}
+
+type differ struct {
+	diffs []FieldDiff
+}
+
+func (d *differ) add(field string, desired, actual interface{}) {
+	d.diffs = append(d.diffs, FieldDiff{Field: field, Desired: desired, Actual: actual})
+}
+
+func (d *differ) compare(field string, desired, actual interface{}) {
+	if !reflect.DeepEqual(desired, actual) {
+		d.add(field, desired, actual)
+	}
+}
+
+// compareSubset compares the keys of the desired map, allowing more keys in
+// the actual map.
+func (d *differ) compareSubset(field string, desired, actual map[string]string) {
+	for _, key := range sortedKeys(desired) {
+		if value, ok := actual[key]; !ok {
+			d.add(field+"["+key+"]", desired[key], nil)
+		} else if value != desired[key] {
+			d.add(field+"["+key+"]", desired[key], value)
+		}
+	}
+}
+
+// compareMaps compares all of the keys of both maps.
+func (d *differ) compareMaps(field string, desired, actual map[string]string) {
+	d.compareSubset(field, desired, actual)
+	for _, key := range sortedKeys(actual) {
+		if _, ok := desired[key]; !ok {
+			d.add(field+"["+key+"]", nil, actual[key])
+		}
+	}
+}
+
+// compareSets compares two lists, regardless of the order.
+func (d *differ) compareSets(field string, desired, actual []string) {
+	for _, value := range desired {
+		if !containsString(actual, value) {
+			d.add(field, value, nil)
+		}
+	}
+	for _, value := range actual {
+		if !containsString(desired, value) {
+			d.add(field, nil, value)
+		}
+	}
+}
+
+func (d *differ) comparePortBindings(desired, actual map[Port][]PortBinding) {
+	ports := make(map[Port]bool)
+	for port := range desired {
+		ports[port] = true
+	}
+	for port := range actual {
+		ports[port] = true
+	}
+	var sorted []string
+	for port := range ports {
+		sorted = append(sorted, string(port))
+	}
+	sort.Strings(sorted)
+	for _, port := range sorted {
+		desiredBindings := normalizePortBindings(desired[Port(port)])
+		actualBindings := normalizePortBindings(actual[Port(port)])
+		if !reflect.DeepEqual(desiredBindings, actualBindings) {
+			field := "HostConfig.PortBindings[" + port + "]"
+			switch {
+			case desiredBindings == nil:
+				d.add(field, nil, actual[Port(port)])
+			case actualBindings == nil:
+				d.add(field, desired[Port(port)], nil)
+			default:
+				d.add(field, desired[Port(port)], actual[Port(port)])
+			}
+		}
+	}
+}
+
+// normalizePortBindings returns the bindings in a comparable form, binding
+// the empty host IP to all of the interfaces.
+func normalizePortBindings(bindings []PortBinding) []string {
+	if len(bindings) == 0 {
+		return nil
+	}
+	normalized := make([]string, len(bindings))
+	for i, binding := range bindings {
+		ip := binding.HostIP
+		if ip == "" {
+			ip = "0.0.0.0"
+		}
+		normalized[i] = ip + ":" + binding.HostPort
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// normalizeImage adds the implicit latest tag to the references without a
+// tag nor a digest.
+func normalizeImage(image string) string {
+	if image == "" || strings.HasPrefix(image, "sha256:") {
+		return image
+	}
+	if repository, tag := ParseRepositoryTag(image); tag == "" {
+		return repository + ":" + DefaultTag
+	}
+	return image
+}
+
+// normalizeNetworkMode maps the names of the default network to bridge.
+func normalizeNetworkMode(mode string) string {
+	if mode == "" || mode == "default" {
+		return "bridge"
+	}
+	return mode
+}
+
+// envMap returns the variables of an environment, in the form KEY=value, by
+// name.
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, variable := range env {
+		if i := strings.Index(variable, "="); i >= 0 {
+			m[variable[:i]] = variable[i+1:]
+		} else {
+			m[variable] = ""
+		}
+	}
+	return m
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffIgnoresDefaults(t *testing.T) {
+	desired := CreateContainerOptions{
+		Config: &Config{
+			Image:        "nginx",
+			Env:          []string{"PORT=80"},
+			Labels:       map[string]string{"app": "shop"},
+			ExposedPorts: map[Port]struct{}{"80/tcp": {}},
+		},
+		HostConfig: &HostConfig{
+			Binds:        []string{"/srv:/usr/share/nginx/html:ro"},
+			PortBindings: map[Port][]PortBinding{"80/tcp": {{HostPort: "8080"}}},
+		},
+	}
+	actual := Container{
+		Config: &Config{
+			Image:        "nginx:latest",
+			Env:          []string{"PATH=/usr/bin", "PORT=80", "NGINX_VERSION=1.25"},
+			Cmd:          []string{"nginx", "-g", "daemon off;"},
+			Labels:       map[string]string{"app": "shop", "maintainer": "NGINX"},
+			ExposedPorts: map[Port]struct{}{"80/tcp": {}, "443/tcp": {}},
+		},
+		HostConfig: &HostConfig{
+			Binds:         []string{"/srv:/usr/share/nginx/html:ro"},
+			PortBindings:  map[Port][]PortBinding{"80/tcp": {{HostIP: "0.0.0.0", HostPort: "8080"}}},
+			NetworkMode:   "default",
+			RestartPolicy: RestartPolicy{Name: "no"},
+		},
+	}
+	if diffs := Diff(desired, actual); len(diffs) > 0 {
+		t.Errorf("Diff: unexpected differences: %#v.", diffs)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	desired := CreateContainerOptions{
+		Config: &Config{
+			Image:  "nginx:1.25",
+			Env:    []string{"PORT=80", "DEBUG=1"},
+			Labels: map[string]string{"app": "shop"},
+		},
+		HostConfig: &HostConfig{
+			Binds:         []string{"/srv:/data"},
+			PortBindings:  map[Port][]PortBinding{"80/tcp": {{HostPort: "8080"}}},
+			RestartPolicy: AlwaysRestart(),
+		},
+	}
+	actual := Container{
+		Config: &Config{
+			Image:  "nginx:1.24",
+			Env:    []string{"PORT=8080"},
+			Labels: map[string]string{"app": "blog"},
+		},
+		HostConfig: &HostConfig{
+			Binds:        []string{"/var/srv:/data"},
+			PortBindings: map[Port][]PortBinding{"80/tcp": {{HostPort: "8081"}}, "443/tcp": {{HostPort: "8443"}}},
+		},
+	}
+	expected := []FieldDiff{
+		{Field: "Config.Image", Desired: "nginx:1.25", Actual: "nginx:1.24"},
+		{Field: "Config.Env[DEBUG]", Desired: "1"},
+		{Field: "Config.Env[PORT]", Desired: "80", Actual: "8080"},
+		{Field: "Config.Labels[app]", Desired: "shop", Actual: "blog"},
+		{Field: "HostConfig.Binds", Desired: "/srv:/data"},
+		{Field: "HostConfig.Binds", Actual: "/var/srv:/data"},
+		{Field: "HostConfig.PortBindings[443/tcp]", Actual: []PortBinding{{HostPort: "8443"}}},
+		{Field: "HostConfig.PortBindings[80/tcp]", Desired: []PortBinding{{HostPort: "8080"}}, Actual: []PortBinding{{HostPort: "8081"}}},
+		{Field: "HostConfig.RestartPolicy", Desired: AlwaysRestart(), Actual: RestartPolicy{Name: "no"}},
+	}
+	if diffs := Diff(desired, actual); !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Diff: wrong differences.\nWant %#v.\nGot  %#v.", expected, diffs)
+	}
+}
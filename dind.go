@@ -0,0 +1,262 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultDinDImage   = "docker:dind"
+	defaultDinDTimeout = time.Minute
+
+	dindPort     Port = "2376/tcp"
+	dindCertsDir      = "/certs"
+)
+
+// ErrDinDPortNotPublished is returned by StartDinD when the port of the inner
+// daemon is not published by the outer one.
+var ErrDinDPortNotPublished = errors.New("the port of the docker:dind daemon is not published")
+
+// DinDOptions is the set of options that can be used when starting a
+// Docker-in-Docker daemon.
+type DinDOptions struct {
+	// Image is the image of the daemon. It defaults to docker:dind, and
+	// it's pulled when missing.
+	Image string
+
+	// Name is the name of the container. Docker picks a random name when
+	// it's empty.
+	Name string
+
+	// Timeout is how long to wait for the inner daemon to answer. It
+	// defaults to one minute.
+	Timeout time.Duration
+}
+
+// DinD is a Docker-in-Docker daemon, running in a privileged container of
+// another daemon.
+type DinD struct {
+	// Container is the container running the daemon.
+	Container *Container
+
+	// Client is a client of the inner daemon, using TLS with certificates
+	// generated for the container.
+	Client *Client
+
+	outer *Client
+}
+
+// StartDinD launches a Docker-in-Docker daemon in a privileged container,
+// waits for it to answer and returns a client pointed at it, for hermetic
+// integration tests. The daemon listens on the port 2376, published on a
+// random port of the host, with certificates generated for the container.
+//
+// The container is removed when the daemon fails to start. Otherwise, the
+// caller should remove it with Close once done.
+func (c *Client) StartDinD(opts DinDOptions) (*DinD, error) {
+	if opts.Image == "" {
+		opts.Image = defaultDinDImage
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultDinDTimeout
+	}
+	host := "127.0.0.1"
+	if c.endpointURL.Scheme != "unix" {
+		if h, _, err := net.SplitHostPort(c.endpointURL.Host); err == nil {
+			host = h
+		} else {
+			host = c.endpointURL.Host
+		}
+	}
+	certs, err := newDinDCerts(host)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.PullImageIfMissing(opts.Image, AuthConfiguration{}); err != nil {
+		return nil, err
+	}
+	config := Config{Image: opts.Image, Env: []string{"DOCKER_TLS_CERTDIR="}, Cmd: dindCommand()}
+	hostConfig := HostConfig{Privileged: true}
+	PublishRandomPorts(&config, &hostConfig, dindPort)
+	container, err := c.CreateContainer(CreateContainerOptions{
+		Name:       opts.Name,
+		Config:     &config,
+		HostConfig: &hostConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+	dind := &DinD{Container: container, outer: c}
+	if err := dind.start(host, certs, opts.Timeout); err != nil {
+		dind.Close()
+		return nil, err
+	}
+	return dind, nil
+}
+
+func (d *DinD) start(host string, certs *dindCerts, timeout time.Duration) error {
+	for name, content := range certs.server {
+		err := d.outer.CopyFileToContainer(d.Container.ID, CopyFileToContainerOptions{
+			Path:    dindCertsDir + "/" + name,
+			Content: bytes.NewReader(content),
+			Size:    int64(len(content)),
+			Mode:    0600,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := d.outer.StartContainer(d.Container.ID, nil); err != nil {
+		return err
+	}
+	container, err := d.outer.InspectContainer(d.Container.ID)
+	if err != nil {
+		return err
+	}
+	d.Container = container
+	if container.NetworkSettings == nil {
+		return ErrDinDPortNotPublished
+	}
+	port, ok := container.NetworkSettings.HostPorts()[dindPort]
+	if !ok {
+		return ErrDinDPortNotPublished
+	}
+	client, err := NewClient("https://" + net.JoinHostPort(host, strconv.Itoa(port.Port)))
+	if err != nil {
+		return err
+	}
+	client.TLSConfig = certs.client
+	client.HTTPClient = &http.Client{Transport: client.newTransport()}
+	if err := client.WaitForDaemon(timeout); err != nil {
+		return err
+	}
+	d.Client = client
+	return nil
+}
+
+// Close removes the container of the daemon, along with its volumes.
+func (d *DinD) Close() error {
+	return d.outer.RemoveContainer(RemoveContainerOptions{ID: d.Container.ID, RemoveVolumes: true, Force: true})
+}
+
+// dindCommand returns the arguments of the entrypoint of the docker:dind
+// image, starting dockerd with the certificates copied to the container.
+func dindCommand() []string {
+	return []string{
+		"--host=tcp://0.0.0.0:2376",
+		"--tlsverify",
+		"--tlscacert=" + dindCertsDir + "/ca.pem",
+		"--tlscert=" + dindCertsDir + "/server-cert.pem",
+		"--tlskey=" + dindCertsDir + "/server-key.pem",
+	}
+}
+
+// dindCerts holds the certificates generated for a Docker-in-Docker daemon:
+// the PEM files of the server, by name, and the TLS configuration of the
+// client.
+type dindCerts struct {
+	server map[string][]byte
+	client *tls.Config
+}
+
+// newDinDCerts generates a CA, along with a server certificate valid for the
+// given host and a client certificate, both signed by the CA.
+func newDinDCerts(host string) (*dindCerts, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := certTemplate("go-dockerclient dind CA")
+	caTemplate.IsCA = true
+	caTemplate.BasicConstraintsValid = true
+	caTemplate.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	serverTemplate := certTemplate("go-dockerclient dind server")
+	serverTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	serverTemplate.DNSNames = []string{"localhost"}
+	serverTemplate.IPAddresses = []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
+	if ip := net.ParseIP(host); ip != nil {
+		serverTemplate.IPAddresses = append(serverTemplate.IPAddresses, ip)
+	} else if host != "localhost" {
+		serverTemplate.DNSNames = append(serverTemplate.DNSNames, host)
+	}
+	serverDER, serverKey, err := signedCert(serverTemplate, ca, caKey)
+	if err != nil {
+		return nil, err
+	}
+	serverKeyDER, err := x509.MarshalECPrivateKey(serverKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientTemplate := certTemplate("go-dockerclient dind client")
+	clientTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	clientDER, clientKey, err := signedCert(clientTemplate, ca, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	caPool := x509.NewCertPool()
+	caPool.AddCert(ca)
+	return &dindCerts{
+		server: map[string][]byte{
+			"ca.pem":          pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+			"server-cert.pem": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverDER}),
+			"server-key.pem":  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: serverKeyDER}),
+		},
+		client: &tls.Config{
+			Certificates: []tls.Certificate{{Certificate: [][]byte{clientDER}, PrivateKey: clientKey}},
+			RootCAs:      caPool,
+		},
+	}, nil
+}
+
+func certTemplate(name string) *x509.Certificate {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+}
+
+// signedCert generates a key and a certificate for it, from the given
+// template, signed by the CA.
+func signedCert(template, ca *x509.Certificate, caKey *ecdsa.PrivateKey) ([]byte, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return der, key, nil
+}
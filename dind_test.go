@@ -0,0 +1,187 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"archive/tar"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// dindServer simulates a daemon running docker:dind containers. Starting the
+// container starts an inner daemon with the certificates uploaded to it,
+// unless publish is false.
+type dindServer struct {
+	*httptest.Server
+	publish bool
+
+	mut      sync.Mutex
+	files    map[string][]byte
+	created  *Config
+	inner    *httptest.Server
+	requests []string
+}
+
+func newDinDServer(publish bool) *dindServer {
+	s := &dindServer{publish: publish, files: make(map[string][]byte)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *dindServer) Close() {
+	if s.inner != nil {
+		s.inner.Close()
+	}
+	s.Server.Close()
+}
+
+func (s *dindServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/images/docker:dind/json":
+		w.Write([]byte(`{"Id":"dind"}`))
+	case r.Method == "POST" && r.URL.Path == "/containers/create":
+		var config Config
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.created = &config
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id":"dind1"}`))
+	case r.Method == "PUT" && r.URL.Path == "/containers/dind1/archive":
+		tr := tar.NewReader(r.Body)
+		hdr, err := tr.Next()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.files["/"+hdr.Name], _ = ioutil.ReadAll(tr)
+	case r.Method == "POST" && r.URL.Path == "/containers/dind1/start":
+		if err := s.startInner(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "GET" && r.URL.Path == "/containers/dind1/json":
+		ports := "{}"
+		if s.inner != nil {
+			_, port, _ := net.SplitHostPort(s.inner.Listener.Addr().String())
+			ports = fmt.Sprintf(`{"2376/tcp":[{"HostIP":"0.0.0.0","HostPort":%q}]}`, port)
+		}
+		fmt.Fprintf(w, `{"Id":"dind1","NetworkSettings":{"Ports":%s}}`, ports)
+	case r.Method == "DELETE" && r.URL.Path == "/containers/dind1":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (s *dindServer) startInner() error {
+	if !s.publish {
+		return nil
+	}
+	cert, err := tls.X509KeyPair(s.files["/certs/server-cert.pem"], s.files["/certs/server-key.pem"])
+	if err != nil {
+		return err
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(s.files["/certs/ca.pem"]) {
+		return fmt.Errorf("invalid CA")
+	}
+	s.inner = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	s.inner.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    caPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	s.inner.StartTLS()
+	return nil
+}
+
+func TestStartDinD(t *testing.T) {
+	server := newDinDServer(true)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dind, err := client.StartDinD(DinDOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dind.Client.Ping(); err != nil {
+		t.Errorf("StartDinD: inner daemon unreachable: %s", err)
+	}
+	if !reflect.DeepEqual(server.created.Cmd, dindCommand()) || server.created.Image != "docker:dind" {
+		t.Errorf("StartDinD: wrong config. Got %#v.", server.created)
+	}
+	if err := dind.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"GET /images/docker:dind/json",
+		"POST /containers/create",
+		"PUT /containers/dind1/archive",
+		"PUT /containers/dind1/archive",
+		"PUT /containers/dind1/archive",
+		"POST /containers/dind1/start",
+		"GET /containers/dind1/json",
+		"DELETE /containers/dind1",
+	}
+	if !reflect.DeepEqual(server.requests, expected) {
+		t.Errorf("StartDinD: wrong requests. Want %#v. Got %#v.", expected, server.requests)
+	}
+}
+
+func TestStartDinDPortNotPublished(t *testing.T) {
+	server := newDinDServer(false)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.StartDinD(DinDOptions{})
+	if err != ErrDinDPortNotPublished {
+		t.Errorf("StartDinD: wrong error. Want %#v. Got %#v.", ErrDinDPortNotPublished, err)
+	}
+	if last := server.requests[len(server.requests)-1]; last != "DELETE /containers/dind1" {
+		t.Errorf("StartDinD: the container should be removed. Last request: %q.", last)
+	}
+}
+
+func TestNewDinDCertsHost(t *testing.T) {
+	certs, err := newDinDCerts("docker.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certs.server["server-cert.pem"], certs.server["server-key.pem"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"docker.example.com", "localhost", "127.0.0.1"} {
+		_, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: certs.client.RootCAs})
+		if err != nil {
+			t.Errorf("newDinDCerts: invalid server certificate for %q: %s", host, err)
+		}
+	}
+}
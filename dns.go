@@ -0,0 +1,65 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// HostGateway stands for the IP of the host in HostConfig.ExtraHosts, as in
+// ExtraHost("host.docker.internal", HostGateway). It requires Docker 20.10 or
+// newer.
+const HostGateway = "host-gateway"
+
+var dnsSearchDomain = regexp.MustCompile(`^([a-zA-Z0-9_]([a-zA-Z0-9_-]*[a-zA-Z0-9_])?\.?)+$`)
+
+// ExtraHost returns the entry of HostConfig.ExtraHosts resolving host to ip,
+// an IP address or HostGateway.
+func ExtraHost(host, ip string) string {
+	return host + ":" + ip
+}
+
+// InvalidResolverConfig is the error returned by CreateContainer for the
+// invalid values of the DNS settings and of the extra hosts of HostConfig.
+type InvalidResolverConfig struct {
+	Field string
+	Value string
+}
+
+func (err *InvalidResolverConfig) Error() string {
+	return "Invalid HostConfig." + err.Field + ": " + err.Value
+}
+
+// validateResolver checks the format of the DNS settings and of the extra
+// hosts of the host config.
+func (hostConfig *HostConfig) validateResolver() error {
+	for _, dns := range hostConfig.DNS {
+		if net.ParseIP(dns) == nil {
+			return &InvalidResolverConfig{Field: "DNS", Value: dns}
+		}
+	}
+	for _, domain := range hostConfig.DNSSearch {
+		// "." removes the search domains
+		if domain != "." && !dnsSearchDomain.MatchString(domain) {
+			return &InvalidResolverConfig{Field: "DNSSearch", Value: domain}
+		}
+	}
+	for _, option := range hostConfig.DNSOptions {
+		if option == "" || strings.ContainsAny(option, " \t") {
+			return &InvalidResolverConfig{Field: "DNSOptions", Value: option}
+		}
+	}
+	for _, entry := range hostConfig.ExtraHosts {
+		// the daemon splits the entries at the first colon, since IPv6
+		// addresses hold colons too
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || (parts[1] != HostGateway && net.ParseIP(parts[1]) == nil) {
+			return &InvalidResolverConfig{Field: "ExtraHosts", Value: entry}
+		}
+	}
+	return nil
+}
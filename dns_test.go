@@ -0,0 +1,52 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHostConfigValidateResolver(t *testing.T) {
+	var tests = []struct {
+		hostConfig HostConfig
+		expected   error
+	}{
+		{HostConfig{}, nil},
+		{HostConfig{
+			DNS:        []string{"8.8.8.8", "2001:4860:4860::8888"},
+			DNSSearch:  []string{"example.com", "svc.cluster.local."},
+			DNSOptions: []string{"ndots:2", "rotate"},
+			ExtraHosts: []string{ExtraHost("db", "10.0.0.2"), ExtraHost("v6", "::1"), ExtraHost("host.docker.internal", HostGateway)},
+		}, nil},
+		{HostConfig{DNSSearch: []string{"."}}, nil},
+		{HostConfig{DNS: []string{"dns.example.com"}}, &InvalidResolverConfig{Field: "DNS", Value: "dns.example.com"}},
+		{HostConfig{DNSSearch: []string{"bad domain"}}, &InvalidResolverConfig{Field: "DNSSearch", Value: "bad domain"}},
+		{HostConfig{DNSOptions: []string{"ndots: 2"}}, &InvalidResolverConfig{Field: "DNSOptions", Value: "ndots: 2"}},
+		{HostConfig{ExtraHosts: []string{"db=10.0.0.2"}}, &InvalidResolverConfig{Field: "ExtraHosts", Value: "db=10.0.0.2"}},
+		{HostConfig{ExtraHosts: []string{"db:gateway"}}, &InvalidResolverConfig{Field: "ExtraHosts", Value: "db:gateway"}},
+	}
+	for _, tt := range tests {
+		if err := tt.hostConfig.validateResolver(); !reflect.DeepEqual(err, tt.expected) {
+			t.Errorf("HostConfig.validateResolver(%#v): wrong error. Want %#v. Got %#v.", tt.hostConfig, tt.expected, err)
+		}
+	}
+}
+
+func TestCreateContainerInvalidExtraHost(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	_, err := client.CreateContainer(CreateContainerOptions{
+		Config:     &Config{Image: "base"},
+		HostConfig: &HostConfig{ExtraHosts: []string{"db"}},
+	})
+	if _, ok := err.(*InvalidResolverConfig); !ok {
+		t.Errorf("CreateContainer: wrong error. Want a *InvalidResolverConfig. Got %#v.", err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("CreateContainer: the container should not be created. Got %d requests.", len(fakeRT.requests))
+	}
+}
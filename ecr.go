@@ -0,0 +1,171 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ecrTokenLifetime is the validity of the tokens issued by ECR, for
+	// tokens returned by a TokenSource without expiration time.
+	ecrTokenLifetime = 12 * time.Hour
+
+	defaultECRRefreshWindow = 30 * time.Minute
+	defaultECRHelper        = "docker-credential-ecr-login"
+)
+
+var ecrRegistryRegexp = regexp.MustCompile(`^[0-9]{12}\.dkr\.ecr(-fips)?\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// AuthProvider provides registry credentials on demand. It's used by the
+// client, through its AuthProvider field, to obtain the credentials of
+// registries whose credentials expire, like Amazon ECR.
+type AuthProvider interface {
+	// Credentials returns the credentials for the given registry host.
+	// The boolean result is false when the provider doesn't handle the
+	// registry.
+	Credentials(registry string) (AuthConfiguration, bool, error)
+}
+
+// ECRAuthProvider is an AuthProvider for Amazon ECR registries. It caches
+// the tokens obtained from TokenSource and fetches new ones before they
+// expire, as they are only valid for 12 hours.
+//
+// Without TokenSource, tokens are obtained from the ECR credential helper
+// (docker-credential-ecr-login) every time. They're not cached here, as the
+// helper keeps its own cache and doesn't tell when its tokens expire.
+type ECRAuthProvider struct {
+	// TokenSource returns an authorization token for the given registry,
+	// as returned by the GetAuthorizationToken call of the ECR API (the
+	// base64 encoding of "AWS:<password>"), along with its expiration
+	// time. A zero expiration time means the token expires 12 hours after
+	// it's obtained.
+	TokenSource func(registry string) (token string, expiresAt time.Time, err error)
+
+	// HelperPath is the path of the credential helper, used when
+	// TokenSource is nil. It defaults to docker-credential-ecr-login, looked
+	// up in the PATH.
+	HelperPath string
+
+	// RefreshWindow is how long before their expiration tokens are
+	// refreshed. It defaults to 30 minutes.
+	RefreshWindow time.Duration
+
+	mut    sync.Mutex
+	tokens map[string]ecrCredentials
+	now    func() time.Time
+}
+
+type ecrCredentials struct {
+	auth      AuthConfiguration
+	expiresAt time.Time
+}
+
+// IsECRRegistry reports whether the given registry host belongs to Amazon
+// ECR.
+func IsECRRegistry(registry string) bool {
+	return ecrRegistryRegexp.MatchString(normalizeRegistryHost(registry))
+}
+
+// Credentials returns the credentials for the given ECR registry, fetching a
+// new token when the cached one is about to expire.
+func (p *ECRAuthProvider) Credentials(registry string) (AuthConfiguration, bool, error) {
+	registry = normalizeRegistryHost(registry)
+	if !IsECRRegistry(registry) {
+		return AuthConfiguration{}, false, nil
+	}
+	if p.TokenSource == nil {
+		auth, err := p.fromHelper(registry)
+		return auth, true, err
+	}
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	refreshWindow := p.RefreshWindow
+	if refreshWindow <= 0 {
+		refreshWindow = defaultECRRefreshWindow
+	}
+	if creds, ok := p.tokens[registry]; ok && now().Add(refreshWindow).Before(creds.expiresAt) {
+		return creds.auth, true, nil
+	}
+	creds, err := p.fromTokenSource(registry)
+	if err != nil {
+		return AuthConfiguration{}, true, err
+	}
+	if creds.expiresAt.IsZero() {
+		creds.expiresAt = now().Add(ecrTokenLifetime)
+	}
+	if p.tokens == nil {
+		p.tokens = make(map[string]ecrCredentials)
+	}
+	p.tokens[registry] = creds
+	return creds.auth, true, nil
+}
+
+func (p *ECRAuthProvider) fromTokenSource(registry string) (ecrCredentials, error) {
+	token, expiresAt, err := p.TokenSource(registry)
+	if err != nil {
+		return ecrCredentials{}, err
+	}
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return ecrCredentials{}, err
+	}
+	userpass := strings.SplitN(string(data), ":", 2)
+	if len(userpass) != 2 {
+		return ecrCredentials{}, errors.New("invalid ECR authorization token")
+	}
+	return ecrCredentials{
+		auth: AuthConfiguration{
+			Username:      userpass[0],
+			Password:      userpass[1],
+			ServerAddress: registry,
+		},
+		expiresAt: expiresAt,
+	}, nil
+}
+
+// fromHelper gets credentials from a docker credential helper, which reads
+// the registry from its standard input and writes the credentials to its
+// standard output, in JSON format.
+func (p *ECRAuthProvider) fromHelper(registry string) (AuthConfiguration, error) {
+	helper := p.HelperPath
+	if helper == "" {
+		helper = defaultECRHelper
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(helper, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return AuthConfiguration{}, fmt.Errorf("%s: %s: %s", helper, err, strings.TrimSpace(stderr.String()))
+	}
+	var resp struct {
+		ServerURL string
+		Username  string
+		Secret    string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return AuthConfiguration{}, err
+	}
+	return AuthConfiguration{
+		Username:      resp.Username,
+		Password:      resp.Secret,
+		ServerAddress: registry,
+	}, nil
+}
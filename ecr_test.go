@@ -0,0 +1,159 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testECRRegistry = "123456789012.dkr.ecr.us-east-1.amazonaws.com"
+
+func TestIsECRRegistry(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected bool
+	}{
+		{testECRRegistry, true},
+		{"https://" + testECRRegistry, true},
+		{"123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com", true},
+		{"123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn", true},
+		{"12345.dkr.ecr.us-east-1.amazonaws.com", false},
+		{"quay.io", false},
+		{"docker.io", false},
+	}
+	for _, tt := range tests {
+		if got := IsECRRegistry(tt.input); got != tt.expected {
+			t.Errorf("IsECRRegistry(%q): wrong result. Want %v. Got %v.", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestECRAuthProviderTokenSource(t *testing.T) {
+	now := time.Date(2015, 4, 23, 0, 0, 0, 0, time.UTC)
+	var calls int
+	provider := ECRAuthProvider{
+		TokenSource: func(registry string) (string, time.Time, error) {
+			calls++
+			token := base64.StdEncoding.EncodeToString([]byte("AWS:password" + strconv.Itoa(calls)))
+			return token, now.Add(12 * time.Hour), nil
+		},
+		now: func() time.Time { return now },
+	}
+	auth, ok, err := provider.Credentials(testECRRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AuthConfiguration{Username: "AWS", Password: "password1", ServerAddress: testECRRegistry}
+	if !ok || auth != expected {
+		t.Errorf("ECRAuthProvider.Credentials: wrong result. Want %#v. Got %#v (%v).", expected, auth, ok)
+	}
+	now = now.Add(11 * time.Hour)
+	if auth, _, _ = provider.Credentials(testECRRegistry); auth.Password != "password1" {
+		t.Errorf("ECRAuthProvider.Credentials: token should be cached. Got %#v.", auth)
+	}
+	now = now.Add(45 * time.Minute)
+	if auth, _, _ = provider.Credentials(testECRRegistry); auth.Password != "password2" {
+		t.Errorf("ECRAuthProvider.Credentials: token should be refreshed before expiring. Got %#v.", auth)
+	}
+	if calls != 2 {
+		t.Errorf("ECRAuthProvider.Credentials: wrong number of token requests. Want 2. Got %d.", calls)
+	}
+}
+
+func TestECRAuthProviderOtherRegistry(t *testing.T) {
+	provider := ECRAuthProvider{
+		TokenSource: func(registry string) (string, time.Time, error) {
+			t.Fatal("TokenSource should not be called")
+			return "", time.Time{}, nil
+		},
+	}
+	if _, ok, err := provider.Credentials("quay.io"); ok || err != nil {
+		t.Errorf("ECRAuthProvider.Credentials: wrong result for non-ECR registry. Got %v, %v.", ok, err)
+	}
+}
+
+func TestECRAuthProviderTokenSourceError(t *testing.T) {
+	expectedErr := errors.New("expired AWS credentials")
+	provider := ECRAuthProvider{
+		TokenSource: func(registry string) (string, time.Time, error) {
+			return "", time.Time{}, expectedErr
+		},
+	}
+	if _, ok, err := provider.Credentials(testECRRegistry); !ok || err != expectedErr {
+		t.Errorf("ECRAuthProvider.Credentials: wrong result. Want true, %#v. Got %v, %#v.", expectedErr, ok, err)
+	}
+}
+
+func TestECRAuthProviderHelper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	helper := filepath.Join(dir, "docker-credential-ecr-login")
+	script := "#!/bin/sh\necho >> " + filepath.Join(dir, "calls") + "\nread registry\necho \"{\\\"ServerURL\\\":\\\"$registry\\\",\\\"Username\\\":\\\"AWS\\\",\\\"Secret\\\":\\\"$1-$registry\\\"}\"\n"
+	if err := ioutil.WriteFile(helper, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	provider := ECRAuthProvider{HelperPath: helper}
+	auth, ok, err := provider.Credentials(testECRRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AuthConfiguration{Username: "AWS", Password: "get-" + testECRRegistry, ServerAddress: testECRRegistry}
+	if !ok || auth != expected {
+		t.Errorf("ECRAuthProvider.Credentials: wrong result. Want %#v. Got %#v (%v).", expected, auth, ok)
+	}
+	if _, _, err := provider.Credentials(testECRRegistry); err != nil {
+		t.Fatal(err)
+	}
+	calls, err := ioutil.ReadFile(filepath.Join(dir, "calls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 {
+		t.Errorf("ECRAuthProvider.Credentials: the helper should be called every time. Want 2 calls. Got %d.", len(calls))
+	}
+}
+
+func TestPullImageUsesAuthProvider(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "Pulling 1/100", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	client.AuthConfigs = &AuthConfigurations{Configs: map[string]AuthConfiguration{
+		testECRRegistry: {Username: "stale", Password: "expired"},
+	}}
+	client.AuthProvider = &ECRAuthProvider{
+		TokenSource: func(registry string) (string, time.Time, error) {
+			return base64.StdEncoding.EncodeToString([]byte("AWS:fresh")), time.Time{}, nil
+		},
+	}
+	var buf bytes.Buffer
+	opts := PullImageOptions{Repository: testECRRegistry + "/app", OutputStream: &buf}
+	if err := client.PullImage(opts, AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := base64.URLEncoding.DecodeString(fakeRT.requests[0].Header.Get("X-Registry-Auth"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got AuthConfiguration
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	expected := AuthConfiguration{Username: "AWS", Password: "fresh", ServerAddress: testECRRegistry}
+	if got != expected {
+		t.Errorf("PullImage: wrong credentials sent. Want %#v. Got %#v.", expected, got)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import "errors"
+
+// ErrMissingName is returned by the Ensure helpers when the name of the
+// resource is empty.
+var ErrMissingName = errors.New("the name of the resource to ensure is missing")
+
+// EnsureAction is what an Ensure helper did to reconcile a resource with its
+// spec.
+type EnsureAction int
+
+const (
+	// EnsureUnchanged means that the resource matched its spec, or that
+	// the difference was ignored.
+	EnsureUnchanged EnsureAction = iota
+
+	// EnsureCreated means that the resource was missing, and was created.
+	EnsureCreated
+
+	// EnsureRecreated means that the resource differed from its spec, and
+	// was removed and created again.
+	EnsureRecreated
+)
+
+// DiffPolicy tells the Ensure helpers what to do with the resources which
+// differ from their spec.
+type DiffPolicy int
+
+const (
+	// DiffRecreate removes the resource and creates it again. Beware that
+	// recreating a volume deletes its data.
+	DiffRecreate DiffPolicy = iota
+
+	// DiffIgnore keeps the resource as is.
+	DiffIgnore
+
+	// DiffFail keeps the resource as is, and returns a *SpecMismatch.
+	DiffFail
+)
+
+// SpecMismatch is the error returned by the Ensure helpers when a resource
+// differs from its spec, with the DiffFail policy.
+type SpecMismatch struct {
+	Kind string
+	Name string
+}
+
+func (err *SpecMismatch) Error() string {
+	return "The " + err.Kind + " " + err.Name + " differs from its spec"
+}
+
+// EnsureContainerOptions specify parameters to the EnsureContainer function.
+type EnsureContainerOptions struct {
+	// CreateContainerOptions is the spec of the container, which must be
+	// named.
+	CreateContainerOptions
+
+	// Policy tells what to do when the container differs from its spec,
+	// according to Differs.
+	Policy DiffPolicy
+
+	// Differs tells whether the container differs from its spec. By
+	// default, the container differs when Diff reports differences.
+	Differs func(desired CreateContainerOptions, actual *Container) bool
+
+	// Start starts the container once it's created, and when it's found
+	// stopped.
+	Start bool
+}
+
+// EnsureContainer creates the container described by opts when it's
+// missing, and recreates it, according to the policy, when it differs from
+// its spec. It's a no-op when the container matches its spec. The container
+// is returned as inspected, unless it's created.
+func (c *Client) EnsureContainer(opts EnsureContainerOptions) (*Container, EnsureAction, error) {
+	if opts.Name == "" {
+		return nil, EnsureUnchanged, ErrMissingName
+	}
+	if opts.Differs == nil {
+		opts.Differs = containerDiffers
+	}
+	action := EnsureCreated
+	actual, err := c.InspectContainer(opts.Name)
+	if _, ok := err.(*NoSuchContainer); ok {
+		actual, err = nil, nil
+	}
+	if err != nil {
+		return nil, EnsureUnchanged, err
+	}
+	if actual != nil {
+		if !opts.Differs(opts.CreateContainerOptions, actual) || opts.Policy == DiffIgnore {
+			if opts.Start && !actual.State.Running {
+				return actual, EnsureUnchanged, c.StartContainer(actual.ID, nil)
+			}
+			return actual, EnsureUnchanged, nil
+		}
+		if opts.Policy == DiffFail {
+			return actual, EnsureUnchanged, &SpecMismatch{Kind: "container", Name: opts.Name}
+		}
+		err := c.RemoveContainer(RemoveContainerOptions{ID: actual.ID, Force: true})
+		if err != nil {
+			return nil, EnsureUnchanged, err
+		}
+		action = EnsureRecreated
+	}
+	container, err := c.CreateContainer(opts.CreateContainerOptions)
+	if err != nil {
+		return nil, EnsureUnchanged, err
+	}
+	if opts.Start {
+		if err := c.StartContainer(container.ID, nil); err != nil {
+			return container, action, err
+		}
+	}
+	return container, action, nil
+}
+
+// containerDiffers is the default comparison of EnsureContainer.
+func containerDiffers(desired CreateContainerOptions, actual *Container) bool {
+	return len(Diff(desired, *actual)) > 0
+}
+
+// EnsureNetworkOptions specify parameters to the EnsureNetwork function.
+type EnsureNetworkOptions struct {
+	// CreateNetworkOptions is the spec of the network, which must be
+	// named.
+	CreateNetworkOptions
+
+	// Policy tells what to do when the network differs from its spec,
+	// according to Differs.
+	Policy DiffPolicy
+
+	// Differs tells whether the network differs from its spec. By
+	// default, the driver, the options and the labels are compared.
+	Differs func(desired CreateNetworkOptions, actual *Network) bool
+}
+
+// EnsureNetwork creates the network described by opts when it's missing, and
+// recreates it, according to the policy, when it differs from its spec. It's
+// a no-op when the network matches its spec. Recreating a network fails when
+// containers are connected to it.
+func (c *Client) EnsureNetwork(opts EnsureNetworkOptions) (*Network, EnsureAction, error) {
+	if opts.Name == "" {
+		return nil, EnsureUnchanged, ErrMissingName
+	}
+	if opts.Differs == nil {
+		opts.Differs = networkDiffers
+	}
+	action := EnsureCreated
+	actual, err := c.NetworkInfo(opts.Name)
+	if err == ErrNoSuchNetwork {
+		actual, err = nil, nil
+	}
+	if err != nil {
+		return nil, EnsureUnchanged, err
+	}
+	if actual != nil {
+		if !opts.Differs(opts.CreateNetworkOptions, actual) || opts.Policy == DiffIgnore {
+			return actual, EnsureUnchanged, nil
+		}
+		if opts.Policy == DiffFail {
+			return actual, EnsureUnchanged, &SpecMismatch{Kind: "network", Name: opts.Name}
+		}
+		if err := c.RemoveNetwork(actual.ID); err != nil {
+			return nil, EnsureUnchanged, err
+		}
+		action = EnsureRecreated
+	}
+	network, err := c.CreateNetwork(opts.CreateNetworkOptions)
+	if err != nil {
+		return nil, EnsureUnchanged, err
+	}
+	return network, action, nil
+}
+
+// networkDiffers is the default comparison of EnsureNetwork.
+func networkDiffers(desired CreateNetworkOptions, actual *Network) bool {
+	driver := desired.Driver
+	if driver == "" {
+		driver = "bridge"
+	}
+	return driver != actual.Driver || desired.Internal != actual.Internal ||
+		!equalStringMaps(desired.Options, actual.Options) || !equalStringMaps(desired.Labels, actual.Labels)
+}
+
+// EnsureVolumeOptions specify parameters to the EnsureVolume function.
+type EnsureVolumeOptions struct {
+	// CreateVolumeOptions is the spec of the volume, which must be named.
+	CreateVolumeOptions
+
+	// Policy tells what to do when the volume differs from its spec,
+	// according to Differs.
+	Policy DiffPolicy
+
+	// Differs tells whether the volume differs from its spec. By default,
+	// the driver, the options of the driver and the labels are compared.
+	Differs func(desired CreateVolumeOptions, actual *Volume) bool
+}
+
+// EnsureVolume creates the volume described by opts when it's missing, and
+// recreates it, according to the policy, when it differs from its spec. It's
+// a no-op when the volume matches its spec. Recreating a volume deletes its
+// data, and fails when containers use it.
+func (c *Client) EnsureVolume(opts EnsureVolumeOptions) (*Volume, EnsureAction, error) {
+	if opts.Name == "" {
+		return nil, EnsureUnchanged, ErrMissingName
+	}
+	if opts.Differs == nil {
+		opts.Differs = volumeDiffers
+	}
+	action := EnsureCreated
+	actual, err := c.InspectVolume(opts.Name)
+	if err == ErrNoSuchVolume {
+		actual, err = nil, nil
+	}
+	if err != nil {
+		return nil, EnsureUnchanged, err
+	}
+	if actual != nil {
+		if !opts.Differs(opts.CreateVolumeOptions, actual) || opts.Policy == DiffIgnore {
+			return actual, EnsureUnchanged, nil
+		}
+		if opts.Policy == DiffFail {
+			return actual, EnsureUnchanged, &SpecMismatch{Kind: "volume", Name: opts.Name}
+		}
+		if err := c.RemoveVolume(actual.Name); err != nil {
+			return nil, EnsureUnchanged, err
+		}
+		action = EnsureRecreated
+	}
+	volume, err := c.CreateVolume(opts.CreateVolumeOptions)
+	if err != nil {
+		return nil, EnsureUnchanged, err
+	}
+	return volume, action, nil
+}
+
+// volumeDiffers is the default comparison of EnsureVolume.
+func volumeDiffers(desired CreateVolumeOptions, actual *Volume) bool {
+	driver := desired.Driver
+	if driver == "" {
+		driver = "local"
+	}
+	return driver != actual.Driver || !equalStringMaps(desired.DriverOpts, actual.Options) ||
+		!equalStringMaps(desired.Labels, actual.Labels)
+}
+
+// equalStringMaps compares two maps, a nil map being equal to an empty one.
+func equalStringMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// ensureServer simulates a daemon with a container named web, running the
+// image given to newEnsureServer, a network named backend and a volume named
+// data.
+type ensureServer struct {
+	*httptest.Server
+	mut      sync.Mutex
+	image    string
+	requests []string
+}
+
+func newEnsureServer(image string) *ensureServer {
+	s := &ensureServer{image: image}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *ensureServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	switch r.Method + " " + r.URL.Path {
+	case "GET /containers/web/json":
+		json.NewEncoder(w).Encode(Container{
+			ID:     "web1",
+			Config: &Config{Image: s.image, Env: []string{"PATH=/usr/bin", "PORT=80"}, Cmd: []string{"nginx"}},
+			State:  State{Running: true},
+		})
+	case "GET /networks/backend":
+		w.Write([]byte(`{"Name":"backend","Id":"net1","Driver":"bridge","Options":{},"Labels":{"app":"shop"}}`))
+	case "GET /volumes/data":
+		w.Write([]byte(`{"Name":"data","Driver":"local","Labels":{"app":"shop"}}`))
+	case "POST /containers/create":
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id":"web2"}`))
+	case "POST /networks/create":
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id":"net2"}`))
+	case "POST /volumes/create":
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Name":"cache","Driver":"local"}`))
+	case "DELETE /containers/web1", "POST /containers/web2/start":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func TestEnsureContainer(t *testing.T) {
+	var tests = []struct {
+		image    string
+		policy   DiffPolicy
+		action   EnsureAction
+		requests []string
+	}{
+		{"nginx:1.25", DiffRecreate, EnsureUnchanged, []string{"GET /containers/web/json"}},
+		{"nginx:1.24", DiffIgnore, EnsureUnchanged, []string{"GET /containers/web/json"}},
+		{"nginx:1.24", DiffRecreate, EnsureRecreated, []string{
+			"GET /containers/web/json",
+			"DELETE /containers/web1",
+			"POST /containers/create",
+			"POST /containers/web2/start",
+		}},
+	}
+	for _, tt := range tests {
+		server := newEnsureServer(tt.image)
+		client, err := NewClient(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, action, err := client.EnsureContainer(EnsureContainerOptions{
+			CreateContainerOptions: CreateContainerOptions{
+				Name:   "web",
+				Config: &Config{Image: "nginx:1.25", Env: []string{"PORT=80"}},
+			},
+			Policy: tt.policy,
+			Start:  true,
+		})
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if action != tt.action {
+			t.Errorf("EnsureContainer(%s): wrong action. Want %d. Got %d.", tt.image, tt.action, action)
+		}
+		if !reflect.DeepEqual(server.requests, tt.requests) {
+			t.Errorf("EnsureContainer(%s): wrong requests. Want %#v. Got %#v.", tt.image, tt.requests, server.requests)
+		}
+	}
+}
+
+func TestEnsureContainerDiffFail(t *testing.T) {
+	server := newEnsureServer("nginx:1.24")
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = client.EnsureContainer(EnsureContainerOptions{
+		CreateContainerOptions: CreateContainerOptions{Name: "web", Config: &Config{Image: "nginx:1.25"}},
+		Policy:                 DiffFail,
+	})
+	expected := &SpecMismatch{Kind: "container", Name: "web"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("EnsureContainer: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+}
+
+func TestEnsureNetwork(t *testing.T) {
+	server := newEnsureServer("")
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, action, err := client.EnsureNetwork(EnsureNetworkOptions{
+		CreateNetworkOptions: CreateNetworkOptions{Name: "backend", Labels: map[string]string{"app": "shop"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action != EnsureUnchanged {
+		t.Errorf("EnsureNetwork: wrong action. Want %d. Got %d.", EnsureUnchanged, action)
+	}
+	network, action, err := client.EnsureNetwork(EnsureNetworkOptions{
+		CreateNetworkOptions: CreateNetworkOptions{Name: "frontend"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action != EnsureCreated || network.ID != "net2" {
+		t.Errorf("EnsureNetwork: wrong result. Want a created network. Got %d, %#v.", action, network)
+	}
+}
+
+func TestEnsureVolume(t *testing.T) {
+	server := newEnsureServer("")
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = client.EnsureVolume(EnsureVolumeOptions{
+		CreateVolumeOptions: CreateVolumeOptions{Name: "data", Driver: "nfs"},
+		Policy:              DiffFail,
+	})
+	expected := &SpecMismatch{Kind: "volume", Name: "data"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("EnsureVolume: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+	volume, action, err := client.EnsureVolume(EnsureVolumeOptions{
+		CreateVolumeOptions: CreateVolumeOptions{Name: "cache"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action != EnsureCreated || volume.Name != "cache" {
+		t.Errorf("EnsureVolume: wrong result. Want a created volume. Got %d, %#v.", action, volume)
+	}
+}
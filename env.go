@@ -6,8 +6,10 @@ package docker
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"strconv"
 	"strings"
 )
@@ -31,7 +33,11 @@ func (env *Env) Exists(key string) bool {
 // whenever its value if 0, no, false, none or an empty string. Any other value
 // will be interpreted as true.
 func (env *Env) GetBool(key string) (value bool) {
-	s := strings.ToLower(strings.Trim(env.Get(key), " \t"))
+	return parseBool(env.Get(key))
+}
+
+func parseBool(s string) bool {
+	s = strings.ToLower(strings.Trim(s, " \t"))
 	if s == "" || s == "0" || s == "no" || s == "false" || s == "none" {
 		return false
 	}
@@ -115,6 +121,29 @@ func (env *Env) GetList(key string) []string {
 	return l
 }
 
+// GetSubEnv returns the value of the provided key as an Env. It handles the
+// value as a JSON representation of an object.
+//
+// It returns nil if the key is not defined or if its value cannot be decoded
+// as an object.
+func (env *Env) GetSubEnv(key string) *Env {
+	sval := env.Get(key)
+	if sval == "" {
+		return nil
+	}
+	var sub Env
+	if err := sub.Decode(strings.NewReader(sval)); err != nil {
+		return nil
+	}
+	return &sub
+}
+
+// SetSubEnv stores the given Env in the provided key, after serializing it to
+// a JSON object.
+func (env *Env) SetSubEnv(key string, sub *Env) error {
+	return env.SetJSON(key, sub.Map())
+}
+
 // SetList stores the given list in the provided key, after serializing it to
 // JSON format.
 func (env *Env) SetList(key string, value []string) error {
@@ -154,6 +183,116 @@ func (env *Env) SetAuto(key string, value interface{}) {
 	}
 }
 
+// DecodeInto decodes the env into the value pointed to by iface, which is
+// typically a pointer to a struct whose fields are tagged as they would be for
+// the json package.
+//
+// Each value is converted according to the type of its destination: strings
+// are used verbatim, booleans follow the rules of GetBool and any other value
+// is decoded as JSON.
+func (env *Env) DecodeInto(iface interface{}) error {
+	v := reflect.ValueOf(iface)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("docker: DecodeInto requires a non-nil pointer")
+	}
+	t := v.Elem().Type()
+	var fields map[string]reflect.Type
+	if t.Kind() == reflect.Struct {
+		fields = make(map[string]reflect.Type)
+		jsonFieldTypes(t, fields)
+	}
+	obj := make(map[string]json.RawMessage)
+	for key, sval := range env.Map() {
+		var ftype reflect.Type
+		if t.Kind() == reflect.Map {
+			ftype = t.Elem()
+		} else {
+			ftype = fields[strings.ToLower(key)]
+		}
+		obj[key] = envRawValue(sval, ftype)
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, iface)
+}
+
+// jsonFieldTypes collects the types of the fields of the given struct type,
+// indexed by the lower-cased name used by the json package.
+func jsonFieldTypes(t reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if parts := strings.Split(tag, ","); parts[0] != "" {
+				name = parts[0]
+			}
+		}
+		ftype := field.Type
+		if ftype.Kind() == reflect.Ptr {
+			ftype = ftype.Elem()
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" && ftype.Kind() == reflect.Struct {
+			jsonFieldTypes(ftype, fields)
+			continue
+		}
+		if _, ok := fields[strings.ToLower(name)]; !ok {
+			fields[strings.ToLower(name)] = ftype
+		}
+	}
+}
+
+// envRawValue converts the given value to JSON, according to the type of its
+// destination.
+func envRawValue(sval string, t reflect.Type) json.RawMessage {
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil {
+		switch t.Kind() {
+		case reflect.String:
+			data, _ := json.Marshal(sval)
+			return data
+		case reflect.Bool:
+			return json.RawMessage(strconv.FormatBool(parseBool(sval)))
+		}
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(sval), &v); err == nil {
+		return json.RawMessage(sval)
+	}
+	data, _ := json.Marshal(sval)
+	return data
+}
+
+// ToMap returns the map representation of the env, with values that are
+// valid JSON decoded into their Go representation and any other value kept
+// as a string. It's meant to ease the migration from Env to plain maps and
+// typed structs.
+func (env *Env) ToMap() map[string]interface{} {
+	m := make(map[string]interface{})
+	for key, sval := range env.Map() {
+		var v interface{}
+		if err := json.Unmarshal([]byte(sval), &v); err != nil {
+			v = sval
+		}
+		m[key] = v
+	}
+	return m
+}
+
+// FromMap adds each key-value pair of the given map to the env, using
+// SetAuto.
+func (env *Env) FromMap(m map[string]interface{}) {
+	for key, value := range m {
+		env.SetAuto(key, value)
+	}
+}
+
 // Map returns the map representation of the env.
 func (env *Env) Map() map[string]string {
 	if len(*env) == 0 {
@@ -162,6 +301,10 @@ func (env *Env) Map() map[string]string {
 	m := make(map[string]string)
 	for _, kv := range *env {
 		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 1 {
+			m[parts[0]] = ""
+			continue
+		}
 		m[parts[0]] = parts[1]
 	}
 	return m
@@ -347,3 +347,128 @@ type unmarshable struct {
 func (unmarshable) MarshalJSON() ([]byte, error) {
 	return nil, errors.New("cannot marshal")
 }
+
+func TestMapWithoutValue(t *testing.T) {
+	env := Env([]string{"PATH=/usr/bin", "DEBUG"})
+	expected := map[string]string{"PATH": "/usr/bin", "DEBUG": ""}
+	if got := env.Map(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Env.Map(): wrong result. Want %v. Got %v", expected, got)
+	}
+}
+
+func TestGetSubEnv(t *testing.T) {
+	env := Env([]string{`Driver={"Name":"aufs","Dirs":10}`, "Plain=value"})
+	sub := env.GetSubEnv("Driver")
+	if sub == nil {
+		t.Fatal("Env.GetSubEnv(): unexpected nil result")
+	}
+	if got := sub.Get("Name"); got != "aufs" {
+		t.Errorf("Env.GetSubEnv(): wrong Name. Want %q. Got %q.", "aufs", got)
+	}
+	if got := sub.GetInt("Dirs"); got != 10 {
+		t.Errorf("Env.GetSubEnv(): wrong Dirs. Want %d. Got %d.", 10, got)
+	}
+	if sub := env.GetSubEnv("Plain"); sub != nil {
+		t.Errorf("Env.GetSubEnv(): expected nil for non-object value. Got %v.", sub)
+	}
+	if sub := env.GetSubEnv("Missing"); sub != nil {
+		t.Errorf("Env.GetSubEnv(): expected nil for missing key. Got %v.", sub)
+	}
+}
+
+func TestSetSubEnv(t *testing.T) {
+	var env Env
+	sub := Env([]string{"Name=aufs"})
+	if err := env.SetSubEnv("Driver", &sub); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.GetSubEnv("Driver").Get("Name"); got != "aufs" {
+		t.Errorf("Env.SetSubEnv(): wrong result. Want %q. Got %q.", "aufs", got)
+	}
+}
+
+func TestDecodeInto(t *testing.T) {
+	type embedded struct {
+		Containers int
+	}
+	type target struct {
+		embedded
+		ID         string
+		APIVersion string `json:"ApiVersion"`
+		Debug      bool
+		Memory     *bool
+		Images     int64
+		Labels     []string
+		Ignored    string `json:"-"`
+	}
+	env := Env([]string{
+		"ID=123",
+		"ApiVersion=1.17",
+		"Debug=1",
+		"Memory=no",
+		"Images=42",
+		`Labels=["a","b"]`,
+		"Containers=3",
+		"Ignored=x",
+		"Unknown=not json",
+	})
+	var got target
+	if err := env.DecodeInto(&got); err != nil {
+		t.Fatal(err)
+	}
+	memory := false
+	expected := target{
+		embedded:   embedded{Containers: 3},
+		ID:         "123",
+		APIVersion: "1.17",
+		Debug:      true,
+		Memory:     &memory,
+		Images:     42,
+		Labels:     []string{"a", "b"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Env.DecodeInto(): wrong result. Want %#v. Got %#v.", expected, got)
+	}
+}
+
+func TestDecodeIntoMap(t *testing.T) {
+	env := Env([]string{"A=1", "B=text"})
+	var got map[string]string
+	if err := env.DecodeInto(&got); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"A": "1", "B": "text"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Env.DecodeInto(): wrong result. Want %v. Got %v.", expected, got)
+	}
+}
+
+func TestDecodeIntoNonPointer(t *testing.T) {
+	env := Env([]string{"A=1"})
+	var got struct{ A int }
+	if err := env.DecodeInto(got); err == nil {
+		t.Error("Env.DecodeInto(): expected error for non-pointer, got <nil>")
+	}
+}
+
+func TestToMap(t *testing.T) {
+	env := Env([]string{"Name=aufs", "Count=3", `List=["a"]`, "Flag=true"})
+	expected := map[string]interface{}{
+		"Name":  "aufs",
+		"Count": float64(3),
+		"List":  []interface{}{"a"},
+		"Flag":  true,
+	}
+	if got := env.ToMap(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Env.ToMap(): wrong result. Want %#v. Got %#v.", expected, got)
+	}
+}
+
+func TestFromMap(t *testing.T) {
+	var env Env
+	env.FromMap(map[string]interface{}{"Name": "aufs", "Count": float64(3), "List": []string{"a"}})
+	expected := map[string]string{"Name": "aufs", "Count": "3", "List": `["a"]`}
+	if got := env.Map(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Env.FromMap(): wrong result. Want %v. Got %v.", expected, got)
+	}
+}
@@ -5,13 +5,11 @@
 package docker
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
-	"net"
 	"net/http"
 	"net/http/httputil"
 	"sync"
@@ -25,6 +23,22 @@ type APIEvents struct {
 	ID     string `json:"ID,omitempty" yaml:"ID,omitempty"`
 	From   string `json:"From,omitempty" yaml:"From,omitempty"`
 	Time   int64  `json:"Time,omitempty" yaml:"Time,omitempty"`
+
+	// TimeNano is the time of the event in nanoseconds, sent by Docker API
+	// 1.22 and newer.
+	TimeNano int64 `json:"TimeNano,omitempty" yaml:"TimeNano,omitempty"`
+
+	// Type, Action and Actor are sent by Docker API 1.22 and newer.
+	Type   string   `json:"Type,omitempty" yaml:"Type,omitempty"`
+	Action string   `json:"Action,omitempty" yaml:"Action,omitempty"`
+	Actor  APIActor `json:"Actor,omitempty" yaml:"Actor,omitempty"`
+}
+
+// APIActor represents the object that generated an event, like a container,
+// along with its attributes.
+type APIActor struct {
+	ID         string            `json:"ID,omitempty" yaml:"ID,omitempty"`
+	Attributes map[string]string `json:"Attributes,omitempty" yaml:"Attributes,omitempty"`
 }
 
 type eventMonitoringState struct {
@@ -81,7 +95,7 @@ func (c *Client) RemoveEventListener(listener chan *APIEvents) error {
 	if err != nil {
 		return err
 	}
-	if len(c.eventMonitor.listeners) == 0 {
+	if c.eventMonitor.noListeners() {
 		err = c.eventMonitor.disableEventMonitoring()
 		if err != nil {
 			return err
@@ -255,28 +269,22 @@ func (c *Client) eventHijack(startTime int64, eventChan chan *APIEvents, errChan
 	if startTime != 0 {
 		uri += fmt.Sprintf("?since=%d", startTime)
 	}
-	protocol := c.endpointURL.Scheme
-	address := c.endpointURL.Path
-	if protocol != "unix" {
-		protocol = "tcp"
-		address = c.endpointURL.Host
-	}
-	var dial net.Conn
-	var err error
-	if c.TLSConfig == nil {
-		dial, err = net.Dial(protocol, address)
-	} else {
-		dial, err = tls.Dial(protocol, address, c.TLSConfig)
+	start := time.Now()
+	if err := c.checkRequest("GET", uri); err != nil {
+		c.audit("GET", uri, nil, start, nil, err)
+		return err
 	}
+	req, err := http.NewRequest("GET", c.getURL(uri), nil)
 	if err != nil {
 		return err
 	}
-	conn := httputil.NewClientConn(dial, nil)
-	req, err := http.NewRequest("GET", uri, nil)
+	dial, err := c.dialHijack(req)
 	if err != nil {
 		return err
 	}
+	conn := httputil.NewClientConn(dial, nil)
 	res, err := conn.Do(req)
+	c.audit("GET", uri, nil, start, res, err)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,254 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"strings"
+	"sync"
+)
+
+const subscriptionBuffer = 16
+
+// EventFilter is a predicate selecting events, evaluated by the client.
+type EventFilter func(event *APIEvents) bool
+
+// EventFilters returns an EventFilter with the semantics of the filters of
+// the events API: values of the same key are alternatives, and every key
+// must match. For example, {"type": {"container"}, "event": {"start",
+// "die"}} selects the containers that start or die.
+//
+// The supported keys are type, event (or action), container, image, label
+// (as "key" or "key=value"), volume, network and daemon. Unknown keys never
+// match.
+func EventFilters(filters map[string][]string) EventFilter {
+	return func(event *APIEvents) bool {
+		for key, values := range filters {
+			if !matchEventFilter(event, key, values) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AllEventFilters returns an EventFilter selecting the events selected by
+// all of the given filters.
+func AllEventFilters(filters ...EventFilter) EventFilter {
+	return func(event *APIEvents) bool {
+		for _, filter := range filters {
+			if !filter(event) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyEventFilter returns an EventFilter selecting the events selected by
+// any of the given filters.
+func AnyEventFilter(filters ...EventFilter) EventFilter {
+	return func(event *APIEvents) bool {
+		for _, filter := range filters {
+			if filter(event) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func matchEventFilter(event *APIEvents, key string, values []string) bool {
+	eventType := event.Type
+	if eventType == "" {
+		// daemons older than API 1.22 only send container events
+		eventType = "container"
+	}
+	action := event.Action
+	if action == "" {
+		action = event.Status
+	}
+	id := event.Actor.ID
+	if id == "" {
+		id = event.ID
+	}
+	attributes := event.Actor.Attributes
+	for _, value := range values {
+		switch key {
+		case "type":
+			if value == eventType {
+				return true
+			}
+		case "event", "action":
+			if value == action {
+				return true
+			}
+		case "container", "volume", "network", "daemon":
+			if key == eventType && (value == id || value == attributes["name"]) {
+				return true
+			}
+		case "image":
+			image := attributes["image"]
+			if eventType == "container" && image == "" {
+				image = event.From
+			}
+			if eventType == "image" && (value == id || value == attributes["name"]) || value == image {
+				return true
+			}
+		case "label":
+			parts := strings.SplitN(value, "=", 2)
+			if v, ok := attributes[parts[0]]; ok && (len(parts) == 1 || parts[1] == v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EventMux shares a single stream of events among several subscriptions,
+// each one with its own filter, so components interested in events don't
+// open one connection each.
+type EventMux struct {
+	stream *EventStream
+	done   chan struct{}
+	once   sync.Once
+
+	mut           sync.Mutex
+	subscriptions map[*EventSubscription]struct{}
+	closed        bool
+}
+
+// EventSubscription is a subscription to the events of an EventMux.
+type EventSubscription struct {
+	// C receives the events selected by the filter of the subscription.
+	// It's closed when the subscription is closed or when the stream of
+	// the EventMux ends.
+	C <-chan *APIEvents
+
+	c      chan *APIEvents
+	filter EventFilter
+	mux    *EventMux
+	done   chan struct{}
+	once   sync.Once
+
+	// mut keeps c open while an event is sent to it
+	mut    sync.Mutex
+	closed bool
+}
+
+// NewEventMux starts a stream of events from the given cursor, as in
+// Resume, shared among the subscriptions of the returned EventMux.
+//
+// A subscription that doesn't keep up with the events holds back the
+// others, once its buffer is full. Consumers may subscribe, or close their
+// subscription, while receiving events.
+func (c *Client) NewEventMux(cursor EventCursor) (*EventMux, error) {
+	stream, err := c.Resume(cursor)
+	if err != nil {
+		return nil, err
+	}
+	m := &EventMux{
+		stream:        stream,
+		done:          make(chan struct{}),
+		subscriptions: make(map[*EventSubscription]struct{}),
+	}
+	go m.run()
+	return m, nil
+}
+
+// Subscribe adds a subscription to the events selected by the given filter,
+// from the next event on. A nil filter selects all events.
+func (m *EventMux) Subscribe(filter EventFilter) *EventSubscription {
+	c := make(chan *APIEvents, subscriptionBuffer)
+	s := &EventSubscription{C: c, c: c, filter: filter, mux: m, done: make(chan struct{})}
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if m.closed {
+		s.closed = true
+		close(c)
+		return s
+	}
+	m.subscriptions[s] = struct{}{}
+	return s
+}
+
+// subscribed returns the current subscriptions, so events are sent without
+// holding the lock of the EventMux.
+func (m *EventMux) subscribed() []*EventSubscription {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	subscriptions := make([]*EventSubscription, 0, len(m.subscriptions))
+	for s := range m.subscriptions {
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions
+}
+
+func (m *EventMux) run() {
+	for event := range m.stream.C {
+		for _, s := range m.subscribed() {
+			if s.filter == nil || s.filter(event) {
+				s.send(event, m.done)
+			}
+		}
+	}
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.closed = true
+	for s := range m.subscriptions {
+		delete(m.subscriptions, s)
+		s.closeC()
+	}
+}
+
+// send sends the event to the subscription, unless it's closed, or until
+// either the subscription or the EventMux is closed.
+func (s *EventSubscription) send(event *APIEvents, done <-chan struct{}) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.c <- event:
+	case <-s.done:
+	case <-done:
+	}
+}
+
+func (s *EventSubscription) closeC() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.c)
+	}
+}
+
+// Err returns the error that ended the stream of events, as in
+// EventStream.Err.
+func (m *EventMux) Err() error {
+	return m.stream.Err()
+}
+
+// Close ends the stream of events, closing all subscriptions.
+func (m *EventMux) Close() error {
+	m.once.Do(func() {
+		close(m.done)
+	})
+	return m.stream.Close()
+}
+
+// Close removes the subscription from its EventMux, and closes C.
+func (s *EventSubscription) Close() {
+	s.once.Do(func() {
+		// done unblocks a pending send before C is closed
+		close(s.done)
+		s.closeC()
+		m := s.mux
+		m.mut.Lock()
+		defer m.mut.Unlock()
+		delete(m.subscriptions, s)
+	})
+}
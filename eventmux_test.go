@@ -0,0 +1,141 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventFilters(t *testing.T) {
+	start := &APIEvents{Type: "container", Action: "start", Actor: APIActor{ID: "c1", Attributes: map[string]string{"name": "web", "image": "nginx", "app": "shop"}}}
+	pull := &APIEvents{Type: "image", Action: "pull", Actor: APIActor{ID: "nginx:latest", Attributes: map[string]string{"name": "nginx:latest"}}}
+	legacy := &APIEvents{Status: "die", ID: "c2", From: "redis"}
+	var tests = []struct {
+		filters  map[string][]string
+		expected []bool
+	}{
+		{nil, []bool{true, true, true}},
+		{map[string][]string{"type": {"container"}}, []bool{true, false, true}},
+		{map[string][]string{"event": {"start", "die"}}, []bool{true, false, true}},
+		{map[string][]string{"type": {"container"}, "event": {"pull"}}, []bool{false, false, false}},
+		{map[string][]string{"container": {"web", "c2"}}, []bool{true, false, true}},
+		{map[string][]string{"image": {"nginx", "redis"}}, []bool{true, false, true}},
+		{map[string][]string{"image": {"nginx:latest"}}, []bool{false, true, false}},
+		{map[string][]string{"label": {"app"}}, []bool{true, false, false}},
+		{map[string][]string{"label": {"app=blog"}}, []bool{false, false, false}},
+		{map[string][]string{"unknown": {"x"}}, []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		filter := EventFilters(tt.filters)
+		for i, event := range []*APIEvents{start, pull, legacy} {
+			if got := filter(event); got != tt.expected[i] {
+				t.Errorf("EventFilters(%v): wrong result for %#v. Want %v. Got %v.", tt.filters, event, tt.expected[i], got)
+			}
+		}
+	}
+}
+
+func TestEventFilterCombinators(t *testing.T) {
+	yes := func(*APIEvents) bool { return true }
+	no := func(*APIEvents) bool { return false }
+	event := &APIEvents{}
+	if !AllEventFilters()(event) || AllEventFilters(yes, no)(event) || !AllEventFilters(yes, yes)(event) {
+		t.Error("AllEventFilters: wrong results")
+	}
+	if AnyEventFilter()(event) || !AnyEventFilter(no, yes)(event) || AnyEventFilter(no, no)(event) {
+		t.Error("AnyEventFilter: wrong results")
+	}
+}
+
+func TestEventMux(t *testing.T) {
+	queries := make(chan string, 1)
+	gate := make(chan struct{})
+	server := eventsServer(streamEvents, queries, gate)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := client.NewEventMux(EventCursor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	all := m.Subscribe(nil)
+	c1 := m.Subscribe(EventFilters(map[string][]string{"container": {"c1"}}))
+	closed := m.Subscribe(nil)
+	closed.Close()
+	if _, ok := <-closed.C; ok {
+		t.Error("EventSubscription.Close: C should be closed")
+	}
+	close(gate)
+	var cursor EventCursor
+	if got := receiveEvents(t, &EventStream{C: all.C}, &cursor, 4); len(got) != 4 {
+		t.Errorf("EventMux: wrong events. Got %#v.", got)
+	}
+	expected := []string{"create c1", "start c1", "die c1"}
+	got := receiveEvents(t, &EventStream{C: c1.C}, &cursor, 3)
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("EventMux: wrong filtered events. Want %#v. Got %#v.", expected, got)
+			break
+		}
+	}
+	for _, s := range []*EventSubscription{all, c1} {
+		if _, ok := <-s.C; ok {
+			t.Error("EventMux: subscriptions should be closed at the end of the stream")
+		}
+	}
+}
+
+func TestEventMuxSubscribeWhileReceiving(t *testing.T) {
+	var events []APIEvents
+	for i := 0; i < 2*subscriptionBuffer; i++ {
+		events = append(events, APIEvents{Status: "start", ID: "c1", Time: 1429776000, TimeNano: 1429776000000000000 + int64(i)})
+	}
+	queries := make(chan string, 1)
+	server := eventsServer(events, queries, nil)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := client.NewEventMux(EventCursor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// slow never receives, and holds back the stream once its buffer is
+	// full
+	slow := m.Subscribe(nil)
+	s := m.Subscribe(nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for len(slow.C) < subscriptionBuffer {
+			time.Sleep(time.Millisecond)
+		}
+		<-s.C
+		m.Subscribe(nil).Close()
+		s.Close()
+		m.Close()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("EventMux: deadlock when subscribing while receiving")
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-slow.C:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("EventMux.Close: subscriptions should be closed")
+		}
+	}
+}
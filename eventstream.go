@@ -0,0 +1,237 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EventCursor is the position of a consumer in the stream of events. The
+// consumer advances it once it's done with each event, and persists it, for
+// example in JSON, so it resumes the stream where it stopped when it
+// restarts, without missing events or receiving them twice.
+//
+// Time is the time of the last event received, in nanoseconds, and Keys
+// identify the events received at that time, once per occurrence.
+type EventCursor struct {
+	Time int64    `json:"Time,omitempty" yaml:"Time,omitempty"`
+	Keys []string `json:"Keys,omitempty" yaml:"Keys,omitempty"`
+}
+
+// eventTime returns the time of the event in nanoseconds, using the time in
+// seconds sent by daemons older than API 1.22.
+func eventTime(event *APIEvents) int64 {
+	if event.TimeNano != 0 {
+		return event.TimeNano
+	}
+	return event.Time * int64(time.Second)
+}
+
+// eventKey identifies an event among the ones that happened at the same
+// time.
+func eventKey(event *APIEvents) string {
+	return event.Type + " " + event.ID + " " + event.Status + " " + event.Action
+}
+
+// eventReplay skips the events replayed by the daemon when a stream is
+// resumed from a cursor: the ones older than the cursor, and, among the ones
+// at the time of the cursor, as many occurrences of each key as the cursor
+// holds, so that identical events happening at the same time are neither
+// missed nor received twice.
+type eventReplay struct {
+	time   int64
+	counts map[string]int
+}
+
+func (cursor *EventCursor) replay() *eventReplay {
+	r := &eventReplay{time: cursor.Time, counts: make(map[string]int)}
+	for _, k := range cursor.Keys {
+		r.counts[k]++
+	}
+	return r
+}
+
+// skip tells whether the given event was received before the cursor, and
+// counts it as replayed.
+func (r *eventReplay) skip(event *APIEvents) bool {
+	t := eventTime(event)
+	if t != r.time {
+		return t < r.time
+	}
+	key := eventKey(event)
+	if r.counts[key] == 0 {
+		return false
+	}
+	r.counts[key]--
+	return true
+}
+
+// Advance moves the cursor past the given event.
+func (cursor *EventCursor) Advance(event *APIEvents) {
+	if t := eventTime(event); t > cursor.Time {
+		cursor.Time = t
+		cursor.Keys = nil
+	}
+	cursor.Keys = append(cursor.Keys, eventKey(event))
+}
+
+// since returns the value of the since parameter replaying the events from
+// the cursor. The daemon only takes seconds, so the events of the second of
+// the cursor are received again and skipped.
+func (cursor *EventCursor) since() string {
+	return strconv.FormatInt(cursor.Time/int64(time.Second), 10)
+}
+
+// EventStream is a stream of events, started from a cursor by
+// Client.Resume. Unlike the event listeners, it has its own connection to
+// the daemon.
+type EventStream struct {
+	// C receives the events. It's closed when the stream ends.
+	C <-chan *APIEvents
+
+	client *Client
+	opts   EventStreamOptions
+
+	mut  sync.Mutex
+	body io.Closer
+	err  error
+	done chan struct{}
+}
+
+// EventStreamOptions is the set of options that can be used when starting a
+// stream of events.
+type EventStreamOptions struct {
+	// Reconnect reconnects the stream when it ends while the daemon
+	// restarts, replaying the events missed meanwhile. The stream ends
+	// when the daemon is not back within ReconnectTimeout, which defaults
+	// to one minute.
+	Reconnect        bool
+	ReconnectTimeout time.Duration
+}
+
+// Resume starts a stream of the events that happened after the given
+// cursor, replaying the events that happened since then. A zero cursor
+// starts the stream with the next event.
+func (c *Client) Resume(cursor EventCursor) (*EventStream, error) {
+	return c.ResumeWithOptions(cursor, EventStreamOptions{})
+}
+
+// ResumeWithOptions is like Resume, with the given options.
+func (c *Client) ResumeWithOptions(cursor EventCursor, opts EventStreamOptions) (*EventStream, error) {
+	if opts.ReconnectTimeout <= 0 {
+		opts.ReconnectTimeout = defaultReconnectTimeout
+	}
+	body, err := c.openEvents(cursor)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan *APIEvents)
+	s := &EventStream{C: events, client: c, opts: opts, body: body, done: make(chan struct{})}
+	go s.run(json.NewDecoder(body), cursor, events)
+	return s, nil
+}
+
+func (c *Client) openEvents(cursor EventCursor) (io.ReadCloser, error) {
+	path := "/events"
+	if cursor.Time != 0 {
+		path += "?since=" + cursor.since()
+	}
+	resp, err := c.doRequest("GET", path, DoOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *EventStream) run(decoder *json.Decoder, cursor EventCursor, events chan<- *APIEvents) {
+	defer close(events)
+	replay := cursor.replay()
+	for {
+		var event APIEvents
+		if err := decoder.Decode(&event); err != nil {
+			if decoder = s.reconnect(cursor, err); decoder == nil {
+				return
+			}
+			replay = cursor.replay()
+			continue
+		}
+		if replay.skip(&event) {
+			continue
+		}
+		cursor.Advance(&event)
+		select {
+		case events <- &event:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// reconnect opens a new connection when the stream ended with the given
+// error and may be reconnected. Otherwise, it stops the stream and returns
+// nil.
+func (s *EventStream) reconnect(cursor EventCursor, err error) *json.Decoder {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	err = s.client.classifyStreamEnd("", err)
+	if !s.opts.Reconnect {
+		s.stop(err)
+		return nil
+	}
+	if waitErr := s.client.WaitForDaemon(s.opts.ReconnectTimeout); waitErr != nil {
+		s.stop(err)
+		return nil
+	}
+	body, openErr := s.client.openEvents(cursor)
+	if openErr != nil {
+		s.stop(openErr)
+		return nil
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	select {
+	case <-s.done:
+		body.Close()
+		return nil
+	default:
+	}
+	s.body = body
+	return json.NewDecoder(body)
+}
+
+// Err returns the error that ended the stream, if any. It's a *StreamError
+// when the daemon is gone or when the stream was closed by Close, and io.EOF
+// when the daemon closed the stream otherwise.
+func (s *EventStream) Err() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.err
+}
+
+// Close ends the stream.
+func (s *EventStream) Close() error {
+	s.stop(&StreamError{Cause: StreamCanceled})
+	return nil
+}
+
+func (s *EventStream) stop(err error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	s.err = err
+	close(s.done)
+	s.body.Close()
+}
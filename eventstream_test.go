@@ -0,0 +1,170 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var streamEvents = []APIEvents{
+	{Status: "create", ID: "c1", Time: 1429776000, TimeNano: 1429776000100000000},
+	{Status: "start", ID: "c1", Time: 1429776000, TimeNano: 1429776000200000000},
+	{Status: "create", ID: "c2", Time: 1429776000, TimeNano: 1429776000200000000},
+	{Status: "die", ID: "c1", Time: 1429776001, TimeNano: 1429776001000000000},
+}
+
+// eventsServer streams the events that happened since the requested time,
+// at the resolution of a second like the daemon, and then closes the
+// stream. When gate is not nil, the events are only sent once it's closed.
+func eventsServer(events []APIEvents, queries chan<- string, gate <-chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_ping" {
+			w.Write([]byte("OK"))
+			return
+		}
+		if r.URL.Path != "/events" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		queries <- r.URL.RawQuery
+		if gate != nil {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-gate
+		}
+		since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+		enc := json.NewEncoder(w)
+		for _, event := range events {
+			if event.Time >= since {
+				enc.Encode(event)
+			}
+		}
+	}))
+}
+
+func receiveEvents(t *testing.T, s *EventStream, cursor *EventCursor, n int) []string {
+	var received []string
+	for i := 0; i < n; i++ {
+		select {
+		case event, ok := <-s.C:
+			if !ok {
+				t.Fatalf("EventStream: closed after %d events", i)
+			}
+			received = append(received, event.Status+" "+event.ID)
+			cursor.Advance(event)
+		case <-time.After(5 * time.Second):
+			t.Fatal("EventStream: timed out waiting for events")
+		}
+	}
+	return received
+}
+
+func TestResumeEventStream(t *testing.T) {
+	queries := make(chan string, 2)
+	server := eventsServer(streamEvents, queries, nil)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cursor EventCursor
+	s, err := client.Resume(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveEvents(t, s, &cursor, 2); !reflect.DeepEqual(got, []string{"create c1", "start c1"}) {
+		t.Errorf("EventStream: wrong events. Got %#v.", got)
+	}
+	s.Close()
+	if e, ok := s.Err().(*StreamError); !ok || e.Cause != StreamCanceled {
+		t.Errorf("EventStream.Err: wrong error after Close. Want a canceled *StreamError. Got %#v.", s.Err())
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored EventCursor
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	s, err = client.Resume(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveEvents(t, s, &restored, 2); !reflect.DeepEqual(got, []string{"create c2", "die c1"}) {
+		t.Errorf("EventStream: wrong replayed events. Got %#v.", got)
+	}
+	if _, ok := <-s.C; ok {
+		t.Error("EventStream: C should be closed at the end of the stream")
+	}
+	if err := s.Err(); err != io.EOF {
+		t.Errorf("EventStream.Err: wrong error. Want %#v. Got %#v.", io.EOF, err)
+	}
+	<-queries
+	if query := <-queries; query != "since=1429776000" {
+		t.Errorf("Resume: wrong query. Want %q. Got %q.", "since=1429776000", query)
+	}
+	expected := EventCursor{Time: 1429776001000000000, Keys: []string{eventKey(&streamEvents[3])}}
+	if !reflect.DeepEqual(restored, expected) {
+		t.Errorf("EventCursor.Advance: wrong cursor. Want %#v. Got %#v.", expected, restored)
+	}
+}
+
+func TestEventCursorSecondsOnly(t *testing.T) {
+	var cursor EventCursor
+	first := &APIEvents{Status: "start", ID: "c1", Time: 1429776000}
+	second := &APIEvents{Status: "start", ID: "c2", Time: 1429776000}
+	cursor.Advance(first)
+	replay := cursor.replay()
+	if !replay.skip(first) || replay.skip(second) {
+		t.Errorf("EventCursor: wrong events skipped with %#v.", cursor)
+	}
+	if older := (&APIEvents{Status: "stop", ID: "c3", Time: 1429775999}); !replay.skip(older) {
+		t.Errorf("EventCursor: older events should be skipped with %#v.", cursor)
+	}
+}
+
+func TestEventCursorRepeatedEvents(t *testing.T) {
+	event := APIEvents{Status: "exec_start", ID: "c1", Time: 1429776000}
+	var cursor EventCursor
+	cursor.Advance(&event)
+	cursor.Advance(&event)
+	replay := cursor.replay()
+	if !replay.skip(&event) || !replay.skip(&event) || replay.skip(&event) {
+		t.Errorf("EventCursor: should skip each occurrence once with %#v.", cursor)
+	}
+	queries := make(chan string, 2)
+	server := eventsServer([]APIEvents{event, event, event}, queries, nil)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var live EventCursor
+	s, err := client.Resume(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveEvents(t, s, &live, 3); len(got) != 3 {
+		t.Errorf("EventStream: wrong events. Got %#v.", got)
+	}
+	s, err = client.Resume(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveEvents(t, s, &cursor, 1); !reflect.DeepEqual(got, []string{"exec_start c1"}) {
+		t.Errorf("EventStream: wrong replayed events. Got %#v.", got)
+	}
+	if _, ok := <-s.C; ok {
+		t.Error("EventStream: the replayed events should be skipped")
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // CreateExecOptions specify parameters to the CreateExecContainer function.
@@ -25,6 +27,29 @@ type CreateExecOptions struct {
 	Tty          bool     `json:"Tty,omitempty" yaml:"Tty,omitempty"`
 	Cmd          []string `json:"Cmd,omitempty" yaml:"Cmd,omitempty"`
 	Container    string   `json:"Container,omitempty" yaml:"Container,omitempty"`
+
+	// Privileged gives extended privileges to the command, as with
+	// docker exec --privileged.
+	Privileged bool `json:"Privileged,omitempty" yaml:"Privileged,omitempty"`
+
+	// User runs the command as the given user, in one of the formats
+	// "user", "user:group", "uid" or "uid:gid", instead of the user of the
+	// container.
+	User string `json:"User,omitempty" yaml:"User,omitempty"`
+
+	// Env and WorkingDir require Docker API 1.25 and 1.35 or newer, and
+	// are left out of the request on older versions.
+	Env        []string `json:"Env,omitempty" yaml:"Env,omitempty" apiVersion:"1.25"`
+	WorkingDir string   `json:"WorkingDir,omitempty" yaml:"WorkingDir,omitempty" apiVersion:"1.35"`
+
+	// ConsoleSize is the initial size of the TTY, as [height, width]. It
+	// requires Docker API 1.42 or newer.
+	ConsoleSize *[2]uint `json:"ConsoleSize,omitempty" yaml:"ConsoleSize,omitempty" apiVersion:"1.42"`
+
+	// WaitRestarting is how long to wait for a restarting container to
+	// run again, before creating the exec instance. Without it,
+	// CreateExec fails with ContainerRestarting right away.
+	WaitRestarting time.Duration `json:"-" yaml:"-"`
 }
 
 // StartExecOptions specify parameters to the StartExecContainer function.
@@ -40,6 +65,8 @@ type StartExecOptions struct {
 	ErrorStream  io.Writer `qs:"-"`
 
 	// Use raw terminal? Usually true when the container contains a TTY.
+	// The terminal of InputStream, if any, is put in raw mode for the
+	// session, unless the NORAW environment variable is set.
 	RawTerminal bool `qs:"-"`
 
 	// If set, after a successful connect, a sentinel will be sent and then the
@@ -87,13 +114,44 @@ type ExecInspect struct {
 // CreateExec sets up an exec instance in a running container `id`, returning the exec
 // instance, or an error in case of failure.
 //
+// The daemon refuses to create exec instances in a restarting container.
+// CreateExec then fails with ContainerRestarting, unless
+// opts.WaitRestarting is set.
+//
 // See http://goo.gl/8izrzI for more details
 func (c *Client) CreateExec(opts CreateExecOptions) (*Exec, error) {
+	if opts.ConsoleSize != nil {
+		if err := c.requireAPIVersion("CreateExecOptions.ConsoleSize", apiVersion142); err != nil {
+			return nil, err
+		}
+	}
+	if opts.WaitRestarting <= 0 {
+		return c.createExec(opts)
+	}
+	var (
+		exec *Exec
+		err  error
+	)
+	// retried like a ping, until the container is not restarting anymore
+	waitForDaemon(func() error {
+		exec, err = c.createExec(opts)
+		if _, ok := err.(*ContainerRestarting); ok {
+			return err
+		}
+		return nil
+	}, opts.WaitRestarting, time.Now, time.Sleep)
+	return exec, err
+}
+
+func (c *Client) createExec(opts CreateExecOptions) (*Exec, error) {
 	path := fmt.Sprintf("/containers/%s/exec", opts.Container)
 	body, status, err := c.do("POST", path, opts, false)
 	if status == http.StatusNotFound {
 		return nil, &NoSuchContainer{ID: opts.Container}
 	}
+	if e, ok := err.(*Error); ok && status == http.StatusConflict && strings.Contains(e.Message, "is restarting") {
+		return nil, &ContainerRestarting{ID: opts.Container}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -175,3 +233,13 @@ type NoSuchExec struct {
 func (err *NoSuchExec) Error() string {
 	return "No such exec instance: " + err.ID
 }
+
+// ContainerRestarting is the error returned by CreateExec when the container
+// is restarting.
+type ContainerRestarting struct {
+	ID string
+}
+
+func (err *ContainerRestarting) Error() string {
+	return "Container restarting: " + err.ID
+}
@@ -13,6 +13,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestExecCreate(t *testing.T) {
@@ -55,6 +56,68 @@ func TestExecCreate(t *testing.T) {
 	}
 }
 
+func TestExecCreateUserAndPrivileged(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	config := CreateExecOptions{
+		Container:   "test",
+		Cmd:         []string{"id"},
+		Privileged:  true,
+		User:        "1000:1000",
+		Env:         []string{"DEBUG=1"},
+		WorkingDir:  "/tmp",
+		ConsoleSize: &[2]uint{24, 80},
+	}
+	if _, err := client.CreateExec(config); err != nil {
+		t.Fatal(err)
+	}
+	var gotBody map[string]interface{}
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	if gotBody["Privileged"] != true {
+		t.Errorf("ExecCreate: wrong Privileged. Want true. Got %#v.", gotBody["Privileged"])
+	}
+	if gotBody["User"] != "1000:1000" {
+		t.Errorf("ExecCreate: wrong User. Want %q. Got %#v.", "1000:1000", gotBody["User"])
+	}
+	if gotBody["WorkingDir"] != "/tmp" {
+		t.Errorf("ExecCreate: wrong WorkingDir. Want %q. Got %#v.", "/tmp", gotBody["WorkingDir"])
+	}
+	expectedSize := []interface{}{24.0, 80.0}
+	if !reflect.DeepEqual(gotBody["ConsoleSize"], expectedSize) {
+		t.Errorf("ExecCreate: wrong ConsoleSize. Want %#v. Got %#v.", expectedSize, gotBody["ConsoleSize"])
+	}
+}
+
+func TestExecCreateRestarting(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests++; requests < 3 {
+			http.Error(w, "Container test is restarting, wait until the container is running", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "4fa6e0f0c678"}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.CreateExec(CreateExecOptions{Container: "test", Cmd: []string{"id"}})
+	if e, ok := err.(*ContainerRestarting); !ok || e.ID != "test" {
+		t.Errorf("CreateExec: wrong error. Want a *ContainerRestarting. Got %#v.", err)
+	}
+	exec, err := client.CreateExec(CreateExecOptions{Container: "test", Cmd: []string{"id"}, WaitRestarting: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exec.ID != "4fa6e0f0c678" || requests != 3 {
+		t.Errorf("CreateExec: wrong exec after %d requests. Got %#v.", requests, exec)
+	}
+}
+
 func TestExecStartDetached(t *testing.T) {
 	execID := "4fa6e0f0c6786287e131c3852c58a2e01cc697a68231826813597e4994f1d6e2"
 	fakeRT := &FakeRoundTripper{status: http.StatusOK}
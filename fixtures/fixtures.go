@@ -0,0 +1,235 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fixtures provides ephemeral containers for integration tests:
+// databases, queues or any other service started from an image, published
+// on random ports of the host and removed once the test is done.
+package fixtures
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/reverb/go-dockerclient"
+)
+
+const (
+	defaultTimeout = time.Minute
+	pollInterval   = 100 * time.Millisecond
+)
+
+var (
+	// ErrNotReady is returned by StartFixture when the container is not
+	// ready within the timeout.
+	ErrNotReady = errors.New("fixture not ready before the timeout")
+
+	// ErrUnhealthy is returned by StartFixture when the healthcheck of the
+	// container fails.
+	ErrUnhealthy = errors.New("fixture is unhealthy")
+
+	// ErrCanceled is returned by StartFixture and Fixture.Wait when the
+	// wait is canceled.
+	ErrCanceled = errors.New("wait for the fixture canceled")
+
+	// ErrPortNotPublished is returned by Fixture.Address for the ports that
+	// are not published.
+	ErrPortNotPublished = errors.New("port not published")
+)
+
+// Options is the set of options that can be used when starting a fixture.
+type Options struct {
+	// Cmd and Env are set in the configuration of the container.
+	Cmd []string
+	Env []string
+
+	// Ports lists the ports of the container, like "5432/tcp", published
+	// on random ports of the host.
+	Ports []docker.Port
+
+	// Auth is used to pull the image when it's missing.
+	Auth docker.AuthConfiguration
+
+	// The container is ready once all of the conditions below are met.
+	// WaitForPort waits for the given port, which must be in Ports, to
+	// accept connections. WaitForLog waits for a line of the logs of the
+	// container to match the expression. WaitForHealthy waits for the
+	// healthcheck of the image to pass. WaitFor is any other strategy,
+	// like the ones combined by WaitForAll. Without conditions, the
+	// container is ready once it's running.
+	WaitForPort    docker.Port
+	WaitForLog     *regexp.Regexp
+	WaitForHealthy bool
+	WaitFor        WaitStrategy
+
+	// Timeout is how long to wait for the container to be ready. It
+	// defaults to one minute.
+	Timeout time.Duration
+
+	// Cancel, when closed, stops the wait for the container to be ready.
+	Cancel <-chan struct{}
+}
+
+// strategy returns the strategy combining the conditions in opts.
+func (opts *Options) strategy() WaitStrategy {
+	var strategies []WaitStrategy
+	if opts.WaitForHealthy {
+		strategies = append(strategies, WaitForHealthy())
+	}
+	if opts.WaitForPort != "" {
+		strategies = append(strategies, WaitForPort(opts.WaitForPort))
+	}
+	if opts.WaitForLog != nil {
+		strategies = append(strategies, WaitForLogLine(opts.WaitForLog))
+	}
+	if opts.WaitFor != nil {
+		strategies = append(strategies, opts.WaitFor)
+	}
+	if len(strategies) == 0 {
+		return waitForRunning
+	}
+	return WaitForAll(strategies...)
+}
+
+// Fixture is a running container started by StartFixture.
+type Fixture struct {
+	Client    *docker.Client
+	Container *docker.Container
+
+	// Host is the host where the published ports can be reached.
+	Host string
+}
+
+// StartFixture starts a container from the given image, pulling it when
+// it's missing. The container gets a random name, and its ports are published
+// on random ports of the host. StartFixture returns once the container is
+// ready, according to the conditions in opts.
+//
+// The container is removed when it fails to get ready. Otherwise, the caller
+// should remove it with Terminate once done.
+func StartFixture(client *docker.Client, image string, opts Options) (*Fixture, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	host, err := endpointHost(client.Endpoint())
+	if err != nil {
+		return nil, err
+	}
+	if err := client.PullImageIfMissing(image, opts.Auth); err != nil {
+		return nil, err
+	}
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	config := docker.Config{Image: image, Cmd: opts.Cmd, Env: opts.Env}
+	var hostConfig docker.HostConfig
+	docker.PublishRandomPorts(&config, &hostConfig, opts.Ports...)
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Name:       name,
+		Config:     &config,
+		HostConfig: &hostConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+	f := &Fixture{Client: client, Container: container, Host: host}
+	if err := f.start(opts); err != nil {
+		f.Terminate()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Fixture) start(opts Options) error {
+	if err := f.Client.StartContainer(f.Container.ID, nil); err != nil {
+		return err
+	}
+	return f.Wait(opts.strategy(), opts.Timeout, opts.Cancel)
+}
+
+// Wait inspects the container until the given strategy is ready, the
+// timeout expires or cancel is closed. A nil cancel channel never stops the
+// wait. Unless the strategy is ready first, an exited container fails the
+// wait.
+func (f *Fixture) Wait(strategy WaitStrategy, timeout time.Duration, cancel <-chan struct{}) error {
+	deadline := time.Now().Add(timeout)
+	var end func()
+	for {
+		container, err := f.Client.InspectContainer(f.Container.ID)
+		if err != nil {
+			return err
+		}
+		f.Container = container
+		if end == nil {
+			// the strategy begins once the container is inspected
+			strategy, end = beginWait(strategy, f)
+			defer end()
+		}
+		ready, err := strategy.Ready(f)
+		if ready || err != nil {
+			return err
+		}
+		if !container.State.Running {
+			return fmt.Errorf("fixture exited with code %d", container.State.ExitCode)
+		}
+		if time.Now().After(deadline) {
+			return ErrNotReady
+		}
+		select {
+		case <-cancel:
+			return ErrCanceled
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Address returns the address, in the form host:port, where the given port
+// of the container is published.
+func (f *Fixture) Address(port docker.Port) (string, error) {
+	if f.Container.NetworkSettings == nil {
+		return "", ErrPortNotPublished
+	}
+	hostPort, ok := f.Container.NetworkSettings.HostPorts()[port]
+	if !ok {
+		return "", ErrPortNotPublished
+	}
+	return net.JoinHostPort(f.Host, strconv.Itoa(hostPort.Port)), nil
+}
+
+// Terminate removes the container, along with its volumes.
+func (f *Fixture) Terminate() error {
+	return f.Client.RemoveContainer(docker.RemoveContainerOptions{ID: f.Container.ID, RemoveVolumes: true, Force: true})
+}
+
+// endpointHost returns the host where the ports published by the daemon at
+// the given endpoint can be reached.
+func endpointHost(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "unix" {
+		return "127.0.0.1", nil
+	}
+	host, _, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		return u.Host, nil
+	}
+	return host, nil
+}
+
+func randomName() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "fixture-" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fixtures
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reverb/go-dockerclient"
+)
+
+// fakeDaemon simulates a daemon running a single container, whose inspect
+// output and logs are set by the tests.
+type fakeDaemon struct {
+	*httptest.Server
+
+	mut      sync.Mutex
+	name     string
+	inspect  string
+	logs     string
+	requests []string
+}
+
+func newFakeDaemon() *fakeDaemon {
+	d := &fakeDaemon{}
+	d.Server = httptest.NewServer(http.HandlerFunc(d.serveHTTP))
+	return d
+}
+
+func (d *fakeDaemon) set(inspect, logs string) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	d.inspect, d.logs = inspect, logs
+}
+
+func (d *fakeDaemon) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	d.requests = append(d.requests, r.Method+" "+r.URL.Path)
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/images/postgres:9.4/json":
+		w.Write([]byte(`{"Id":"postgres"}`))
+	case r.Method == "POST" && r.URL.Path == "/containers/create":
+		d.name = r.URL.Query().Get("name")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id":"f1"}`))
+	case r.Method == "POST" && r.URL.Path == "/containers/f1/start":
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "GET" && r.URL.Path == "/containers/f1/json":
+		w.Write([]byte(d.inspect))
+	case r.Method == "GET" && r.URL.Path == "/containers/f1/logs":
+		header := make([]byte, 8)
+		header[0] = 1
+		binary.BigEndian.PutUint32(header[4:], uint32(len(d.logs)))
+		w.Write(append(header, d.logs...))
+	case r.Method == "DELETE" && r.URL.Path == "/containers/f1":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (d *fakeDaemon) lastRequest() string {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.requests[len(d.requests)-1]
+}
+
+func TestStartFixture(t *testing.T) {
+	daemon := newFakeDaemon()
+	defer daemon.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	inspect := fmt.Sprintf(`{"Id":"f1","State":{"Running":true},"NetworkSettings":{"Ports":{"5432/tcp":[{"HostIP":"0.0.0.0","HostPort":%q}]}}}`, port)
+	daemon.set(inspect, "starting\n")
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		daemon.set(inspect, "starting\nready to accept connections\n")
+	}()
+	client, err := docker.NewClient(daemon.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := StartFixture(client, "postgres:9.4", Options{
+		Ports:       []docker.Port{"5432/tcp"},
+		WaitForPort: "5432/tcp",
+		WaitForLog:  regexp.MustCompile("ready to accept connections"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(daemon.name, "fixture-") {
+		t.Errorf("StartFixture: wrong name. Got %q.", daemon.name)
+	}
+	address, err := f.Address("5432/tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != l.Addr().String() {
+		t.Errorf("Fixture.Address: wrong address. Want %q. Got %q.", l.Addr().String(), address)
+	}
+	if _, err := f.Address("6379/tcp"); err != ErrPortNotPublished {
+		t.Errorf("Fixture.Address: wrong error. Want %#v. Got %#v.", ErrPortNotPublished, err)
+	}
+	if err := f.Terminate(); err != nil {
+		t.Fatal(err)
+	}
+	if last := daemon.lastRequest(); last != "DELETE /containers/f1" {
+		t.Errorf("Fixture.Terminate: wrong request. Got %q.", last)
+	}
+}
+
+func TestStartFixtureExited(t *testing.T) {
+	daemon := newFakeDaemon()
+	defer daemon.Close()
+	daemon.set(`{"Id":"f1","State":{"Running":false,"ExitCode":3}}`, "")
+	client, err := docker.NewClient(daemon.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = StartFixture(client, "postgres:9.4", Options{})
+	if err == nil || err.Error() != "fixture exited with code 3" {
+		t.Errorf("StartFixture: wrong error. Got %v.", err)
+	}
+	if last := daemon.lastRequest(); last != "DELETE /containers/f1" {
+		t.Errorf("StartFixture: the container should be removed. Last request: %q.", last)
+	}
+}
+
+func TestStartFixtureUnhealthy(t *testing.T) {
+	daemon := newFakeDaemon()
+	defer daemon.Close()
+	daemon.set(`{"Id":"f1","State":{"Running":true,"Health":{"Status":"unhealthy","FailingStreak":3}}}`, "")
+	client, err := docker.NewClient(daemon.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = StartFixture(client, "postgres:9.4", Options{WaitForHealthy: true})
+	if err != ErrUnhealthy {
+		t.Errorf("StartFixture: wrong error. Want %#v. Got %#v.", ErrUnhealthy, err)
+	}
+}
+
+func TestStartFixtureTimeout(t *testing.T) {
+	daemon := newFakeDaemon()
+	defer daemon.Close()
+	daemon.set(`{"Id":"f1","State":{"Running":true,"Health":{"Status":"starting"}}}`, "")
+	client, err := docker.NewClient(daemon.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = StartFixture(client, "postgres:9.4", Options{WaitForHealthy: true, Timeout: 200 * time.Millisecond})
+	if err != ErrNotReady {
+		t.Errorf("StartFixture: wrong error. Want %#v. Got %#v.", ErrNotReady, err)
+	}
+}
@@ -0,0 +1,339 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fixtures
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/reverb/go-dockerclient"
+)
+
+// WaitStrategy is a condition for a fixture to be ready.
+type WaitStrategy interface {
+	// Ready checks the condition once, on the container as last inspected
+	// in f.Container. It returns an error when the condition can't be met
+	// anymore.
+	Ready(f *Fixture) (bool, error)
+}
+
+// WaitFunc is a function used as a WaitStrategy.
+type WaitFunc func(f *Fixture) (bool, error)
+
+// Ready calls fn(f).
+func (fn WaitFunc) Ready(f *Fixture) (bool, error) {
+	return fn(f)
+}
+
+// statefulStrategy is implemented by the strategies that keep state during
+// a wait, like a deadline or a stream of logs. Fixture.Wait calls begin
+// before the first check, and uses the returned strategy for this wait only,
+// calling end once the wait is over. This way, the same strategy can be
+// used for several waits, even concurrently.
+type statefulStrategy interface {
+	begin(f *Fixture) (strategy WaitStrategy, end func())
+}
+
+// beginWait begins a wait with the given strategy.
+func beginWait(strategy WaitStrategy, f *Fixture) (WaitStrategy, func()) {
+	if s, ok := strategy.(statefulStrategy); ok {
+		return s.begin(f)
+	}
+	return strategy, func() {}
+}
+
+type allStrategy []WaitStrategy
+
+// WaitForAll returns a strategy that is ready once all of the given
+// strategies are ready. It's always ready when there are no strategies.
+func WaitForAll(strategies ...WaitStrategy) WaitStrategy {
+	return allStrategy(strategies)
+}
+
+func (all allStrategy) Ready(f *Fixture) (bool, error) {
+	for _, strategy := range all {
+		if ready, err := strategy.Ready(f); !ready || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (all allStrategy) begin(f *Fixture) (WaitStrategy, func()) {
+	begun := make(allStrategy, len(all))
+	ends := make([]func(), len(all))
+	for i, strategy := range all {
+		begun[i], ends[i] = beginWait(strategy, f)
+	}
+	return begun, func() {
+		for _, end := range ends {
+			end()
+		}
+	}
+}
+
+type timeoutStrategy struct {
+	strategy WaitStrategy
+	timeout  time.Duration
+}
+
+// WithTimeout returns a strategy that fails with ErrNotReady when the given
+// strategy is not ready within the timeout, starting with each Fixture.Wait.
+// Like the timeout of Fixture.Wait, but for a single strategy among others.
+// When checked outside of Fixture.Wait, it never times out.
+func WithTimeout(strategy WaitStrategy, timeout time.Duration) WaitStrategy {
+	return timeoutStrategy{strategy: strategy, timeout: timeout}
+}
+
+func (s timeoutStrategy) Ready(f *Fixture) (bool, error) {
+	return s.strategy.Ready(f)
+}
+
+func (s timeoutStrategy) begin(f *Fixture) (WaitStrategy, func()) {
+	strategy, end := beginWait(s.strategy, f)
+	deadline := time.Now().Add(s.timeout)
+	return WaitFunc(func(f *Fixture) (bool, error) {
+		ready, err := strategy.Ready(f)
+		if !ready && err == nil && time.Now().After(deadline) {
+			return false, ErrNotReady
+		}
+		return ready, err
+	}), end
+}
+
+var waitForRunning = WaitFunc(func(f *Fixture) (bool, error) {
+	return f.Container.State.Running, nil
+})
+
+type logLineStrategy struct {
+	re *regexp.Regexp
+}
+
+// WaitForLogLine returns a strategy that is ready once a line of the logs of
+// the container, on stdout or stderr, matches the given expression. During
+// Fixture.Wait, the logs are followed as they're written, rather than
+// downloaded at each check.
+func WaitForLogLine(re *regexp.Regexp) WaitStrategy {
+	return logLineStrategy{re: re}
+}
+
+func logsOptions(f *Fixture, watch *logWatch, follow bool) docker.LogsOptions {
+	return docker.LogsOptions{
+		Container:    f.Container.ID,
+		OutputStream: watch.writer(),
+		ErrorStream:  watch.writer(),
+		Stdout:       true,
+		Stderr:       true,
+		Follow:       follow,
+		RawTerminal:  f.Container.Config != nil && f.Container.Config.Tty,
+	}
+}
+
+// Ready downloads the logs written so far, and looks for a matching line.
+func (s logLineStrategy) Ready(f *Fixture) (bool, error) {
+	watch := &logWatch{re: s.re}
+	err := f.Client.Logs(logsOptions(f, watch, false))
+	watch.finish(err)
+	return watch.ready()
+}
+
+func (s logLineStrategy) begin(f *Fixture) (WaitStrategy, func()) {
+	watch := &logWatch{re: s.re}
+	opts := logsOptions(f, watch, true)
+	client := f.Client
+	follow := func() {
+		watch.finish(client.Logs(opts))
+	}
+	go follow()
+	return WaitFunc(func(*Fixture) (bool, error) {
+		ready, err := watch.ready()
+		if !ready && err == nil && watch.restart() {
+			// the stream ended while the container may still be
+			// running, as when the daemon restarts
+			go follow()
+		}
+		return ready, err
+	}), watch.stop
+}
+
+// errWaitOver stops the stream of logs once the wait is over.
+var errWaitOver = errors.New("wait over")
+
+// logWatch looks for a line of logs matching re, in the logs written to its
+// writers.
+type logWatch struct {
+	re *regexp.Regexp
+
+	mut     sync.Mutex
+	writers []*logLineWriter
+	matched bool
+	ended   bool
+	stopped bool
+	err     error
+}
+
+// writer returns a writer for one of the streams of the logs.
+func (w *logWatch) writer() io.Writer {
+	lw := &logLineWriter{watch: w}
+	w.writers = append(w.writers, lw)
+	return lw
+}
+
+func (w *logWatch) match(line []byte) {
+	if w.re.Match(line) {
+		w.matched = true
+	}
+}
+
+// finish records the end of the stream of logs.
+func (w *logWatch) finish(err error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	for _, lw := range w.writers {
+		// the last line may not end with a newline
+		if len(lw.line) > 0 {
+			w.match(lw.line)
+			lw.line = nil
+		}
+	}
+	w.ended = true
+	if err != errWaitOver {
+		w.err = err
+	}
+}
+
+func (w *logWatch) ready() (bool, error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	if w.matched {
+		return true, nil
+	}
+	return false, w.err
+}
+
+// restart reports whether the stream ended without error and should be
+// followed again, from the start of the logs.
+func (w *logWatch) restart() bool {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	if !w.ended || w.stopped || w.err != nil {
+		return false
+	}
+	w.ended = false
+	return true
+}
+
+// stop makes the stream of logs end with its next write.
+func (w *logWatch) stop() {
+	w.mut.Lock()
+	w.stopped = true
+	w.mut.Unlock()
+}
+
+// logLineWriter splits the logs written to it in lines, of any length.
+type logLineWriter struct {
+	watch *logWatch
+	line  []byte
+}
+
+func (lw *logLineWriter) Write(p []byte) (int, error) {
+	w := lw.watch
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	if w.stopped || w.matched {
+		return 0, errWaitOver
+	}
+	n := len(p)
+	for len(p) > 0 && !w.matched {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			lw.line = append(lw.line, p...)
+			break
+		}
+		lw.line = append(lw.line, p[:i]...)
+		w.match(lw.line)
+		lw.line = lw.line[:0]
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// WaitForPort returns a strategy that is ready once the host port where the
+// given port of the container is published accepts connections.
+func WaitForPort(port docker.Port) WaitStrategy {
+	return WaitFunc(func(f *Fixture) (bool, error) {
+		address, err := f.Address(port)
+		if err == ErrPortNotPublished {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err != nil {
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	})
+}
+
+// WaitForHTTP returns a strategy that is ready once a GET request to the
+// given path, on the host port where the given port of the container is
+// published, answers with the given status.
+func WaitForHTTP(port docker.Port, path string, status int) WaitStrategy {
+	client := &http.Client{Timeout: time.Second}
+	return WaitFunc(func(f *Fixture) (bool, error) {
+		address, err := f.Address(port)
+		if err == ErrPortNotPublished {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		resp, err := client.Get("http://" + address + path)
+		if err != nil {
+			return false, nil
+		}
+		resp.Body.Close()
+		return resp.StatusCode == status, nil
+	})
+}
+
+// WaitForHealthy returns a strategy that is ready once the healthcheck of
+// the container passes. It fails with ErrUnhealthy when the healthcheck
+// fails.
+func WaitForHealthy() WaitStrategy {
+	return WaitFunc(func(f *Fixture) (bool, error) {
+		health := f.Container.State.Health
+		if health != nil && health.Status == "unhealthy" {
+			return false, ErrUnhealthy
+		}
+		return health != nil && health.Status == "healthy", nil
+	})
+}
+
+// WaitForExit returns a strategy that is ready once the container exits
+// with the code 0, for one-shot fixtures like database migrations. It fails
+// when the container exits with another code.
+func WaitForExit() WaitStrategy {
+	return WaitFunc(func(f *Fixture) (bool, error) {
+		state := f.Container.State
+		if state.Running || state.FinishedAt.IsZero() {
+			return false, nil
+		}
+		if state.ExitCode != 0 {
+			return false, fmt.Errorf("fixture exited with code %d", state.ExitCode)
+		}
+		return true, nil
+	})
+}
@@ -0,0 +1,155 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fixtures
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reverb/go-dockerclient"
+)
+
+func newTestFixture(t *testing.T, daemon *fakeDaemon) *Fixture {
+	client, err := docker.NewClient(daemon.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Fixture{Client: client, Container: &docker.Container{ID: "f1"}, Host: "127.0.0.1"}
+}
+
+func TestWaitForHTTP(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer app.Close()
+	_, port, _ := net.SplitHostPort(app.Listener.Addr().String())
+	f := &Fixture{Host: "127.0.0.1", Container: &docker.Container{
+		NetworkSettings: &docker.NetworkSettings{Ports: map[docker.Port][]docker.PortBinding{"8080/tcp": {{HostPort: port}}}},
+	}}
+	strategy := WaitForHTTP("8080/tcp", "/health", http.StatusOK)
+	if ready, err := strategy.Ready(f); ready || err != nil {
+		t.Errorf("WaitForHTTP: wrong result. Want false, <nil>. Got %v, %v.", ready, err)
+	}
+	status = http.StatusOK
+	if ready, err := strategy.Ready(f); !ready || err != nil {
+		t.Errorf("WaitForHTTP: wrong result. Want true, <nil>. Got %v, %v.", ready, err)
+	}
+}
+
+func TestWaitForLogLine(t *testing.T) {
+	daemon := newFakeDaemon()
+	defer daemon.Close()
+	daemon.set("", "starting\nlistening on :8080\n")
+	f := newTestFixture(t, daemon)
+	if ready, err := WaitForLogLine(regexp.MustCompile(`^listening on :\d+$`)).Ready(f); !ready || err != nil {
+		t.Errorf("WaitForLogLine: wrong result. Want true, <nil>. Got %v, %v.", ready, err)
+	}
+	if ready, err := WaitForLogLine(regexp.MustCompile(`^starting.listening`)).Ready(f); ready || err != nil {
+		t.Errorf("WaitForLogLine: matched across lines. Got %v, %v.", ready, err)
+	}
+}
+
+func TestWaitForLogLineFollow(t *testing.T) {
+	daemon := newFakeDaemon()
+	defer daemon.Close()
+	long := strings.Repeat("x", 100*1024) + "end"
+	daemon.set(`{"Id":"f1","State":{"Running":true}}`, "starting\n"+long+"\nlistening on :8080")
+	f := newTestFixture(t, daemon)
+	err := f.Wait(WaitForLogLine(regexp.MustCompile(`^listening on :\d+$`)), time.Second, nil)
+	if err != nil {
+		t.Errorf("WaitForLogLine: unexpected error: %s", err)
+	}
+	err = f.Wait(WaitForLogLine(regexp.MustCompile(`^x+end$`)), time.Second, nil)
+	if err != nil {
+		t.Errorf("WaitForLogLine: unexpected error on a long line: %s", err)
+	}
+}
+
+func TestWaitForExit(t *testing.T) {
+	var tests = []struct {
+		inspect string
+		ready   bool
+		err     error
+	}{
+		{`{"Id":"f1","State":{"Running":true}}`, false, nil},
+		{`{"Id":"f1","State":{"ExitCode":0,"FinishedAt":"2015-04-23T10:00:00Z"}}`, true, nil},
+		{`{"Id":"f1","State":{"ExitCode":2,"FinishedAt":"2015-04-23T10:00:00Z"}}`, false, fmt.Errorf("fixture exited with code 2")},
+	}
+	daemon := newFakeDaemon()
+	defer daemon.Close()
+	for _, tt := range tests {
+		daemon.set(tt.inspect, "")
+		f := newTestFixture(t, daemon)
+		if err := f.Wait(WaitForExit(), 0, nil); !tt.ready && tt.err == nil {
+			if err != ErrNotReady {
+				t.Errorf("WaitForExit(%s): wrong error. Want %#v. Got %#v.", tt.inspect, ErrNotReady, err)
+			}
+		} else if tt.ready && err != nil {
+			t.Errorf("WaitForExit(%s): unexpected error: %s", tt.inspect, err)
+		} else if tt.err != nil && (err == nil || err.Error() != tt.err.Error()) {
+			t.Errorf("WaitForExit(%s): wrong error. Want %v. Got %v.", tt.inspect, tt.err, err)
+		}
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	never := WaitFunc(func(*Fixture) (bool, error) { return false, nil })
+	strategy := WithTimeout(never, 50*time.Millisecond)
+	first, end := beginWait(strategy, nil)
+	defer end()
+	if _, err := first.Ready(nil); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := first.Ready(nil); err != ErrNotReady {
+		t.Errorf("WithTimeout: wrong error. Want %#v. Got %#v.", ErrNotReady, err)
+	}
+	second, end := beginWait(strategy, nil)
+	defer end()
+	if _, err := second.Ready(nil); err != nil {
+		t.Errorf("WithTimeout: the deadline should start with each wait. Got %#v.", err)
+	}
+	if _, err := strategy.Ready(nil); err != nil {
+		t.Errorf("WithTimeout: should not time out outside of a wait. Got %#v.", err)
+	}
+}
+
+func TestWaitForAll(t *testing.T) {
+	ready := WaitFunc(func(*Fixture) (bool, error) { return true, nil })
+	notReady := WaitFunc(func(*Fixture) (bool, error) { return false, nil })
+	if ok, _ := WaitForAll().Ready(nil); !ok {
+		t.Error("WaitForAll: should be ready without strategies")
+	}
+	if ok, _ := WaitForAll(ready, ready).Ready(nil); !ok {
+		t.Error("WaitForAll: should be ready")
+	}
+	if ok, _ := WaitForAll(ready, notReady).Ready(nil); ok {
+		t.Error("WaitForAll: should not be ready")
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	daemon := newFakeDaemon()
+	defer daemon.Close()
+	daemon.set(`{"Id":"f1","State":{"Running":true}}`, "")
+	f := newTestFixture(t, daemon)
+	cancel := make(chan struct{})
+	close(cancel)
+	never := WaitFunc(func(*Fixture) (bool, error) { return false, nil })
+	if err := f.Wait(never, time.Minute, cancel); err != ErrCanceled {
+		t.Errorf("Fixture.Wait: wrong error. Want %#v. Got %#v.", ErrCanceled, err)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultGCInterval = time.Minute
+
+// ErrGCRunning is returned by GC.Start when the collector is already running.
+var ErrGCRunning = errors.New("garbage collector already running")
+
+// GCOptions is the set of policies of a GC.
+type GCOptions struct {
+	// Labels restricts the collection to the resources with the given
+	// labels, in the format of the label filter: "key" or "key=value".
+	// Every label must match.
+	Labels []string
+
+	// MinAge keeps the containers that exited, and the images that were
+	// created, less than MinAge ago.
+	MinAge time.Duration
+
+	// KeepFailed keeps the containers that exited with a non-zero code,
+	// for later inspection.
+	KeepFailed bool
+
+	// Images enables the removal of dangling images, the untagged images
+	// that are not the parent of any other image.
+	Images bool
+
+	// Volumes enables the removal of dangling volumes, the volumes not
+	// used by any container. It requires Docker API 1.21 or newer.
+	Volumes bool
+
+	// Interval is the time between two collections, when the collector is
+	// started. It defaults to one minute.
+	Interval time.Duration
+
+	// OnCollect, when set, is called after each periodic collection with
+	// its result.
+	OnCollect func(*GCResult, error)
+}
+
+// GCResult lists the resources removed by a collection.
+type GCResult struct {
+	Containers []string
+	Images     []string
+	Volumes    []string
+}
+
+// GC is a garbage collector that removes exited containers and, optionally,
+// dangling images and volumes, according to the policies in GCOptions. It
+// may run a single collection, with Collect, or run periodically, with
+// Start.
+type GC struct {
+	client *Client
+	opts   GCOptions
+	now    func() time.Time
+
+	mut  sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewGC returns a garbage collector that uses the given client.
+func NewGC(client *Client, opts GCOptions) *GC {
+	return &GC{client: client, opts: opts, now: time.Now}
+}
+
+// Collect runs a single collection. Resources removed by someone else or
+// still in use when they're removed are skipped. When some of them can't be
+// removed, the error is a *MultiError, and the result lists the resources
+// that were removed.
+func (gc *GC) Collect() (*GCResult, error) {
+	var result GCResult
+	errs := make(map[string]error)
+	var err error
+	if result.Containers, err = gc.collectContainers(errs); err != nil {
+		return nil, err
+	}
+	if gc.opts.Images {
+		if result.Images, err = gc.collectImages(errs); err != nil {
+			return &result, err
+		}
+	}
+	if gc.opts.Volumes {
+		if result.Volumes, err = gc.collectVolumes(errs); err != nil {
+			return &result, err
+		}
+	}
+	if len(errs) > 0 {
+		return &result, &MultiError{Errors: errs}
+	}
+	return &result, nil
+}
+
+func (gc *GC) filters(extra map[string][]string) map[string][]string {
+	if len(gc.opts.Labels) > 0 {
+		extra["label"] = gc.opts.Labels
+	}
+	return extra
+}
+
+func (gc *GC) collectContainers(errs map[string]error) ([]string, error) {
+	containers, err := gc.client.ListContainers(ListContainersOptions{
+		All:     true,
+		Filters: gc.filters(map[string][]string{"status": {"exited", "dead"}}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, summary := range containers {
+		container, err := gc.client.InspectContainer(summary.ID)
+		if err != nil {
+			if _, ok := err.(*NoSuchContainer); !ok {
+				errs[summary.ID] = err
+			}
+			continue
+		}
+		if container.State.Running || gc.now().Sub(container.State.FinishedAt) < gc.opts.MinAge {
+			continue
+		}
+		if gc.opts.KeepFailed && container.State.ExitCode != 0 {
+			continue
+		}
+		err = gc.client.RemoveContainer(RemoveContainerOptions{ID: summary.ID})
+		if _, ok := err.(*NoSuchContainer); ok {
+			continue
+		}
+		if err != nil {
+			errs[summary.ID] = err
+			continue
+		}
+		removed = append(removed, summary.ID)
+	}
+	return removed, nil
+}
+
+func (gc *GC) collectImages(errs map[string]error) ([]string, error) {
+	images, err := gc.client.ListImages(ListImagesOptions{
+		Filters: gc.filters(map[string][]string{"dangling": {"true"}}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, image := range images {
+		if gc.now().Sub(time.Unix(image.Created, 0)) < gc.opts.MinAge {
+			continue
+		}
+		err := gc.client.RemoveImage(image.ID)
+		if err == ErrNoSuchImage {
+			continue
+		}
+		if e, ok := err.(*Error); ok && e.Status == http.StatusConflict {
+			// used by a container
+			continue
+		}
+		if err != nil {
+			errs[image.ID] = err
+			continue
+		}
+		removed = append(removed, image.ID)
+	}
+	return removed, nil
+}
+
+func (gc *GC) collectVolumes(errs map[string]error) ([]string, error) {
+	volumes, err := gc.client.ListVolumes(ListVolumesOptions{
+		Filters: gc.filters(map[string][]string{"dangling": {"true"}}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, volume := range volumes {
+		err := gc.client.RemoveVolume(volume.Name)
+		if err == ErrNoSuchVolume || err == ErrVolumeInUse {
+			continue
+		}
+		if err != nil {
+			errs[volume.Name] = err
+			continue
+		}
+		removed = append(removed, volume.Name)
+	}
+	return removed, nil
+}
+
+// Start runs collections periodically, in a separate goroutine, until Stop
+// is called. The first collection runs right away.
+func (gc *GC) Start() error {
+	gc.mut.Lock()
+	defer gc.mut.Unlock()
+	if gc.stop != nil {
+		return ErrGCRunning
+	}
+	interval := gc.opts.Interval
+	if interval <= 0 {
+		interval = defaultGCInterval
+	}
+	gc.stop = make(chan struct{})
+	gc.done = make(chan struct{})
+	go gc.run(interval, gc.stop, gc.done)
+	return nil
+}
+
+func (gc *GC) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := gc.Collect()
+		if gc.opts.OnCollect != nil {
+			gc.opts.OnCollect(result, err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops the periodic collections, waiting for the running one, if any,
+// to finish.
+func (gc *GC) Stop() {
+	gc.mut.Lock()
+	stop, done := gc.stop, gc.done
+	gc.stop, gc.done = nil, nil
+	gc.mut.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
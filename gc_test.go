@@ -0,0 +1,162 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var gcNow = time.Date(2015, 4, 23, 12, 0, 0, 0, time.UTC)
+
+// gcServer simulates a daemon with a few exited containers, dangling images
+// and dangling volumes, recording the removals and the list queries.
+func gcServer() (*httptest.Server, func() []string, map[string]string) {
+	var mut sync.Mutex
+	var removals []string
+	queries := make(map[string]string)
+	inspects := map[string]string{
+		"old":    `{"Id":"old","State":{"ExitCode":0,"FinishedAt":"2015-04-23T10:00:00Z"}}`,
+		"failed": `{"Id":"failed","State":{"ExitCode":1,"FinishedAt":"2015-04-23T10:00:00Z"}}`,
+		"recent": `{"Id":"recent","State":{"ExitCode":0,"FinishedAt":"2015-04-23T11:59:00Z"}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/containers/json":
+			queries["containers"] = r.URL.Query().Get("filters")
+			w.Write([]byte(`[{"Id":"old"},{"Id":"failed"},{"Id":"recent"},{"Id":"gone"}]`))
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/containers/"):
+			id := strings.Split(r.URL.Path, "/")[2]
+			inspect, ok := inspects[id]
+			if !ok {
+				http.Error(w, "no such container", http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(inspect))
+		case r.Method == "GET" && r.URL.Path == "/images/json":
+			queries["images"] = r.URL.Query().Get("filters")
+			w.Write([]byte(`[{"Id":"img1","Created":1429776000},{"Id":"img2","Created":1429776000},{"Id":"img3","Created":1429790400}]`))
+		case r.Method == "GET" && r.URL.Path == "/volumes":
+			queries["volumes"] = r.URL.Query().Get("filters")
+			w.Write([]byte(`{"Volumes":[{"Name":"vol1"},{"Name":"vol2"}]}`))
+		case r.Method == "DELETE":
+			if r.URL.Path == "/images/img2" || r.URL.Path == "/volumes/vol2" {
+				http.Error(w, "in use", http.StatusConflict)
+				return
+			}
+			removals = append(removals, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	return server, func() []string {
+		mut.Lock()
+		defer mut.Unlock()
+		return append([]string(nil), removals...)
+	}, queries
+}
+
+func TestGCCollect(t *testing.T) {
+	server, removals, queries := gcServer()
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gc := NewGC(client, GCOptions{
+		Labels:     []string{"ci=true"},
+		MinAge:     time.Hour,
+		KeepFailed: true,
+		Images:     true,
+		Volumes:    true,
+	})
+	gc.now = func() time.Time { return gcNow }
+	result, err := gc.Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &GCResult{Containers: []string{"old"}, Images: []string{"img1"}, Volumes: []string{"vol1"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("GC.Collect: wrong result. Want %#v. Got %#v.", expected, result)
+	}
+	expectedRemovals := []string{"/containers/old", "/images/img1", "/volumes/vol1"}
+	if got := removals(); !reflect.DeepEqual(got, expectedRemovals) {
+		t.Errorf("GC.Collect: wrong removals. Want %#v. Got %#v.", expectedRemovals, got)
+	}
+	expectedQueries := map[string]string{
+		"containers": `{"label":["ci=true"],"status":["exited","dead"]}`,
+		"images":     `{"dangling":["true"],"label":["ci=true"]}`,
+		"volumes":    `{"dangling":["true"],"label":["ci=true"]}`,
+	}
+	if !reflect.DeepEqual(queries, expectedQueries) {
+		t.Errorf("GC.Collect: wrong filters. Want %#v. Got %#v.", expectedQueries, queries)
+	}
+}
+
+func TestGCCollectContainersOnly(t *testing.T) {
+	server, removals, _ := gcServer()
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gc := NewGC(client, GCOptions{})
+	gc.now = func() time.Time { return gcNow }
+	result, err := gc.Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &GCResult{Containers: []string{"old", "failed", "recent"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("GC.Collect: wrong result. Want %#v. Got %#v.", expected, result)
+	}
+	if got := removals(); len(got) != 3 {
+		t.Errorf("GC.Collect: wrong removals. Got %#v.", got)
+	}
+}
+
+func TestGCStartStop(t *testing.T) {
+	server, _, _ := gcServer()
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collected := make(chan *GCResult, 10)
+	gc := NewGC(client, GCOptions{
+		Interval:  time.Millisecond,
+		OnCollect: func(result *GCResult, err error) { collected <- result },
+	})
+	if err := gc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.Start(); err != ErrGCRunning {
+		t.Errorf("GC.Start: wrong error. Want %#v. Got %#v.", ErrGCRunning, err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-collected:
+		case <-time.After(5 * time.Second):
+			t.Fatal("GC: timed out waiting for a collection")
+		}
+	}
+	gc.Stop()
+	for len(collected) > 0 {
+		<-collected
+	}
+	time.Sleep(10 * time.Millisecond)
+	if len(collected) > 0 {
+		t.Error("GC.Stop: collections should stop")
+	}
+	gc.Stop()
+}
@@ -5,6 +5,7 @@
 package docker
 
 import (
+	"archive/tar"
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
@@ -15,6 +16,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -22,10 +24,33 @@ import (
 type APIImages struct {
 	ID          string   `json:"Id" yaml:"Id"`
 	RepoTags    []string `json:"RepoTags,omitempty" yaml:"RepoTags,omitempty"`
+	RepoDigests []string `json:"RepoDigests,omitempty" yaml:"RepoDigests,omitempty"`
 	Created     int64    `json:"Created,omitempty" yaml:"Created,omitempty"`
 	Size        int64    `json:"Size,omitempty" yaml:"Size,omitempty"`
 	VirtualSize int64    `json:"VirtualSize,omitempty" yaml:"VirtualSize,omitempty"`
 	ParentID    string   `json:"ParentId,omitempty" yaml:"ParentId,omitempty"`
+
+	Labels map[string]string `json:"Labels,omitempty" yaml:"Labels,omitempty"`
+
+	// Descriptor is the OCI descriptor of the image, sent by Docker API
+	// 1.48 and newer when the daemon uses the containerd image store.
+	Descriptor *Descriptor `json:"Descriptor,omitempty" yaml:"Descriptor,omitempty"`
+
+	// Manifests is only set by ListImages when requested, with
+	// ListImagesOptions.Manifests.
+	Manifests []ImageManifestSummary `json:"Manifests,omitempty" yaml:"Manifests,omitempty"`
+}
+
+// Platforms returns the platforms of the image available locally, from its
+// Manifests.
+func (image *APIImages) Platforms() []Platform {
+	var platforms []Platform
+	for _, manifest := range image.Manifests {
+		if manifest.Available && manifest.Kind == ManifestKindImage && manifest.ImageData != nil {
+			platforms = append(platforms, manifest.ImageData.Platform)
+		}
+	}
+	return platforms
 }
 
 // Image is the type representing a docker image and its various properties
@@ -41,6 +66,60 @@ type Image struct {
 	Config          *Config   `json:"Config,omitempty" yaml:"Config,omitempty"`
 	Architecture    string    `json:"Architecture,omitempty" yaml:"Architecture,omitempty"`
 	Size            int64     `json:"Size,omitempty" yaml:"Size,omitempty"`
+	RepoDigests     []string  `json:"RepoDigests,omitempty" yaml:"RepoDigests,omitempty"`
+
+	// Descriptor is the OCI descriptor of the image, sent by Docker API
+	// 1.48 and newer when the daemon uses the containerd image store.
+	Descriptor *Descriptor `json:"Descriptor,omitempty" yaml:"Descriptor,omitempty"`
+
+	// Manifests is only set by InspectImageWithOptions, when requested.
+	Manifests []ImageManifestSummary `json:"Manifests,omitempty" yaml:"Manifests,omitempty"`
+}
+
+// ImageManifestSummary describes one of the manifests of a multi-platform
+// image, as known by the daemon.
+type ImageManifestSummary struct {
+	ID              string                    `json:"ID" yaml:"ID"`
+	Descriptor      Descriptor                `json:"Descriptor" yaml:"Descriptor"`
+	Available       bool                      `json:"Available,omitempty" yaml:"Available,omitempty"`
+	Size            ImageManifestSize         `json:"Size,omitempty" yaml:"Size,omitempty"`
+	Kind            string                    `json:"Kind,omitempty" yaml:"Kind,omitempty"`
+	ImageData       *ImageManifestData        `json:"ImageData,omitempty" yaml:"ImageData,omitempty"`
+	AttestationData *ImageManifestAttestation `json:"AttestationData,omitempty" yaml:"AttestationData,omitempty"`
+}
+
+// Kinds of the manifests of an ImageManifestSummary.
+const (
+	ManifestKindImage       = "image"
+	ManifestKindAttestation = "attestation"
+	ManifestKindUnknown     = "unknown"
+)
+
+// ImageManifestSize is the size of a manifest, in bytes: the size of its
+// content in the store, and the total size, including the unpacked layers.
+type ImageManifestSize struct {
+	Total   int64 `json:"Total,omitempty" yaml:"Total,omitempty"`
+	Content int64 `json:"Content,omitempty" yaml:"Content,omitempty"`
+}
+
+// ImageManifestData holds the details of an image manifest, for manifests
+// of the "image" kind.
+type ImageManifestData struct {
+	Platform   Platform      `json:"Platform" yaml:"Platform"`
+	Containers []string      `json:"Containers,omitempty" yaml:"Containers,omitempty"`
+	Size       ImageDataSize `json:"Size,omitempty" yaml:"Size,omitempty"`
+}
+
+// ImageDataSize is the size of the unpacked layers of an image manifest, in
+// bytes.
+type ImageDataSize struct {
+	Unpacked int64 `json:"Unpacked,omitempty" yaml:"Unpacked,omitempty"`
+}
+
+// ImageManifestAttestation holds the details of an attestation manifest:
+// the digest of the image manifest it's attached to.
+type ImageManifestAttestation struct {
+	For string `json:"For" yaml:"For"`
 }
 
 // ImageHistory represent a layer in an image's history returned by the
@@ -75,6 +154,11 @@ type ImagePre012 struct {
 type ListImagesOptions struct {
 	All     bool
 	Filters map[string][]string
+
+	// Manifests includes the manifests of the images in the result, when
+	// the daemon uses the containerd image store. It requires Docker API
+	// 1.47 or newer.
+	Manifests bool `qs:"manifests"`
 }
 
 var (
@@ -92,23 +176,166 @@ var (
 	// ErrMultipleContexts is the error returned when both a ContextDir and
 	// InputStream are provided in BuildImageOptions
 	ErrMultipleContexts = errors.New("image build may not be provided BOTH context dir and input stream")
+
+	// ErrMustSpecifyNames is the error returned when the Names field on
+	// ExportImagesOptions is nil or empty
+	ErrMustSpecifyNames = errors.New("must specify at least one name to export")
+
+	// ErrNoImageConfig is the error returned by InspectImageWithOptions when
+	// the config of the image can't be found in its export.
+	ErrNoImageConfig = errors.New("image config not found in the exported image")
 )
 
+// maxImageConfigSize is the size of the largest file kept in memory while
+// looking for the config of an image in its export.
+const maxImageConfigSize = 1 << 20
+
 // ListImages returns the list of available images in the server.
 //
 // See http://goo.gl/2rOLFF for more details.
 func (c *Client) ListImages(opts ListImagesOptions) ([]APIImages, error) {
-	path := "/images/json?" + queryString(opts)
-	body, _, err := c.do("GET", path, nil, false)
+	images := []APIImages{}
+	err := c.ListImagesFunc(opts, func(image APIImages) error {
+		images = append(images, image)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// ListImagesFunc calls fn for each image matching the given criteria, as the
+// list is received, without holding it in memory. It stops at the first
+// error returned by fn, and returns it.
+func (c *Client) ListImagesFunc(opts ListImagesOptions, fn func(APIImages) error) error {
+	if opts.Manifests {
+		if err := c.requireAPIVersion("ListImagesOptions.Manifests", apiVersion147); err != nil {
+			return err
+		}
+	}
+	return c.listJSON("/images/json?"+queryString(opts), func(element []byte) error {
+		var image APIImages
+		if err := json.Unmarshal(element, &image); err != nil {
+			return err
+		}
+		return fn(image)
+	})
+}
+
+// ListDanglingImages returns the images that have no tag and are not the
+// parent of any other image.
+func (c *Client) ListDanglingImages() ([]APIImages, error) {
+	images, err := c.ListImages(ListImagesOptions{Filters: map[string][]string{"dangling": {"true"}}})
 	if err != nil {
 		return nil, err
 	}
-	var images []APIImages
-	err = json.Unmarshal(body, &images)
+	// older daemons ignore the dangling filter
+	var dangling []APIImages
+	for _, image := range images {
+		if isUntagged(image.RepoTags) {
+			dangling = append(dangling, image)
+		}
+	}
+	return dangling, nil
+}
+
+// Labels of the OCI image specification, describing an image. They are
+// usually set by the build, from the annotations of the same keys.
+const (
+	LabelImageSource   = "org.opencontainers.image.source"
+	LabelImageVersion  = "org.opencontainers.image.version"
+	LabelImageRevision = "org.opencontainers.image.revision"
+	LabelImageCreated  = "org.opencontainers.image.created"
+	LabelImageTitle    = "org.opencontainers.image.title"
+	LabelImageVendor   = "org.opencontainers.image.vendor"
+	LabelImageLicenses = "org.opencontainers.image.licenses"
+	LabelImageURL      = "org.opencontainers.image.url"
+	LabelImageBaseName = "org.opencontainers.image.base.name"
+)
+
+// ListImagesByLabel returns the local images having all of the given labels,
+// in the format of the label filter: "key" or "key=value".
+func (c *Client) ListImagesByLabel(labels ...string) ([]APIImages, error) {
+	images, err := c.ListImages(ListImagesOptions{Filters: map[string][]string{"label": labels}})
 	if err != nil {
 		return nil, err
 	}
-	return images, nil
+	// older daemons ignore the label filter
+	var matching []APIImages
+	for _, image := range images {
+		if hasLabels(image.Labels, labels) {
+			matching = append(matching, image)
+		}
+	}
+	return matching, nil
+}
+
+// IndexImagesByLabel returns the local images having the given label, indexed
+// by its value, like the images built from each source repository, to audit a
+// fleet of hosts.
+func (c *Client) IndexImagesByLabel(key string) (map[string][]APIImages, error) {
+	images, err := c.ListImagesByLabel(key)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string][]APIImages)
+	for _, image := range images {
+		value := image.Labels[key]
+		index[value] = append(index[value], image)
+	}
+	return index, nil
+}
+
+func hasLabels(imageLabels map[string]string, labels []string) bool {
+	for _, label := range labels {
+		key, value := label, ""
+		i := strings.Index(label, "=")
+		if i >= 0 {
+			key, value = label[:i], label[i+1:]
+		}
+		v, ok := imageLabels[key]
+		if !ok || (i >= 0 && v != value) {
+			return false
+		}
+	}
+	return true
+}
+
+func isUntagged(tags []string) bool {
+	for _, tag := range tags {
+		if tag != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
+
+// ListContainersByAncestor returns the containers created from the given
+// image, or from an image built on top of it. The image may be referenced by
+// name, ID, ID prefix or digest, as it's resolved to its ID first. Stopped
+// containers are included when all is true. It requires Docker API 1.21 or
+// newer.
+func (c *Client) ListContainersByAncestor(image string, all bool) ([]APIContainers, error) {
+	img, err := c.InspectImage(image)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListContainers(ListContainersOptions{
+		All:     all,
+		Filters: map[string][]string{"ancestor": {img.ID}},
+	})
+}
+
+// ImageInUse reports whether any container, running or not, uses the given
+// image or an image built on top of it, so that removing it would fail or
+// break the container.
+func (c *Client) ImageInUse(image string) (bool, error) {
+	containers, err := c.ListContainersByAncestor(image, true)
+	if err != nil {
+		return false, err
+	}
+	return len(containers) > 0, nil
 }
 
 // ImageHistory returns the history of the image by its name or ID.
@@ -174,11 +401,15 @@ func (c *Client) InspectImage(name string) (*Image, error) {
 	if err != nil {
 		return nil, err
 	}
+	return c.decodeImage(body)
+}
 
+func (c *Client) decodeImage(body []byte) (*Image, error) {
 	var image Image
+	var err error
 
 	// if the caller elected to skip checking the server's version, assume it's the latest
-	if c.SkipServerVersionCheck || c.expectedAPIVersion.GreaterThanOrEqualTo(apiVersion112) {
+	if c.SkipServerVersionCheck || c.getExpectedAPIVersion().GreaterThanOrEqualTo(apiVersion112) {
 		err = json.Unmarshal(body, &image)
 		if err != nil {
 			return nil, err
@@ -206,6 +437,130 @@ func (c *Client) InspectImage(name string) (*Image, error) {
 	return &image, nil
 }
 
+// InspectImageOptions specify parameters to the InspectImageWithOptions
+// function.
+type InspectImageOptions struct {
+	// Manifests includes the manifests of the image in the result. It
+	// requires Docker API 1.48 or newer.
+	Manifests bool `qs:"manifests"`
+
+	// RawConfig fetches the raw config of the image, the document whose
+	// digest is the ID of the image. The config is read from an export of
+	// the image, which makes it as expensive as ExportImage.
+	RawConfig bool `qs:"-"`
+}
+
+// ImageInspect is an image along with the raw documents returned by
+// InspectImageWithOptions, for tools that sign or audit images.
+type ImageInspect struct {
+	Image
+
+	// Raw is the response of the daemon, as is, including the fields
+	// unknown to the client.
+	Raw json.RawMessage
+
+	// RawConfig is the raw config of the image, when requested.
+	RawConfig json.RawMessage
+}
+
+// InspectImageWithOptions returns an image by its name or ID, like
+// InspectImage, along with the raw response of the daemon and, optionally,
+// the manifests and the raw config of the image.
+func (c *Client) InspectImageWithOptions(name string, opts InspectImageOptions) (*ImageInspect, error) {
+	if opts.Manifests {
+		if err := c.requireAPIVersion("InspectImageOptions.Manifests", apiVersion148); err != nil {
+			return nil, err
+		}
+	}
+	path := "/images/" + name + "/json"
+	if opts.Manifests {
+		path += "?" + queryString(opts)
+	}
+	body, status, err := c.do("GET", path, nil, false)
+	if status == http.StatusNotFound {
+		return nil, ErrNoSuchImage
+	}
+	if err != nil {
+		return nil, err
+	}
+	image, err := c.decodeImage(body)
+	if err != nil {
+		return nil, err
+	}
+	inspect := ImageInspect{Image: *image, Raw: json.RawMessage(body)}
+	if opts.RawConfig {
+		if inspect.RawConfig, err = c.imageConfig(name); err != nil {
+			return nil, err
+		}
+	}
+	return &inspect, nil
+}
+
+// imageConfig exports the given image and returns its config, as listed in
+// the manifest.json file of the archive.
+func (c *Client) imageConfig(name string) (json.RawMessage, error) {
+	r, w := io.Pipe()
+	errs := make(chan error, 1)
+	go func() {
+		err := c.ExportImage(ExportImageOptions{Name: name, OutputStream: w})
+		w.CloseWithError(err)
+		errs <- err
+	}()
+	config, err := imageConfigFromArchive(r)
+	if err == nil {
+		// the archive may be followed by padding
+		_, err = io.Copy(ioutil.Discard, r)
+	}
+	r.CloseWithError(err)
+	if exportErr := <-errs; exportErr != nil && err == nil {
+		return nil, exportErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func imageConfigFromArchive(r io.Reader) (json.RawMessage, error) {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA || hdr.Size > maxImageConfigSize {
+			continue
+		}
+		if !strings.HasSuffix(hdr.Name, ".json") && !strings.HasPrefix(hdr.Name, "blobs/") {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = data
+	}
+	var manifest []struct{ Config string }
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil || len(manifest) == 0 {
+		return nil, ErrNoImageConfig
+	}
+	config, ok := files[manifest[0].Config]
+	if !ok {
+		return nil, ErrNoImageConfig
+	}
+	return json.RawMessage(config), nil
+}
+
+// ImageExists reports whether the given image exists. Unlike InspectImage, it
+// doesn't decode the image, and a missing image is not an error.
+func (c *Client) ImageExists(name string) (bool, error) {
+	return c.exists("/images/" + name + "/json")
+}
+
 // PushImageOptions represents options to use in the PushImage method.
 //
 // See http://goo.gl/pN8A3P for more details.
@@ -221,23 +576,42 @@ type PushImageOptions struct {
 
 	OutputStream  io.Writer `qs:"-"`
 	RawJSONStream bool      `qs:"-"`
+
+	// Messages, when set, receives the progress messages sent by the
+	// server. It must be consumed concurrently, and is closed when
+	// PushImage returns. It's ignored when RawJSONStream is set.
+	Messages chan<- *JSONMessage `qs:"-"`
 }
 
 // PushImage pushes an image to a remote registry, logging progress to w.
 //
 // An empty instance of AuthConfiguration may be used for unauthenticated
-// pushes.
+// pushes, or to use the credentials in the AuthConfigs of the client.
 //
 // See http://goo.gl/pN8A3P for more details.
 func (c *Client) PushImage(opts PushImageOptions, auth AuthConfiguration) error {
 	if opts.Name == "" {
 		return ErrNoSuchImage
 	}
+	if opts.Messages != nil {
+		defer close(opts.Messages)
+	}
 	name := opts.Name
 	opts.Name = ""
 	path := "/images/" + name + "/push?" + queryString(&opts)
-	headers := headersWithAuth(auth)
-	return c.stream("POST", path, true, opts.RawJSONStream, headers, nil, opts.OutputStream, nil)
+	auth, err := c.authFor(name, auth)
+	if err != nil {
+		return err
+	}
+	return c.withAuth(auth, func(auth AuthConfiguration) error {
+		return c.stream("POST", path, streamOptions{
+			setRawTerminal: true,
+			rawJSONStream:  opts.RawJSONStream,
+			headers:        headersWithAuth(auth),
+			stdout:         opts.OutputStream,
+			messages:       sendMessages(opts.Messages),
+		})
+	})
 }
 
 // PullImageOptions present the set of options available for pulling an image
@@ -250,23 +624,103 @@ type PullImageOptions struct {
 	Tag           string
 	OutputStream  io.Writer `qs:"-"`
 	RawJSONStream bool      `qs:"-"`
+
+	// Messages, when set, receives the progress messages sent by the
+	// server, whose ProgressDetail gives the completion of each layer. It
+	// must be consumed concurrently, and is closed when PullImage returns.
+	// It's ignored when RawJSONStream is set.
+	Messages chan<- *JSONMessage `qs:"-"`
+
+	// Trust, when set, resolves the tag to its signed digest, which is
+	// pulled, and then tagged with the tag, failing when the tag has no
+	// valid signature.
+	Trust TrustResolver `qs:"-"`
 }
 
 // PullImage pulls an image from a remote registry, logging progress to w.
 //
+// The repository may be pinned to a digest, as in busybox@sha256:4b82b6ae,
+// in which case Tag must be empty. When auth is empty, the credentials in
+// the AuthConfigs of the client are used, if any.
+//
 // See http://goo.gl/ACyYNS for more details.
 func (c *Client) PullImage(opts PullImageOptions, auth AuthConfiguration) error {
+	if opts.Messages != nil {
+		defer close(opts.Messages)
+	}
 	if opts.Repository == "" {
 		return ErrNoSuchImage
 	}
+	if opts.Tag == "" && strings.Contains(opts.Repository, "@") {
+		opts.Repository, opts.Tag = ParseRepositoryTag(opts.Repository)
+	}
+	var pinnedTag string
+	if opts.Trust != nil {
+		var err error
+		if pinnedTag, err = c.resolveTrusted(&opts); err != nil {
+			return err
+		}
+	}
+	if c.ImageVerifier != nil {
+		tag, err := c.verifyPull(&opts, auth)
+		if err != nil {
+			return err
+		}
+		if pinnedTag == "" {
+			pinnedTag = tag
+		}
+	}
 
-	headers := headersWithAuth(auth)
-	return c.createImage(queryString(&opts), headers, nil, opts.OutputStream, opts.RawJSONStream)
+	auth, err := c.authFor(opts.Repository, auth)
+	if err != nil {
+		return err
+	}
+	err = c.withAuth(auth, func(auth AuthConfiguration) error {
+		return c.createImage(queryString(&opts), headersWithAuth(auth), nil, opts.OutputStream, opts.RawJSONStream, sendMessages(opts.Messages))
+	})
+	if err != nil || pinnedTag == "" {
+		return err
+	}
+	return c.TagImage(opts.Repository+"@"+opts.Tag, TagImageOptions{Repo: opts.Repository, Tag: pinnedTag, Force: true})
 }
 
-func (c *Client) createImage(qs string, headers map[string]string, in io.Reader, w io.Writer, rawJSONStream bool) error {
+// PullImageIfMissing pulls the given image, like busybox:1.36, unless it's
+// available locally already. An image without a tag is pulled with the
+// latest tag, rather than with all the tags of the repository.
+func (c *Client) PullImageIfMissing(image string, auth AuthConfiguration) error {
+	_, err := c.InspectImage(image)
+	if err != ErrNoSuchImage {
+		return err
+	}
+	repository, tag := ParseRepositoryTag(image)
+	if tag == "" {
+		tag = DefaultTag
+	}
+	return c.PullImage(PullImageOptions{Repository: repository, Tag: tag}, auth)
+}
+
+func (c *Client) createImage(qs string, headers map[string]string, in io.Reader, w io.Writer, rawJSONStream bool, messages func(*JSONMessage) error) error {
 	path := "/images/create?" + qs
-	return c.stream("POST", path, true, rawJSONStream, headers, in, w, nil)
+	return c.stream("POST", path, streamOptions{
+		setRawTerminal: true,
+		rawJSONStream:  rawJSONStream,
+		headers:        headers,
+		in:             in,
+		stdout:         w,
+		messages:       messages,
+	})
+}
+
+// sendMessages returns a function sending the messages of a JSON stream to
+// the given channel, or nil when the channel is nil.
+func sendMessages(ch chan<- *JSONMessage) func(*JSONMessage) error {
+	if ch == nil {
+		return nil
+	}
+	return func(m *JSONMessage) error {
+		ch <- m
+		return nil
+	}
 }
 
 // LoadImageOptions represents the options for LoadImage Docker API Call
@@ -274,13 +728,47 @@ func (c *Client) createImage(qs string, headers map[string]string, in io.Reader,
 // See http://goo.gl/Y8NNCq for more details.
 type LoadImageOptions struct {
 	InputStream io.Reader
+
+	// OutputStream, when set, receives the output of the load.
+	OutputStream io.Writer
+
+	// Messages, when set, receives the progress and result messages sent
+	// by the server, which include the names of the loaded images (see
+	// JSONMessage.LoadedImage). It must be consumed concurrently, and is
+	// closed when LoadImage returns. Older versions of the API don't send
+	// any message.
+	Messages chan<- *JSONMessage
+
+	// BandwidthLimit, if positive, caps the rate of the upload of
+	// InputStream, in bytes per second.
+	BandwidthLimit int64
 }
 
 // LoadImage imports a tarball docker image
 //
 // See http://goo.gl/Y8NNCq for more details.
 func (c *Client) LoadImage(opts LoadImageOptions) error {
-	return c.stream("POST", "/images/load", true, false, nil, opts.InputStream, nil, nil)
+	if opts.Messages != nil {
+		defer close(opts.Messages)
+	}
+	return c.stream("POST", "/images/load", streamOptions{
+		setRawTerminal: true,
+		in:             newThrottledReader(opts.InputStream, opts.BandwidthLimit),
+		stdout:         opts.OutputStream,
+		messages:       sendMessages(opts.Messages),
+	})
+}
+
+// LoadedImage returns the name, or the ID for untagged images, of the image
+// reported as loaded by the message, or an empty string if the message
+// doesn't report a loaded image.
+func (m *JSONMessage) LoadedImage() string {
+	for _, prefix := range []string{"Loaded image: ", "Loaded image ID: "} {
+		if strings.HasPrefix(m.Stream, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(m.Stream, prefix))
+		}
+	}
+	return ""
 }
 
 // ExportImageOptions represent the options for ExportImage Docker API call
@@ -289,13 +777,50 @@ func (c *Client) LoadImage(opts LoadImageOptions) error {
 type ExportImageOptions struct {
 	Name         string
 	OutputStream io.Writer
+
+	// TransferProgress, if set, is called with the total number of bytes
+	// written to OutputStream after every write.
+	TransferProgress func(written int64)
+
+	// BandwidthLimit, if positive, caps the rate of the transfer, in bytes
+	// per second.
+	BandwidthLimit int64
 }
 
 // ExportImage exports an image (as a tar file) into the stream
 //
 // See http://goo.gl/mi6kvk for more details.
 func (c *Client) ExportImage(opts ExportImageOptions) error {
-	return c.stream("GET", fmt.Sprintf("/images/%s/get", opts.Name), true, false, nil, nil, opts.OutputStream, nil)
+	return c.stream("GET", fmt.Sprintf("/images/%s/get", opts.Name), streamOptions{
+		setRawTerminal: true,
+		stdout:         newProgressWriter(newThrottledWriter(opts.OutputStream, opts.BandwidthLimit), opts.TransferProgress),
+	})
+}
+
+// ExportImagesOptions represent the options for ExportImages Docker API call.
+type ExportImagesOptions struct {
+	Names        []string
+	OutputStream io.Writer
+
+	// TransferProgress, if set, is called with the total number of bytes
+	// written to OutputStream after every write.
+	TransferProgress func(written int64)
+
+	// BandwidthLimit, if positive, caps the rate of the transfer, in bytes
+	// per second.
+	BandwidthLimit int64
+}
+
+// ExportImages exports the given images, which may be names, name:tag
+// references or IDs, in a single tarball, written to the output stream.
+func (c *Client) ExportImages(opts ExportImagesOptions) error {
+	if len(opts.Names) == 0 {
+		return ErrMustSpecifyNames
+	}
+	return c.stream("GET", "/images/get?"+url.Values{"names": opts.Names}.Encode(), streamOptions{
+		setRawTerminal: true,
+		stdout:         newProgressWriter(newThrottledWriter(opts.OutputStream, opts.BandwidthLimit), opts.TransferProgress),
+	})
 }
 
 // ImportImageOptions present the set of informations available for importing
@@ -309,6 +834,10 @@ type ImportImageOptions struct {
 
 	InputStream  io.Reader `qs:"-"`
 	OutputStream io.Writer `qs:"-"`
+
+	// BandwidthLimit, if positive, caps the rate of the upload of
+	// InputStream, or of the file in Source, in bytes per second.
+	BandwidthLimit int64 `qs:"-"`
 }
 
 // ImportImage imports an image from a url, a file or stdin
@@ -321,16 +850,23 @@ func (c *Client) ImportImage(opts ImportImageOptions) error {
 	if opts.Source != "-" {
 		opts.InputStream = nil
 	}
+	var file *os.File
 	if opts.Source != "-" && !isURL(opts.Source) {
-		f, err := os.Open(opts.Source)
-		if err != nil {
+		var err error
+		if file, err = os.Open(opts.Source); err != nil {
 			return err
 		}
-		b, err := ioutil.ReadAll(f)
-		opts.InputStream = bytes.NewBuffer(b)
+		opts.InputStream = file
 		opts.Source = "-"
 	}
-	return c.createImage(queryString(&opts), nil, opts.InputStream, opts.OutputStream, false)
+	in := newThrottledReader(opts.InputStream, opts.BandwidthLimit)
+	err := c.createImage(queryString(&opts), nil, in, opts.OutputStream, false, nil)
+	if err != nil && file != nil {
+		// the file is closed by the HTTP client once the body is sent,
+		// unless the request fails before that
+		file.Close()
+	}
+	return err
 }
 
 // BuildImageOptions present the set of informations available for building an
@@ -352,38 +888,187 @@ type BuildImageOptions struct {
 	Auth                AuthConfiguration  `qs:"-"` // for older docker X-Registry-Auth header
 	AuthConfigs         AuthConfigurations `qs:"-"` // for newer docker X-Registry-Config header
 	ContextDir          string             `qs:"-"`
+
+	// BuildArgs contains values for the ARG instructions of the Dockerfile.
+	BuildArgs map[string]string `qs:"buildargs"`
+
+	// CacheFrom lists images used as cache sources for the build, usually
+	// images pushed to a registry by previous builds on other machines.
+	CacheFrom []string `qs:"cachefrom"`
+
+	// InlineCache embeds the build cache metadata in the resulting image,
+	// so it can be used in the CacheFrom option of later builds once it's
+	// pushed. It requires the BuildKit builder. Exporting the cache to a
+	// registry or to a local directory separately from the image is not
+	// supported by the remote API and requires docker buildx.
+	InlineCache bool `qs:"-"`
+
+	// Version selects the builder backend. It requires Docker API 1.38 or
+	// newer.
+	Version BuilderVersion `qs:"version"`
+
+	// BuildID identifies the build, so it can be aborted with CancelBuild.
+	// It requires the BuildKit builder.
+	BuildID string `qs:"buildid"`
+
+	// Squash merges the layers created by the build into a single layer,
+	// on top of the base image. It requires Docker API 1.25 or newer, with
+	// experimental features enabled in the daemon.
+	Squash bool `qs:"squash"`
+
+	// StatusChan, when set, receives the status updates sent by the
+	// BuildKit builder. It must be consumed concurrently, and is closed
+	// when BuildImage returns. It's ignored when RawJSONStream is set.
+	StatusChan chan<- *BuildStatus `qs:"-"`
+
+	// Messages, when set, receives the messages sent by the server, like
+	// the output of the build steps. It must be consumed concurrently, and
+	// is closed when BuildImage returns. It's ignored when RawJSONStream
+	// is set.
+	Messages chan<- *JSONMessage `qs:"-"`
 }
 
+// BuilderVersion identifies the builder backend used by BuildImage.
+type BuilderVersion string
+
+const (
+	// BuilderV1 is the classic builder.
+	BuilderV1 BuilderVersion = "1"
+
+	// BuilderBuildKit is the BuildKit builder.
+	BuilderBuildKit BuilderVersion = "2"
+)
+
 // BuildImage builds an image from a tarball's url or a Dockerfile in the input
 // stream.
 //
 // See http://goo.gl/wRsW76 for more details.
 func (c *Client) BuildImage(opts BuildImageOptions) error {
+	if opts.StatusChan != nil {
+		defer close(opts.StatusChan)
+	}
+	if opts.Messages != nil {
+		defer close(opts.Messages)
+	}
 	if opts.OutputStream == nil {
 		return ErrMissingOutputStream
 	}
+	if opts.AuthConfigs.Configs == nil && c.AuthConfigs != nil {
+		opts.AuthConfigs = *c.AuthConfigs
+	}
 	var headers = headersWithAuth(opts.Auth, opts.AuthConfigs)
 
 	if opts.Remote != "" && opts.Name == "" {
 		opts.Name = opts.Remote
 	}
+	if opts.Version == BuilderBuildKit && c.isPodman() {
+		opts.Version = BuilderV1
+	}
+	if opts.Version != "" {
+		if err := c.requireAPIVersion("BuildImageOptions.Version", apiVersion138); err != nil {
+			return err
+		}
+	}
+	if opts.Squash {
+		if err := c.requireAPIVersion("BuildImageOptions.Squash", apiVersion125); err != nil {
+			return err
+		}
+	}
+	if opts.InlineCache {
+		buildArgs := make(map[string]string, len(opts.BuildArgs)+1)
+		for k, v := range opts.BuildArgs {
+			buildArgs[k] = v
+		}
+		buildArgs["BUILDKIT_INLINE_CACHE"] = "1"
+		opts.BuildArgs = buildArgs
+	}
 	if opts.InputStream != nil || opts.ContextDir != "" {
 		headers["Content-Type"] = "application/tar"
 	} else if opts.Remote == "" {
 		return ErrMissingRepo
 	}
+	var tarStream *io.PipeReader
 	if opts.ContextDir != "" {
 		if opts.InputStream != nil {
 			return ErrMultipleContexts
 		}
 		var err error
-		if opts.InputStream, err = createTarStream(opts.ContextDir); err != nil {
+		if tarStream, err = createTarStream(opts.ContextDir, opts.Dockerfile); err != nil {
 			return err
 		}
+		opts.InputStream = tarStream
+	}
+
+	messages := sendMessages(opts.Messages)
+	if opts.StatusChan != nil {
+		messages = func(m *JSONMessage) error {
+			if opts.Messages != nil {
+				opts.Messages <- m
+			}
+			if m.ID != buildkitTraceID || m.Aux == nil {
+				return nil
+			}
+			status, err := decodeBuildStatus(m.Aux)
+			if err != nil {
+				return err
+			}
+			opts.StatusChan <- status
+			return nil
+		}
+	}
+	err := c.stream("POST", fmt.Sprintf("/build?%s", queryString(&opts)), streamOptions{
+		setRawTerminal: true,
+		rawJSONStream:  opts.RawJSONStream,
+		headers:        headers,
+		in:             opts.InputStream,
+		stdout:         opts.OutputStream,
+		messages:       messages,
+	})
+	if err != nil && tarStream != nil {
+		// stops the goroutine writing the context, which may not have
+		// been read
+		tarStream.CloseWithError(err)
+	}
+	return err
+}
+
+// BuildImageFromDockerfile builds an image from the given Dockerfile content,
+// without touching the disk. The build context is created in memory and
+// contains the Dockerfile and the given files, indexed by their path in the
+// context. The files map may be nil.
+//
+// The Dockerfile is stored in the context using the name in opts.Dockerfile,
+// or "Dockerfile" when it's empty. opts.InputStream and opts.ContextDir must
+// not be set.
+func (c *Client) BuildImageFromDockerfile(dockerfile string, files map[string][]byte, opts BuildImageOptions) error {
+	if opts.InputStream != nil || opts.ContextDir != "" {
+		return ErrMultipleContexts
+	}
+	name := opts.Dockerfile
+	if name == "" {
+		name = "Dockerfile"
+	}
+	contents := make(map[string][]byte, len(files)+1)
+	for path, data := range files {
+		contents[path] = data
 	}
+	contents[name] = []byte(dockerfile)
+	context, err := createTarFromFiles(contents)
+	if err != nil {
+		return err
+	}
+	opts.InputStream = context
+	return c.BuildImage(opts)
+}
 
-	return c.stream("POST", fmt.Sprintf("/build?%s",
-		queryString(&opts)), true, opts.RawJSONStream, headers, opts.InputStream, opts.OutputStream, nil)
+// CancelBuild aborts the build started with the given BuildID. It's usually
+// called from another goroutine, while BuildImage is still running.
+func (c *Client) CancelBuild(id string) error {
+	if c.isPodman() {
+		return ErrNotSupportedByPodman
+	}
+	_, _, err := c.do("POST", "/build/cancel?"+url.Values{"id": {id}}.Encode(), nil, false)
+	return err
 }
 
 // TagImageOptions present the set of options to tag an image.
@@ -395,13 +1080,18 @@ type TagImageOptions struct {
 	Force bool
 }
 
-// TagImage adds a tag to the image identified by the given name.
+// TagImage adds a tag to the image identified by the given name. The name may
+// be pinned to a digest, as in busybox@sha256:4b82b6ae, but the new tag can't
+// be a digest.
 //
 // See http://goo.gl/5g6qFy for more details.
 func (c *Client) TagImage(name string, opts TagImageOptions) error {
 	if name == "" {
 		return ErrNoSuchImage
 	}
+	if strings.Contains(opts.Repo, "@") || strings.Contains(opts.Tag, ":") {
+		return ErrInvalidReference
+	}
 	_, status, err := c.do("POST", fmt.Sprintf("/images/"+name+"/tag?%s",
 		queryString(&opts)), nil, false)
 
@@ -439,6 +1129,70 @@ func headersWithAuth(auths ...interface{}) map[string]string {
 	return headers
 }
 
+// DistributionInspect describes an image as stored in its registry.
+type DistributionInspect struct {
+	Descriptor Descriptor `json:"Descriptor" yaml:"Descriptor"`
+	Platforms  []Platform `json:"Platforms,omitempty" yaml:"Platforms,omitempty"`
+}
+
+// Descriptor describes the manifest of an image, as stored in a registry.
+type Descriptor struct {
+	MediaType string `json:"mediaType,omitempty" yaml:"mediaType,omitempty"`
+	Digest    string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	Size      int64  `json:"size,omitempty" yaml:"size,omitempty"`
+}
+
+// Platform describes a platform supported by an image.
+type Platform struct {
+	Architecture string   `json:"architecture,omitempty" yaml:"architecture,omitempty"`
+	OS           string   `json:"os,omitempty" yaml:"os,omitempty"`
+	OSVersion    string   `json:"os.version,omitempty" yaml:"os.version,omitempty"`
+	OSFeatures   []string `json:"os.features,omitempty" yaml:"os.features,omitempty"`
+	Variant      string   `json:"variant,omitempty" yaml:"variant,omitempty"`
+}
+
+// InspectDistribution asks the daemon to look up the given image reference in
+// its registry, returning the descriptor of its manifest and the platforms it
+// supports. It requires Docker API 1.30 or newer.
+//
+// An empty instance of AuthConfiguration may be used for public images.
+func (c *Client) InspectDistribution(name string, auth AuthConfiguration) (*DistributionInspect, error) {
+	if c.isPodman() {
+		return nil, ErrNotSupportedByPodman
+	}
+	auth, err := c.authFor(name, auth)
+	if err != nil {
+		return nil, err
+	}
+	var inspect DistributionInspect
+	err = c.withAuth(auth, func(auth AuthConfiguration) error {
+		body, _, err := c.doWithOptions("GET", "/distribution/"+name+"/json", DoOptions{Headers: headersWithAuth(auth)})
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, &inspect)
+	})
+	if err != nil {
+		if e, ok := err.(*Error); ok && e.Status == http.StatusNotFound {
+			return nil, ErrNoSuchImage
+		}
+		return nil, err
+	}
+	return &inspect, nil
+}
+
+// ResolveImageDigest returns the digest of the manifest currently referenced
+// by the given image reference in its registry, like sha256:4b82b6ae. The
+// digest can be used to pin the image, as in busybox@sha256:4b82b6ae, when
+// pulling it or creating containers.
+func (c *Client) ResolveImageDigest(name string, auth AuthConfiguration) (string, error) {
+	inspect, err := c.InspectDistribution(name, auth)
+	if err != nil {
+		return "", err
+	}
+	return inspect.Descriptor.Digest, nil
+}
+
 // APIImageSearch reflect the result of a search on the dockerHub
 //
 // See http://goo.gl/xI5lLZ for more details.
@@ -5,11 +5,13 @@
 package docker
 
 import (
+	"archive/tar"
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"reflect"
@@ -17,10 +19,10 @@ import (
 	"testing"
 )
 
-func newTestClient(rt *FakeRoundTripper) Client {
+func newTestClient(rt *FakeRoundTripper) *Client {
 	endpoint := "http://localhost:4243"
 	u, _ := parseEndpoint("http://localhost:4243")
-	client := Client{
+	client := &Client{
 		HTTPClient:             &http.Client{Transport: rt},
 		endpoint:               endpoint,
 		endpointURL:            u,
@@ -672,6 +674,27 @@ func TestBuildImageParameters(t *testing.T) {
 	}
 }
 
+func TestBuildImageSquash(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	opts := BuildImageOptions{
+		Name:         "testImage",
+		Squash:       true,
+		InputStream:  &buf,
+		OutputStream: &buf,
+	}
+	if err := client.BuildImage(opts); err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	expected := map[string][]string{"t": {opts.Name}, "squash": {"1"}}
+	got := map[string][]string(req.URL.Query())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("BuildImage: wrong query string. Want %#v. Got %#v.", expected, got)
+	}
+}
+
 func TestBuildImageParametersForRemoteBuild(t *testing.T) {
 	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
 	client := newTestClient(fakeRT)
@@ -794,6 +817,21 @@ func TestTagImageParameters(t *testing.T) {
 	}
 }
 
+func TestTagImageDigest(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	if err := client.TagImage("busybox@sha256:4b82b6ae", TagImageOptions{Repo: "testImage", Tag: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if path := fakeRT.requests[0].URL.Path; path != "/images/busybox@sha256:4b82b6ae/tag" {
+		t.Errorf("TagImage: wrong path. Want %q. Got %q.", "/images/busybox@sha256:4b82b6ae/tag", path)
+	}
+	err := client.TagImage("busybox", TagImageOptions{Repo: "testImage", Tag: "sha256:4b82b6ae"})
+	if err != ErrInvalidReference {
+		t.Errorf("TagImage: wrong error. Want %#v. Got %#v.", ErrInvalidReference, err)
+	}
+}
+
 func TestTagImageMissingRepo(t *testing.T) {
 	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
 	client := newTestClient(fakeRT)
@@ -860,6 +898,195 @@ func TestExportImage(t *testing.T) {
 	}
 }
 
+func TestExportImages(t *testing.T) {
+	var buf bytes.Buffer
+	fakeRT := &FakeRoundTripper{message: "tarball", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := ExportImagesOptions{Names: []string{"busybox:latest", "tsuru/python"}, OutputStream: &buf}
+	err := client.ExportImages(opts)
+	if nil != err {
+		t.Error(err)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != "GET" {
+		t.Errorf("ExportImages: wrong method. Expected %q. Got %q.", "GET", req.Method)
+	}
+	expectedPath := "/images/get"
+	if req.URL.Path != expectedPath {
+		t.Errorf("ExportImages: wrong path. Expected %q. Got %q.", expectedPath, req.URL.Path)
+	}
+	expectedNames := []string{"busybox:latest", "tsuru/python"}
+	if names := req.URL.Query()["names"]; !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("ExportImages: wrong names. Expected %q. Got %q.", expectedNames, names)
+	}
+	if buf.String() != "tarball" {
+		t.Errorf("ExportImages: wrong output. Expected %q. Got %q.", "tarball", buf.String())
+	}
+}
+
+func TestExportImagesNoNames(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "", status: http.StatusOK})
+	err := client.ExportImages(ExportImagesOptions{OutputStream: &bytes.Buffer{}})
+	if err != ErrMustSpecifyNames {
+		t.Errorf("ExportImages: wrong error. Expected %#v. Got %#v.", ErrMustSpecifyNames, err)
+	}
+}
+
+func TestLoadImageMessages(t *testing.T) {
+	body := `{"stream":"Loaded image: busybox:latest\n"}
+{"stream":"Loaded image ID: sha256:abc\n"}
+`
+	fakeRT := &FakeRoundTripper{
+		message: body,
+		status:  http.StatusOK,
+		header:  map[string]string{"Content-Type": "application/json"},
+	}
+	client := newTestClient(fakeRT)
+	messages := make(chan *JSONMessage, 10)
+	var buf bytes.Buffer
+	opts := LoadImageOptions{InputStream: &bytes.Buffer{}, OutputStream: &buf, Messages: messages}
+	if err := client.LoadImage(opts); err != nil {
+		t.Fatal(err)
+	}
+	var loaded []string
+	for m := range messages {
+		loaded = append(loaded, m.LoadedImage())
+	}
+	expected := []string{"busybox:latest", "sha256:abc"}
+	if !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("LoadImage: wrong loaded images. Expected %q. Got %q.", expected, loaded)
+	}
+	expectedOutput := "Loaded image: busybox:latest\nLoaded image ID: sha256:abc\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("LoadImage: wrong output. Expected %q. Got %q.", expectedOutput, buf.String())
+	}
+}
+
+func TestPullImageDigest(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "Pulling 1/100", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	opts := PullImageOptions{Repository: "base@sha256:4b82b6ae", OutputStream: &buf}
+	err := client.PullImage(opts, AuthConfiguration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	expected := map[string][]string{"fromImage": {"base"}, "tag": {"sha256:4b82b6ae"}}
+	got := map[string][]string(req.URL.Query())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("PullImage: wrong query string. Want %#v. Got %#v.", expected, got)
+	}
+}
+
+func TestPullImageIfMissing(t *testing.T) {
+	var tests = []struct {
+		image string
+		query string
+	}{
+		{"postgres", "fromImage=postgres&tag=latest"},
+		{"postgres:9.4", "fromImage=postgres&tag=9.4"},
+		{"postgres:9.4@sha256:4b82b6ae", "fromImage=postgres&tag=sha256%3A4b82b6ae"},
+	}
+	for _, tt := range tests {
+		var query string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/images/create" {
+				query = r.URL.RawQuery
+				return
+			}
+			http.Error(w, "no such image", http.StatusNotFound)
+		}))
+		client, err := NewClient(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.PullImageIfMissing(tt.image, AuthConfiguration{}); err != nil {
+			t.Fatal(err)
+		}
+		if query != tt.query {
+			t.Errorf("PullImageIfMissing(%q): wrong query. Want %q. Got %q.", tt.image, tt.query, query)
+		}
+		server.Close()
+	}
+}
+
+func TestPullImageIfMissingAvailable(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id":"b750fe79269d"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	if err := client.PullImageIfMissing("postgres:9.4", AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fakeRT.requests) != 1 || fakeRT.requests[0].URL.Path != "/images/postgres:9.4/json" {
+		t.Errorf("PullImageIfMissing: the image should only be inspected. Got %d requests.", len(fakeRT.requests))
+	}
+}
+
+func TestInspectDistribution(t *testing.T) {
+	body := `{
+  "Descriptor": {
+    "mediaType": "application/vnd.docker.distribution.manifest.list.v2+json",
+    "digest": "sha256:4b82b6ae",
+    "size": 1864
+  },
+  "Platforms": [{"architecture": "amd64", "os": "linux"}, {"architecture": "arm", "os": "linux", "variant": "v7"}]
+}`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	auth := AuthConfiguration{Username: "gopher"}
+	inspect, err := client.InspectDistribution("busybox:latest", auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DistributionInspect{
+		Descriptor: Descriptor{
+			MediaType: "application/vnd.docker.distribution.manifest.list.v2+json",
+			Digest:    "sha256:4b82b6ae",
+			Size:      1864,
+		},
+		Platforms: []Platform{{Architecture: "amd64", OS: "linux"}, {Architecture: "arm", OS: "linux", Variant: "v7"}},
+	}
+	if !reflect.DeepEqual(*inspect, expected) {
+		t.Errorf("InspectDistribution: wrong result. Want %#v. Got %#v.", expected, *inspect)
+	}
+	req := fakeRT.requests[0]
+	if req.URL.Path != "/distribution/busybox:latest/json" {
+		t.Errorf("InspectDistribution: wrong path. Want %q. Got %q.", "/distribution/busybox:latest/json", req.URL.Path)
+	}
+	if req.Header.Get("X-Registry-Auth") == "" {
+		t.Error("InspectDistribution: missing X-Registry-Auth header")
+	}
+	digest, err := client.ResolveImageDigest("busybox:latest", auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != "sha256:4b82b6ae" {
+		t.Errorf("ResolveImageDigest: wrong digest. Want %q. Got %q.", "sha256:4b82b6ae", digest)
+	}
+}
+
+func TestInspectDistributionRateLimiter(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "{}", status: http.StatusOK})
+	limiter, clock := newFakeRateLimiter(1, 1)
+	client.RateLimiter = limiter
+	for i := 0; i < 2; i++ {
+		if _, err := client.InspectDistribution("busybox", AuthConfiguration{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(clock.slept) != 1 {
+		t.Errorf("InspectDistribution: wrong number of waits. Want 1. Got %d.", len(clock.slept))
+	}
+}
+
+func TestInspectDistributionNotFound(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such image", status: http.StatusNotFound})
+	_, err := client.ResolveImageDigest("busybox:latest", AuthConfiguration{})
+	if err != ErrNoSuchImage {
+		t.Errorf("ResolveImageDigest: wrong error. Want %#v. Got %#v.", ErrNoSuchImage, err)
+	}
+}
+
 func TestSearchImages(t *testing.T) {
 	body := `[
 	{
@@ -899,3 +1126,328 @@ func TestSearchImages(t *testing.T) {
 		t.Errorf("SearchImages: Wrong return value. Want %#v. Got %#v.", expected, result)
 	}
 }
+
+func TestImageExists(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id":"b750fe79269d"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	exists, err := client.ImageExists("tsuru/python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Error("ImageExists: want true. Got false.")
+	}
+	if path := fakeRT.requests[0].URL.Path; path != "/images/tsuru/python/json" {
+		t.Errorf("ImageExists: wrong path. Want %q. Got %q.", "/images/tsuru/python/json", path)
+	}
+	client = newTestClient(&FakeRoundTripper{message: "no such image", status: http.StatusNotFound})
+	exists, err = client.ImageExists("tsuru/python")
+	if exists || err != nil {
+		t.Errorf("ImageExists: wrong result for a missing image. Want false, <nil>. Got %v, %v.", exists, err)
+	}
+}
+
+func TestInspectImageWithOptions(t *testing.T) {
+	body := `{"Id":"sha256:abc","Architecture":"arm64","Descriptor":{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"sha256:def","size":123},"Manifests":[{"ID":"sha256:123","Descriptor":{"digest":"sha256:123"},"Available":true,"Kind":"image","ImageData":{"Platform":{"architecture":"arm64","os":"linux"}}}],"Unknown":true}`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	inspect, err := client.InspectImageWithOptions("busybox", InspectImageOptions{Manifests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fakeRT.requests[0].URL.RawQuery; got != "manifests=1" {
+		t.Errorf("InspectImageWithOptions: wrong query string. Want %q. Got %q.", "manifests=1", got)
+	}
+	if string(inspect.Raw) != body {
+		t.Errorf("InspectImageWithOptions: wrong raw response. Want %q. Got %q.", body, inspect.Raw)
+	}
+	if inspect.ID != "sha256:abc" || inspect.Descriptor == nil || inspect.Descriptor.Digest != "sha256:def" {
+		t.Errorf("InspectImageWithOptions: wrong image: %#v.", inspect.Image)
+	}
+	if len(inspect.Manifests) != 1 || inspect.Manifests[0].ImageData.Platform.Architecture != "arm64" {
+		t.Errorf("InspectImageWithOptions: wrong manifests: %#v.", inspect.Manifests)
+	}
+}
+
+func TestInspectImageWithOptionsRawConfig(t *testing.T) {
+	config := `{"architecture":"amd64","os":"linux","rootfs":{"type":"layers"}}`
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	files := []struct{ name, content string }{
+		{"blobs/sha256/aaa", config},
+		{"index.json", `{"schemaVersion":2}`},
+		{"manifest.json", `[{"Config":"blobs/sha256/aaa","RepoTags":["busybox:latest"]}]`},
+	}
+	for _, f := range files {
+		tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content))})
+		tw.Write([]byte(f.content))
+	}
+	tw.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images/busybox/json":
+			w.Write([]byte(`{"Id":"sha256:aaa"}`))
+		case "/images/busybox/get":
+			w.Write(archive.Bytes())
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inspect, err := client.InspectImageWithOptions("busybox", InspectImageOptions{RawConfig: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(inspect.RawConfig) != config {
+		t.Errorf("InspectImageWithOptions: wrong config. Want %q. Got %q.", config, inspect.RawConfig)
+	}
+}
+
+func TestImageConfigFromArchiveNoManifest(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	tw.WriteHeader(&tar.Header{Name: "repositories", Mode: 0644, Size: 2})
+	tw.Write([]byte("{}"))
+	tw.Close()
+	if _, err := imageConfigFromArchive(&archive); err != ErrNoImageConfig {
+		t.Errorf("imageConfigFromArchive: wrong error. Want %#v. Got %#v.", ErrNoImageConfig, err)
+	}
+}
+
+func TestInspectImageWithOptionsNotFound(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such image", status: http.StatusNotFound})
+	if _, err := client.InspectImageWithOptions("busybox", InspectImageOptions{}); err != ErrNoSuchImage {
+		t.Errorf("InspectImageWithOptions: wrong error. Want %#v. Got %#v.", ErrNoSuchImage, err)
+	}
+}
+
+func TestListImagesManifests(t *testing.T) {
+	body := `[{"Id":"sha256:abc","RepoTags":["busybox:latest"],"Descriptor":{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"sha256:abc","size":9535},"Manifests":[` +
+		`{"ID":"sha256:111","Descriptor":{"digest":"sha256:111"},"Available":true,"Size":{"Total":4300000,"Content":2100000},"Kind":"image","ImageData":{"Platform":{"architecture":"amd64","os":"linux"},"Size":{"Unpacked":2200000}}},` +
+		`{"ID":"sha256:222","Descriptor":{"digest":"sha256:222"},"Available":false,"Kind":"image","ImageData":{"Platform":{"architecture":"arm64","os":"linux","variant":"v8"}}},` +
+		`{"ID":"sha256:333","Descriptor":{"digest":"sha256:333"},"Available":true,"Kind":"attestation","AttestationData":{"For":"sha256:111"}}]}]`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	images, err := client.ListImages(ListImagesOptions{Manifests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fakeRT.requests[0].URL.RawQuery; got != "manifests=1" {
+		t.Errorf("ListImages: wrong query string. Want %q. Got %q.", "manifests=1", got)
+	}
+	if len(images) != 1 || images[0].Descriptor == nil || len(images[0].Manifests) != 3 {
+		t.Fatalf("ListImages: wrong images: %#v.", images)
+	}
+	manifests := images[0].Manifests
+	if manifests[0].Size.Total != 4300000 || manifests[0].ImageData.Size.Unpacked != 2200000 {
+		t.Errorf("ListImages: wrong sizes: %#v.", manifests[0])
+	}
+	if manifests[2].AttestationData == nil || manifests[2].AttestationData.For != "sha256:111" {
+		t.Errorf("ListImages: wrong attestation: %#v.", manifests[2])
+	}
+	expected := []Platform{{Architecture: "amd64", OS: "linux"}}
+	if platforms := images[0].Platforms(); !reflect.DeepEqual(platforms, expected) {
+		t.Errorf("Platforms: wrong platforms. Want %#v. Got %#v.", expected, platforms)
+	}
+}
+
+func TestListImagesManifestsUnsupported(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "[]", status: http.StatusOK}
+	client, err := NewVersionedClient("http://localhost:4243", "1.46")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.HTTPClient = &http.Client{Transport: fakeRT}
+	_, err = client.ListImages(ListImagesOptions{Manifests: true})
+	if e, ok := err.(*UnsupportedField); !ok || e.Field != "ListImagesOptions.Manifests" {
+		t.Errorf("ListImages: wrong error. Want *UnsupportedField. Got %#v.", err)
+	}
+	if len(fakeRT.requests) > 0 {
+		t.Errorf("ListImages: unexpected request: %#v.", fakeRT.requests[0].URL)
+	}
+}
+
+func TestListDanglingImages(t *testing.T) {
+	body := `[{"Id":"a","RepoTags":["<none>:<none>"]},{"Id":"b","RepoTags":["busybox:latest"]},{"Id":"c"}]`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	images, err := client.ListDanglingImages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || images[0].ID != "a" || images[1].ID != "c" {
+		t.Errorf("ListDanglingImages: wrong images: %#v.", images)
+	}
+	expected := `{"dangling":["true"]}`
+	if got := fakeRT.requests[0].URL.Query().Get("filters"); got != expected {
+		t.Errorf("ListDanglingImages: wrong filters. Want %q. Got %q.", expected, got)
+	}
+}
+
+func TestListImagesByLabel(t *testing.T) {
+	body := `[{"Id":"a","Labels":{"org.opencontainers.image.version":"1.0"}},{"Id":"b","Labels":{"org.opencontainers.image.version":"2.0"}},{"Id":"c"}]`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	images, err := client.ListImagesByLabel(LabelImageVersion + "=1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 || images[0].ID != "a" {
+		t.Errorf("ListImagesByLabel: wrong images: %#v.", images)
+	}
+	expected := `{"label":["org.opencontainers.image.version=1.0"]}`
+	if got := fakeRT.requests[0].URL.Query().Get("filters"); got != expected {
+		t.Errorf("ListImagesByLabel: wrong filters. Want %q. Got %q.", expected, got)
+	}
+}
+
+func TestIndexImagesByLabel(t *testing.T) {
+	body := `[
+	{"Id":"a","Labels":{"org.opencontainers.image.source":"https://github.com/org/api"}},
+	{"Id":"b","Labels":{"org.opencontainers.image.source":"https://github.com/org/web"}},
+	{"Id":"c","Labels":{"org.opencontainers.image.source":"https://github.com/org/api"}},
+	{"Id":"d"}
+]`
+	client := newTestClient(&FakeRoundTripper{message: body, status: http.StatusOK})
+	index, err := client.IndexImagesByLabel(LabelImageSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string][]string)
+	for value, images := range index {
+		for _, image := range images {
+			ids[value] = append(ids[value], image.ID)
+		}
+	}
+	expected := map[string][]string{
+		"https://github.com/org/api": {"a", "c"},
+		"https://github.com/org/web": {"b"},
+	}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("IndexImagesByLabel: wrong index. Want %#v. Got %#v.", expected, ids)
+	}
+}
+
+func ancestorServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images/sha256:4b82/json", "/images/busybox@sha256:9c1d/json":
+			w.Write([]byte(`{"Id":"sha256:4b82b6ae"}`))
+		case "/containers/json":
+			if r.URL.Query().Get("filters") == `{"ancestor":["sha256:4b82b6ae"]}` && r.URL.Query().Get("all") == "1" {
+				w.Write([]byte(`[{"Id":"c1","ImageID":"sha256:4b82b6ae"}]`))
+				return
+			}
+			w.Write([]byte(`[]`))
+		default:
+			http.Error(w, "no such image", http.StatusNotFound)
+		}
+	}))
+}
+
+func TestListContainersByAncestor(t *testing.T) {
+	server := ancestorServer()
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"sha256:4b82", "busybox@sha256:9c1d"} {
+		containers, err := client.ListContainersByAncestor(name, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(containers) != 1 || containers[0].ImageID != "sha256:4b82b6ae" {
+			t.Errorf("ListContainersByAncestor(%q): wrong containers: %#v.", name, containers)
+		}
+	}
+	if _, err := client.ListContainersByAncestor("unknown", true); err != ErrNoSuchImage {
+		t.Errorf("ListContainersByAncestor: wrong error. Want %#v. Got %#v.", ErrNoSuchImage, err)
+	}
+}
+
+func TestImageInUse(t *testing.T) {
+	server := ancestorServer()
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inUse, err := client.ImageInUse("sha256:4b82")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inUse {
+		t.Error("ImageInUse: want true. Got false.")
+	}
+}
+
+func TestPullImageMessages(t *testing.T) {
+	body := `{"status":"Pulling fs layer","id":"a3ed95caeb02"}
+{"status":"Downloading","progressDetail":{"current":512,"total":2048},"progress":"[=====>    ]","id":"a3ed95caeb02"}
+{"status":"Pull complete","progressDetail":{},"id":"a3ed95caeb02"}`
+	client := newTestClient(&FakeRoundTripper{message: body, status: http.StatusOK, header: map[string]string{"Content-Type": "application/json"}})
+	messages := make(chan *JSONMessage, 10)
+	var buf bytes.Buffer
+	err := client.PullImage(PullImageOptions{Repository: "base", OutputStream: &buf, Messages: messages}, AuthConfiguration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*JSONMessage
+	for m := range messages {
+		got = append(got, m)
+	}
+	if len(got) != 3 {
+		t.Fatalf("PullImage: wrong number of messages. Want 3. Got %d.", len(got))
+	}
+	if p := got[1].ProgressDetail; p == nil || p.Percent() != 25 {
+		t.Errorf("PullImage: wrong progress: %#v.", p)
+	}
+	if got[0].ProgressDetail != nil || got[2].ProgressDetail.Percent() != -1 {
+		t.Errorf("PullImage: wrong progress details: %#v, %#v.", got[0].ProgressDetail, got[2].ProgressDetail)
+	}
+	if buf.Len() == 0 {
+		t.Error("PullImage: the output should still be written.")
+	}
+}
+
+func TestPushImageMessages(t *testing.T) {
+	body := `{"status":"Pushing","progressDetail":{"current":10,"total":10},"id":"511136ea3c5a"}`
+	client := newTestClient(&FakeRoundTripper{message: body, status: http.StatusOK, header: map[string]string{"Content-Type": "application/json"}})
+	messages := make(chan *JSONMessage, 10)
+	if err := client.PushImage(PushImageOptions{Name: "test", Messages: messages}, AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	m, ok := <-messages
+	if !ok || m.ID != "511136ea3c5a" || m.ProgressDetail.Percent() != 100 {
+		t.Errorf("PushImage: wrong message: %#v.", m)
+	}
+	if _, ok := <-messages; ok {
+		t.Error("PushImage: the channel should be closed.")
+	}
+}
+
+func TestBuildImageMessages(t *testing.T) {
+	body := `{"stream":"Step 1/2 : FROM busybox\n"}
+{"stream":"Successfully built 4b82b6ae\n"}`
+	client := newTestClient(&FakeRoundTripper{message: body, status: http.StatusOK, header: map[string]string{"Content-Type": "application/json"}})
+	messages := make(chan *JSONMessage, 10)
+	var buf bytes.Buffer
+	opts := BuildImageOptions{Name: "test", Remote: "github.com/fsouza/go-dockerclient", OutputStream: &buf, Messages: messages}
+	if err := client.BuildImage(opts); err != nil {
+		t.Fatal(err)
+	}
+	var streams []string
+	for m := range messages {
+		streams = append(streams, m.Stream)
+	}
+	expected := []string{"Step 1/2 : FROM busybox\n", "Successfully built 4b82b6ae\n"}
+	if !reflect.DeepEqual(streams, expected) {
+		t.Errorf("BuildImage: wrong messages. Want %#v. Got %#v.", expected, streams)
+	}
+}
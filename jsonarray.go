@@ -0,0 +1,114 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// errNotJSONArray is returned when a list endpoint responds with something
+// other than a JSON array.
+var errNotJSONArray = errors.New("expected a JSON array")
+
+// decodeJSONArray reads the JSON array in r one element at a time, calling
+// fn with the raw JSON of each element, so large lists are never held in
+// memory. The slice given to fn is reused for the next element. A null
+// array is treated as an empty one.
+func decodeJSONArray(r io.Reader, fn func(element []byte) error) error {
+	br := bufio.NewReader(r)
+	b, err := skipJSONSpace(br)
+	if err != nil {
+		return err
+	}
+	if b == 'n' {
+		var rest [3]byte
+		if _, err := io.ReadFull(br, rest[:]); err != nil || string(rest[:]) != "ull" {
+			return errNotJSONArray
+		}
+		return nil
+	}
+	if b != '[' {
+		return errNotJSONArray
+	}
+	var element []byte
+	for {
+		b, err := skipJSONSpace(br)
+		if err != nil {
+			return err
+		}
+		if b == ']' && element == nil {
+			return nil
+		}
+		element = append(element[:0], b)
+		var depth int
+		var inString, escaped bool
+		for {
+			if inString {
+				switch {
+				case escaped:
+					escaped = false
+				case b == '\\':
+					escaped = true
+				case b == '"':
+					inString = false
+				}
+			} else {
+				switch b {
+				case '"':
+					inString = true
+				case '{', '[':
+					depth++
+				case '}', ']':
+					depth--
+				}
+			}
+			if b, err = br.ReadByte(); err == io.EOF {
+				return io.ErrUnexpectedEOF
+			} else if err != nil {
+				return err
+			}
+			if depth == 0 && !inString && (b == ',' || b == ']') {
+				break
+			}
+			element = append(element, b)
+		}
+		if err := fn(element); err != nil {
+			return err
+		}
+		if b == ']' {
+			return nil
+		}
+	}
+}
+
+// listJSON sends a GET request to a list endpoint, calling fn with each
+// element of the array it returns, as it's received.
+func (c *Client) listJSON(path string, fn func(element []byte) error) error {
+	c.waitRateLimit(path)
+	resp, err := c.doRequest("GET", path, DoOptions{})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeJSONArray(resp.Body, fn)
+}
+
+// skipJSONSpace returns the first byte of r that isn't JSON whitespace.
+func skipJSONSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			return b, nil
+		}
+	}
+}
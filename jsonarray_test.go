@@ -0,0 +1,133 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONArray(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected []string
+	}{
+		{`[]`, nil},
+		{` [ ] `, nil},
+		{`null`, nil},
+		{`[{"Id":"a"}]`, []string{`{"Id":"a"}`}},
+		{"[\n  {\"Id\": \"a\"},\n  {\"Id\": \"b\"}\n]\n", []string{`{"Id": "a"}`, `{"Id": "b"}` + "\n"}},
+		{`[{"Name":"x,]}\"{"},[1,[2]],"s]",3]`, []string{`{"Name":"x,]}\"{"}`, `[1,[2]]`, `"s]"`, `3`}},
+	}
+	for _, tt := range tests {
+		var got []string
+		err := decodeJSONArray(strings.NewReader(tt.input), func(element []byte) error {
+			got = append(got, string(element))
+			return nil
+		})
+		if err != nil {
+			t.Errorf("decodeJSONArray(%q): unexpected error: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("decodeJSONArray(%q): wrong elements. Want %#v. Got %#v.", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestDecodeJSONArrayErrors(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected error
+	}{
+		{``, io.ErrUnexpectedEOF},
+		{`{"Id":"a"}`, errNotJSONArray},
+		{`nil`, errNotJSONArray},
+		{`[{"Id":"a"}`, io.ErrUnexpectedEOF},
+		{`[{"Id":"a"},`, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		err := decodeJSONArray(strings.NewReader(tt.input), func([]byte) error { return nil })
+		if err != tt.expected {
+			t.Errorf("decodeJSONArray(%q): wrong error. Want %#v. Got %#v.", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestListContainersFunc(t *testing.T) {
+	body := `[{"Id":"a"},{"Id":"b"},{"Id":"c"}]`
+	client := newTestClient(&FakeRoundTripper{message: body, status: http.StatusOK})
+	errStop := errors.New("stop")
+	var ids []string
+	err := client.ListContainersFunc(ListContainersOptions{All: true}, func(container APIContainers) error {
+		ids = append(ids, container.ID)
+		if container.ID == "b" {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("ListContainersFunc: wrong error. Want %#v. Got %#v.", errStop, err)
+	}
+	if expected := []string{"a", "b"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("ListContainersFunc: wrong containers. Want %#v. Got %#v.", expected, ids)
+	}
+}
+
+func TestListImagesFunc(t *testing.T) {
+	body := `[{"Id":"a","RepoTags":["busybox:latest"]},{"Id":"b"}]`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	var ids []string
+	err := client.ListImagesFunc(ListImagesOptions{All: true}, func(image APIImages) error {
+		ids = append(ids, image.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"a", "b"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("ListImagesFunc: wrong images. Want %#v. Got %#v.", expected, ids)
+	}
+	if path := fakeRT.requests[0].URL.Path; path != "/images/json" {
+		t.Errorf("ListImagesFunc: wrong path. Want %q. Got %q.", "/images/json", path)
+	}
+}
+
+func largeContainerList(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"Id":"%064d","Image":"busybox","Command":"sleep 3600","Created":1429776000,"Status":"Up 2 hours","Ports":[{"PrivatePort":80,"Type":"tcp"}],"Names":["/container-%d"]}`, i, i)
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func BenchmarkListContainers(b *testing.B) {
+	client := newTestClient(&FakeRoundTripper{message: largeContainerList(5000), status: http.StatusOK})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.ListContainers(ListContainersOptions{All: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListContainersFunc(b *testing.B) {
+	client := newTestClient(&FakeRoundTripper{message: largeContainerList(5000), status: http.StatusOK})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := client.ListContainersFunc(ListContainersOptions{All: true}, func(APIContainers) error { return nil })
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
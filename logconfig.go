@@ -0,0 +1,181 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// LogConfig is the logging driver of a container, with its options. The
+// common drivers and their options are listed below.
+type LogConfig struct {
+	Type   string            `json:"Type,omitempty" yaml:"Type,omitempty"`
+	Config map[string]string `json:"Config,omitempty" yaml:"Config,omitempty"`
+}
+
+// Logging drivers of containers, set in the Type of LogConfig.
+const (
+	LogDriverJSONFile = "json-file"
+	LogDriverLocal    = "local"
+	LogDriverSyslog   = "syslog"
+	LogDriverJournald = "journald"
+	LogDriverGELF     = "gelf"
+	LogDriverFluentd  = "fluentd"
+	LogDriverAWSLogs  = "awslogs"
+	LogDriverNone     = "none"
+)
+
+// Options of the logging drivers, set in the Config of LogConfig. The tag,
+// labels and env options, supported by most drivers, pass the metadata of
+// the container along with the logs, like the syslog tag or the fields of
+// the journal.
+const (
+	LogOptTag         = "tag"
+	LogOptLabels      = "labels"
+	LogOptLabelsRegex = "labels-regex"
+	LogOptEnv         = "env"
+	LogOptEnvRegex    = "env-regex"
+	LogOptMaxSize     = "max-size"
+	LogOptMaxFile     = "max-file"
+	LogOptCompress    = "compress"
+	LogOptMode        = "mode"
+	LogOptBufferMax   = "max-buffer-size"
+
+	LogOptSyslogAddress       = "syslog-address"
+	LogOptSyslogFacility      = "syslog-facility"
+	LogOptSyslogFormat        = "syslog-format"
+	LogOptSyslogTLSCACert     = "syslog-tls-ca-cert"
+	LogOptSyslogTLSCert       = "syslog-tls-cert"
+	LogOptSyslogTLSKey        = "syslog-tls-key"
+	LogOptSyslogTLSSkipVerify = "syslog-tls-skip-verify"
+
+	LogOptGELFAddress           = "gelf-address"
+	LogOptGELFCompressionType   = "gelf-compression-type"
+	LogOptGELFCompressionLevel  = "gelf-compression-level"
+	LogOptGELFTCPMaxReconnect   = "gelf-tcp-max-reconnect"
+	LogOptGELFTCPReconnectDelay = "gelf-tcp-reconnect-delay"
+
+	LogOptFluentdAddress      = "fluentd-address"
+	LogOptFluentdAsync        = "fluentd-async"
+	LogOptFluentdBufferLimit  = "fluentd-buffer-limit"
+	LogOptFluentdRetryWait    = "fluentd-retry-wait"
+	LogOptFluentdMaxRetries   = "fluentd-max-retries"
+	LogOptFluentdSubSecond    = "fluentd-sub-second-precision"
+	LogOptFluentdRequestAck   = "fluentd-request-ack"
+	LogOptFluentdWriteTimeout = "fluentd-write-timeout"
+
+	LogOptAWSLogsRegion             = "awslogs-region"
+	LogOptAWSLogsEndpoint           = "awslogs-endpoint"
+	LogOptAWSLogsGroup              = "awslogs-group"
+	LogOptAWSLogsStream             = "awslogs-stream"
+	LogOptAWSLogsCreateGroup        = "awslogs-create-group"
+	LogOptAWSLogsDateFormat         = "awslogs-datetime-format"
+	LogOptAWSLogsPattern            = "awslogs-multiline-pattern"
+	LogOptAWSLogsCredentials        = "awslogs-credentials-endpoint"
+	LogOptAWSLogsFormat             = "awslogs-format"
+	LogOptAWSLogsForceFlushInterval = "awslogs-force-flush-interval-seconds"
+	LogOptAWSLogsMaxBufferedEvents  = "awslogs-max-buffered-events"
+)
+
+// logDriverRequired lists the required options of the known drivers.
+var logDriverRequired = map[string][]string{
+	LogDriverGELF:    {LogOptGELFAddress},
+	LogDriverAWSLogs: {LogOptAWSLogsGroup},
+}
+
+var logSize = regexp.MustCompile(`^-1$|^[0-9]+(\.[0-9]+)?\s*([kKmMgGtTpP][iI]?)?[bB]?$`)
+
+// logOptionFormats checks the values of the options whose format is known.
+var logOptionFormats = map[string]func(string) bool{
+	LogOptMaxSize:                   logSize.MatchString,
+	LogOptBufferMax:                 logSize.MatchString,
+	LogOptFluentdBufferLimit:        logSize.MatchString,
+	LogOptMaxFile:                   isNonNegativeInt,
+	LogOptFluentdMaxRetries:         isNonNegativeInt,
+	LogOptGELFTCPMaxReconnect:       isNonNegativeInt,
+	LogOptGELFTCPReconnectDelay:     isNonNegativeInt,
+	LogOptAWSLogsForceFlushInterval: isNonNegativeInt,
+	LogOptAWSLogsMaxBufferedEvents:  isNonNegativeInt,
+	LogOptCompress:                  isBool,
+	LogOptSyslogTLSSkipVerify:       isBool,
+	LogOptFluentdAsync:              isBool,
+	LogOptFluentdSubSecond:          isBool,
+	LogOptFluentdRequestAck:         isBool,
+	LogOptAWSLogsCreateGroup:        isBool,
+	LogOptFluentdRetryWait:          isDuration,
+	LogOptFluentdWriteTimeout:       isDuration,
+	LogOptMode: func(value string) bool {
+		return value == "blocking" || value == "non-blocking"
+	},
+	LogOptGELFCompressionType: func(value string) bool {
+		return value == "gzip" || value == "zlib" || value == "none"
+	},
+	LogOptGELFCompressionLevel: func(value string) bool {
+		level, err := strconv.Atoi(value)
+		return err == nil && level >= -1 && level <= 9
+	},
+}
+
+func isNonNegativeInt(value string) bool {
+	n, err := strconv.Atoi(value)
+	return err == nil && n >= 0
+}
+
+func isBool(value string) bool {
+	_, err := strconv.ParseBool(value)
+	return err == nil
+}
+
+func isDuration(value string) bool {
+	_, err := time.ParseDuration(value)
+	return err == nil
+}
+
+// InvalidLogConfig is the error returned by Validate and CreateContainer when
+// an option of a logging driver is missing, or has a value in the wrong
+// format.
+type InvalidLogConfig struct {
+	Driver  string
+	Option  string
+	Value   string
+	Missing bool
+}
+
+func (err *InvalidLogConfig) Error() string {
+	if err.Missing {
+		return "Missing option " + err.Option + " of logging driver " + err.Driver
+	}
+	return "Invalid value " + strconv.Quote(err.Value) + " of option " + err.Option + " of logging driver " + err.Driver
+}
+
+// Validate checks the options of the logging driver: the required options of
+// the known drivers must be set, and the options whose format is known, like
+// max-size or mode, must be well formed. The other options are left to the
+// daemon, which knows the options of its version and of the plugins. The
+// empty driver is the default one of the daemon.
+func (config LogConfig) Validate() error {
+	for _, option := range logDriverRequired[config.Type] {
+		if config.Config[option] == "" {
+			return &InvalidLogConfig{Driver: config.Type, Option: option, Missing: true}
+		}
+	}
+	for option, value := range config.Config {
+		if valid, ok := logOptionFormats[option]; ok && !valid(value) {
+			return &InvalidLogConfig{Driver: config.Type, Option: option, Value: value}
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
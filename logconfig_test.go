@@ -0,0 +1,55 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLogConfigValidate(t *testing.T) {
+	var tests = []struct {
+		config   LogConfig
+		expected error
+	}{
+		{LogConfig{}, nil},
+		{LogConfig{Type: "my-plugin", Config: map[string]string{"anything": "goes"}}, nil},
+		{LogConfig{Type: LogDriverSyslog, Config: map[string]string{LogOptSyslogAddress: "udp://1.2.3.4:514", LogOptTag: "{{.Name}}"}}, nil},
+		{LogConfig{Type: LogDriverJournald, Config: map[string]string{LogOptLabels: "app", LogOptMode: "non-blocking"}}, nil},
+		{LogConfig{Type: LogDriverGELF}, &InvalidLogConfig{Driver: LogDriverGELF, Option: LogOptGELFAddress, Missing: true}},
+		{LogConfig{Type: LogDriverAWSLogs, Config: map[string]string{LogOptAWSLogsRegion: "us-east-1"}}, &InvalidLogConfig{Driver: LogDriverAWSLogs, Option: LogOptAWSLogsGroup, Missing: true}},
+		{LogConfig{Type: LogDriverLocal, Config: map[string]string{LogOptMaxSize: "10m", LogOptMaxFile: "3", LogOptCompress: "true"}}, nil},
+		{LogConfig{Type: LogDriverJSONFile, Config: map[string]string{LogOptMaxSize: "-1", LogOptLabelsRegex: "^com\\.example"}}, nil},
+		{LogConfig{Type: LogDriverFluentd, Config: map[string]string{LogOptFluentdRequestAck: "true", LogOptFluentdWriteTimeout: "5s"}}, nil},
+		{LogConfig{Type: LogDriverGELF, Config: map[string]string{LogOptGELFAddress: "tcp://1.2.3.4:12201", LogOptGELFTCPMaxReconnect: "3", LogOptGELFTCPReconnectDelay: "1"}}, nil},
+		{LogConfig{Type: LogDriverAWSLogs, Config: map[string]string{LogOptAWSLogsGroup: "app", LogOptAWSLogsEndpoint: "https://logs.example.com", LogOptAWSLogsFormat: "json/emf",
+			LogOptAWSLogsForceFlushInterval: "5", LogOptAWSLogsMaxBufferedEvents: "4096"}}, nil},
+		{LogConfig{Type: LogDriverLocal, Config: map[string]string{"some-future-option": "1"}}, nil},
+		{LogConfig{Type: LogDriverLocal, Config: map[string]string{LogOptMaxSize: "ten megs"}}, &InvalidLogConfig{Driver: LogDriverLocal, Option: LogOptMaxSize, Value: "ten megs"}},
+		{LogConfig{Type: LogDriverJournald, Config: map[string]string{LogOptMode: "async"}}, &InvalidLogConfig{Driver: LogDriverJournald, Option: LogOptMode, Value: "async"}},
+		{LogConfig{Type: LogDriverFluentd, Config: map[string]string{LogOptFluentdRetryWait: "1000"}}, &InvalidLogConfig{Driver: LogDriverFluentd, Option: LogOptFluentdRetryWait, Value: "1000"}},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); !reflect.DeepEqual(err, tt.expected) {
+			t.Errorf("LogConfig.Validate(%#v): wrong error. Want %#v. Got %#v.", tt.config, tt.expected, err)
+		}
+	}
+}
+
+func TestCreateContainerInvalidLogConfig(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	_, err := client.CreateContainer(CreateContainerOptions{
+		Config:     &Config{Image: "base"},
+		HostConfig: &HostConfig{LogConfig: LogConfig{Type: LogDriverGELF}},
+	})
+	if _, ok := err.(*InvalidLogConfig); !ok {
+		t.Errorf("CreateContainer: wrong error. Want a *InvalidLogConfig. Got %#v.", err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("CreateContainer: the container should not be created. Got %d requests.", len(fakeRT.requests))
+	}
+}
@@ -11,13 +11,240 @@ import (
 	"io"
 	"net"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/docker/docker/pkg/promise"
 )
 
+// DockerVersion contains version information about the docker server.
+type DockerVersion struct {
+	Version       string             `json:"Version,omitempty" yaml:"Version,omitempty"`
+	APIVersion    string             `json:"ApiVersion,omitempty" yaml:"ApiVersion,omitempty"`
+	GitCommit     string             `json:"GitCommit,omitempty" yaml:"GitCommit,omitempty"`
+	GoVersion     string             `json:"GoVersion,omitempty" yaml:"GoVersion,omitempty"`
+	Os            string             `json:"Os,omitempty" yaml:"Os,omitempty"`
+	Arch          string             `json:"Arch,omitempty" yaml:"Arch,omitempty"`
+	KernelVersion string             `json:"KernelVersion,omitempty" yaml:"KernelVersion,omitempty"`
+	Components    []ComponentVersion `json:"Components,omitempty" yaml:"Components,omitempty"`
+}
+
+// ComponentVersion contains version information about one of the components
+// of the server, like the engine or containerd. It's reported by recent
+// versions of Docker and by Podman.
+type ComponentVersion struct {
+	Name    string            `json:"Name,omitempty" yaml:"Name,omitempty"`
+	Version string            `json:"Version,omitempty" yaml:"Version,omitempty"`
+	Details map[string]string `json:"Details,omitempty" yaml:"Details,omitempty"`
+}
+
+// DockerInfo contains system-wide information about the Docker server.
+type DockerInfo struct {
+	ID                 string      `json:"ID,omitempty" yaml:"ID,omitempty"`
+	Name               string      `json:"Name,omitempty" yaml:"Name,omitempty"`
+	Containers         int         `json:"Containers,omitempty" yaml:"Containers,omitempty"`
+	Images             int         `json:"Images,omitempty" yaml:"Images,omitempty"`
+	Driver             string      `json:"Driver,omitempty" yaml:"Driver,omitempty"`
+	DriverStatus       [][2]string `json:"DriverStatus,omitempty" yaml:"DriverStatus,omitempty"`
+	ExecutionDriver    string      `json:"ExecutionDriver,omitempty" yaml:"ExecutionDriver,omitempty"`
+	KernelVersion      string      `json:"KernelVersion,omitempty" yaml:"KernelVersion,omitempty"`
+	OperatingSystem    string      `json:"OperatingSystem,omitempty" yaml:"OperatingSystem,omitempty"`
+	NCPU               int         `json:"NCPU,omitempty" yaml:"NCPU,omitempty"`
+	MemTotal           int64       `json:"MemTotal,omitempty" yaml:"MemTotal,omitempty"`
+	Debug              bool        `json:"Debug,omitempty" yaml:"Debug,omitempty"`
+	NFd                int         `json:"NFd,omitempty" yaml:"NFd,omitempty"`
+	NGoroutines        int         `json:"NGoroutines,omitempty" yaml:"NGoroutines,omitempty"`
+	NEventsListener    int         `json:"NEventsListener,omitempty" yaml:"NEventsListener,omitempty"`
+	MemoryLimit        bool        `json:"MemoryLimit,omitempty" yaml:"MemoryLimit,omitempty"`
+	SwapLimit          bool        `json:"SwapLimit,omitempty" yaml:"SwapLimit,omitempty"`
+	IPv4Forwarding     bool        `json:"IPv4Forwarding,omitempty" yaml:"IPv4Forwarding,omitempty"`
+	InitPath           string      `json:"InitPath,omitempty" yaml:"InitPath,omitempty"`
+	InitSha1           string      `json:"InitSha1,omitempty" yaml:"InitSha1,omitempty"`
+	IndexServerAddress string      `json:"IndexServerAddress,omitempty" yaml:"IndexServerAddress,omitempty"`
+	DockerRootDir      string      `json:"DockerRootDir,omitempty" yaml:"DockerRootDir,omitempty"`
+	Labels             []string    `json:"Labels,omitempty" yaml:"Labels,omitempty"`
+	CgroupDriver       string      `json:"CgroupDriver,omitempty" yaml:"CgroupDriver,omitempty"`
+	CgroupVersion      string      `json:"CgroupVersion,omitempty" yaml:"CgroupVersion,omitempty"`
+	SecurityOptions    []string    `json:"SecurityOptions,omitempty" yaml:"SecurityOptions,omitempty"`
+
+	// Runtimes are the OCI runtimes available to the containers, by name,
+	// like runc, kata or runsc for gVisor, and DefaultRuntime is the one
+	// used when HostConfig.Runtime is empty. They're reported since API
+	// 1.25.
+	Runtimes       map[string]Runtime `json:"Runtimes,omitempty" yaml:"Runtimes,omitempty"`
+	DefaultRuntime string             `json:"DefaultRuntime,omitempty" yaml:"DefaultRuntime,omitempty"`
+
+	// Containerd describes the containerd instance used by the daemon.
+	// It's reported since API 1.46.
+	Containerd *ContainerdInfo `json:"Containerd,omitempty" yaml:"Containerd,omitempty"`
+}
+
+// ContainerdInfo describes the containerd instance used by the daemon, and the
+// namespaces of the containers and of the plugins of the daemon.
+type ContainerdInfo struct {
+	Address    string               `json:"Address,omitempty" yaml:"Address,omitempty"`
+	Namespaces ContainerdNamespaces `json:"Namespaces,omitempty" yaml:"Namespaces,omitempty"`
+}
+
+// ContainerdNamespaces are the containerd namespaces used by the daemon.
+type ContainerdNamespaces struct {
+	Containers string `json:"Containers,omitempty" yaml:"Containers,omitempty"`
+	Plugins    string `json:"Plugins,omitempty" yaml:"Plugins,omitempty"`
+}
+
+// DriverTypeContainerdSnapshotter is the driver-type reported in the
+// DriverStatus of the daemons using the containerd image store.
+const DriverTypeContainerdSnapshotter = "io.containerd.snapshotter.v1"
+
+// Runtime is an OCI runtime available to the containers.
+type Runtime struct {
+	Path        string   `json:"path,omitempty" yaml:"path,omitempty"`
+	RuntimeArgs []string `json:"runtimeArgs,omitempty" yaml:"runtimeArgs,omitempty"`
+}
+
+// RuntimeNames returns the names of the available runtimes, sorted.
+func (info *DockerInfo) RuntimeNames() []string {
+	names := make([]string, 0, len(info.Runtimes))
+	for name := range info.Runtimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NoSuchRuntime is the error returned by CheckRuntime when the runtime is not
+// available.
+type NoSuchRuntime struct {
+	Name      string
+	Available []string
+}
+
+func (err *NoSuchRuntime) Error() string {
+	return "No such runtime: " + err.Name + " (available: " + strings.Join(err.Available, ", ") + ")"
+}
+
+// CheckRuntime checks that the daemon has the given runtime, to be set in
+// HostConfig.Runtime, before creating a container with it. The daemon only
+// fails when the container starts otherwise. The empty name stands for the
+// default runtime, and is always available.
+func (c *Client) CheckRuntime(name string) error {
+	if name == "" {
+		return nil
+	}
+	info, err := c.ServerInfo()
+	if err != nil {
+		return err
+	}
+	if _, ok := info.Runtimes[name]; !ok {
+		return &NoSuchRuntime{Name: name, Available: info.RuntimeNames()}
+	}
+	return nil
+}
+
+// securityOption returns the options of the given security feature of the
+// daemon, reported as "name=seccomp,profile=default" since API 1.26, or just
+// as "seccomp" before. ok is false when the feature is not enabled.
+func (info *DockerInfo) securityOption(name string) (options map[string]string, ok bool) {
+	for _, opt := range info.SecurityOptions {
+		if opt == name {
+			return map[string]string{"name": name}, true
+		}
+		options = make(map[string]string)
+		for _, field := range strings.Split(opt, ",") {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) == 2 {
+				options[parts[0]] = parts[1]
+			}
+		}
+		if options["name"] == name {
+			return options, true
+		}
+	}
+	return nil, false
+}
+
+// Rootless tells whether the daemon runs as an unprivileged user.
+func (info *DockerInfo) Rootless() bool {
+	_, ok := info.securityOption("rootless")
+	return ok
+}
+
+// UsernsRemap tells whether the daemon remaps the users of the containers
+// to unprivileged users of the host.
+func (info *DockerInfo) UsernsRemap() bool {
+	_, ok := info.securityOption("userns")
+	return ok
+}
+
+// SELinuxEnabled tells whether the daemon labels the containers for SELinux.
+func (info *DockerInfo) SELinuxEnabled() bool {
+	_, ok := info.securityOption("selinux")
+	return ok
+}
+
+// AppArmorEnabled tells whether the daemon confines the containers with
+// AppArmor profiles.
+func (info *DockerInfo) AppArmorEnabled() bool {
+	_, ok := info.securityOption("apparmor")
+	return ok
+}
+
+// CgroupV2 tells whether the host uses the unified hierarchy of cgroups v2.
+// The cgroup version is only reported since API 1.40, older daemons only
+// supporting cgroups v1.
+func (info *DockerInfo) CgroupV2() bool {
+	return info.CgroupVersion == "2"
+}
+
+// ContainerdImageStore tells whether the daemon stores the images in
+// containerd, with a snapshotter, rather than with a graph driver. Such
+// daemons keep the manifests of all the platforms of the images, which
+// changes the sizes and the digests they report, and list the platforms of
+// each image with ListImagesOptions.Manifests.
+func (info *DockerInfo) ContainerdImageStore() bool {
+	for _, status := range info.DriverStatus {
+		if status[0] == "driver-type" {
+			return status[1] == DriverTypeContainerdSnapshotter
+		}
+	}
+	return false
+}
+
+// ServerVersion returns version information about the docker server.
+//
+// It's the typed counterpart of Version.
+func (c *Client) ServerVersion() (*DockerVersion, error) {
+	env, err := c.Version()
+	if err != nil {
+		return nil, err
+	}
+	var version DockerVersion
+	if err := env.DecodeInto(&version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// ServerInfo returns system-wide information about the Docker server.
+//
+// It's the typed counterpart of Info. Flags reported as integers by older
+// versions of the API are converted to booleans.
+func (c *Client) ServerInfo() (*DockerInfo, error) {
+	env, err := c.Info()
+	if err != nil {
+		return nil, err
+	}
+	var info DockerInfo
+	if err := env.DecodeInto(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
 // Version returns version information about the docker server.
 //
+// New code should use ServerVersion, which returns a typed value.
+//
 // See http://goo.gl/BOZrF5 for more details.
 func (c *Client) Version() (*Env, error) {
 	body, _, err := c.do("GET", "/version", nil, false)
@@ -33,6 +260,8 @@ func (c *Client) Version() (*Env, error) {
 
 // Info returns system-wide information about the Docker server.
 //
+// New code should use ServerInfo, which returns a typed value.
+//
 // See http://goo.gl/wmqZsW for more details.
 func (c *Client) Info() (*Env, error) {
 	body, _, err := c.do("GET", "/info", nil, false)
@@ -86,15 +315,9 @@ func (c *Client) Exec(opts ExecOptions) error {
 		return fmt.Errorf("Couldn't get an operation id for the exec command")
 	}
 	var (
-		hijacked = make(chan io.Closer)
+		hijacked = make(chan struct{})
 		errCh    chan error
 	)
-	// Block the return until the chan gets closed
-	defer func() {
-		if _, ok := <-hijacked; ok {
-			fmt.Println("Hijack did not finish (chan still open)")
-		}
-	}()
 
 	doPath := "/exec/" + id.Id + "/start"
 	errCh = promise.Go(func() error {
@@ -106,10 +329,8 @@ func (c *Client) Exec(opts ExecOptions) error {
 	})
 
 	select {
-	case closer := <-hijacked:
-		if closer != nil {
-			defer closer.Close()
-		}
+	case <-hijacked:
+		hijacked <- struct{}{}
 	case err := <-errCh:
 		if err != nil {
 			return err
@@ -121,9 +342,7 @@ func (c *Client) Exec(opts ExecOptions) error {
 		outFd                       uintptr
 	)
 
-	if _, ok := opts.InputStream.(*os.File); ok {
-		isTerminalIn = true
-	}
+	isTerminalIn = IsTerminal(opts.InputStream)
 	if file, ok := opts.OutputStream.(*os.File); ok {
 		isTerminalOut = true
 		outFd = file.Fd()
@@ -142,13 +361,21 @@ func (c *Client) Exec(opts ExecOptions) error {
 }
 
 // ParseRepositoryTag gets the name of the repository and returns it splitted
-// in two parts: the repository and the tag.
+// in two parts: the repository and the tag. For references pinned to a
+// digest, the digest is returned in place of the tag.
 //
 // Some examples:
 //
 //     localhost.localdomain:5000/samalba/hipache:latest -> localhost.localdomain:5000/samalba/hipache, latest
 //     localhost.localdomain:5000/samalba/hipache -> localhost.localdomain:5000/samalba/hipache, ""
+//     busybox@sha256:4b82b6ae -> busybox, sha256:4b82b6ae
+//     busybox:1.0@sha256:4b82b6ae -> busybox, sha256:4b82b6ae
 func ParseRepositoryTag(repoTag string) (repository string, tag string) {
+	if n := strings.Index(repoTag, "@"); n >= 0 {
+		// the digest takes precedence over the tag, as in docker pull
+		repository, _ = ParseRepositoryTag(repoTag[:n])
+		return repository, repoTag[n+1:]
+	}
 	n := strings.LastIndex(repoTag, ":")
 	if n < 0 {
 		return repoTag, ""
@@ -158,3 +385,15 @@ func ParseRepositoryTag(repoTag string) (repository string, tag string) {
 	}
 	return repoTag, ""
 }
+
+// joinRepositoryTag is the inverse of ParseRepositoryTag, joining digests
+// with "@" and tags with ":".
+func joinRepositoryTag(repository, tag string) string {
+	switch {
+	case tag == "":
+		return repository
+	case strings.Contains(tag, ":"):
+		return repository + "@" + tag
+	}
+	return repository + ":" + tag
+}
@@ -12,12 +12,6 @@ import (
 	"testing"
 )
 
-type DockerVersion struct {
-	Version   string
-	GitCommit string
-	GoVersion string
-}
-
 func TestVersion(t *testing.T) {
 	body := `{
      "Version":"0.2.2",
@@ -146,6 +140,21 @@ func TestParseRepositoryTag(t *testing.T) {
 			"tsuru/python",
 			"2.7",
 		},
+		{
+			"busybox@sha256:4b82b6ae",
+			"busybox",
+			"sha256:4b82b6ae",
+		},
+		{
+			"busybox:1.0@sha256:4b82b6ae",
+			"busybox",
+			"sha256:4b82b6ae",
+		},
+		{
+			"localhost.localdomain:5000/samalba/hipache@sha256:4b82b6ae",
+			"localhost.localdomain:5000/samalba/hipache",
+			"sha256:4b82b6ae",
+		},
 	}
 	for _, tt := range tests {
 		repo, tag := ParseRepositoryTag(tt.input)
@@ -157,3 +166,155 @@ func TestParseRepositoryTag(t *testing.T) {
 		}
 	}
 }
+
+func TestServerVersion(t *testing.T) {
+	body := `{
+     "Version":"1.5.0",
+     "ApiVersion":"1.17",
+     "GitCommit":"a8a31ef",
+     "GoVersion":"go1.4.1",
+     "Os":"linux",
+     "Arch":"amd64",
+     "KernelVersion":"3.18.5-tinycore64"
+}`
+	fakeRT := FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(&fakeRT)
+	expected := DockerVersion{
+		Version:       "1.5.0",
+		APIVersion:    "1.17",
+		GitCommit:     "a8a31ef",
+		GoVersion:     "go1.4.1",
+		Os:            "linux",
+		Arch:          "amd64",
+		KernelVersion: "3.18.5-tinycore64",
+	}
+	version, err := client.ServerVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*version, expected) {
+		t.Errorf("ServerVersion(): Wrong result.\nWant %#v.\nGot %#v.", expected, *version)
+	}
+}
+
+func TestServerVersionError(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "internal error", status: http.StatusInternalServerError}
+	client := newTestClient(fakeRT)
+	version, err := client.ServerVersion()
+	if version != nil {
+		t.Errorf("ServerVersion(): expected <nil> value, got %#v.", version)
+	}
+	if err == nil {
+		t.Error("ServerVersion(): unexpected <nil> error")
+	}
+}
+
+func TestServerInfo(t *testing.T) {
+	body := `{
+     "Containers":11,
+     "Images":16,
+     "Driver":"aufs",
+     "DriverStatus":[["Root Dir","/var/lib/docker/aufs"],["Dirs","27"]],
+     "Debug":0,
+     "NFd":11,
+     "NGoroutines":21,
+     "MemoryLimit":1,
+     "SwapLimit":0,
+     "Labels":["storage=ssd"]
+}`
+	fakeRT := FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(&fakeRT)
+	expected := DockerInfo{
+		Containers:   11,
+		Images:       16,
+		Driver:       "aufs",
+		DriverStatus: [][2]string{{"Root Dir", "/var/lib/docker/aufs"}, {"Dirs", "27"}},
+		NFd:          11,
+		NGoroutines:  21,
+		MemoryLimit:  true,
+		Labels:       []string{"storage=ssd"},
+	}
+	info, err := client.ServerInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*info, expected) {
+		t.Errorf("ServerInfo(): Wrong result.\nWant %#v.\nGot %#v.", expected, *info)
+	}
+}
+
+func TestServerInfoHostCapabilities(t *testing.T) {
+	var tests = []struct {
+		body                                     string
+		rootless, userns, selinux, apparmor, cg2 bool
+	}{
+		{`{"CgroupDriver":"systemd","CgroupVersion":"2","SecurityOptions":["name=seccomp,profile=default","name=rootless","name=cgroupns"]}`, true, false, false, false, true},
+		{`{"CgroupDriver":"cgroupfs","CgroupVersion":"1","SecurityOptions":["name=apparmor","name=seccomp,profile=default","name=userns"]}`, false, true, false, true, false},
+		{`{"SecurityOptions":["selinux","seccomp"]}`, false, false, true, false, false},
+		{`{}`, false, false, false, false, false},
+	}
+	for _, tt := range tests {
+		client := newTestClient(&FakeRoundTripper{message: tt.body, status: http.StatusOK})
+		info, err := client.ServerInfo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []bool{info.Rootless(), info.UsernsRemap(), info.SELinuxEnabled(), info.AppArmorEnabled(), info.CgroupV2()}
+		expected := []bool{tt.rootless, tt.userns, tt.selinux, tt.apparmor, tt.cg2}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("DockerInfo(%s): wrong capabilities. Want %v. Got %v.", tt.body, expected, got)
+		}
+	}
+}
+
+func TestServerInfoContainerdImageStore(t *testing.T) {
+	var tests = []struct {
+		body     string
+		expected bool
+	}{
+		{`{"Driver":"overlayfs","DriverStatus":[["driver-type","io.containerd.snapshotter.v1"]],"Containerd":{"Address":"/run/containerd/containerd.sock","Namespaces":{"Containers":"moby","Plugins":"plugins.moby"}}}`, true},
+		{`{"Driver":"overlay2","DriverStatus":[["Backing Filesystem","extfs"],["Supports d_type","true"]]}`, false},
+		{`{}`, false},
+	}
+	for _, tt := range tests {
+		client := newTestClient(&FakeRoundTripper{message: tt.body, status: http.StatusOK})
+		info, err := client.ServerInfo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.ContainerdImageStore(); got != tt.expected {
+			t.Errorf("DockerInfo(%s): wrong image store. Want %v. Got %v.", tt.body, tt.expected, got)
+		}
+	}
+	client := newTestClient(&FakeRoundTripper{message: tests[0].body, status: http.StatusOK})
+	info, err := client.ServerInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ContainerdInfo{Address: "/run/containerd/containerd.sock", Namespaces: ContainerdNamespaces{Containers: "moby", Plugins: "plugins.moby"}}
+	if !reflect.DeepEqual(info.Containerd, expected) {
+		t.Errorf("ServerInfo: wrong containerd. Want %#v. Got %#v.", expected, info.Containerd)
+	}
+}
+
+func TestCheckRuntime(t *testing.T) {
+	body := `{"DefaultRuntime":"runc","Runtimes":{"runc":{"path":"runc"},"runsc":{"path":"/usr/local/bin/runsc","runtimeArgs":["--platform=kvm"]}}}`
+	client := newTestClient(&FakeRoundTripper{message: body, status: http.StatusOK})
+	info, err := client.ServerInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := info.RuntimeNames(); !reflect.DeepEqual(names, []string{"runc", "runsc"}) || info.DefaultRuntime != "runc" {
+		t.Errorf("ServerInfo: wrong runtimes. Got %#v and %q.", names, info.DefaultRuntime)
+	}
+	if args := info.Runtimes["runsc"].RuntimeArgs; !reflect.DeepEqual(args, []string{"--platform=kvm"}) {
+		t.Errorf("ServerInfo: wrong runtime args. Got %#v.", args)
+	}
+	if err := client.CheckRuntime("runsc"); err != nil {
+		t.Error(err)
+	}
+	expected := &NoSuchRuntime{Name: "kata", Available: []string{"runc", "runsc"}}
+	if err := client.CheckRuntime("kata"); !reflect.DeepEqual(err, expected) {
+		t.Errorf("CheckRuntime: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+}
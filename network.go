@@ -0,0 +1,91 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrNoSuchNetwork is the error returned when the network does not exist.
+var ErrNoSuchNetwork = errors.New("no such network")
+
+// Network represents a network.
+type Network struct {
+	Name     string            `json:"Name" yaml:"Name"`
+	ID       string            `json:"Id" yaml:"Id"`
+	Scope    string            `json:"Scope,omitempty" yaml:"Scope,omitempty"`
+	Driver   string            `json:"Driver,omitempty" yaml:"Driver,omitempty"`
+	Internal bool              `json:"Internal,omitempty" yaml:"Internal,omitempty"`
+	Options  map[string]string `json:"Options,omitempty" yaml:"Options,omitempty"`
+	Labels   map[string]string `json:"Labels,omitempty" yaml:"Labels,omitempty"`
+}
+
+// CreateNetworkOptions specify parameters to the CreateNetwork function.
+type CreateNetworkOptions struct {
+	Name     string            `json:"Name" yaml:"Name"`
+	Driver   string            `json:"Driver,omitempty" yaml:"Driver,omitempty"`
+	Internal bool              `json:"Internal,omitempty" yaml:"Internal,omitempty"`
+	Options  map[string]string `json:"Options,omitempty" yaml:"Options,omitempty"`
+	Labels   map[string]string `json:"Labels,omitempty" yaml:"Labels,omitempty"`
+}
+
+// NetworkExists reports whether the given network exists. A missing network
+// is not an error. It requires Docker API 1.21 or newer.
+func (c *Client) NetworkExists(id string) (bool, error) {
+	return c.exists("/networks/" + id)
+}
+
+// NetworkInfo returns information about a network by its name or ID. It
+// requires Docker API 1.21 or newer.
+func (c *Client) NetworkInfo(id string) (*Network, error) {
+	body, status, err := c.do("GET", "/networks/"+id, nil, false)
+	if status == http.StatusNotFound {
+		return nil, ErrNoSuchNetwork
+	}
+	if err != nil {
+		return nil, err
+	}
+	var network Network
+	if err := json.Unmarshal(body, &network); err != nil {
+		return nil, err
+	}
+	return &network, nil
+}
+
+// CreateNetwork creates a network, returning it. The daemon picks the
+// default driver, bridge on Linux, when Driver is empty. It requires Docker
+// API 1.21 or newer.
+func (c *Client) CreateNetwork(opts CreateNetworkOptions) (*Network, error) {
+	body, _, err := c.do("POST", "/networks/create", opts, false)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		ID string `json:"Id"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return &Network{
+		Name:     opts.Name,
+		ID:       resp.ID,
+		Driver:   opts.Driver,
+		Internal: opts.Internal,
+		Options:  opts.Options,
+		Labels:   opts.Labels,
+	}, nil
+}
+
+// RemoveNetwork removes a network by its name or ID. It requires Docker API
+// 1.21 or newer.
+func (c *Client) RemoveNetwork(id string) error {
+	_, status, err := c.do("DELETE", "/networks/"+id, nil, false)
+	if status == http.StatusNotFound {
+		return ErrNoSuchNetwork
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestNetworkExists(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Name":"bridge"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	exists, err := client.NetworkExists("bridge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Error("NetworkExists: want true. Got false.")
+	}
+	if path := fakeRT.requests[0].URL.Path; path != "/networks/bridge" {
+		t.Errorf("NetworkExists: wrong path. Want %q. Got %q.", "/networks/bridge", path)
+	}
+	client = newTestClient(&FakeRoundTripper{message: "no such network", status: http.StatusNotFound})
+	exists, err = client.NetworkExists("bridge")
+	if exists || err != nil {
+		t.Errorf("NetworkExists: wrong result for a missing network. Want false, <nil>. Got %v, %v.", exists, err)
+	}
+}
+
+func TestCreateNetwork(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id":"net1","Warning":""}`, status: http.StatusCreated}
+	client := newTestClient(fakeRT)
+	network, err := client.CreateNetwork(CreateNetworkOptions{Name: "backend", Internal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network.ID != "net1" || network.Name != "backend" {
+		t.Errorf("CreateNetwork: wrong network: %#v.", network)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"Name": "backend", "Internal": true}
+	if !reflect.DeepEqual(body, expected) {
+		t.Errorf("CreateNetwork: wrong body. Want %#v. Got %#v.", expected, body)
+	}
+}
+
+func TestNetworkInfoNotFound(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such network", status: http.StatusNotFound})
+	if _, err := client.NetworkInfo("backend"); err != ErrNoSuchNetwork {
+		t.Errorf("NetworkInfo: wrong error. Want %#v. Got %#v.", ErrNoSuchNetwork, err)
+	}
+	if err := client.RemoveNetwork("backend"); err != ErrNoSuchNetwork {
+		t.Errorf("RemoveNetwork: wrong error. Want %#v. Got %#v.", ErrNoSuchNetwork, err)
+	}
+}
@@ -0,0 +1,424 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"strconv"
+	"strings"
+)
+
+// OCISpecVersion is the version of the OCI runtime spec of the specs returned
+// by Container.OCISpec.
+const OCISpecVersion = "1.0.2"
+
+// OCISpec is the configuration of a container in the format of the OCI
+// runtime spec, the config.json of the bundles run by runc, crun or
+// containerd.
+//
+// See https://github.com/opencontainers/runtime-spec/blob/master/config.md
+// for more details.
+type OCISpec struct {
+	Version     string            `json:"ociVersion"`
+	Process     *OCIProcess       `json:"process,omitempty"`
+	Root        *OCIRoot          `json:"root,omitempty"`
+	Hostname    string            `json:"hostname,omitempty"`
+	Mounts      []OCIMount        `json:"mounts,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Linux       *OCILinux         `json:"linux,omitempty"`
+}
+
+// OCIProcess is the process of an OCISpec.
+type OCIProcess struct {
+	Terminal        bool             `json:"terminal,omitempty"`
+	User            OCIUser          `json:"user"`
+	Args            []string         `json:"args,omitempty"`
+	Env             []string         `json:"env,omitempty"`
+	Cwd             string           `json:"cwd"`
+	Capabilities    *OCICapabilities `json:"capabilities,omitempty"`
+	Rlimits         []OCIRlimit      `json:"rlimits,omitempty"`
+	NoNewPrivileges bool             `json:"noNewPrivileges,omitempty"`
+	ApparmorProfile string           `json:"apparmorProfile,omitempty"`
+	OOMScoreAdj     *int             `json:"oomScoreAdj,omitempty"`
+}
+
+// OCIUser is the user of an OCIProcess. The names of the users and groups
+// can only be resolved in the file system of the image, so Username keeps
+// the name of the user, when it's not numeric.
+type OCIUser struct {
+	UID            uint32   `json:"uid"`
+	GID            uint32   `json:"gid"`
+	AdditionalGids []uint32 `json:"additionalGids,omitempty"`
+	Username       string   `json:"username,omitempty"`
+}
+
+// OCICapabilities are the capabilities of an OCIProcess, with the CAP_
+// prefix.
+type OCICapabilities struct {
+	Bounding  []string `json:"bounding"`
+	Effective []string `json:"effective"`
+	Permitted []string `json:"permitted"`
+}
+
+// OCIRlimit is a resource limit of an OCIProcess, like RLIMIT_NOFILE.
+type OCIRlimit struct {
+	Type string `json:"type"`
+	Hard uint64 `json:"hard"`
+	Soft uint64 `json:"soft"`
+}
+
+// OCIRoot is the root file system of an OCISpec, relative to the bundle.
+type OCIRoot struct {
+	Path     string `json:"path"`
+	Readonly bool   `json:"readonly,omitempty"`
+}
+
+// OCIMount is a mount of an OCISpec.
+type OCIMount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// OCILinux is the Linux specific configuration of an OCISpec.
+type OCILinux struct {
+	Namespaces    []OCINamespace    `json:"namespaces,omitempty"`
+	Resources     *OCIResources     `json:"resources,omitempty"`
+	Sysctl        map[string]string `json:"sysctl,omitempty"`
+	MaskedPaths   []string          `json:"maskedPaths,omitempty"`
+	ReadonlyPaths []string          `json:"readonlyPaths,omitempty"`
+}
+
+// OCINamespace is a namespace of an OCILinux. The namespace is created when
+// Path is empty, and joined otherwise.
+type OCINamespace struct {
+	Type string `json:"type"`
+	Path string `json:"path,omitempty"`
+}
+
+// OCIResources are the cgroup limits of an OCILinux.
+type OCIResources struct {
+	Memory  *OCIMemory  `json:"memory,omitempty"`
+	CPU     *OCICPU     `json:"cpu,omitempty"`
+	BlockIO *OCIBlockIO `json:"blockIO,omitempty"`
+}
+
+// OCIMemory are the memory limits of an OCIResources, in bytes.
+type OCIMemory struct {
+	Limit            int64   `json:"limit,omitempty"`
+	Swap             int64   `json:"swap,omitempty"`
+	Swappiness       *uint64 `json:"swappiness,omitempty"`
+	DisableOOMKiller bool    `json:"disableOOMKiller,omitempty"`
+}
+
+// OCICPU are the CPU limits of an OCIResources. Quota and Period, in
+// microseconds, limit the CPU time of the container.
+type OCICPU struct {
+	Shares          uint64 `json:"shares,omitempty"`
+	Quota           int64  `json:"quota,omitempty"`
+	Period          uint64 `json:"period,omitempty"`
+	RealtimeRuntime int64  `json:"realtimeRuntime,omitempty"`
+	RealtimePeriod  uint64 `json:"realtimePeriod,omitempty"`
+	Cpus            string `json:"cpus,omitempty"`
+	Mems            string `json:"mems,omitempty"`
+}
+
+// OCIBlockIO are the block IO limits of an OCIResources.
+type OCIBlockIO struct {
+	Weight uint16 `json:"weight,omitempty"`
+}
+
+// defaultCapabilities are the capabilities the daemon gives to the
+// containers, unless they're privileged.
+var defaultCapabilities = []string{
+	CapChown, CapDACOverride, CapFsetid, CapFowner, CapMknod, CapNetRaw, CapSetgid,
+	CapSetuid, CapSetfcap, CapSetpcap, CapNetBindService, CapSysChroot, CapKill,
+	CapAuditWrite,
+}
+
+// defaultMaskedPaths and defaultReadonlyPaths are the paths the daemon masks,
+// or mounts read-only, in the containers, unless they're privileged.
+var (
+	defaultMaskedPaths = []string{
+		"/proc/asound", "/proc/acpi", "/proc/kcore", "/proc/keys", "/proc/latency_stats",
+		"/proc/timer_list", "/proc/timer_stats", "/proc/sched_debug", "/proc/scsi",
+		"/sys/firmware", "/sys/devices/virtual/powercap",
+	}
+	defaultReadonlyPaths = []string{
+		"/proc/bus", "/proc/fs", "/proc/irq", "/proc/sys", "/proc/sysrq-trigger",
+	}
+)
+
+// defaultShmSize is the size of /dev/shm in the containers, unless
+// HostConfig.ShmSize is set.
+const defaultShmSize = 64 << 20
+
+// cpuPeriod is the period of the CPU quota derived from HostConfig.NanoCpus,
+// in microseconds.
+const cpuPeriod = 100000
+
+// OCISpec returns an approximation of the configuration of the container, as
+// inspected, in the format of the OCI runtime spec, for running it with
+// another runtime, like containerd or podman, or for analysing its security
+// settings. The root file system is expected in the rootfs directory of the
+// bundle.
+//
+// The defaults of the daemon, like the capabilities, the masked paths and the
+// mounts of /proc, /dev and /sys, are made explicit. The settings which can't
+// be expressed without the daemon, like the networks, the published ports,
+// the devices and the seccomp profiles, are left out. Named volumes are
+// bound by name, and the namespaces shared with another container have the
+// path container:<id>: they must be replaced with paths on the host, like
+// the mount point of the volume or /proc/<pid>/ns/net.
+func (c *Container) OCISpec() *OCISpec {
+	config, hostConfig := c.Config, c.HostConfig
+	if config == nil {
+		config = &Config{}
+	}
+	if hostConfig == nil {
+		hostConfig = &HostConfig{}
+	}
+	spec := OCISpec{
+		Version:  OCISpecVersion,
+		Root:     &OCIRoot{Path: "rootfs", Readonly: hostConfig.ReadonlyRootfs},
+		Hostname: config.Hostname,
+	}
+	process := OCIProcess{
+		Terminal:     config.Tty,
+		User:         ociUser(config.User, hostConfig.GroupAdd),
+		Args:         ociArgs(c, config),
+		Env:          config.Env,
+		Cwd:          config.WorkingDir,
+		Capabilities: ociCapabilities(hostConfig),
+	}
+	if process.Cwd == "" {
+		process.Cwd = "/"
+	}
+	for _, ulimit := range hostConfig.Ulimits {
+		process.Rlimits = append(process.Rlimits, OCIRlimit{
+			Type: "RLIMIT_" + strings.ToUpper(ulimit.Name),
+			Hard: uint64(ulimit.Hard),
+			Soft: uint64(ulimit.Soft),
+		})
+	}
+	for _, opt := range hostConfig.SecurityOpt {
+		switch {
+		case opt == "no-new-privileges" || opt == NoNewPrivileges || opt == "no-new-privileges:true":
+			process.NoNewPrivileges = true
+		case strings.HasPrefix(opt, "apparmor=") || strings.HasPrefix(opt, "apparmor:"):
+			process.ApparmorProfile = opt[len("apparmor="):]
+		}
+	}
+	if hostConfig.OomScoreAdj != 0 {
+		score := hostConfig.OomScoreAdj
+		process.OOMScoreAdj = &score
+	}
+	spec.Process = &process
+	spec.Mounts = ociMounts(hostConfig)
+	if len(config.Labels) > 0 || len(hostConfig.Annotations) > 0 {
+		spec.Annotations = make(map[string]string, len(config.Labels)+len(hostConfig.Annotations))
+		for key, value := range config.Labels {
+			spec.Annotations[key] = value
+		}
+		for key, value := range hostConfig.Annotations {
+			spec.Annotations[key] = value
+		}
+	}
+	linux := OCILinux{
+		Namespaces: ociNamespaces(hostConfig),
+		Resources:  ociResources(config, hostConfig),
+		Sysctl:     hostConfig.Sysctls,
+	}
+	if !hostConfig.Privileged {
+		linux.MaskedPaths, linux.ReadonlyPaths = defaultMaskedPaths, defaultReadonlyPaths
+		if len(hostConfig.MaskedPaths) > 0 {
+			linux.MaskedPaths = hostConfig.MaskedPaths
+		}
+		if len(hostConfig.ReadonlyPaths) > 0 {
+			linux.ReadonlyPaths = hostConfig.ReadonlyPaths
+		}
+	}
+	spec.Linux = &linux
+	return &spec
+}
+
+// ociArgs returns the command of the container, as resolved by the daemon
+// from the entrypoint and the command of the container and its image.
+func ociArgs(c *Container, config *Config) []string {
+	if c.Path != "" {
+		return append([]string{c.Path}, c.Args...)
+	}
+	return append(append([]string(nil), config.Entrypoint...), config.Cmd...)
+}
+
+// ociUser parses a user in the format of Config.User: user[:group], by name
+// or ID.
+func ociUser(user string, groups []string) OCIUser {
+	var result OCIUser
+	name, group := user, ""
+	if i := strings.Index(user, ":"); i >= 0 {
+		name, group = user[:i], user[i+1:]
+	}
+	if uid, err := strconv.ParseUint(name, 10, 32); err == nil {
+		result.UID = uint32(uid)
+	} else {
+		result.Username = name
+	}
+	if gid, err := strconv.ParseUint(group, 10, 32); err == nil {
+		result.GID = uint32(gid)
+	}
+	for _, group := range groups {
+		if gid, err := strconv.ParseUint(group, 10, 32); err == nil {
+			result.AdditionalGids = append(result.AdditionalGids, uint32(gid))
+		}
+	}
+	return result
+}
+
+// ociCapabilities applies HostConfig.CapAdd and HostConfig.CapDrop to the
+// default capabilities, or to all of them for privileged containers.
+func ociCapabilities(hostConfig *HostConfig) *OCICapabilities {
+	enabled := make(map[string]bool)
+	if hostConfig.Privileged {
+		for _, name := range capabilities {
+			enabled[name] = true
+		}
+	} else {
+		for _, name := range defaultCapabilities {
+			enabled[name] = true
+		}
+	}
+	for _, name := range hostConfig.CapDrop {
+		name = strings.TrimPrefix(strings.ToUpper(name), "CAP_")
+		if name == CapAll {
+			enabled = make(map[string]bool)
+		}
+		delete(enabled, name)
+	}
+	for _, name := range hostConfig.CapAdd {
+		name = strings.TrimPrefix(strings.ToUpper(name), "CAP_")
+		if name == CapAll {
+			for _, name := range capabilities {
+				enabled[name] = true
+			}
+		}
+		enabled[name] = true
+	}
+	caps := []string{}
+	for _, name := range capabilities {
+		if enabled[name] {
+			caps = append(caps, "CAP_"+name)
+		}
+	}
+	return &OCICapabilities{Bounding: caps, Effective: caps, Permitted: caps}
+}
+
+// ociMounts returns the default mounts of the daemon, followed by the binds
+// and the tmpfs mounts of the container.
+func ociMounts(hostConfig *HostConfig) []OCIMount {
+	shmSize := int64(defaultShmSize)
+	if hostConfig.ShmSize > 0 {
+		shmSize = hostConfig.ShmSize
+	}
+	mounts := []OCIMount{
+		{Destination: "/proc", Type: "proc", Source: "proc", Options: []string{"nosuid", "noexec", "nodev"}},
+		{Destination: "/dev", Type: "tmpfs", Source: "tmpfs", Options: []string{"nosuid", "strictatime", "mode=755", "size=65536k"}},
+		{Destination: "/dev/pts", Type: "devpts", Source: "devpts", Options: []string{"nosuid", "noexec", "newinstance", "ptmxmode=0666", "mode=0620", "gid=5"}},
+		{Destination: "/sys", Type: "sysfs", Source: "sysfs", Options: []string{"nosuid", "noexec", "nodev", "ro"}},
+		{Destination: "/sys/fs/cgroup", Type: "cgroup", Source: "cgroup", Options: []string{"nosuid", "noexec", "nodev", "relatime", "ro"}},
+		{Destination: "/dev/mqueue", Type: "mqueue", Source: "mqueue", Options: []string{"nosuid", "noexec", "nodev"}},
+		{Destination: "/dev/shm", Type: "tmpfs", Source: "shm", Options: []string{"nosuid", "noexec", "nodev", "mode=1777", "size=" + strconv.FormatInt(shmSize, 10)}},
+	}
+	for _, bind := range hostConfig.Binds {
+		parts := strings.SplitN(bind, ":", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		options := []string{"rbind"}
+		if len(parts) == 3 {
+			options = append(options, strings.Split(parts[2], ",")...)
+		}
+		if !containsString(options, "ro") && !containsString(options, "rw") {
+			options = append(options, "rw")
+		}
+		mounts = append(mounts, OCIMount{Destination: parts[1], Type: "bind", Source: parts[0], Options: options})
+	}
+	for _, destination := range sortedKeys(hostConfig.Tmpfs) {
+		options := []string{"nosuid", "nodev", "noexec"}
+		if opts := hostConfig.Tmpfs[destination]; opts != "" {
+			options = append(options, strings.Split(opts, ",")...)
+		}
+		mounts = append(mounts, OCIMount{Destination: destination, Type: "tmpfs", Source: "tmpfs", Options: options})
+	}
+	return mounts
+}
+
+// ociNamespaces returns the namespaces of the container, leaving out the
+// ones shared with the host.
+func ociNamespaces(hostConfig *HostConfig) []OCINamespace {
+	namespaces := []OCINamespace{{Type: "mount"}}
+	modes := []struct {
+		kind string
+		mode string
+	}{
+		{"pid", hostConfig.PidMode},
+		{"network", hostConfig.NetworkMode},
+		{"ipc", hostConfig.IpcMode},
+		{"uts", hostConfig.UTSMode},
+		{"cgroup", hostConfig.CgroupnsMode},
+	}
+	for _, m := range modes {
+		switch {
+		case m.mode == NamespaceHost:
+		case strings.HasPrefix(m.mode, "container:"):
+			namespaces = append(namespaces, OCINamespace{Type: m.kind, Path: m.mode})
+		default:
+			namespaces = append(namespaces, OCINamespace{Type: m.kind})
+		}
+	}
+	return namespaces
+}
+
+// ociResources returns the cgroup limits of the container, or nil when it
+// has none.
+func ociResources(config *Config, hostConfig *HostConfig) *OCIResources {
+	var resources OCIResources
+	memory := OCIMemory{
+		Limit:            config.Memory,
+		Swap:             config.MemorySwap,
+		DisableOOMKiller: hostConfig.OomKillDisable,
+	}
+	if hostConfig.MemorySwappiness != nil && *hostConfig.MemorySwappiness >= 0 {
+		swappiness := uint64(*hostConfig.MemorySwappiness)
+		memory.Swappiness = &swappiness
+	}
+	if memory != (OCIMemory{}) {
+		resources.Memory = &memory
+	}
+	cpu := OCICPU{
+		Shares:          uint64(config.CPUShares),
+		RealtimeRuntime: hostConfig.CPURealtimeRuntime,
+		RealtimePeriod:  uint64(hostConfig.CPURealtimePeriod),
+		Cpus:            hostConfig.CpusetCpus,
+		Mems:            hostConfig.CpusetMems,
+	}
+	if cpu.Cpus == "" {
+		cpu.Cpus = config.CPUSet
+	}
+	if hostConfig.NanoCpus > 0 {
+		cpu.Quota = hostConfig.NanoCpus * cpuPeriod / 1e9
+		cpu.Period = cpuPeriod
+	}
+	if cpu != (OCICPU{}) {
+		resources.CPU = &cpu
+	}
+	if hostConfig.BlkioWeight > 0 {
+		resources.BlockIO = &OCIBlockIO{Weight: hostConfig.BlkioWeight}
+	}
+	if resources == (OCIResources{}) {
+		return nil
+	}
+	return &resources
+}
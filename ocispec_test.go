@@ -0,0 +1,119 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestContainerOCISpec(t *testing.T) {
+	container := Container{
+		Path: "/docker-entrypoint.sh",
+		Args: []string{"nginx", "-g", "daemon off;"},
+		Config: &Config{
+			Hostname:   "web",
+			User:       "101:101",
+			Env:        []string{"PATH=/usr/bin"},
+			Tty:        true,
+			Memory:     512 << 20,
+			CPUShares:  512,
+			Labels:     map[string]string{"app": "shop"},
+			WorkingDir: "/srv",
+		},
+		HostConfig: &HostConfig{
+			Binds:          []string{"/srv/html:/usr/share/nginx/html:ro", "cache:/var/cache/nginx"},
+			Tmpfs:          map[string]string{"/run": "size=1m"},
+			CapDrop:        []string{CapAll},
+			CapAdd:         []string{"cap_net_bind_service", CapChown},
+			SecurityOpt:    []string{NoNewPrivileges, AppArmorProfile("docker-nginx")},
+			Ulimits:        []ULimit{{Name: "nofile", Soft: 1024, Hard: 4096}},
+			NetworkMode:    NamespaceOfContainer("proxy"),
+			PidMode:        NamespaceHost,
+			ReadonlyRootfs: true,
+			NanoCpus:       NanoCPUs(1.5),
+			Sysctls:        map[string]string{"net.core.somaxconn": "1024"},
+			Annotations:    map[string]string{"io.kubernetes.cri-o.Devices": "/dev/fuse"},
+		},
+	}
+	spec := container.OCISpec()
+	process := spec.Process
+	if !reflect.DeepEqual(process.Args, []string{"/docker-entrypoint.sh", "nginx", "-g", "daemon off;"}) {
+		t.Errorf("OCISpec: wrong args. Got %#v.", process.Args)
+	}
+	if process.User.UID != 101 || process.User.GID != 101 || process.Cwd != "/srv" || !process.Terminal {
+		t.Errorf("OCISpec: wrong process. Got %#v.", process)
+	}
+	expectedCaps := []string{"CAP_CHOWN", "CAP_NET_BIND_SERVICE"}
+	if !reflect.DeepEqual(process.Capabilities.Bounding, expectedCaps) {
+		t.Errorf("OCISpec: wrong capabilities. Want %#v. Got %#v.", expectedCaps, process.Capabilities.Bounding)
+	}
+	if !process.NoNewPrivileges || process.ApparmorProfile != "docker-nginx" {
+		t.Errorf("OCISpec: wrong security options. Got %#v.", process)
+	}
+	expectedRlimits := []OCIRlimit{{Type: "RLIMIT_NOFILE", Hard: 4096, Soft: 1024}}
+	if !reflect.DeepEqual(process.Rlimits, expectedRlimits) {
+		t.Errorf("OCISpec: wrong rlimits. Want %#v. Got %#v.", expectedRlimits, process.Rlimits)
+	}
+	if !spec.Root.Readonly || spec.Hostname != "web" {
+		t.Errorf("OCISpec: wrong root or hostname. Got %#v, %q.", spec.Root, spec.Hostname)
+	}
+	expectedMounts := []OCIMount{
+		{Destination: "/usr/share/nginx/html", Type: "bind", Source: "/srv/html", Options: []string{"rbind", "ro"}},
+		{Destination: "/var/cache/nginx", Type: "bind", Source: "cache", Options: []string{"rbind", "rw"}},
+		{Destination: "/run", Type: "tmpfs", Source: "tmpfs", Options: []string{"nosuid", "nodev", "noexec", "size=1m"}},
+	}
+	if mounts := spec.Mounts[len(spec.Mounts)-3:]; !reflect.DeepEqual(mounts, expectedMounts) {
+		t.Errorf("OCISpec: wrong mounts. Want %#v. Got %#v.", expectedMounts, mounts)
+	}
+	expectedNamespaces := []OCINamespace{
+		{Type: "mount"},
+		{Type: "network", Path: "container:proxy"},
+		{Type: "ipc"},
+		{Type: "uts"},
+		{Type: "cgroup"},
+	}
+	if !reflect.DeepEqual(spec.Linux.Namespaces, expectedNamespaces) {
+		t.Errorf("OCISpec: wrong namespaces. Want %#v. Got %#v.", expectedNamespaces, spec.Linux.Namespaces)
+	}
+	expectedCPU := &OCICPU{Shares: 512, Quota: 150000, Period: 100000}
+	if !reflect.DeepEqual(spec.Linux.Resources.CPU, expectedCPU) {
+		t.Errorf("OCISpec: wrong CPU limits. Want %#v. Got %#v.", expectedCPU, spec.Linux.Resources.CPU)
+	}
+	if spec.Linux.Resources.Memory.Limit != 512<<20 {
+		t.Errorf("OCISpec: wrong memory limit. Got %d.", spec.Linux.Resources.Memory.Limit)
+	}
+	expectedAnnotations := map[string]string{"app": "shop", "io.kubernetes.cri-o.Devices": "/dev/fuse"}
+	if !reflect.DeepEqual(spec.Annotations, expectedAnnotations) {
+		t.Errorf("OCISpec: wrong annotations. Want %#v. Got %#v.", expectedAnnotations, spec.Annotations)
+	}
+	if !reflect.DeepEqual(spec.Linux.MaskedPaths, defaultMaskedPaths) {
+		t.Errorf("OCISpec: wrong masked paths. Got %#v.", spec.Linux.MaskedPaths)
+	}
+	if _, err := json.Marshal(spec); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContainerOCISpecPrivileged(t *testing.T) {
+	container := Container{
+		Config:     &Config{Image: "docker:dind", User: "builder"},
+		HostConfig: &HostConfig{Privileged: true},
+	}
+	spec := container.OCISpec()
+	if len(spec.Process.Capabilities.Bounding) != len(capabilities) {
+		t.Errorf("OCISpec: wrong number of capabilities. Want %d. Got %d.", len(capabilities), len(spec.Process.Capabilities.Bounding))
+	}
+	if spec.Linux.MaskedPaths != nil || spec.Linux.ReadonlyPaths != nil {
+		t.Errorf("OCISpec: unexpected masked paths for a privileged container. Got %#v.", spec.Linux)
+	}
+	if spec.Process.User.Username != "builder" || spec.Process.Cwd != "/" {
+		t.Errorf("OCISpec: wrong process. Got %#v.", spec.Process)
+	}
+	if spec.Linux.Resources != nil {
+		t.Errorf("OCISpec: unexpected resources. Got %#v.", spec.Linux.Resources)
+	}
+}
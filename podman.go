@@ -0,0 +1,115 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"errors"
+	"strings"
+)
+
+// CompatMode selects the flavor of the Docker API spoken by the daemon.
+type CompatMode int
+
+const (
+	// CompatAuto detects the daemon from the response of the /version
+	// endpoint.
+	CompatAuto CompatMode = iota
+
+	// CompatDocker assumes the daemon is Docker.
+	CompatDocker
+
+	// CompatPodman assumes the daemon is Podman, serving its Docker
+	// compatible API, as in podman.socket.
+	CompatPodman
+)
+
+// ErrNotSupportedByPodman is returned by the methods that call endpoints
+// Podman doesn't implement in its compatible API.
+var ErrNotSupportedByPodman = errors.New("operation not supported by Podman")
+
+// IsPodman reports whether the client talks to Podman, according to the
+// Compat field of the client. With CompatAuto, the daemon is detected with a
+// call to the /version endpoint, unless it was already detected while
+// negotiating the API version.
+//
+// When talking to Podman, the client adjusts the calls that behave
+// differently in its compatible API:
+//
+//	WaitContainer explicitly waits for the container to stop running
+//	BuildImage uses the classic builder, as Podman doesn't run BuildKit
+//	CancelBuild and InspectDistribution return ErrNotSupportedByPodman
+//
+// These calls never detect the daemon themselves: with CompatAuto, they only
+// adjust to Podman when it was detected, by IsPodman or while negotiating
+// the API version.
+func (c *Client) IsPodman() (bool, error) {
+	switch c.Compat {
+	case CompatDocker:
+		return false, nil
+	case CompatPodman:
+		return true, nil
+	}
+	// The daemon is detected while negotiating the API version, saving a
+	// call to /version.
+	if err := c.ensureAPIVersion(""); err != nil {
+		return false, err
+	}
+	if podman, ok := c.detectedPodman(); ok {
+		return podman, nil
+	}
+	version, err := c.ServerVersion()
+	if err != nil {
+		return false, err
+	}
+	isPodman := isPodmanVersion(version)
+	c.setPodman(isPodman)
+	return isPodman, nil
+}
+
+// isPodman is like IsPodman, without calling /version. With CompatAuto, the
+// daemon is assumed to be Docker unless it was already detected, or is
+// detected by the negotiation of the API version the adjusted call would do
+// anyway. A failed negotiation is left to be reported by that call.
+func (c *Client) isPodman() bool {
+	switch c.Compat {
+	case CompatDocker:
+		return false
+	case CompatPodman:
+		return true
+	}
+	if err := c.ensureAPIVersion(""); err != nil {
+		return false
+	}
+	podman, _ := c.detectedPodman()
+	return podman
+}
+
+// detectedPodman returns whether the daemon was detected as Podman, and
+// whether it was detected at all.
+func (c *Client) detectedPodman() (podman bool, ok bool) {
+	c.compatMut.Lock()
+	defer c.compatMut.Unlock()
+	if c.podman == nil {
+		return false, false
+	}
+	return *c.podman, true
+}
+
+func (c *Client) setPodman(podman bool) {
+	c.compatMut.Lock()
+	c.podman = &podman
+	c.compatMut.Unlock()
+}
+
+// isPodmanVersion reports whether the given /version response comes from
+// Podman, which lists a "Podman Engine" component.
+func isPodmanVersion(version *DockerVersion) bool {
+	for _, component := range version.Components {
+		if strings.Contains(strings.ToLower(component.Name), "podman") {
+			return true
+		}
+	}
+	return false
+}
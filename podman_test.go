@@ -0,0 +1,155 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const podmanVersion = `{"Platform":{"Name":"linux/amd64/fedora-38"},"Components":[{"Name":"Podman Engine","Version":"4.6.1","Details":{"APIVersion":"4.6.1","Os":"linux"}}],"Version":"4.6.1","ApiVersion":"1.41","MinAPIVersion":"1.24","Os":"linux","Arch":"amd64"}`
+
+// podmanServer returns a server answering /version as Podman does, and
+// recording the requests it gets.
+func podmanServer(version string) (*httptest.Server, *[]*http.Request) {
+	var mut sync.Mutex
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		requests = append(requests, r)
+		mut.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/version"):
+			w.Write([]byte(version))
+		case strings.HasSuffix(r.URL.Path, "/wait"):
+			w.Write([]byte(`{"StatusCode":0}`))
+		default:
+			w.Write([]byte(`{"stream":"done"}`))
+		}
+	}))
+	return server, &requests
+}
+
+func TestIsPodman(t *testing.T) {
+	var tests = []struct {
+		version  string
+		expected bool
+	}{
+		{podmanVersion, true},
+		{`{"Version":"1.5.0","ApiVersion":"1.17"}`, false},
+		{`{"Version":"24.0.5","ApiVersion":"1.43","Components":[{"Name":"Engine","Version":"24.0.5"}]}`, false},
+	}
+	for _, tt := range tests {
+		server, requests := podmanServer(tt.version)
+		client, err := NewClient(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			podman, err := client.IsPodman()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if podman != tt.expected {
+				t.Errorf("IsPodman(%s): wrong result. Want %v. Got %v.", tt.version, tt.expected, podman)
+			}
+		}
+		if len(*requests) != 1 {
+			t.Errorf("IsPodman: detection should be cached. Got %d requests.", len(*requests))
+		}
+		server.Close()
+	}
+}
+
+func TestIsPodmanCompat(t *testing.T) {
+	server, requests := podmanServer(podmanVersion)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Compat = CompatDocker
+	if podman, _ := client.IsPodman(); podman {
+		t.Error("IsPodman: should honor CompatDocker")
+	}
+	client.Compat = CompatPodman
+	if podman, _ := client.IsPodman(); !podman {
+		t.Error("IsPodman: should honor CompatPodman")
+	}
+	if len(*requests) != 0 {
+		t.Errorf("IsPodman: should not detect the daemon when Compat is set. Got %d requests.", len(*requests))
+	}
+}
+
+func TestIsPodmanDetectedOnVersionNegotiation(t *testing.T) {
+	server, requests := podmanServer(podmanVersion)
+	defer server.Close()
+	client, err := NewVersionedClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.WaitContainer("abc"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*requests) != 2 {
+		t.Fatalf("WaitContainer: wrong number of requests. Want 2. Got %d.", len(*requests))
+	}
+	if got := (*requests)[1].URL.Query().Get("condition"); got != "not-running" {
+		t.Errorf("WaitContainer: wrong wait condition. Want %q. Got %q.", "not-running", got)
+	}
+}
+
+func TestIsPodmanNotDetectedByCalls(t *testing.T) {
+	server, requests := podmanServer(podmanVersion)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.WaitContainer("abc"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*requests) != 2 {
+		t.Fatalf("WaitContainer: wrong number of requests. Want 2. Got %d.", len(*requests))
+	}
+	if got := (*requests)[0].URL.Path; got != "/containers/abc/wait" {
+		t.Errorf("WaitContainer: wrong path. Want %q. Got %q.", "/containers/abc/wait", got)
+	}
+}
+
+func TestPodmanAdjustments(t *testing.T) {
+	server, requests := podmanServer(podmanVersion)
+	defer server.Close()
+	client, err := NewVersionedClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	opts := BuildImageOptions{
+		Name:         "test",
+		InputStream:  &bytes.Buffer{},
+		OutputStream: &buf,
+		Version:      BuilderBuildKit,
+	}
+	if err := client.BuildImage(opts); err != nil {
+		t.Fatal(err)
+	}
+	build := (*requests)[len(*requests)-1]
+	if got := build.URL.Query().Get("version"); got != string(BuilderV1) {
+		t.Errorf("BuildImage: wrong builder version. Want %q. Got %q.", BuilderV1, got)
+	}
+	if err := client.CancelBuild("build-1"); err != ErrNotSupportedByPodman {
+		t.Errorf("CancelBuild: wrong error. Want %#v. Got %#v.", ErrNotSupportedByPodman, err)
+	}
+	if _, err := client.InspectDistribution("busybox", AuthConfiguration{}); err != ErrNotSupportedByPodman {
+		t.Errorf("InspectDistribution: wrong error. Want %#v. Got %#v.", ErrNotSupportedByPodman, err)
+	}
+}
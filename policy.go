@@ -0,0 +1,86 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// RequestPolicy decides whether the client may send a request to the daemon,
+// so embedders can restrict their own use of the socket of the daemon.
+// CheckRequest is given the method and the path of the request, without the
+// API version prefix nor the query string, like "POST" and
+// "/containers/abc/exec". The request is not sent when it returns an error,
+// which is returned by the call.
+type RequestPolicy interface {
+	CheckRequest(method, path string) error
+}
+
+// ErrRequestDenied is returned by the calls denied by an EndpointPolicy.
+type ErrRequestDenied struct {
+	Method string
+	Path   string
+}
+
+func (err *ErrRequestDenied) Error() string {
+	return fmt.Sprintf("request %s %s denied by the policy of the client", err.Method, err.Path)
+}
+
+// EndpointPolicy is a RequestPolicy allowing or denying the requests by the
+// endpoint they reach. The patterns match the paths of the requests and the
+// paths below them, segment by segment, with the syntax of path.Match, like
+// "/plugins" or "/containers/*/exec". They may start with a method, like
+// "DELETE /images".
+type EndpointPolicy struct {
+	// Denied lists the patterns of the denied requests.
+	Denied []string
+
+	// Allowed, when not empty, lists the patterns of the only requests
+	// allowed, unless they're denied. Versioned clients need "GET
+	// /version" to negotiate the API version with the daemon.
+	Allowed []string
+}
+
+// CheckRequest implements the RequestPolicy interface.
+func (p *EndpointPolicy) CheckRequest(method, path string) error {
+	for _, pattern := range p.Denied {
+		if matchEndpoint(pattern, method, path) {
+			return &ErrRequestDenied{Method: method, Path: path}
+		}
+	}
+	if len(p.Allowed) == 0 {
+		return nil
+	}
+	for _, pattern := range p.Allowed {
+		if matchEndpoint(pattern, method, path) {
+			return nil
+		}
+	}
+	return &ErrRequestDenied{Method: method, Path: path}
+}
+
+// matchEndpoint tells whether the pattern of an EndpointPolicy matches the
+// given request.
+func matchEndpoint(pattern, method, p string) bool {
+	if i := strings.Index(pattern, " "); i >= 0 {
+		if !strings.EqualFold(pattern[:i], method) {
+			return false
+		}
+		pattern = strings.TrimSpace(pattern[i+1:])
+	}
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	if len(segments) < len(patternSegments) {
+		return false
+	}
+	for i, patternSegment := range patternSegments {
+		if ok, err := path.Match(patternSegment, segments[i]); !ok || err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestEndpointPolicy(t *testing.T) {
+	policy := &EndpointPolicy{
+		Denied:  []string{"/plugins", "/swarm", "POST /containers/*/exec", "DELETE /images"},
+		Allowed: []string{"/containers", "/images", "GET /version"},
+	}
+	var tests = []struct {
+		method  string
+		path    string
+		allowed bool
+	}{
+		{"GET", "/containers/json", true},
+		{"POST", "/containers/abc/start", true},
+		{"POST", "/containers/abc/exec", false},
+		{"GET", "/plugins", false},
+		{"POST", "/plugins/pull", false},
+		{"POST", "/swarm/init", false},
+		{"GET", "/images/busybox/json", true},
+		{"DELETE", "/images/busybox", false},
+		{"GET", "/version", true},
+		{"GET", "/volumes", false},
+	}
+	for _, tt := range tests {
+		err := policy.CheckRequest(tt.method, tt.path)
+		if tt.allowed && err != nil {
+			t.Errorf("CheckRequest(%q, %q): unexpected error: %s", tt.method, tt.path, err)
+		}
+		if !tt.allowed && !reflect.DeepEqual(err, &ErrRequestDenied{Method: tt.method, Path: tt.path}) {
+			t.Errorf("CheckRequest(%q, %q): wrong error. Want *ErrRequestDenied. Got %#v.", tt.method, tt.path, err)
+		}
+	}
+}
+
+func TestClientRequestPolicy(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "[]", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	client.RequestPolicy = &EndpointPolicy{Denied: []string{"/plugins"}}
+	if _, err := client.ListContainers(ListContainersOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Do("GET", "/plugins?filters=%7B%7D", DoOptions{})
+	expected := &ErrRequestDenied{Method: "GET", Path: "/plugins"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("Do: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+	if len(fakeRT.requests) != 1 {
+		t.Errorf("RequestPolicy: the denied calls should not be sent. Got %d requests.", len(fakeRT.requests))
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net"
+	"strconv"
+)
+
+// HostPort is a port of the host where a port of a container is published.
+// IP is the address the port is bound to, "0.0.0.0" for all the interfaces.
+type HostPort struct {
+	IP   string
+	Port int
+}
+
+// String returns the address of the port, in the form ip:port.
+func (p HostPort) String() string {
+	return net.JoinHostPort(p.IP, strconv.Itoa(p.Port))
+}
+
+// PublishRandomPorts exposes the given ports of a container and publishes
+// them on random ports of the host, chosen by the daemon when the container
+// starts. Use HostPorts to find out which ones.
+func PublishRandomPorts(config *Config, hostConfig *HostConfig, ports ...Port) {
+	if config.ExposedPorts == nil {
+		config.ExposedPorts = make(map[Port]struct{}, len(ports))
+	}
+	if hostConfig.PortBindings == nil {
+		hostConfig.PortBindings = make(map[Port][]PortBinding, len(ports))
+	}
+	for _, port := range ports {
+		config.ExposedPorts[port] = struct{}{}
+		hostConfig.PortBindings[port] = []PortBinding{{}}
+	}
+}
+
+// HostPorts returns the ports of the host where the ports of the container
+// are published. When a port is published on several addresses, the IPv4
+// one is preferred.
+func (settings *NetworkSettings) HostPorts() map[Port]HostPort {
+	ports := make(map[Port]HostPort)
+	for port, bindings := range settings.Ports {
+		for _, binding := range bindings {
+			number, err := parsePort(binding.HostPort)
+			if err != nil {
+				continue
+			}
+			if _, ok := ports[port]; ok && net.ParseIP(binding.HostIP).To4() == nil {
+				continue
+			}
+			ports[port] = HostPort{IP: binding.HostIP, Port: number}
+		}
+	}
+	return ports
+}
+
+// HostPorts inspects the given container, that must be running, and
+// returns the ports of the host where its ports are published.
+func (c *Client) HostPorts(id string) (map[Port]HostPort, error) {
+	container, err := c.InspectContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	if container.NetworkSettings == nil {
+		return map[Port]HostPort{}, nil
+	}
+	return container.NetworkSettings.HostPorts(), nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPublishRandomPorts(t *testing.T) {
+	config := Config{ExposedPorts: map[Port]struct{}{"22/tcp": {}}}
+	var hostConfig HostConfig
+	PublishRandomPorts(&config, &hostConfig, "80/tcp", "53/udp")
+	expectedExposed := map[Port]struct{}{"22/tcp": {}, "80/tcp": {}, "53/udp": {}}
+	if !reflect.DeepEqual(config.ExposedPorts, expectedExposed) {
+		t.Errorf("PublishRandomPorts: wrong exposed ports. Want %#v. Got %#v.", expectedExposed, config.ExposedPorts)
+	}
+	expectedBindings := map[Port][]PortBinding{"80/tcp": {{}}, "53/udp": {{}}}
+	if !reflect.DeepEqual(hostConfig.PortBindings, expectedBindings) {
+		t.Errorf("PublishRandomPorts: wrong bindings. Want %#v. Got %#v.", expectedBindings, hostConfig.PortBindings)
+	}
+}
+
+func TestHostPorts(t *testing.T) {
+	jsonContainer := `{
+     "Id": "4fa6e0f0c678",
+     "NetworkSettings": {
+          "Ports": {
+               "80/tcp": [{"HostIp": "::", "HostPort": "49154"}, {"HostIp": "0.0.0.0", "HostPort": "49153"}],
+               "53/udp": [{"HostIp": "127.0.0.1", "HostPort": "49155"}],
+               "22/tcp": null
+          }
+     }
+}`
+	client := newTestClient(&FakeRoundTripper{message: jsonContainer, status: http.StatusOK})
+	ports, err := client.HostPorts("4fa6e0f0c678")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[Port]HostPort{
+		"80/tcp": {IP: "0.0.0.0", Port: 49153},
+		"53/udp": {IP: "127.0.0.1", Port: 49155},
+	}
+	if !reflect.DeepEqual(ports, expected) {
+		t.Errorf("HostPorts: wrong ports. Want %#v. Got %#v.", expected, ports)
+	}
+	if addr := ports["53/udp"].String(); addr != "127.0.0.1:49155" {
+		t.Errorf("HostPort.String: wrong address. Want %q. Got %q.", "127.0.0.1:49155", addr)
+	}
+}
+
+func TestHostPortsNotFound(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	_, err := client.HostPorts("a2344")
+	if _, ok := err.(*NoSuchContainer); !ok {
+		t.Errorf("HostPorts: wrong error. Want a *NoSuchContainer. Got %#v.", err)
+	}
+}
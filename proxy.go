@@ -0,0 +1,157 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// SetProxy sets the function that selects the proxy used to reach tcp://
+// endpoints, instead of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables. It replaces HTTPClient with a client using the proxy, so it must
+// be called before the first call to the API, and after any change to
+// TLSConfig. A nil proxy function disables proxies.
+//
+// Connections to unix sockets never go through a proxy.
+func (c *Client) SetProxy(proxy func(*http.Request) (*url.URL, error)) {
+	if proxy == nil {
+		proxy = noProxy
+	}
+	c.proxy = proxy
+	c.HTTPClient = &http.Client{Transport: c.newTransport()}
+}
+
+// SetDialer sets the function used to open the connections to the daemon,
+// over tcp or unix sockets, instead of net.Dial. It's also used by the
+// hijacked connections of attach, exec and the event listeners, over TLS or
+// not. It may, for example, route them through a SOCKS proxy or a VPN, or
+// intercept them in tests. Like SetProxy, it replaces HTTPClient, so it must
+// be called before the first call to the API.
+func (c *Client) SetDialer(dial func(network, address string) (net.Conn, error)) {
+	c.dialer = dial
+	c.HTTPClient = &http.Client{Transport: c.newTransport()}
+}
+
+// SetTransport sets the http.RoundTripper used to send the requests to tcp://
+// endpoints. Hijacked connections, used by attach and exec, and requests to
+// unix sockets don't go through it, but through the dialer of the client.
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.HTTPClient = &http.Client{Transport: transport}
+}
+
+// newTransport returns a transport using the proxy, dialer and TLS settings
+// of the client.
+func (c *Client) newTransport() *http.Transport {
+	proxy := c.proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+	return &http.Transport{Proxy: proxy, Dial: c.dialer, TLSClientConfig: c.TLSConfig}
+}
+
+// dial opens a connection to the given address, with the dialer of the
+// client. Failures to reach a unix socket are reported as
+// *ErrConnectionFailed when their cause is known.
+func (c *Client) dial(network, address string) (net.Conn, error) {
+	dial := c.dialer
+	if dial == nil {
+		dial = net.Dial
+	}
+	conn, err := dial(network, address)
+	if err != nil && network == "unix" {
+		return nil, socketError(address, err)
+	}
+	return conn, err
+}
+
+func noProxy(*http.Request) (*url.URL, error) {
+	return nil, nil
+}
+
+// proxyFor returns the proxy to use for the given request, or nil when it
+// must be sent directly to the daemon.
+func (c *Client) proxyFor(req *http.Request) (*url.URL, error) {
+	if c.endpointURL.Scheme == "unix" {
+		return nil, nil
+	}
+	if c.proxy != nil {
+		return c.proxy(req)
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// dialHijack opens the connection used by a hijacked request, through the
+// proxy selected for the request, if any, and using TLS when the client is
+// configured to.
+func (c *Client) dialHijack(req *http.Request) (net.Conn, error) {
+	if c.endpointURL.Scheme == "unix" {
+		return c.dial("unix", c.endpointURL.Path)
+	}
+	address := c.endpointURL.Host
+	proxyURL, err := c.proxyFor(req)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil && c.TLSConfig != nil && c.dialer == nil {
+		return tlsDial("tcp", address, c.TLSConfig)
+	}
+	var conn net.Conn
+	if proxyURL == nil {
+		conn, err = c.dial("tcp", address)
+	} else {
+		conn, err = dialThroughProxy(c.dial, proxyURL, address)
+	}
+	if err != nil || c.TLSConfig == nil {
+		return conn, err
+	}
+	return tlsHandshake(conn, address, c.TLSConfig, nil)
+}
+
+// dialThroughProxy opens a tunnel to the given address with a CONNECT
+// request to an HTTP proxy, reached with the given dial function.
+func dialThroughProxy(dial func(network, address string) (net.Conn, error), proxyURL *url.URL, address string) (net.Conn, error) {
+	if proxyURL.Scheme != "http" && proxyURL.Scheme != "" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	proxyAddress := proxyURL.Host
+	if _, _, err := net.SplitHostPort(proxyAddress); err != nil {
+		proxyAddress = net.JoinHostPort(proxyAddress, "80")
+	}
+	conn, err := dial("tcp", proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", proxyURL.Host, address, resp.Status)
+	}
+	return conn, nil
+}
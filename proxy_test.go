@@ -0,0 +1,292 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer backend.Close()
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.RequestURI)
+		resp, err := (&http.Transport{}).RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	client, err := NewClient(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetProxy(http.ProxyURL(proxyURL))
+	if err := client.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if len(proxied) != 1 || proxied[0] != backend.URL+"/_ping" {
+		t.Errorf("SetProxy: the request should go through the proxy. Got %#v.", proxied)
+	}
+}
+
+func TestProxyForUnixSocket(t *testing.T) {
+	client, err := NewClient("unix:///var/run/docker.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetProxy(http.ProxyURL(&url.URL{Scheme: "http", Host: "proxy:3128"}))
+	req, _ := http.NewRequest("GET", client.getURL("/_ping"), nil)
+	if proxyURL, err := client.proxyFor(req); proxyURL != nil || err != nil {
+		t.Errorf("proxyFor: unix sockets should not use a proxy. Got %v, %v.", proxyURL, err)
+	}
+}
+
+// connectProxy accepts a single CONNECT request, answering with the given
+// status, and then echoes what it receives.
+func connectProxy(t *testing.T, status string, requests chan<- *http.Request) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		requests <- req
+		io.WriteString(conn, "HTTP/1.1 "+status+"\r\n\r\n")
+		io.Copy(conn, br)
+	}()
+	return l
+}
+
+func TestDialThroughProxy(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	l := connectProxy(t, "200 Connection established", requests)
+	defer l.Close()
+	proxyURL := &url.URL{Scheme: "http", Host: l.Addr().String(), User: url.UserPassword("user", "secret")}
+	conn, err := dialThroughProxy(net.Dial, proxyURL, "docker.example.com:2375")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	req := <-requests
+	if req.Method != "CONNECT" || req.Host != "docker.example.com:2375" {
+		t.Errorf("dialThroughProxy: wrong request: %s %s.", req.Method, req.Host)
+	}
+	if auth := req.Header.Get("Proxy-Authorization"); auth != "Basic dXNlcjpzZWNyZXQ=" {
+		t.Errorf("dialThroughProxy: wrong credentials. Got %q.", auth)
+	}
+	io.WriteString(conn, "ping")
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "ping" {
+		t.Errorf("dialThroughProxy: wrong tunnel output. Want %q. Got %q.", "ping", buf)
+	}
+}
+
+// tunnelProxy accepts a single CONNECT request, and tunnels the connection
+// to the given address.
+func tunnelProxy(t *testing.T, address string, requests chan<- *http.Request) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		requests <- req
+		backend, err := net.Dial("tcp", address)
+		if err != nil {
+			io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+			return
+		}
+		defer backend.Close()
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go io.Copy(backend, br)
+		io.Copy(conn, backend)
+	}()
+	return l
+}
+
+func TestAddEventListenerThroughProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"create","id":"dfdf82bd3881","from":"base:latest","time":1374067924}`))
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+	requests := make(chan *http.Request, 1)
+	l := tunnelProxy(t, server.Listener.Addr().String(), requests)
+	defer l.Close()
+	client, err := NewClient("tcp://docker.example.com:2375")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.SetProxy(http.ProxyURL(&url.URL{Scheme: "http", Host: l.Addr().String()}))
+	listener := make(chan *APIEvents, 10)
+	defer client.RemoveEventListener(listener)
+	if err := client.AddEventListener(listener); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-listener:
+		if event.ID != "dfdf82bd3881" {
+			t.Errorf("AddEventListener: wrong event. Got %#v.", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AddEventListener: timed out waiting on events.")
+	}
+	if req := <-requests; req.Method != "CONNECT" || req.Host != "docker.example.com:2375" {
+		t.Errorf("AddEventListener: wrong proxy request: %s %s.", req.Method, req.Host)
+	}
+}
+
+func TestDialThroughProxyRefused(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	l := connectProxy(t, "407 Proxy Authentication Required", requests)
+	defer l.Close()
+	_, err := dialThroughProxy(net.Dial, &url.URL{Scheme: "http", Host: l.Addr().String()}, "docker.example.com:2375")
+	if err == nil || !strings.Contains(err.Error(), "407") {
+		t.Errorf("dialThroughProxy: wrong error. Got %v.", err)
+	}
+}
+
+func TestDialThroughProxyUnsupportedScheme(t *testing.T) {
+	_, err := dialThroughProxy(net.Dial, &url.URL{Scheme: "socks5", Host: "127.0.0.1:1080"}, "docker.example.com:2375")
+	if err == nil {
+		t.Error("dialThroughProxy: expected an error for a socks5 proxy.")
+	}
+}
+
+func TestSetDialer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+	client, err := NewClient("tcp://docker.example.com:2375")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dialed []string
+	client.SetDialer(func(network, address string) (net.Conn, error) {
+		dialed = append(dialed, network+" "+address)
+		return net.Dial("tcp", server.Listener.Addr().String())
+	})
+	client.SetProxy(nil)
+	if err := client.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", client.getURL("/containers/abc/attach"), nil)
+	conn, err := client.dialHijack(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	expected := "tcp docker.example.com:2375"
+	if len(dialed) != 2 || dialed[0] != expected || dialed[1] != expected {
+		t.Errorf("SetDialer: wrong dials. Want two dials to %q. Got %#v.", expected, dialed)
+	}
+}
+
+func TestAddEventListenerTLSWithDialer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"create","id":"dfdf82bd3881","from":"base:latest","time":1374067924}`))
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+	cert, err := x509.ParseCertificate(server.TLS.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client, err := NewClient("tcp://127.0.0.1:2376")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.TLSConfig = &tls.Config{RootCAs: pool}
+	var mut sync.Mutex
+	var dialed []string
+	client.SetDialer(func(network, address string) (net.Conn, error) {
+		mut.Lock()
+		dialed = append(dialed, network+" "+address)
+		mut.Unlock()
+		return net.Dial("tcp", server.Listener.Addr().String())
+	})
+	client.SetProxy(nil)
+	listener := make(chan *APIEvents, 10)
+	defer client.RemoveEventListener(listener)
+	if err := client.AddEventListener(listener); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-listener:
+		if event.ID != "dfdf82bd3881" {
+			t.Errorf("AddEventListener: wrong event. Got %#v.", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AddEventListener: timed out waiting on events.")
+	}
+	mut.Lock()
+	defer mut.Unlock()
+	if len(dialed) == 0 || dialed[0] != "tcp 127.0.0.1:2376" {
+		t.Errorf("AddEventListener: the dialer of the client should be used. Got %#v.", dialed)
+	}
+}
+
+func TestSetTransport(t *testing.T) {
+	client, err := NewClient("tcp://docker.example.com:2375")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeRT := &FakeRoundTripper{message: "OK", status: http.StatusOK}
+	client.SetTransport(fakeRT)
+	if err := client.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fakeRT.requests) != 1 || fakeRT.requests[0].URL.Path != "/_ping" {
+		t.Errorf("SetTransport: wrong requests. Got %#v.", fakeRT.requests)
+	}
+}
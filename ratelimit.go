@@ -0,0 +1,120 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a client-side rate limiter for calls to the Docker API. It
+// keeps one token bucket per endpoint class, where the class of an endpoint is
+// the first segment of its path (for example, "containers", "images" or
+// "exec").
+//
+// A RateLimiter can be assigned to the RateLimiter field of a Client. It's
+// only applied to regular API calls: streaming endpoints (logs, attach,
+// event listeners, image pulls, builds and so on) are never throttled.
+type RateLimiter struct {
+	rate    float64
+	burst   int
+	limits  map[string]rateLimit
+	buckets map[string]*tokenBucket
+	mut     sync.Mutex
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+type rateLimit struct {
+	rate  float64
+	burst int
+}
+
+type tokenBucket struct {
+	rateLimit
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter that allows, for each endpoint class,
+// up to rate calls per second, with bursts of at most burst calls.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:    rate,
+		burst:   burst,
+		limits:  make(map[string]rateLimit),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+		sleep:   time.Sleep,
+	}
+}
+
+// SetLimit overrides the rate and burst used for the given endpoint class.
+func (l *RateLimiter) SetLimit(class string, rate float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.limits[class] = rateLimit{rate: rate, burst: burst}
+	delete(l.buckets, class)
+}
+
+// Wait blocks until a call to the given path is allowed by the limiter.
+func (l *RateLimiter) Wait(path string) {
+	if d := l.reserve(endpointClass(path)); d > 0 {
+		l.sleep(d)
+	}
+}
+
+// reserve takes a token from the bucket of the given class, returning how
+// long the caller must wait before the token becomes available.
+func (l *RateLimiter) reserve(class string) time.Duration {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	now := l.now()
+	b, ok := l.buckets[class]
+	if !ok {
+		limit, ok := l.limits[class]
+		if !ok {
+			limit = rateLimit{rate: l.rate, burst: l.burst}
+		}
+		b = &tokenBucket{rateLimit: limit, tokens: float64(limit.burst), last: now}
+		l.buckets[class] = b
+	}
+	if b.rate <= 0 {
+		return 0
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > float64(b.burst) {
+			b.tokens = float64(b.burst)
+		}
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// endpointClass returns the class of the given API path, which is its first
+// segment.
+func endpointClass(path string) string {
+	if i := strings.IndexAny(path, "?#"); i > -1 {
+		path = path[:i]
+	}
+	path = strings.TrimPrefix(path, "/")
+	if i := strings.Index(path, "/"); i > -1 {
+		path = path[:i]
+	}
+	return path
+}
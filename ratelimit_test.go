@@ -0,0 +1,183 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.slept = append(c.slept, d)
+	c.now = c.now.Add(d)
+}
+
+func newFakeRateLimiter(rate float64, burst int) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1420070400, 0)}
+	limiter := NewRateLimiter(rate, burst)
+	limiter.now = clock.Now
+	limiter.sleep = clock.Sleep
+	return limiter, clock
+}
+
+func TestEndpointClass(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected string
+	}{
+		{"/containers/json?all=1", "containers"},
+		{"/containers/abc/start", "containers"},
+		{"/images/create", "images"},
+		{"/exec/abc/json", "exec"},
+		{"/_ping", "_ping"},
+		{"/version", "version"},
+		{"/info?x=1", "info"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := endpointClass(tt.input); got != tt.expected {
+			t.Errorf("endpointClass(%q): wrong class. Want %q. Got %q.", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	limiter, clock := newFakeRateLimiter(2, 3)
+	for i := 0; i < 3; i++ {
+		limiter.Wait("/containers/json")
+	}
+	if len(clock.slept) != 0 {
+		t.Fatalf("RateLimiter: unexpected wait within burst: %v", clock.slept)
+	}
+	limiter.Wait("/containers/json")
+	expected := []time.Duration{500 * time.Millisecond}
+	if len(clock.slept) != 1 || clock.slept[0] != expected[0] {
+		t.Errorf("RateLimiter: wrong waits. Want %v. Got %v.", expected, clock.slept)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	limiter, clock := newFakeRateLimiter(1, 1)
+	limiter.Wait("/images/json")
+	clock.now = clock.now.Add(time.Second)
+	limiter.Wait("/images/json")
+	if len(clock.slept) != 0 {
+		t.Errorf("RateLimiter: unexpected wait after refill: %v", clock.slept)
+	}
+}
+
+func TestRateLimiterPerClass(t *testing.T) {
+	limiter, clock := newFakeRateLimiter(1, 1)
+	limiter.Wait("/containers/json")
+	limiter.Wait("/images/json")
+	limiter.Wait("/exec/abc/start")
+	if len(clock.slept) != 0 {
+		t.Errorf("RateLimiter: classes should not share buckets. Got waits %v.", clock.slept)
+	}
+}
+
+func TestRateLimiterSetLimit(t *testing.T) {
+	limiter, clock := newFakeRateLimiter(1, 1)
+	limiter.SetLimit("containers", 10, 1)
+	limiter.Wait("/containers/json")
+	limiter.Wait("/containers/json")
+	expected := 100 * time.Millisecond
+	if len(clock.slept) != 1 || clock.slept[0] != expected {
+		t.Errorf("RateLimiter.SetLimit: wrong waits. Want [%v]. Got %v.", expected, clock.slept)
+	}
+	limiter.SetLimit("images", 0, 1)
+	for i := 0; i < 5; i++ {
+		limiter.Wait("/images/json")
+	}
+	if len(clock.slept) != 1 {
+		t.Errorf("RateLimiter.SetLimit: zero rate should disable limiting. Got waits %v.", clock.slept)
+	}
+}
+
+func TestClientRateLimiter(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "[]", status: http.StatusOK})
+	limiter, clock := newFakeRateLimiter(1, 1)
+	client.RateLimiter = limiter
+	for i := 0; i < 3; i++ {
+		if _, err := client.ListContainers(ListContainersOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(clock.slept) != 2 {
+		t.Errorf("ListContainers: wrong number of waits. Want 2. Got %d.", len(clock.slept))
+	}
+}
+
+func TestClientRateLimiterStreamingExempt(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "logs", status: http.StatusOK})
+	limiter, clock := newFakeRateLimiter(1, 1)
+	client.RateLimiter = limiter
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		opts := LogsOptions{Container: "a123456", OutputStream: &buf, Stdout: true, RawTerminal: true}
+		if err := client.Logs(opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(clock.slept) != 0 {
+		t.Errorf("Logs: streaming calls should not be rate limited. Got waits %v.", clock.slept)
+	}
+}
+
+func TestClientRateLimiterExists(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "{}", status: http.StatusOK})
+	limiter, clock := newFakeRateLimiter(1, 1)
+	client.RateLimiter = limiter
+	for i := 0; i < 2; i++ {
+		if _, err := client.ContainerExists("a123456"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(clock.slept) != 1 {
+		t.Errorf("ContainerExists: wrong number of waits. Want 1. Got %d.", len(clock.slept))
+	}
+}
+
+func TestClientRateLimiterContainerStats(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "{}", status: http.StatusOK})
+	limiter, clock := newFakeRateLimiter(1, 1)
+	client.RateLimiter = limiter
+	for i := 0; i < 2; i++ {
+		if _, err := client.containerStats("a123456"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(clock.slept) != 1 {
+		t.Errorf("containerStats: wrong number of waits. Want 1. Got %d.", len(clock.slept))
+	}
+}
+
+func TestClientRateLimiterStartAndWaitContainer(t *testing.T) {
+	server, _ := startAndWaitServer(t, `{"status":"die","id":"4fa6e0f0c678","time":1429790401}`)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, _ := newFakeRateLimiter(1, 1)
+	client.RateLimiter = limiter
+	if _, err := client.StartAndWaitContainer("web", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := limiter.buckets["events"]; !ok {
+		t.Error("StartAndWaitContainer: the subscription to the events was not rate limited.")
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultRegistry is the registry used for references that don't
+	// specify one.
+	DefaultRegistry = "docker.io"
+
+	// DefaultTag is the tag of references that don't specify a tag nor a
+	// digest.
+	DefaultTag = "latest"
+
+	maxReferenceNameLength = 255
+)
+
+// ErrInvalidReference is returned by ParseReference when the given string is
+// not a valid image reference.
+var ErrInvalidReference = errors.New("invalid image reference")
+
+var (
+	referenceDomainRegexp    = regexp.MustCompile(`^(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*(?::[0-9]+)?$`)
+	referenceComponentRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|[-]*)[a-z0-9]+)*$`)
+	referenceTagRegexp       = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	referenceDigestRegexp    = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}$`)
+)
+
+// Reference is a parsed image reference, like
+// localhost:5000/samalba/hipache:latest or busybox@sha256:4b82b6ae.
+type Reference struct {
+	// Registry is the host, and optionally the port, of the registry
+	// storing the image. References to Docker Hub use "docker.io".
+	Registry string
+
+	// Repository is the path of the repository in the registry. Official
+	// images in Docker Hub are in the "library" namespace.
+	Repository string
+
+	// Tag is the tag of the image. It's empty when the reference has
+	// neither a tag nor a digest, in which case DefaultTag is implied.
+	Tag string
+
+	// Digest is the digest of the manifest of the image, if the reference
+	// is pinned to one.
+	Digest string
+}
+
+// ParseReference parses and validates the given image reference, normalizing
+// it as the docker CLI does: references without a registry point to Docker
+// Hub, in the "library" namespace for single-component repositories, and
+// index.docker.io is an alias of docker.io.
+//
+// Some examples:
+//
+//	busybox -> docker.io, library/busybox, "", ""
+//	tsuru/python:2.7 -> docker.io, tsuru/python, 2.7, ""
+//	localhost:5000/samalba/hipache@sha256:4b82... -> localhost:5000, samalba/hipache, "", sha256:4b82...
+func ParseReference(s string) (*Reference, error) {
+	var ref Reference
+	name := s
+	if i := strings.Index(name, "@"); i > -1 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !referenceDigestRegexp.MatchString(ref.Digest) {
+			return nil, ErrInvalidReference
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i > -1 && !strings.Contains(name[i+1:], "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+		if !referenceTagRegexp.MatchString(ref.Tag) {
+			return nil, ErrInvalidReference
+		}
+	}
+	if name == "" || len(name) > maxReferenceNameLength {
+		return nil, ErrInvalidReference
+	}
+	ref.Registry = DefaultRegistry
+	ref.Repository = name
+	if i := strings.Index(name, "/"); i > -1 {
+		first := name[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" || strings.ToLower(first) != first {
+			if !referenceDomainRegexp.MatchString(first) {
+				return nil, ErrInvalidReference
+			}
+			ref.Registry = first
+			ref.Repository = name[i+1:]
+		}
+	}
+	if ref.Registry == "index.docker.io" {
+		ref.Registry = DefaultRegistry
+	}
+	for _, component := range strings.Split(ref.Repository, "/") {
+		if !referenceComponentRegexp.MatchString(component) {
+			return nil, ErrInvalidReference
+		}
+	}
+	if ref.Registry == DefaultRegistry && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	return &ref, nil
+}
+
+// Name returns the fully qualified name of the repository, including the
+// registry, like docker.io/library/busybox.
+func (r *Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the fully qualified form of the reference.
+func (r *Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
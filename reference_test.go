@@ -0,0 +1,75 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("4b82b6ae", 8)
+	var tests = []struct {
+		input    string
+		expected Reference
+		str      string
+	}{
+		{"busybox", Reference{"docker.io", "library/busybox", "", ""}, "docker.io/library/busybox"},
+		{"tsuru/python:2.7", Reference{"docker.io", "tsuru/python", "2.7", ""}, "docker.io/tsuru/python:2.7"},
+		{"index.docker.io/tsuru/python", Reference{"docker.io", "tsuru/python", "", ""}, "docker.io/tsuru/python"},
+		{"docker.io/busybox:latest", Reference{"docker.io", "library/busybox", "latest", ""}, "docker.io/library/busybox:latest"},
+		{
+			"localhost.localdomain:5000/samalba/hipache:latest",
+			Reference{"localhost.localdomain:5000", "samalba/hipache", "latest", ""},
+			"localhost.localdomain:5000/samalba/hipache:latest",
+		},
+		{"localhost/app", Reference{"localhost", "app", "", ""}, "localhost/app"},
+		{"localhost:5000/app", Reference{"localhost:5000", "app", "", ""}, "localhost:5000/app"},
+		{"busybox@" + digest, Reference{"docker.io", "library/busybox", "", digest}, "docker.io/library/busybox@" + digest},
+		{
+			"quay.io/coreos/etcd:v2.0.9@" + digest,
+			Reference{"quay.io", "coreos/etcd", "v2.0.9", digest},
+			"quay.io/coreos/etcd:v2.0.9@" + digest,
+		},
+		{"my_org/my-app__x.y", Reference{"docker.io", "my_org/my-app__x.y", "", ""}, "docker.io/my_org/my-app__x.y"},
+	}
+	for _, tt := range tests {
+		ref, err := ParseReference(tt.input)
+		if err != nil {
+			t.Errorf("ParseReference(%q): unexpected error: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(*ref, tt.expected) {
+			t.Errorf("ParseReference(%q): wrong result. Want %#v. Got %#v.", tt.input, tt.expected, *ref)
+		}
+		if ref.String() != tt.str {
+			t.Errorf("ParseReference(%q).String(): wrong result. Want %q. Got %q.", tt.input, tt.str, ref.String())
+		}
+	}
+}
+
+func TestParseReferenceInvalid(t *testing.T) {
+	var tests = []string{
+		"",
+		":latest",
+		"Busybox",
+		"busybox:",
+		"busybox:-tag",
+		"busybox@sha256:abc",
+		"busybox@" + strings.Repeat("a", 64),
+		"tsuru//python",
+		"tsuru/python-",
+		"-registry.com/app",
+		"registry.com:port/app",
+		"app:" + strings.Repeat("t", 129),
+		strings.Repeat("a", 256),
+	}
+	for _, input := range tests {
+		if ref, err := ParseReference(input); err != ErrInvalidReference {
+			t.Errorf("ParseReference(%q): wrong result. Want error %#v. Got %#v, %#v.", input, ErrInvalidReference, ref, err)
+		}
+	}
+}
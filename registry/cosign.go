@@ -0,0 +1,154 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/reverb/go-dockerclient"
+)
+
+const cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+var (
+	// ErrNotSigned is returned by CosignVerifier when the image has no
+	// valid signature made with the key.
+	ErrNotSigned = errors.New("image not signed with the cosign key")
+
+	// ErrInvalidPublicKey is returned by ParseCosignPublicKey when the key
+	// is not an ECDSA public key in PEM.
+	ErrInvalidPublicKey = errors.New("invalid cosign public key")
+)
+
+// CosignVerifier verifies the signatures made by cosign with a key pair, as
+// an ImageVerifier of the docker package. The signatures are read from the
+// registry of the Client, which must be the registry of the images, in the
+// sha256-<hex>.sig tag of the repository of each image.
+//
+// It's a minimal implementation: the keyless signatures, with certificates
+// from Fulcio, and the transparency log of Rekor are not supported.
+type CosignVerifier struct {
+	Client    *Client
+	PublicKey *ecdsa.PublicKey
+}
+
+// ParseCosignPublicKey parses a public key generated by cosign, in the PEM
+// format of cosign.pub.
+func ParseCosignPublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPublicKey
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, ErrInvalidPublicKey
+	}
+	return ecdsaKey, nil
+}
+
+// VerifyImage checks that one of the signatures of the image is valid for the
+// key, and that its payload names the given digest.
+func (v *CosignVerifier) VerifyImage(ref *docker.Reference, digest string) error {
+	if digest == "" {
+		return ErrNotSigned
+	}
+	manifest, err := v.Client.Manifest(ref.Repository, strings.Replace(digest, ":", "-", 1)+".sig")
+	if err != nil {
+		if e, ok := err.(*Error); ok && e.Status == http.StatusNotFound {
+			return ErrNotSigned
+		}
+		return err
+	}
+	var signatures struct {
+		Layers []struct {
+			Digest      string            `json:"digest"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(manifest.Content, &signatures); err != nil {
+		return err
+	}
+	for _, layer := range signatures.Layers {
+		signature, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		payload, err := v.blob(ref.Repository, layer.Digest)
+		if err != nil {
+			return err
+		}
+		if v.verifySignature(payload, signature) && signedDigest(payload) == digest {
+			return nil
+		}
+	}
+	return ErrNotSigned
+}
+
+// blob fetches the blob with the given digest, checking its content.
+func (v *CosignVerifier) blob(repository, digest string) ([]byte, error) {
+	body, err := v.Client.Blob(repository, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	if "sha256:"+hex.EncodeToString(sum[:]) != digest {
+		return nil, errors.New("wrong digest of blob " + digest)
+	}
+	return content, nil
+}
+
+// verifySignature checks the signature, an ASN.1 ECDSA signature in base64,
+// of the SHA-256 of the payload.
+func (v *CosignVerifier) verifySignature(payload []byte, signature string) bool {
+	der, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	var sig struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(der, &sig); err != nil || len(rest) > 0 {
+		return false
+	}
+	hash := sha256.Sum256(payload)
+	return ecdsa.Verify(v.PublicKey, hash[:], sig.R, sig.S)
+}
+
+// signedDigest returns the digest of the image named by the simple signing
+// payload of a signature.
+func signedDigest(payload []byte) string {
+	var simpleSigning struct {
+		Critical struct {
+			Image struct {
+				Digest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &simpleSigning); err != nil {
+		return ""
+	}
+	return simpleSigning.Critical.Image.Digest
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reverb/go-dockerclient"
+)
+
+const signedImageDigest = "sha256:4b82b6ae1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6"
+
+// cosignRegistry serves the signature of signedImageDigest, made with the
+// given key.
+func cosignRegistry(t *testing.T, key *ecdsa.PrivateKey) *httptest.Server {
+	payload := []byte(`{"critical":{"identity":{"docker-reference":"registry.test/app"},"image":{"docker-manifest-digest":"` +
+		signedImageDigest + `"},"type":"cosign container image signature"},"optional":null}`)
+	hash := sha256.Sum256(payload)
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(struct{ R, S interface{} }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloadDigest := "sha256:" + hex.EncodeToString(hash[:])
+	manifest := `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json","layers":[{
+		"mediaType":"application/vnd.dev.cosign.simplesigning.v1+json",
+		"digest":"` + payloadDigest + `",
+		"annotations":{"dev.cosignproject.cosign/signature":"` + base64.StdEncoding.EncodeToString(der) + `"}}]}`
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v2/app/manifests/sha256-4b82b6ae1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6.sig":
+			w.Write([]byte(manifest))
+		case "/v2/app/blobs/" + payloadDigest:
+			w.Write(payload)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestCosignVerifier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := cosignRegistry(t, key)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := ParseCosignPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := CosignVerifier{Client: client, PublicKey: publicKey}
+	ref := &docker.Reference{Registry: "registry.test", Repository: "app", Tag: "1.0"}
+	if err := verifier.VerifyImage(ref, signedImageDigest); err != nil {
+		t.Errorf("VerifyImage: unexpected error: %s", err)
+	}
+	unsigned := "sha256:00000000000000000000000000000000000000000000000000000000000000ff"
+	if err := verifier.VerifyImage(ref, unsigned); err != ErrNotSigned {
+		t.Errorf("VerifyImage: wrong error. Want %#v. Got %#v.", ErrNotSigned, err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier.PublicKey = &otherKey.PublicKey
+	if err := verifier.VerifyImage(ref, signedImageDigest); err != ErrNotSigned {
+		t.Errorf("VerifyImage: wrong error with another key. Want %#v. Got %#v.", ErrNotSigned, err)
+	}
+}
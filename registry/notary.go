@@ -0,0 +1,363 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reverb/go-dockerclient"
+)
+
+// releasesRole is the delegation where the docker CLI signs the tags pushed
+// with DOCKER_CONTENT_TRUST.
+const releasesRole = "targets/releases"
+
+var (
+	// ErrInvalidTrustSignature is returned by NotaryResolver when the trust
+	// metadata of the repository isn't signed by enough keys of its role.
+	ErrInvalidTrustSignature = errors.New("invalid signatures of the trust metadata")
+
+	// ErrExpiredTrustData is returned by NotaryResolver when the trust
+	// metadata of the repository has expired.
+	ErrExpiredTrustData = errors.New("trust metadata has expired")
+
+	// ErrTagNotSigned is returned by NotaryResolver when the tag is not in
+	// the signed targets of the repository.
+	ErrTagNotSigned = errors.New("tag not signed in the trust metadata")
+)
+
+// NotaryResolver resolves the signed digest of a tag from the TUF metadata
+// served by a Notary server, as a TrustResolver of the docker package. The
+// Client must point to the Notary server, like https://notary.docker.io, and
+// holds the credentials used to get its tokens.
+//
+// The root metadata is verified with its own keys, which are pinned with
+// RootKeyIDs. When RootKeyIDs is empty, the root served by the Notary server
+// is trusted as is, which protects the targets from tampering but not from a
+// compromised server. The timestamp and snapshot metadata are not checked,
+// so it's a minimal implementation that doesn't detect rollbacks.
+type NotaryResolver struct {
+	Client     *Client
+	RootKeyIDs []string
+}
+
+type tufSigned struct {
+	Signed     json.RawMessage `json:"signed"`
+	Signatures []struct {
+		KeyID  string `json:"keyid"`
+		Method string `json:"method"`
+		Sig    []byte `json:"sig"`
+	} `json:"signatures"`
+}
+
+type tufRole struct {
+	Name      string   `json:"name"`
+	KeyIDs    []string `json:"keyids"`
+	Threshold int      `json:"threshold"`
+	Paths     []string `json:"paths"`
+}
+
+type tufRoot struct {
+	Type  string                     `json:"_type"`
+	Keys  map[string]json.RawMessage `json:"keys"`
+	Roles map[string]tufRole         `json:"roles"`
+}
+
+type tufTargets struct {
+	Type    string `json:"_type"`
+	Targets map[string]struct {
+		Hashes map[string][]byte `json:"hashes"`
+	} `json:"targets"`
+	Delegations struct {
+		Keys  map[string]json.RawMessage `json:"keys"`
+		Roles []tufRole                  `json:"roles"`
+	} `json:"delegations"`
+}
+
+// ResolveTrustedDigest returns the digest signed for the tag of the
+// reference, looking it up in the releases delegation first, and then in the
+// targets of the repository, as the docker CLI does.
+func (r *NotaryResolver) ResolveTrustedDigest(ref *docker.Reference) (string, error) {
+	gun := ref.Name()
+	var root tufRoot
+	if err := r.fetch(gun, "root", nil, tufRole{}, &root); err != nil {
+		return "", err
+	}
+	if root.Type != "Root" {
+		return "", ErrInvalidTrustSignature
+	}
+	var targets tufTargets
+	if err := r.fetch(gun, "targets", root.Keys, root.Roles["targets"], &targets); err != nil {
+		return "", err
+	}
+	if targets.Type != "Targets" {
+		return "", ErrInvalidTrustSignature
+	}
+	for _, role := range targets.Delegations.Roles {
+		if role.Name != releasesRole || !matchesPaths(role.Paths, ref.Tag) {
+			continue
+		}
+		var releases tufTargets
+		err := r.fetch(gun, releasesRole, targets.Delegations.Keys, role, &releases)
+		if err != nil {
+			if e, ok := err.(*Error); ok && e.Status == http.StatusNotFound {
+				break
+			}
+			return "", err
+		}
+		if digest := signedTarget(&releases, ref.Tag); digest != "" {
+			return digest, nil
+		}
+	}
+	if digest := signedTarget(&targets, ref.Tag); digest != "" {
+		return digest, nil
+	}
+	return "", ErrTagNotSigned
+}
+
+// fetch downloads the metadata of the given role, checks its signatures with
+// the keys of the role and decodes it. The root is checked with the keys it
+// lists for itself.
+func (r *NotaryResolver) fetch(gun, name string, keys map[string]json.RawMessage, role tufRole, v interface{}) error {
+	resp, err := r.Client.do("GET", "/v2/"+gun+"/_trust/tuf/"+name+".json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var metadata tufSigned
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return err
+	}
+	if name == "root" {
+		var root tufRoot
+		if err := json.Unmarshal(metadata.Signed, &root); err != nil {
+			return err
+		}
+		keys, role = root.Keys, root.Roles["root"]
+		if len(r.RootKeyIDs) > 0 {
+			role.KeyIDs = pinnedKeyIDs(role.KeyIDs, r.RootKeyIDs)
+		}
+	}
+	if err := verifyMetadata(&metadata, keys, role); err != nil {
+		return err
+	}
+	var expiring struct {
+		Expires time.Time `json:"expires"`
+	}
+	if err := json.Unmarshal(metadata.Signed, &expiring); err != nil {
+		return err
+	}
+	if expiring.Expires.Before(time.Now()) {
+		return ErrExpiredTrustData
+	}
+	return json.Unmarshal(metadata.Signed, v)
+}
+
+// verifyMetadata checks that the metadata is signed by at least the threshold
+// of keys of the role.
+func verifyMetadata(metadata *tufSigned, keys map[string]json.RawMessage, role tufRole) error {
+	if role.Threshold < 1 {
+		return ErrInvalidTrustSignature
+	}
+	canonical, err := canonicalJSON(metadata.Signed)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(canonical)
+	valid := make(map[string]bool)
+	for _, sig := range metadata.Signatures {
+		if !containsString(role.KeyIDs, sig.KeyID) {
+			continue
+		}
+		key, err := parseTUFKey(keys[sig.KeyID], sig.KeyID)
+		if err != nil {
+			continue
+		}
+		if verifyTUFSignature(key, sig.Method, hash[:], sig.Sig) {
+			valid[sig.KeyID] = true
+		}
+	}
+	if len(valid) < role.Threshold {
+		return ErrInvalidTrustSignature
+	}
+	return nil
+}
+
+// parseTUFKey parses a public key of the TUF metadata, checking that its ID
+// is the SHA-256 of its canonical JSON.
+func parseTUFKey(data json.RawMessage, id string) (crypto.PublicKey, error) {
+	if data == nil {
+		return nil, ErrInvalidTrustSignature
+	}
+	canonical, err := canonicalJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(canonical); hex.EncodeToString(sum[:]) != id {
+		return nil, ErrInvalidTrustSignature
+	}
+	var key struct {
+		Type  string `json:"keytype"`
+		Value struct {
+			Public []byte `json:"public"`
+		} `json:"keyval"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, err
+	}
+	switch key.Type {
+	case "ecdsa", "rsa":
+		return x509.ParsePKIXPublicKey(key.Value.Public)
+	case "ecdsa-x509", "rsa-x509":
+		block, _ := pem.Decode(key.Value.Public)
+		if block == nil {
+			return nil, ErrInvalidTrustSignature
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, errors.New("unsupported key type: " + key.Type)
+}
+
+// verifyTUFSignature checks a signature of the hash, either an ECDSA
+// signature made of r and s, or an RSA-PSS signature.
+func verifyTUFSignature(key crypto.PublicKey, method string, hash, sig []byte) bool {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if method != "ecdsa" || len(sig) == 0 || len(sig)%2 != 0 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:len(sig)/2])
+		s := new(big.Int).SetBytes(sig[len(sig)/2:])
+		return ecdsa.Verify(k, hash, r, s)
+	case *rsa.PublicKey:
+		if method != "rsapss" {
+			return false
+		}
+		opts := rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		return rsa.VerifyPSS(k, crypto.SHA256, hash, sig, &opts) == nil
+	}
+	return false
+}
+
+// signedTarget returns the digest signed for the tag in the targets.
+func signedTarget(targets *tufTargets, tag string) string {
+	target, ok := targets.Targets[tag]
+	if !ok || len(target.Hashes["sha256"]) != sha256.Size {
+		return ""
+	}
+	return "sha256:" + hex.EncodeToString(target.Hashes["sha256"])
+}
+
+func matchesPaths(paths []string, tag string) bool {
+	for _, path := range paths {
+		if strings.HasPrefix(tag, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func pinnedKeyIDs(ids, pinned []string) []string {
+	var result []string
+	for _, id := range ids {
+		if containsString(pinned, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalJSON encodes the given JSON document in the canonical form signed
+// by Notary: no whitespace and the keys of the objects sorted.
+func canonicalJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case string:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	case json.Number:
+		buf.WriteString(v.String())
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case nil:
+		buf.WriteString("null")
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/reverb/go-dockerclient"
+)
+
+const (
+	releasedDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	targetDigest   = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+type tufKey struct {
+	id      string
+	public  json.RawMessage
+	private *ecdsa.PrivateKey
+}
+
+func newTUFKey(t *testing.T) *tufKey {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := json.Marshal(map[string]interface{}{
+		"keytype": "ecdsa",
+		"keyval":  map[string]interface{}{"private": nil, "public": der},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	canonical, err := canonicalJSON(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(canonical)
+	return &tufKey{id: hex.EncodeToString(sum[:]), public: public, private: private}
+}
+
+// signTUF returns the metadata with the signatures of the given keys.
+func signTUF(t *testing.T, signed interface{}, keys ...*tufKey) []byte {
+	data, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canonical, err := canonicalJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(canonical)
+	var signatures []map[string]interface{}
+	for _, key := range keys {
+		r, s, err := ecdsa.Sign(rand.Reader, key.private, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := make([]byte, 64)
+		rBytes, sBytes := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rBytes):], rBytes)
+		copy(sig[64-len(sBytes):], sBytes)
+		signatures = append(signatures, map[string]interface{}{"keyid": key.id, "method": "ecdsa", "sig": sig})
+	}
+	metadata, err := json.Marshal(map[string]interface{}{"signed": json.RawMessage(data), "signatures": signatures})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata
+}
+
+func tufHashes(digest string) map[string]interface{} {
+	sum, _ := hex.DecodeString(digest[len("sha256:"):])
+	return map[string]interface{}{"hashes": map[string][]byte{"sha256": sum}, "length": 528}
+}
+
+// notaryRepository is the trust data of registry.test/app, with the 1.0 tag
+// signed in the releases delegation, and the latest tag in the targets.
+type notaryRepository struct {
+	root, targets, releases *tufKey
+	expires                 time.Time
+	targetsSigner           *tufKey
+}
+
+func newNotaryRepository(t *testing.T) *notaryRepository {
+	repo := notaryRepository{
+		root:     newTUFKey(t),
+		targets:  newTUFKey(t),
+		releases: newTUFKey(t),
+		expires:  time.Now().Add(time.Hour).UTC(),
+	}
+	repo.targetsSigner = repo.targets
+	return &repo
+}
+
+func (repo *notaryRepository) server(t *testing.T) *httptest.Server {
+	root := signTUF(t, map[string]interface{}{
+		"_type":   "Root",
+		"expires": repo.expires,
+		"version": 1,
+		"keys": map[string]json.RawMessage{
+			repo.root.id:    repo.root.public,
+			repo.targets.id: repo.targets.public,
+		},
+		"roles": map[string]interface{}{
+			"root":    map[string]interface{}{"keyids": []string{repo.root.id}, "threshold": 1},
+			"targets": map[string]interface{}{"keyids": []string{repo.targets.id}, "threshold": 1},
+		},
+	}, repo.root)
+	targets := signTUF(t, map[string]interface{}{
+		"_type":   "Targets",
+		"expires": repo.expires,
+		"version": 3,
+		"targets": map[string]interface{}{"latest": tufHashes(targetDigest)},
+		"delegations": map[string]interface{}{
+			"keys": map[string]json.RawMessage{repo.releases.id: repo.releases.public},
+			"roles": []interface{}{map[string]interface{}{
+				"name":      releasesRole,
+				"keyids":    []string{repo.releases.id},
+				"threshold": 1,
+				"paths":     []string{""},
+			}},
+		},
+	}, repo.targetsSigner)
+	releases := signTUF(t, map[string]interface{}{
+		"_type":   "Targets",
+		"expires": repo.expires,
+		"version": 2,
+		"targets": map[string]interface{}{"1.0": tufHashes(releasedDigest)},
+	}, repo.releases)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v2/registry.test/app/_trust/tuf/root.json":
+			w.Write(root)
+		case "/v2/registry.test/app/_trust/tuf/targets.json":
+			w.Write(targets)
+		case "/v2/registry.test/app/_trust/tuf/targets/releases.json":
+			w.Write(releases)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func (repo *notaryRepository) resolve(t *testing.T, resolver *NotaryResolver, tag string) (string, error) {
+	server := repo.server(t)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver.Client = client
+	return resolver.ResolveTrustedDigest(&docker.Reference{Registry: "registry.test", Repository: "app", Tag: tag})
+}
+
+func TestNotaryResolver(t *testing.T) {
+	repo := newNotaryRepository(t)
+	var tests = []struct {
+		tag    string
+		digest string
+		err    error
+	}{
+		{"1.0", releasedDigest, nil},
+		{"latest", targetDigest, nil},
+		{"2.0", "", ErrTagNotSigned},
+	}
+	for _, tt := range tests {
+		digest, err := repo.resolve(t, &NotaryResolver{}, tt.tag)
+		if digest != tt.digest || err != tt.err {
+			t.Errorf("ResolveTrustedDigest(%q): Want %q, %v. Got %q, %v.", tt.tag, tt.digest, tt.err, digest, err)
+		}
+	}
+}
+
+func TestNotaryResolverPinnedRoot(t *testing.T) {
+	repo := newNotaryRepository(t)
+	digest, err := repo.resolve(t, &NotaryResolver{RootKeyIDs: []string{repo.root.id}}, "1.0")
+	if err != nil || digest != releasedDigest {
+		t.Errorf("ResolveTrustedDigest: Want %q. Got %q, %v.", releasedDigest, digest, err)
+	}
+	_, err = repo.resolve(t, &NotaryResolver{RootKeyIDs: []string{newTUFKey(t).id}}, "1.0")
+	if err != ErrInvalidTrustSignature {
+		t.Errorf("ResolveTrustedDigest: wrong error for an unpinned root. Want %#v. Got %#v.", ErrInvalidTrustSignature, err)
+	}
+}
+
+func TestNotaryResolverInvalidSignature(t *testing.T) {
+	repo := newNotaryRepository(t)
+	repo.targetsSigner = newTUFKey(t)
+	_, err := repo.resolve(t, &NotaryResolver{}, "latest")
+	if err != ErrInvalidTrustSignature {
+		t.Errorf("ResolveTrustedDigest: wrong error. Want %#v. Got %#v.", ErrInvalidTrustSignature, err)
+	}
+}
+
+func TestNotaryResolverExpired(t *testing.T) {
+	repo := newNotaryRepository(t)
+	repo.expires = time.Now().Add(-time.Hour).UTC()
+	_, err := repo.resolve(t, &NotaryResolver{}, "latest")
+	if err != ErrExpiredTrustData {
+		t.Errorf("ResolveTrustedDigest: wrong error. Want %#v. Got %#v.", ErrExpiredTrustData, err)
+	}
+}
+
+func TestNotaryResolverNoTrustData(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := NotaryResolver{Client: client}
+	_, err = resolver.ResolveTrustedDigest(&docker.Reference{Registry: "registry.test", Repository: "app", Tag: "1.0"})
+	if e, ok := err.(*Error); !ok || e.Status != http.StatusNotFound {
+		t.Errorf("ResolveTrustedDigest: wrong error. Want a 404 *Error. Got %#v.", err)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	canonical, err := canonicalJSON([]byte(`{"b": [1, 2.50, true, null], "a": {"d": "<x>", "c": ""}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"a":{"c":"","d":"\u003cx\u003e"},"b":[1,2.50,true,null]}`
+	if string(canonical) != expected {
+		t.Errorf("canonicalJSON: Want %s. Got %s.", expected, canonical)
+	}
+}
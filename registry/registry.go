@@ -0,0 +1,381 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package registry provides a lightweight client for the Docker Registry HTTP
+// API V2, supporting the token authentication used by Docker Hub and most
+// private registries.
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Media types of the manifests accepted by Manifest.
+const (
+	MediaTypeManifestV1   = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+	MediaTypeManifestV2   = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest  = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex     = "application/vnd.oci.image.index.v1+json"
+)
+
+var (
+	// ErrInvalidEndpoint is returned when the endpoint is not a valid HTTP
+	// URL.
+	ErrInvalidEndpoint = errors.New("invalid registry endpoint")
+
+	// ErrMissingDigest is returned when the registry doesn't report the
+	// digest of a manifest.
+	ErrMissingDigest = errors.New("registry did not report the manifest digest")
+)
+
+// Client is a client for the Docker Registry HTTP API V2. It's safe for
+// concurrent use by multiple goroutines.
+type Client struct {
+	HTTPClient *http.Client
+
+	// Username and Password are used for basic authentication, and to
+	// request tokens from the authorization service of the registry. They
+	// may be empty for anonymous access.
+	Username string
+	Password string
+
+	endpoint *url.URL
+	tokens   map[string]string
+	mut      sync.RWMutex
+}
+
+// NewClient returns a Client for the registry at the given endpoint, like
+// https://registry-1.docker.io.
+func NewClient(endpoint string) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, ErrInvalidEndpoint
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	return &Client{
+		HTTPClient: http.DefaultClient,
+		endpoint:   u,
+		tokens:     make(map[string]string),
+	}, nil
+}
+
+// Error is returned by the registry API.
+type Error struct {
+	Status int
+	Errors []ErrorDetail `json:"errors"`
+}
+
+// ErrorDetail is one of the errors reported by the registry API.
+type ErrorDetail struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Detail  json.RawMessage `json:"detail,omitempty"`
+}
+
+func newError(resp *http.Response) *Error {
+	e := Error{Status: resp.StatusCode}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err == nil {
+		json.Unmarshal(body, &e)
+	}
+	return &e
+}
+
+func (e *Error) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("registry API error (%d)", e.Status)
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, detail := range e.Errors {
+		msgs[i] = detail.Code + ": " + detail.Message
+	}
+	return fmt.Sprintf("registry API error (%d): %s", e.Status, strings.Join(msgs, "; "))
+}
+
+// Tags returns the tags of the given repository.
+func (c *Client) Tags(repository string) ([]string, error) {
+	var tags []string
+	path := "/v2/" + repository + "/tags/list"
+	for path != "" {
+		resp, err := c.do("GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, page.Tags...)
+		path = nextPage(resp.Header.Get("Link"))
+	}
+	return tags, nil
+}
+
+// nextPage extracts the path of the next page from a Link header, in the
+// form `</v2/name/tags/list?n=100&last=b>; rel="next"`.
+func nextPage(link string) string {
+	if !strings.Contains(link, `rel="next"`) {
+		return ""
+	}
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	u, err := url.Parse(link[start+1 : end])
+	if err != nil {
+		return ""
+	}
+	return u.RequestURI()
+}
+
+// Manifest is a manifest fetched from a registry.
+type Manifest struct {
+	MediaType string
+	Digest    string
+	Content   []byte
+}
+
+// Manifest fetches the manifest of the given repository. The reference may be
+// a tag or a digest.
+func (c *Client) Manifest(repository, reference string) (*Manifest, error) {
+	resp, err := c.do("GET", "/v2/"+repository+"/manifests/"+reference, manifestHeaders())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Manifest{
+		MediaType: resp.Header.Get("Content-Type"),
+		Digest:    resp.Header.Get("Docker-Content-Digest"),
+		Content:   content,
+	}, nil
+}
+
+// ManifestDigest returns the digest of the manifest with the given reference,
+// without downloading it.
+func (c *Client) ManifestDigest(repository, reference string) (string, error) {
+	resp, err := c.do("HEAD", "/v2/"+repository+"/manifests/"+reference, manifestHeaders())
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", ErrMissingDigest
+	}
+	return digest, nil
+}
+
+// DeleteManifest deletes the manifest with the given digest from the
+// repository. Deleting by tag is not supported by the registry API.
+func (c *Client) DeleteManifest(repository, digest string) error {
+	resp, err := c.do("DELETE", "/v2/"+repository+"/manifests/"+digest, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Blob returns the content of the blob with the given digest. The caller is
+// responsible for closing it.
+func (c *Client) Blob(repository, digest string) (io.ReadCloser, error) {
+	resp, err := c.do("GET", "/v2/"+repository+"/blobs/"+digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func manifestHeaders() map[string]string {
+	return map[string]string{
+		"Accept": strings.Join([]string{
+			MediaTypeManifestV2,
+			MediaTypeManifestList,
+			MediaTypeOCIManifest,
+			MediaTypeOCIIndex,
+			MediaTypeManifestV1,
+		}, ", "),
+	}
+}
+
+// do sends a request to the registry, authenticating when the registry asks
+// for it. Responses with a status code outside of the 2xx range are returned
+// as an *Error.
+func (c *Client) do(method, path string, headers map[string]string) (*http.Response, error) {
+	resp, err := c.send(method, path, headers, c.cachedAuthorization(path))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		authorization, err := c.authorize(path, challenge)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = c.send(method, path, headers, authorization); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, newError(resp)
+	}
+	return resp, nil
+}
+
+func (c *Client) send(method, path string, headers map[string]string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.endpoint.String()+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return c.HTTPClient.Do(req)
+}
+
+// authorize answers the given authentication challenge, returning the value
+// of the Authorization header to use.
+func (c *Client) authorize(path, challenge string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password)), nil
+	case "bearer":
+		token, err := c.fetchToken(params)
+		if err != nil {
+			return "", err
+		}
+		authorization := "Bearer " + token
+		c.mut.Lock()
+		c.tokens[repositoryOf(path)] = authorization
+		c.mut.Unlock()
+		return authorization, nil
+	}
+	return "", fmt.Errorf("unsupported authentication challenge: %q", challenge)
+}
+
+func (c *Client) cachedAuthorization(path string) string {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	return c.tokens[repositoryOf(path)]
+}
+
+// fetchToken requests a token from the authorization service described by
+// the parameters of a Bearer challenge.
+func (c *Client) fetchToken(params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid token realm: %q", params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+	req, err := http.NewRequest("GET", realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", newError(resp)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", errors.New("authorization service did not return a token")
+	}
+	return token.Token, nil
+}
+
+// parseChallenge parses a WWW-Authenticate header, like
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`.
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	scheme := strings.ToLower(parts[0])
+	if len(parts) < 2 {
+		return scheme, params
+	}
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return scheme, params
+}
+
+// repositoryOf returns the repository targeted by the given API path, which
+// is used to cache tokens, as their scope is a repository.
+func repositoryOf(path string) string {
+	path = strings.TrimPrefix(path, "/v2/")
+	for _, sep := range []string{"/tags/", "/manifests/", "/blobs/", "/_trust/"} {
+		if i := strings.LastIndex(path, sep); i > -1 {
+			return path[:i]
+		}
+	}
+	return path
+}
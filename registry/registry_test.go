@@ -0,0 +1,222 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry is a registry that requires tokens issued by its own
+// authorization service.
+type fakeRegistry struct {
+	*httptest.Server
+	mut        sync.Mutex
+	tokenCalls []string
+	deleted    []string
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	r := fakeRegistry{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		user, pass, _ := req.BasicAuth()
+		if user != "user" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.mut.Lock()
+		r.tokenCalls = append(r.tokenCalls, req.URL.Query().Get("scope"))
+		r.mut.Unlock()
+		w.Write([]byte(`{"token":"tok-` + req.URL.Query().Get("scope") + `"}`))
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, req *http.Request) {
+		scope := "repository:library/app:pull"
+		if req.Method == "DELETE" {
+			scope = "repository:library/app:delete"
+		}
+		if req.Header.Get("Authorization") != "Bearer tok-"+scope {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.URL+`/token",service="registry.test",scope="`+scope+`"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors":[{"code":"UNAUTHORIZED","message":"authentication required"}]}`))
+			return
+		}
+		switch {
+		case req.URL.Path == "/v2/library/app/tags/list" && req.URL.Query().Get("last") == "":
+			w.Header().Set("Link", `</v2/library/app/tags/list?last=1.0&n=2>; rel="next"`)
+			w.Write([]byte(`{"name":"library/app","tags":["0.9","1.0"]}`))
+		case req.URL.Path == "/v2/library/app/tags/list":
+			w.Write([]byte(`{"name":"library/app","tags":["latest"]}`))
+		case req.URL.Path == "/v2/library/app/manifests/latest":
+			if !strings.Contains(req.Header.Get("Accept"), MediaTypeManifestList) {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			w.Header().Set("Content-Type", MediaTypeManifestV2)
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+			if req.Method == "GET" {
+				w.Write([]byte(`{"schemaVersion":2}`))
+			}
+		case req.URL.Path == "/v2/library/app/manifests/sha256:abc" && req.Method == "DELETE":
+			r.mut.Lock()
+			r.deleted = append(r.deleted, "sha256:abc")
+			r.mut.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		case req.URL.Path == "/v2/library/app/blobs/sha256:def":
+			w.Write([]byte("layer data"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+		}
+	})
+	r.Server = httptest.NewServer(mux)
+	return &r
+}
+
+func newTestClient(t *testing.T, r *fakeRegistry) *Client {
+	client, err := NewClient(r.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Username = "user"
+	client.Password = "secret"
+	return client
+}
+
+func TestNewClientInvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"tcp://localhost:5000", "localhost:5000", "http://"} {
+		if _, err := NewClient(endpoint); err == nil {
+			t.Errorf("NewClient(%q): expected error, got <nil>", endpoint)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.Close()
+	client := newTestClient(t, r)
+	tags, err := client.Tags("library/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"0.9", "1.0", "latest"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Tags: wrong result. Want %q. Got %q.", expected, tags)
+	}
+	if len(r.tokenCalls) != 1 {
+		t.Errorf("Tags: token should be cached. Got %d token requests.", len(r.tokenCalls))
+	}
+}
+
+func TestManifest(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.Close()
+	client := newTestClient(t, r)
+	manifest, err := client.Manifest("library/app", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Manifest{MediaType: MediaTypeManifestV2, Digest: "sha256:abc", Content: []byte(`{"schemaVersion":2}`)}
+	if !reflect.DeepEqual(manifest, expected) {
+		t.Errorf("Manifest: wrong result. Want %#v. Got %#v.", expected, manifest)
+	}
+	digest, err := client.ManifestDigest("library/app", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != "sha256:abc" {
+		t.Errorf("ManifestDigest: wrong result. Want %q. Got %q.", "sha256:abc", digest)
+	}
+}
+
+func TestManifestNotFound(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.Close()
+	client := newTestClient(t, r)
+	_, err := client.Manifest("library/app", "missing")
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Manifest: wrong error type. Want *Error. Got %#v.", err)
+	}
+	if e.Status != http.StatusNotFound || len(e.Errors) != 1 || e.Errors[0].Code != "MANIFEST_UNKNOWN" {
+		t.Errorf("Manifest: wrong error. Got %#v.", e)
+	}
+}
+
+func TestBlob(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.Close()
+	client := newTestClient(t, r)
+	blob, err := client.Blob("library/app", "sha256:def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blob.Close()
+	data, err := ioutil.ReadAll(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "layer data" {
+		t.Errorf("Blob: wrong content. Want %q. Got %q.", "layer data", string(data))
+	}
+}
+
+func TestDeleteManifest(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.Close()
+	client := newTestClient(t, r)
+	if _, err := client.Tags("library/app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteManifest("library/app", "sha256:abc"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.deleted, []string{"sha256:abc"}) {
+		t.Errorf("DeleteManifest: wrong deleted manifests. Got %q.", r.deleted)
+	}
+	expectedScopes := []string{"repository:library/app:pull", "repository:library/app:delete"}
+	if !reflect.DeepEqual(r.tokenCalls, expectedScopes) {
+		t.Errorf("DeleteManifest: wrong token scopes. Want %q. Got %q.", expectedScopes, r.tokenCalls)
+	}
+}
+
+func TestBadCredentials(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.Close()
+	client := newTestClient(t, r)
+	client.Password = "wrong"
+	_, err := client.Tags("library/app")
+	if e, ok := err.(*Error); !ok || e.Status != http.StatusUnauthorized {
+		t.Errorf("Tags: wrong error. Want *Error with status 401. Got %#v.", err)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	var tests = []struct {
+		input          string
+		expectedScheme string
+		expectedParams map[string]string
+	}{
+		{
+			`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/app:pull,push"`,
+			"bearer",
+			map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:library/app:pull,push"},
+		},
+		{`Basic realm="Registry Realm"`, "basic", map[string]string{"realm": "Registry Realm"}},
+		{`Bearer realm=https://auth/token, service=reg`, "bearer", map[string]string{"realm": "https://auth/token", "service": "reg"}},
+		{`Basic`, "basic", map[string]string{}},
+	}
+	for _, tt := range tests {
+		scheme, params := parseChallenge(tt.input)
+		if scheme != tt.expectedScheme || !reflect.DeepEqual(params, tt.expectedParams) {
+			t.Errorf("parseChallenge(%q): wrong result. Want %q, %v. Got %q, %v.", tt.input, tt.expectedScheme, tt.expectedParams, scheme, params)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var cpuset = regexp.MustCompile(`^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$`)
+
+// NanoCPUs returns the value of HostConfig.NanoCpus giving the container the
+// given number of CPUs, like 1.5.
+func NanoCPUs(cpus float64) int64 {
+	return int64(cpus*1e9 + 0.5)
+}
+
+// WeightDevice is the share of the block IO of a container on a device, from
+// 10 to 1000.
+type WeightDevice struct {
+	Path   string `json:"Path,omitempty" yaml:"Path,omitempty"`
+	Weight uint16 `json:"Weight,omitempty" yaml:"Weight,omitempty"`
+}
+
+// ThrottleDevice is the limit of the block IO of a container on a device, in
+// bytes or operations per second.
+type ThrottleDevice struct {
+	Path string `json:"Path,omitempty" yaml:"Path,omitempty"`
+	Rate uint64 `json:"Rate" yaml:"Rate"`
+}
+
+// validBlkioWeight tells whether the weight is in range, 0 keeping the
+// default weight.
+func validBlkioWeight(weight uint16) bool {
+	return weight == 0 || weight >= 10 && weight <= 1000
+}
+
+// InvalidResource is the error returned by CreateContainer for the resource
+// settings of HostConfig out of their range, or in the wrong format.
+type InvalidResource struct {
+	Field string
+	Value string
+}
+
+func (err *InvalidResource) Error() string {
+	return "Invalid HostConfig." + err.Field + ": " + err.Value
+}
+
+// validateResources checks the ranges of the resource settings of the host
+// config.
+func (hostConfig *HostConfig) validateResources() error {
+	if adj := int64(hostConfig.OomScoreAdj); adj < -1000 || adj > 1000 {
+		return &InvalidResource{Field: "OomScoreAdj", Value: strconv.FormatInt(adj, 10)}
+	}
+	if swappiness := hostConfig.MemorySwappiness; swappiness != nil && (*swappiness < -1 || *swappiness > 100) {
+		return &InvalidResource{Field: "MemorySwappiness", Value: strconv.FormatInt(*swappiness, 10)}
+	}
+	if !validBlkioWeight(hostConfig.BlkioWeight) {
+		return &InvalidResource{Field: "BlkioWeight", Value: strconv.FormatInt(int64(hostConfig.BlkioWeight), 10)}
+	}
+	for _, device := range hostConfig.BlkioWeightDevice {
+		if !validBlkioWeight(device.Weight) {
+			return &InvalidResource{Field: "BlkioWeightDevice", Value: strconv.FormatInt(int64(device.Weight), 10)}
+		}
+	}
+	if hostConfig.CpusetCpus != "" && !cpuset.MatchString(hostConfig.CpusetCpus) {
+		return &InvalidResource{Field: "CpusetCpus", Value: hostConfig.CpusetCpus}
+	}
+	if hostConfig.CpusetMems != "" && !cpuset.MatchString(hostConfig.CpusetMems) {
+		return &InvalidResource{Field: "CpusetMems", Value: hostConfig.CpusetMems}
+	}
+	for field, value := range map[string]int64{
+		"NanoCpus":           hostConfig.NanoCpus,
+		"CPURealtimePeriod":  hostConfig.CPURealtimePeriod,
+		"CPURealtimeRuntime": hostConfig.CPURealtimeRuntime,
+	} {
+		if value < 0 {
+			return &InvalidResource{Field: field, Value: strconv.FormatInt(value, 10)}
+		}
+	}
+	if hostConfig.CPURealtimeRuntime > hostConfig.CPURealtimePeriod && hostConfig.CPURealtimePeriod != 0 {
+		return &InvalidResource{Field: "CPURealtimeRuntime", Value: strconv.FormatInt(hostConfig.CPURealtimeRuntime, 10)}
+	}
+	return nil
+}
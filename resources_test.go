@@ -0,0 +1,95 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHostConfigValidateResources(t *testing.T) {
+	swappiness := func(v int64) *int64 { return &v }
+	var tests = []struct {
+		hostConfig HostConfig
+		expected   error
+	}{
+		{HostConfig{}, nil},
+		{HostConfig{OomKillDisable: true, OomScoreAdj: -500, MemorySwappiness: swappiness(0), KernelMemory: 64 << 20}, nil},
+		{HostConfig{OomScoreAdj: 1001}, &InvalidResource{Field: "OomScoreAdj", Value: "1001"}},
+		{HostConfig{MemorySwappiness: swappiness(101)}, &InvalidResource{Field: "MemorySwappiness", Value: "101"}},
+		{HostConfig{BlkioWeight: 500, BlkioWeightDevice: []WeightDevice{{Path: "/dev/sda", Weight: 10}}}, nil},
+		{HostConfig{CpusetCpus: "0-3,6", CpusetMems: "0", NanoCpus: NanoCPUs(1.5), CPURealtimePeriod: 1000000, CPURealtimeRuntime: 950000}, nil},
+		{HostConfig{CpusetCpus: "0-3;6"}, &InvalidResource{Field: "CpusetCpus", Value: "0-3;6"}},
+		{HostConfig{NanoCpus: -1}, &InvalidResource{Field: "NanoCpus", Value: "-1"}},
+		{HostConfig{CPURealtimePeriod: 1000, CPURealtimeRuntime: 2000}, &InvalidResource{Field: "CPURealtimeRuntime", Value: "2000"}},
+		{HostConfig{BlkioWeight: 5}, &InvalidResource{Field: "BlkioWeight", Value: "5"}},
+		{HostConfig{BlkioWeightDevice: []WeightDevice{{Path: "/dev/sda", Weight: 1001}}}, &InvalidResource{Field: "BlkioWeightDevice", Value: "1001"}},
+	}
+	for _, tt := range tests {
+		if err := tt.hostConfig.validateResources(); !reflect.DeepEqual(err, tt.expected) {
+			t.Errorf("HostConfig.validateResources(%#v): wrong error. Want %#v. Got %#v.", tt.hostConfig, tt.expected, err)
+		}
+	}
+}
+
+func TestCreateContainerMemoryTuning(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client, err := NewVersionedClient("http://localhost:4243", "1.21")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.StrictAPIVersion = true
+	client.HTTPClient = &http.Client{Transport: fakeRT}
+	_, err = client.CreateContainer(CreateContainerOptions{
+		Config:     &Config{Image: "base"},
+		HostConfig: &HostConfig{OomKillDisable: true, OomScoreAdj: 500},
+	})
+	if e, ok := err.(*UnsupportedField); !ok || e.Field != "HostConfig.OomScoreAdj" {
+		t.Errorf("CreateContainer: wrong error. Want *UnsupportedField. Got %#v.", err)
+	}
+	_, err = client.CreateContainer(CreateContainerOptions{
+		Config:     &Config{Image: "base"},
+		HostConfig: &HostConfig{OomScoreAdj: -1001},
+	})
+	if _, ok := err.(*InvalidResource); !ok {
+		t.Errorf("CreateContainer: wrong error. Want *InvalidResource. Got %#v.", err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("CreateContainer: the container should not be created. Got %d requests.", len(fakeRT.requests))
+	}
+}
+
+func TestCreateContainerBlkioThrottling(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	hostConfig := HostConfig{
+		BlkioDeviceReadBps:   []ThrottleDevice{{Path: "/dev/sda", Rate: 10 << 20}},
+		BlkioDeviceWriteBps:  []ThrottleDevice{{Path: "/dev/sda", Rate: 5 << 20}},
+		BlkioDeviceReadIOps:  []ThrottleDevice{{Path: "/dev/sdb", Rate: 1000}},
+		BlkioDeviceWriteIOps: []ThrottleDevice{{Path: "/dev/sdb", Rate: 0}},
+	}
+	if _, err := client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "base"}, HostConfig: &hostConfig}); err != nil {
+		t.Fatal(err)
+	}
+	var gotBody struct{ HostConfig HostConfig }
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotBody.HostConfig, hostConfig) {
+		t.Errorf("CreateContainer: wrong HostConfig. Want %#v. Got %#v.", hostConfig, gotBody.HostConfig)
+	}
+}
+
+func TestNanoCPUs(t *testing.T) {
+	if got := NanoCPUs(1.5); got != 1500000000 {
+		t.Errorf("NanoCPUs(1.5): wrong value. Want %d. Got %d.", 1500000000, got)
+	}
+	if got := NanoCPUs(0.001); got != 1000000 {
+		t.Errorf("NanoCPUs(0.001): wrong value. Want %d. Got %d.", 1000000, got)
+	}
+}
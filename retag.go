@@ -0,0 +1,88 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"io"
+	"sort"
+)
+
+// RetagImageOptions specify parameters to the RetagImage and RetagImages
+// functions.
+type RetagImageOptions struct {
+	// Pull pulls the source image before tagging it, with PullAuth.
+	Pull     bool
+	PullAuth AuthConfiguration
+
+	// Push pushes the new tag to its registry, with PushAuth.
+	Push     bool
+	PushAuth AuthConfiguration
+
+	// RemoveSource removes the source tag once the image is tagged, and
+	// pushed if requested. The image itself is kept, as it's still tagged.
+	RemoveSource bool
+
+	// OutputStream receives the progress of the pull and the push.
+	OutputStream io.Writer
+}
+
+// RetagImage tags the image src as dst, a repository optionally followed by
+// a tag, which defaults to "latest". It may also pull the source, push the
+// new tag and remove the source tag, for promoting images between
+// repositories or registries. Empty instances of AuthConfiguration may be
+// used in the options to use the AuthConfigs of the client.
+//
+// The source must be a tag rather than an image ID when RemoveSource is set.
+func (c *Client) RetagImage(src, dst string, opts RetagImageOptions) error {
+	ref, err := ParseReference(dst)
+	if err != nil {
+		return err
+	}
+	if ref.Digest != "" {
+		return ErrInvalidReference
+	}
+	if opts.Pull {
+		repository, tag := ParseRepositoryTag(src)
+		pullOpts := PullImageOptions{Repository: repository, Tag: tag, OutputStream: opts.OutputStream}
+		if err := c.PullImage(pullOpts, opts.PullAuth); err != nil {
+			return err
+		}
+	}
+	repository, tag := ParseRepositoryTag(dst)
+	if err := c.TagImage(src, TagImageOptions{Repo: repository, Tag: tag}); err != nil {
+		return err
+	}
+	if opts.Push {
+		pushOpts := PushImageOptions{Name: repository, Tag: tag, OutputStream: opts.OutputStream}
+		if err := c.PushImage(pushOpts, opts.PushAuth); err != nil {
+			return err
+		}
+	}
+	if opts.RemoveSource && src != dst {
+		return c.RemoveImage(src)
+	}
+	return nil
+}
+
+// RetagImages calls RetagImage for each pair of source and destination in
+// tags, in the order of the sources. When some of them fail, the error is a
+// *MultiError keyed by source, and the other images are still retagged.
+func (c *Client) RetagImages(tags map[string]string, opts RetagImageOptions) error {
+	sources := make([]string, 0, len(tags))
+	for src := range tags {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+	errs := make(map[string]error)
+	for _, src := range sources {
+		if err := c.RetagImage(src, tags[src], opts); err != nil {
+			errs[src] = err
+		}
+	}
+	if len(errs) > 0 {
+		return &MultiError{Errors: errs}
+	}
+	return nil
+}
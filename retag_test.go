@@ -0,0 +1,105 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func retagServer(failTag string) (*httptest.Server, func() []string) {
+	var mut sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		mut.Unlock()
+		if r.URL.Path == "/images/"+failTag+"/tag" {
+			http.Error(w, "no such image", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	return server, func() []string {
+		mut.Lock()
+		defer mut.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+func TestRetagImage(t *testing.T) {
+	server, requests := retagServer("")
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := RetagImageOptions{Pull: true, Push: true, RemoveSource: true}
+	if err := client.RetagImage("staging.example.com/app:1.2", "prod.example.com/app:1.2", opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"POST /images/create?fromImage=staging.example.com%2Fapp&tag=1.2",
+		"POST /images/staging.example.com/app:1.2/tag?repo=prod.example.com%2Fapp&tag=1.2",
+		"POST /images/prod.example.com/app/push?tag=1.2",
+		"DELETE /images/staging.example.com/app:1.2?",
+	}
+	if got := requests(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("RetagImage: wrong requests.\nWant %#v.\nGot  %#v.", expected, got)
+	}
+}
+
+func TestRetagImageTagOnly(t *testing.T) {
+	server, requests := retagServer("")
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RetagImage("app:rc", "app:stable", RetagImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"POST /images/app:rc/tag?repo=app&tag=stable"}
+	if got := requests(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("RetagImage: wrong requests.\nWant %#v.\nGot  %#v.", expected, got)
+	}
+}
+
+func TestRetagImageInvalidDestination(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{status: http.StatusOK})
+	for _, dst := range []string{"", "app@sha256:4b82b6ae4b82b6ae4b82b6ae4b82b6ae4b82b6ae", "App:1"} {
+		if err := client.RetagImage("app:rc", dst, RetagImageOptions{}); err != ErrInvalidReference {
+			t.Errorf("RetagImage(%q): wrong error. Want %#v. Got %#v.", dst, ErrInvalidReference, err)
+		}
+	}
+}
+
+func TestRetagImages(t *testing.T) {
+	server, requests := retagServer("missing:1")
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.RetagImages(map[string]string{"b:1": "b:2", "missing:1": "missing:2", "a:1": "a:2"}, RetagImageOptions{})
+	multi, ok := err.(*MultiError)
+	if !ok {
+		t.Fatalf("RetagImages: wrong error. Want *MultiError. Got %#v.", err)
+	}
+	if len(multi.Errors) != 1 || multi.Errors["missing:1"] != ErrNoSuchImage {
+		t.Errorf("RetagImages: wrong errors: %#v.", multi.Errors)
+	}
+	expected := []string{
+		"POST /images/a:1/tag?repo=a&tag=2",
+		"POST /images/b:1/tag?repo=b&tag=2",
+		"POST /images/missing:1/tag?repo=missing&tag=2",
+	}
+	if got := requests(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("RetagImages: wrong requests.\nWant %#v.\nGot  %#v.", expected, got)
+	}
+}
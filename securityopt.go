@@ -0,0 +1,54 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Values of HostConfig.SecurityOpt. The daemon silently applies its default
+// profiles when the options are not formatted as it expects, so they're best
+// built with the functions below.
+const (
+	// SeccompUnconfined runs the container without seccomp profile.
+	SeccompUnconfined = "seccomp=unconfined"
+
+	// NoNewPrivileges prevents the processes of the container from
+	// gaining privileges, through setuid binaries for example.
+	NoNewPrivileges = "no-new-privileges=true"
+)
+
+// SeccompProfile returns the security option of the given seccomp profile:
+// either the JSON document of the profile, or the path of a file holding it.
+// The daemon takes the content of the profile, not a path, so the file is
+// read here. The special profile "unconfined" disables seccomp.
+func SeccompProfile(pathOrJSON string) (string, error) {
+	if pathOrJSON == "unconfined" {
+		return SeccompUnconfined, nil
+	}
+	data := []byte(pathOrJSON)
+	if !strings.HasPrefix(strings.TrimSpace(pathOrJSON), "{") {
+		var err error
+		if data, err = ioutil.ReadFile(pathOrJSON); err != nil {
+			return "", err
+		}
+	}
+	var profile bytes.Buffer
+	if err := json.Compact(&profile, data); err != nil {
+		return "", fmt.Errorf("invalid seccomp profile: %s", err)
+	}
+	return "seccomp=" + profile.String(), nil
+}
+
+// AppArmorProfile returns the security option of the given AppArmor profile,
+// which must be loaded on the host. The profile "unconfined" disables
+// AppArmor.
+func AppArmorProfile(name string) string {
+	return "apparmor=" + name
+}
@@ -0,0 +1,49 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSeccompProfile(t *testing.T) {
+	f, err := ioutil.TempFile("", "seccomp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("{\n  \"defaultAction\": \"SCMP_ACT_ERRNO\"\n}\n")
+	f.Close()
+	var tests = []struct {
+		input    string
+		expected string
+	}{
+		{`{"defaultAction": "SCMP_ACT_ALLOW"}`, `seccomp={"defaultAction":"SCMP_ACT_ALLOW"}`},
+		{f.Name(), `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}`},
+		{"unconfined", SeccompUnconfined},
+	}
+	for _, tt := range tests {
+		opt, err := SeccompProfile(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opt != tt.expected {
+			t.Errorf("SeccompProfile(%q): wrong option. Want %q. Got %q.", tt.input, tt.expected, opt)
+		}
+	}
+	for _, input := range []string{`{"defaultAction":`, "/does/not/exist.json"} {
+		if _, err := SeccompProfile(input); err == nil {
+			t.Errorf("SeccompProfile(%q): expected an error", input)
+		}
+	}
+}
+
+func TestAppArmorProfile(t *testing.T) {
+	if opt := AppArmorProfile("docker-nginx"); opt != "apparmor=docker-nginx" {
+		t.Errorf("AppArmorProfile: wrong option. Got %q.", opt)
+	}
+}
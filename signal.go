@@ -4,6 +4,12 @@
 
 package docker
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // Signal represents a signal that can be send to the container on
 // KillContainer call.
 type Signal int
@@ -47,3 +53,58 @@ const (
 	SIGXCPU   = Signal(0x18)
 	SIGXFSZ   = Signal(0x19)
 )
+
+var signalNames = map[string]Signal{
+	"ABRT":   SIGABRT,
+	"ALRM":   SIGALRM,
+	"BUS":    SIGBUS,
+	"CHLD":   SIGCHLD,
+	"CLD":    SIGCLD,
+	"CONT":   SIGCONT,
+	"FPE":    SIGFPE,
+	"HUP":    SIGHUP,
+	"ILL":    SIGILL,
+	"INT":    SIGINT,
+	"IO":     SIGIO,
+	"IOT":    SIGIOT,
+	"KILL":   SIGKILL,
+	"PIPE":   SIGPIPE,
+	"POLL":   SIGPOLL,
+	"PROF":   SIGPROF,
+	"PWR":    SIGPWR,
+	"QUIT":   SIGQUIT,
+	"SEGV":   SIGSEGV,
+	"STKFLT": SIGSTKFLT,
+	"STOP":   SIGSTOP,
+	"SYS":    SIGSYS,
+	"TERM":   SIGTERM,
+	"TRAP":   SIGTRAP,
+	"TSTP":   SIGTSTP,
+	"TTIN":   SIGTTIN,
+	"TTOU":   SIGTTOU,
+	"UNUSED": SIGUNUSED,
+	"URG":    SIGURG,
+	"USR1":   SIGUSR1,
+	"USR2":   SIGUSR2,
+	"VTALRM": SIGVTALRM,
+	"WINCH":  SIGWINCH,
+	"XCPU":   SIGXCPU,
+	"XFSZ":   SIGXFSZ,
+}
+
+// ParseSignal parses a signal in the formats accepted by the StopSignal
+// setting of containers: a name, with or without the SIG prefix, like SIGINT
+// or INT, or a number, like 2.
+func ParseSignal(s string) (Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n <= 0 || n > 64 {
+			return 0, fmt.Errorf("invalid signal: %s", s)
+		}
+		return Signal(n), nil
+	}
+	signal, ok := signalNames[strings.TrimPrefix(strings.ToUpper(s), "SIG")]
+	if !ok {
+		return 0, fmt.Errorf("invalid signal: %s", s)
+	}
+	return signal, nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import "testing"
+
+func TestParseSignal(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected Signal
+	}{
+		{"SIGINT", SIGINT},
+		{"INT", SIGINT},
+		{"sigusr1", SIGUSR1},
+		{"9", SIGKILL},
+		{"37", Signal(37)},
+	}
+	for _, tt := range tests {
+		signal, err := ParseSignal(tt.input)
+		if err != nil {
+			t.Errorf("ParseSignal(%q): unexpected error: %s", tt.input, err)
+		}
+		if signal != tt.expected {
+			t.Errorf("ParseSignal(%q): wrong signal. Want %d. Got %d.", tt.input, tt.expected, signal)
+		}
+	}
+	for _, input := range []string{"", "SIGFOO", "0", "65"} {
+		if _, err := ParseSignal(input); err == nil {
+			t.Errorf("ParseSignal(%q): expected an error", input)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"log"
+	"os"
+	gosignal "os/signal"
+	"syscall"
+)
+
+// proxySignals forwards SIGINT, SIGTERM and SIGWINCH to the given container
+// until the returned function is called.
+func (c *Client) proxySignals(id string, out interface{}) func() {
+	signals := make(chan os.Signal, 8)
+	gosignal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go c.forwardSignals(id, out, signals, done)
+	return func() {
+		gosignal.Stop(signals)
+		close(done)
+	}
+}
+
+func (c *Client) forwardSignals(id string, out interface{}, signals <-chan os.Signal, done <-chan struct{}) {
+	for {
+		select {
+		case sig := <-signals:
+			if err := c.forwardSignal(id, out, sig); err != nil {
+				log.Printf("Error forwarding signal %s: %s", sig, err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (c *Client) forwardSignal(id string, out interface{}, sig os.Signal) error {
+	if sig == syscall.SIGWINCH {
+		height, width, err := TerminalSize(out)
+		if err != nil {
+			return err
+		}
+		return c.ResizeContainerTTY(id, height, width)
+	}
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return nil
+	}
+	return c.KillContainer(KillContainerOptions{ID: id, Signal: Signal(s)})
+}
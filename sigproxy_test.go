@@ -0,0 +1,43 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestForwardSignals(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusNoContent}
+	client := newTestClient(fakeRT)
+	signals := make(chan os.Signal)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		client.forwardSignals("a123456", &bytes.Buffer{}, signals, done)
+		close(finished)
+	}()
+	signals <- syscall.SIGINT
+	signals <- syscall.SIGTERM
+	// not a terminal, so it's not forwarded
+	signals <- syscall.SIGWINCH
+	close(done)
+	<-finished
+	if len(fakeRT.requests) != 2 {
+		t.Fatalf("forwardSignals: wrong number of requests. Want 2. Got %d.", len(fakeRT.requests))
+	}
+	for i, expected := range []string{"2", "15"} {
+		req := fakeRT.requests[i]
+		if req.URL.Path != "/containers/a123456/kill" {
+			t.Errorf("forwardSignals: wrong path. Want %q. Got %q.", "/containers/a123456/kill", req.URL.Path)
+		}
+		if got := req.URL.Query().Get("signal"); got != expected {
+			t.Errorf("forwardSignals: wrong signal. Want %q. Got %q.", expected, got)
+		}
+	}
+}
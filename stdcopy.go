@@ -19,8 +19,16 @@ const (
 var errInvalidStdHeader = errors.New("Unrecognized input header")
 
 func stdCopy(dstout, dsterr io.Writer, src io.Reader) (written int64, err error) {
+	return stdCopyBuffer(dstout, dsterr, src, make([]byte, 32*1024+stdWriterPrefixLen+1))
+}
+
+// stdCopyBuffer is like stdCopy, reading through the given buffer, which is
+// only reallocated for frames larger than it.
+func stdCopyBuffer(dstout, dsterr io.Writer, src io.Reader, buf []byte) (written int64, err error) {
+	if len(buf) <= stdWriterPrefixLen {
+		buf = make([]byte, 32*1024+stdWriterPrefixLen+1)
+	}
 	var (
-		buf       = make([]byte, 32*1024+stdWriterPrefixLen+1)
 		bufLen    = len(buf)
 		nr, nw    int
 		er, ew    error
@@ -0,0 +1,150 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+const defaultReconnectTimeout = time.Minute
+
+// StreamErrorCause is the cause of the end of a stream.
+type StreamErrorCause int
+
+const (
+	// StreamDaemonGone means that the daemon stopped or restarted.
+	StreamDaemonGone StreamErrorCause = iota + 1
+
+	// StreamContainerGone means that the container was removed.
+	StreamContainerGone
+
+	// StreamCanceled means that the stream was closed by the client.
+	StreamCanceled
+)
+
+func (cause StreamErrorCause) String() string {
+	switch cause {
+	case StreamDaemonGone:
+		return "the daemon is gone"
+	case StreamContainerGone:
+		return "the container is gone"
+	case StreamCanceled:
+		return "canceled"
+	}
+	return "unknown cause"
+}
+
+// StreamError is returned when a stream of logs, events or attached output
+// ends unexpectedly, telling why. Err is the error returned by the
+// connection, if any.
+type StreamError struct {
+	Cause StreamErrorCause
+	Err   error
+}
+
+func (e *StreamError) Error() string {
+	if e.Err == nil {
+		return "stream ended: " + e.Cause.String()
+	}
+	return fmt.Sprintf("stream ended: %s: %s", e.Cause, e.Err)
+}
+
+// classifyStreamEnd finds out why a stream that ended with the given error
+// ended, checking that the daemon is still reachable and, when id is not
+// empty, that the container still exists. It returns err untouched when
+// neither is gone.
+func (c *Client) classifyStreamEnd(id string, err error) error {
+	if pingErr := c.Ping(); pingErr != nil {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return &StreamError{Cause: StreamDaemonGone, Err: err}
+	}
+	if id != "" {
+		if _, inspectErr := c.InspectContainer(id); inspectErr != nil {
+			if _, ok := inspectErr.(*NoSuchContainer); ok {
+				return &StreamError{Cause: StreamContainerGone, Err: err}
+			}
+		}
+	}
+	return err
+}
+
+// logResumer strips the timestamps that prefix the lines of the logs followed
+// with Reconnect, and drops the lines received before a reconnection, so the
+// logs can be resumed from the timestamp of the last line received.
+type logResumer struct {
+	keep  bool
+	after time.Time
+	last  time.Time
+}
+
+// writer returns a writer of the lines of the logs to w.
+func (r *logResumer) writer(w io.Writer) *logLineWriter {
+	if w == nil {
+		w = ioutil.Discard
+	}
+	return &logLineWriter{r: r, w: w}
+}
+
+// resume marks the lines received so far as already written.
+func (r *logResumer) resume() {
+	r.after = r.last
+}
+
+type logLineWriter struct {
+	r   *logResumer
+	w   io.Writer
+	buf []byte
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+}
+
+func (w *logLineWriter) writeLine(line []byte) error {
+	if i := bytes.IndexByte(line, ' '); i > 0 {
+		if t, err := time.Parse(time.RFC3339Nano, string(line[:i])); err == nil {
+			if !t.After(w.r.after) {
+				return nil
+			}
+			w.r.last = t
+			if !w.r.keep {
+				line = line[i+1:]
+			}
+		}
+	}
+	_, err := w.w.Write(line)
+	return err
+}
+
+// flush writes the last line, when it's not terminated by a newline.
+func (w *logLineWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.writeLine(w.buf)
+	w.buf = nil
+	return err
+}
+
+// discard drops the last line, when it's not terminated by a newline.
+func (w *logLineWriter) discard() {
+	w.buf = nil
+}
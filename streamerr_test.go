@@ -0,0 +1,185 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func logFrame(line string) []byte {
+	header := make([]byte, 8)
+	header[0] = 1
+	binary.BigEndian.PutUint32(header[4:], uint32(len(line)))
+	return append(header, line...)
+}
+
+func TestClassifyStreamEnd(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_ping":
+			w.Write([]byte("OK"))
+		case "/containers/running/json":
+			w.Write([]byte(`{"Id":"running","State":{"Running":true}}`))
+		default:
+			http.Error(w, "no such container", http.StatusNotFound)
+		}
+	}))
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cut := errors.New("connection reset")
+	if err := client.classifyStreamEnd("running", cut); err != cut {
+		t.Errorf("classifyStreamEnd: wrong error. Want %#v. Got %#v.", cut, err)
+	}
+	err = client.classifyStreamEnd("removed", cut)
+	if e, ok := err.(*StreamError); !ok || e.Cause != StreamContainerGone || e.Err != cut {
+		t.Errorf("classifyStreamEnd: wrong error. Want a *StreamError for a removed container. Got %#v.", err)
+	}
+	server.Close()
+	err = client.classifyStreamEnd("running", cut)
+	if e, ok := err.(*StreamError); !ok || e.Cause != StreamDaemonGone {
+		t.Errorf("classifyStreamEnd: wrong error. Want a *StreamError for a stopped daemon. Got %#v.", err)
+	}
+}
+
+func TestLogsReconnect(t *testing.T) {
+	var mut sync.Mutex
+	var logRequests []string
+	inspects := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+		switch r.URL.Path {
+		case "/_ping":
+			w.Write([]byte("OK"))
+		case "/containers/c1/json":
+			inspects++
+			w.Write([]byte(`{"Id":"c1","State":{"Running":` + strconv.FormatBool(inspects == 1) + `}}`))
+		case "/containers/c1/logs":
+			query := r.URL.Query()
+			logRequests = append(logRequests, query.Get("since")+" "+query.Get("timestamps"))
+			w.Write(logFrame("2015-04-23T08:00:00.5Z line 1\n"))
+			if len(logRequests) == 1 {
+				// the connection breaks in the middle of a frame
+				w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 42})
+				w.(http.Flusher).Flush()
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.Write(logFrame("2015-04-23T08:00:00.7Z line 2\n"))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = client.Logs(LogsOptions{Container: "c1", OutputStream: &out, Stdout: true, Follow: true, Reconnect: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "line 1\nline 2\n" {
+		t.Errorf("Logs: wrong output. Want %q. Got %q.", "line 1\nline 2\n", out.String())
+	}
+	expected := []string{" 1", "1429776000 1"}
+	if !reflect.DeepEqual(logRequests, expected) {
+		t.Errorf("Logs: wrong since and timestamps parameters. Want %#v. Got %#v.", expected, logRequests)
+	}
+}
+
+func TestLogsReconnectContainerGone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_ping":
+			w.Write([]byte("OK"))
+		case "/containers/c1/logs":
+			w.Write(logFrame("2015-04-23T08:00:00.5Z line 1\n"))
+		default:
+			http.Error(w, "no such container", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = client.Logs(LogsOptions{Container: "c1", OutputStream: &out, Stdout: true, Timestamps: true, Follow: true, Reconnect: true})
+	if e, ok := err.(*StreamError); !ok || e.Cause != StreamContainerGone {
+		t.Errorf("Logs: wrong error. Want a *StreamError for a removed container. Got %#v.", err)
+	}
+	if out.String() != "2015-04-23T08:00:00.5Z line 1\n" {
+		t.Errorf("Logs: wrong output. Got %q.", out.String())
+	}
+}
+
+func TestEventStreamReconnect(t *testing.T) {
+	var mut sync.Mutex
+	var queries []string
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_ping" {
+			w.Write([]byte("OK"))
+			return
+		}
+		mut.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		n := len(queries)
+		mut.Unlock()
+		enc := json.NewEncoder(w)
+		switch n {
+		case 1:
+			enc.Encode(streamEvents[0])
+		case 2:
+			enc.Encode(streamEvents[0])
+			enc.Encode(streamEvents[1])
+		default:
+			w.(http.Flusher).Flush()
+			<-block
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := client.ResumeWithOptions(EventCursor{}, EventStreamOptions{Reconnect: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cursor EventCursor
+	got := receiveEvents(t, s, &cursor, 2)
+	if got[0] != "create c1" || got[1] != "start c1" {
+		t.Errorf("EventStream: wrong events. Got %#v.", got)
+	}
+	s.Close()
+	close(block)
+	if _, ok := <-s.C; ok {
+		t.Error("EventStream: C should be closed")
+	}
+	mut.Lock()
+	defer mut.Unlock()
+	if len(queries) < 2 || queries[1] != "since=1429776000" {
+		t.Errorf("EventStream: wrong queries. Got %#v.", queries)
+	}
+	if err := s.Err(); err == io.EOF {
+		t.Errorf("EventStream.Err: wrong error. Got %#v.", err)
+	}
+}
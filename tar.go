@@ -5,47 +5,125 @@
 package docker
 
 import (
+	"archive/tar"
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
-
-	"github.com/docker/docker/pkg/archive"
-	"github.com/docker/docker/pkg/fileutils"
+	"time"
 )
 
-func createTarStream(srcPath string) (io.ReadCloser, error) {
+// createTarStream creates a tar stream with the contents of the given
+// directory, skipping files that match the patterns in its .dockerignore
+// file. The Dockerfile (named dockerfile, or "Dockerfile" when it's empty)
+// and the .dockerignore file are always included, like the docker CLI does.
+func createTarStream(srcPath, dockerfile string) (*io.PipeReader, error) {
 	excludes, err := parseDockerignore(srcPath)
 	if err != nil {
 		return nil, err
 	}
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	excludes.keep(filepath.ToSlash(filepath.Clean(dockerfile)), ".dockerignore")
 
 	if err := validateContextDirectory(srcPath, excludes); err != nil {
 		return nil, err
 	}
-	tarOpts := &archive.TarOptions{
-		ExcludePatterns: excludes,
-		Compression:     archive.Uncompressed,
-		NoLchown:        true,
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(writeTarStream(w, srcPath, excludes))
+	}()
+	return r, nil
+}
+
+// writeTarStream writes the contents of the given directory to w, in tar
+// format, skipping excluded files.
+func writeTarStream(w io.Writer, srcPath string, excludes *ignoreMatcher) error {
+	tw := tar.NewWriter(w)
+	err := walkContext(srcPath, excludes, func(filePath, relFilePath string, f os.FileInfo) error {
+		if f.Mode()&(os.ModeNamedPipe|os.ModeSocket|os.ModeDevice) != 0 {
+			return nil
+		}
+		var link string
+		if f.Mode()&os.ModeSymlink != 0 {
+			var err error
+			if link, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(f, link)
+		if err != nil {
+			return err
+		}
+		header.Name = relFilePath
+		if f.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !f.Mode().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
 	}
-	return archive.TarWithOptions(srcPath, tarOpts)
+	return tw.Close()
+}
+
+// walkContext walks the given directory, calling fn for each file or
+// directory that is not excluded. relFilePath is always slash-separated.
+//
+// Excluded directories are skipped entirely, unless an exception pattern may
+// match some of their contents.
+func walkContext(srcPath string, excludes *ignoreMatcher, fn func(filePath, relFilePath string, f os.FileInfo) error) error {
+	return filepath.Walk(srcPath, func(filePath string, f os.FileInfo, err error) error {
+		relFilePath, relErr := filepath.Rel(srcPath, filePath)
+		if relErr != nil {
+			return relErr
+		}
+		if relFilePath == "." {
+			return err
+		}
+		relFilePath = filepath.ToSlash(relFilePath)
+		if excludes.matches(relFilePath) {
+			if f != nil && f.IsDir() && !excludes.hasExceptions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fn(filePath, relFilePath, f)
+	})
 }
 
 // validateContextDirectory checks if all the contents of the directory
 // can be read and returns an error if some files can't be read.
 // Symlinks which point to non-existing files don't trigger an error
-func validateContextDirectory(srcPath string, excludes []string) error {
+func validateContextDirectory(srcPath string, excludes *ignoreMatcher) error {
 	return filepath.Walk(filepath.Join(srcPath, "."), func(filePath string, f os.FileInfo, err error) error {
 		// skip this directory/file if it's not in the path, it won't get added to the context
 		if relFilePath, err := filepath.Rel(srcPath, filePath); err != nil {
 			return err
-		} else if skip, err := fileutils.Matches(relFilePath, excludes); err != nil {
-			return err
-		} else if skip {
-			if f.IsDir() {
+		} else if relFilePath != "." && excludes.matches(filepath.ToSlash(relFilePath)) {
+			if f != nil && f.IsDir() && !excludes.hasExceptions() {
 				return filepath.SkipDir
 			}
 			return nil
@@ -78,22 +156,163 @@ func validateContextDirectory(srcPath string, excludes []string) error {
 	})
 }
 
-func parseDockerignore(root string) ([]string, error) {
-	var excludes []string
+func parseDockerignore(root string) (*ignoreMatcher, error) {
 	ignore, err := ioutil.ReadFile(path.Join(root, ".dockerignore"))
 	if err != nil && !os.IsNotExist(err) {
-		return excludes, fmt.Errorf("error reading .dockerignore: '%s'", err)
+		return nil, fmt.Errorf("error reading .dockerignore: '%s'", err)
 	}
-	for _, pattern := range strings.Split(string(ignore), "\n") {
-		matches, err := filepath.Match(pattern, "Dockerfile")
+	return newIgnoreMatcher(strings.Split(string(ignore), "\n"))
+}
+
+// ignoreMatcher matches paths against a list of .dockerignore patterns.
+//
+// Patterns follow the syntax of filepath.Match, with the addition of "**",
+// which matches any number of directories. Patterns starting with "!" are
+// exceptions, re-including paths excluded by previous patterns. The last
+// matching pattern wins. A pattern that matches a directory also matches
+// everything inside it.
+type ignoreMatcher struct {
+	patterns []ignorePattern
+	kept     map[string]bool
+}
+
+type ignorePattern struct {
+	text      string
+	exception bool
+	regexp    *regexp.Regexp
+}
+
+func newIgnoreMatcher(lines []string) (*ignoreMatcher, error) {
+	m := ignoreMatcher{kept: make(map[string]bool)}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var exception bool
+		if line[0] == '!' {
+			exception = true
+			line = strings.TrimSpace(line[1:])
+			if line == "" {
+				return nil, fmt.Errorf("bad .dockerignore pattern: '!', error: illegal exclusion pattern")
+			}
+		}
+		pattern := filepath.ToSlash(filepath.Clean(line))
+		pattern = strings.TrimPrefix(pattern, "/")
+		re, err := ignorePatternRegexp(pattern)
 		if err != nil {
-			return excludes, fmt.Errorf("bad .dockerignore pattern: '%s', error: %s", pattern, err)
+			return nil, fmt.Errorf("bad .dockerignore pattern: '%s', error: %s", line, err)
+		}
+		m.patterns = append(m.patterns, ignorePattern{text: line, exception: exception, regexp: re})
+	}
+	return &m, nil
+}
+
+// keep marks the given paths, and the directories containing them, as never
+// excluded. Kept directories are walked, but their contents are still subject
+// to the patterns.
+func (m *ignoreMatcher) keep(paths ...string) {
+	for _, p := range paths {
+		for ; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+			m.kept[p] = true
+		}
+	}
+}
+
+func (m *ignoreMatcher) hasExceptions() bool {
+	for _, p := range m.patterns {
+		if p.exception {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the given slash-separated path, relative to the
+// root of the context, is excluded.
+func (m *ignoreMatcher) matches(relPath string) bool {
+	if m.kept[relPath] {
+		return false
+	}
+	var excluded bool
+	for _, p := range m.patterns {
+		if p.regexp.MatchString(relPath) {
+			excluded = !p.exception
 		}
-		if matches {
-			return excludes, fmt.Errorf("dockerfile was excluded by .dockerignore pattern '%s'", pattern)
+	}
+	return excluded
+}
+
+// ignorePatternRegexp converts a .dockerignore pattern to a regular
+// expression that matches the paths covered by the pattern, including the
+// contents of matching directories.
+func ignorePatternRegexp(pattern string) (*regexp.Regexp, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch {
+		case ch == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				i++
+				buf.WriteString("(.*/)?")
+			} else {
+				buf.WriteString(".*")
+			}
+		case ch == '*':
+			buf.WriteString("[^/]*")
+		case ch == '?':
+			buf.WriteString("[^/]")
+		case ch == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, filepath.ErrBadPattern
+			}
+			buf.WriteString(pattern[i : i+end+2])
+			i += end + 1
+		case ch == '\\' && i+1 < len(pattern):
+			i++
+			buf.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(ch)))
 		}
-		excludes = append(excludes, pattern)
 	}
+	buf.WriteString("(/.*)?$")
+	return regexp.Compile(buf.String())
+}
 
-	return excludes, nil
+// createTarFromFiles creates an in-memory tar archive containing the given
+// files, indexed by their path in the archive. Entries are sorted by path and
+// have a fixed modification time, so the same files always produce the same
+// archive.
+func createTarFromFiles(files map[string][]byte) (io.Reader, error) {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, p := range paths {
+		header := tar.Header{
+			Name:    path.Clean(strings.TrimPrefix(filepath.ToSlash(p), "/")),
+			Mode:    0644,
+			Size:    int64(len(files[p])),
+			ModTime: time.Unix(0, 0),
+		}
+		if err := tw.WriteHeader(&header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(files[p]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
 }
@@ -0,0 +1,100 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/docker/docker/pkg/term"
+)
+
+// ErrNotTerminal is returned by the terminal helpers when the given stream is
+// not a terminal.
+var ErrNotTerminal = errors.New("not a terminal")
+
+// Terminal is a local terminal put in raw mode, for an interactive session
+// with a container, as when attaching to a container or starting an exec
+// instance with a TTY. It must be restored when the session ends.
+type Terminal struct {
+	fd    uintptr
+	state *term.State
+	once  sync.Once
+}
+
+// IsTerminal reports whether the given stream, like os.Stdin, is a terminal.
+func IsTerminal(stream interface{}) bool {
+	_, isTerminal := terminalFd(stream)
+	return isTerminal
+}
+
+// terminalFd returns the file descriptor of the given stream, and whether
+// it's a terminal.
+func terminalFd(stream interface{}) (uintptr, bool) {
+	f, ok := stream.(*os.File)
+	if !ok {
+		return 0, false
+	}
+	return f.Fd(), term.IsTerminal(f.Fd())
+}
+
+// MakeRawTerminal puts the terminal of the given stream, usually os.Stdin, in
+// raw mode, so keystrokes are sent to the container as they're typed and
+// control sequences, like Ctrl-C, are interpreted by the container rather
+// than by the local process.
+func MakeRawTerminal(stream interface{}) (*Terminal, error) {
+	fd, isTerminal := terminalFd(stream)
+	if !isTerminal {
+		return nil, ErrNotTerminal
+	}
+	// unlike SetRawTerminal, MakeRaw doesn't exit the process on SIGINT,
+	// which is left to the caller, like the signal proxy of attach
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	return &Terminal{fd: fd, state: state}, nil
+}
+
+// rawTerminal is a terminal put in raw mode by makeRawTerminal.
+type rawTerminal interface {
+	Restore() error
+}
+
+// makeRawTerminal puts the terminal of the given stream in raw mode, like
+// MakeRawTerminal, for attach and exec. It's replaced in tests, which don't
+// have a terminal.
+var makeRawTerminal = func(stream interface{}) (rawTerminal, error) {
+	terminal, err := MakeRawTerminal(stream)
+	if err != nil {
+		return nil, err
+	}
+	return terminal, nil
+}
+
+// Restore restores the terminal to the state it was in before being put in
+// raw mode. It may be called more than once.
+func (t *Terminal) Restore() error {
+	var err error
+	t.once.Do(func() {
+		err = term.RestoreTerminal(t.fd, t.state)
+	})
+	return err
+}
+
+// TerminalSize returns the size of the terminal of the given stream, usually
+// os.Stdout, in characters.
+func TerminalSize(stream interface{}) (height, width int, err error) {
+	fd, isTerminal := terminalFd(stream)
+	if !isTerminal {
+		return 0, 0, ErrNotTerminal
+	}
+	ws, err := term.GetWinsize(fd)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(ws.Height), int(ws.Width), nil
+}
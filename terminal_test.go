@@ -0,0 +1,83 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTerminalHelpersNotTerminal(t *testing.T) {
+	f, err := ioutil.TempFile("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	for _, stream := range []interface{}{&bytes.Buffer{}, f, nil} {
+		if IsTerminal(stream) {
+			t.Errorf("IsTerminal(%#v): want false. Got true.", stream)
+		}
+		if _, err := MakeRawTerminal(stream); err != ErrNotTerminal {
+			t.Errorf("MakeRawTerminal(%#v): wrong error. Want %#v. Got %#v.", stream, ErrNotTerminal, err)
+		}
+		if _, _, err := TerminalSize(stream); err != ErrNotTerminal {
+			t.Errorf("TerminalSize(%#v): wrong error. Want %#v. Got %#v.", stream, ErrNotTerminal, err)
+		}
+	}
+}
+
+type fakeTerminal struct {
+	restored int
+}
+
+func (t *fakeTerminal) Restore() error {
+	t.restored++
+	return nil
+}
+
+func TestExecStartRawTerminal(t *testing.T) {
+	var terminals []*fakeTerminal
+	defer func(original func(interface{}) (rawTerminal, error)) { makeRawTerminal = original }(makeRawTerminal)
+	makeRawTerminal = func(stream interface{}) (rawTerminal, error) {
+		terminal := &fakeTerminal{}
+		terminals = append(terminals, terminal)
+		return terminal, nil
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	for _, raw := range []bool{true, false} {
+		terminals = nil
+		var stdout bytes.Buffer
+		opts := StartExecOptions{InputStream: strings.NewReader("ls\n"), OutputStream: &stdout, RawTerminal: raw}
+		if err := client.StartExec("4fa6e0f0c678", opts); err != nil {
+			t.Fatal(err)
+		}
+		if !raw {
+			if len(terminals) != 0 {
+				t.Errorf("StartExec: the terminal should not be made raw without RawTerminal.")
+			}
+			continue
+		}
+		if len(terminals) != 1 || terminals[0].restored != 1 {
+			t.Errorf("StartExec: the terminal should be made raw, and restored once. Got %d terminals.", len(terminals))
+		}
+		if stdout.String() != "hello" {
+			t.Errorf("StartExec: wrong output. Want %q. Got %q.", "hello", stdout.String())
+		}
+	}
+}
@@ -317,6 +317,14 @@ func (s *DockerServer) createContainer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	name := r.URL.Query().Get("name")
+	if name != "" {
+		if _, _, err := s.findContainer(name); err == nil {
+			msg := fmt.Sprintf("Conflict. The name %q is already in use by another container.", name)
+			http.Error(w, msg, http.StatusConflict)
+			return
+		}
+	}
 	w.WriteHeader(http.StatusCreated)
 	ports := map[docker.Port][]docker.PortBinding{}
 	for port := range config.ExposedPorts {
@@ -337,7 +345,7 @@ func (s *DockerServer) createContainer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	container := docker.Container{
-		Name:    r.URL.Query().Get("name"),
+		Name:    name,
 		ID:      s.generateID(),
 		Created: time.Now(),
 		Path:    path,
@@ -577,7 +585,7 @@ func (s *DockerServer) findContainer(id string) (*docker.Container, int, error)
 	s.cMut.RLock()
 	defer s.cMut.RUnlock()
 	for i, container := range s.containers {
-		if container.ID == id {
+		if container.ID == id || (container.Name != "" && container.Name == id) {
 			return container, i, nil
 		}
 	}
@@ -241,6 +241,24 @@ func TestCreateContainerInvalidBody(t *testing.T) {
 	}
 }
 
+func TestCreateContainerNameConflict(t *testing.T) {
+	server := DockerServer{}
+	server.imgIDs = map[string]string{"base": "a1234"}
+	server.buildMuxer()
+	body := `{"Cmd":["date"], "Image":"base"}`
+	request, _ := http.NewRequest("POST", "/containers/create?name=web", strings.NewReader(body))
+	server.ServeHTTP(httptest.NewRecorder(), request)
+	recorder := httptest.NewRecorder()
+	request, _ = http.NewRequest("POST", "/containers/create?name=web", strings.NewReader(body))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("CreateContainer: wrong status. Want %d. Got %d.", http.StatusConflict, recorder.Code)
+	}
+	if len(server.containers) != 1 {
+		t.Errorf("CreateContainer: wrong number of containers. Want 1. Got %d.", len(server.containers))
+	}
+}
+
 func TestCreateContainerImageNotFound(t *testing.T) {
 	server := DockerServer{}
 	server.buildMuxer()
@@ -0,0 +1,113 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"io"
+	"time"
+)
+
+// maxThrottleCredit is how far behind its limit a throttled transfer may
+// fall before the unused bandwidth is forgotten, so that a transfer resuming
+// after a pause doesn't burst.
+const maxThrottleCredit = time.Second
+
+// throttle keeps the average rate of a transfer under a limit, in bytes per
+// second, by sleeping as needed after each chunk.
+type throttle struct {
+	limit       int64
+	start       time.Time
+	transferred int64
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newThrottle(limit int64) *throttle {
+	return &throttle{limit: limit, now: time.Now, sleep: time.Sleep}
+}
+
+// chunk returns the largest prefix of p that may be transferred at once.
+func (t *throttle) chunk(p []byte) []byte {
+	if int64(len(p)) > t.limit {
+		return p[:t.limit]
+	}
+	return p
+}
+
+// wait accounts for n transferred bytes, blocking until the average rate of
+// the transfer is back under the limit.
+func (t *throttle) wait(n int) {
+	now := t.now()
+	if t.start.IsZero() {
+		t.start = now
+	}
+	expected := time.Duration(float64(t.transferred) / float64(t.limit) * float64(time.Second))
+	if now.Sub(t.start)-expected > maxThrottleCredit {
+		t.start = now.Add(-expected - maxThrottleCredit)
+	}
+	t.transferred += int64(n)
+	expected = time.Duration(float64(t.transferred) / float64(t.limit) * float64(time.Second))
+	if d := expected - now.Sub(t.start); d > 0 {
+		t.sleep(d)
+	}
+}
+
+type throttledWriter struct {
+	w io.Writer
+	t *throttle
+}
+
+// newThrottledWriter wraps w in a writer limited to the given number of
+// bytes per second, or returns w untouched when there's no limit.
+func newThrottledWriter(w io.Writer, limit int64) io.Writer {
+	if limit <= 0 || w == nil {
+		return w
+	}
+	return &throttledWriter{w: w, t: newThrottle(limit)}
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		n, err := w.w.Write(w.t.chunk(p))
+		written += n
+		w.t.wait(n)
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+type throttledReader struct {
+	r io.Reader
+	t *throttle
+}
+
+// newThrottledReader wraps r in a reader limited to the given number of
+// bytes per second, or returns r untouched when there's no limit.
+func newThrottledReader(r io.Reader, limit int64) io.Reader {
+	if limit <= 0 || r == nil {
+		return r
+	}
+	return &throttledReader{r: r, t: newThrottle(limit)}
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(r.t.chunk(p))
+	r.t.wait(n)
+	return n, err
+}
+
+// Close closes the underlying reader, if it's an io.Closer, as the HTTP
+// client closes the body of the requests once it's sent.
+func (r *throttledReader) Close() error {
+	if closer, ok := r.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func installFakeClock(t *throttle) *fakeClock {
+	clock := &fakeClock{now: time.Unix(1420070400, 0)}
+	t.now = clock.Now
+	t.sleep = clock.Sleep
+	return clock
+}
+
+func TestThrottledWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newThrottledWriter(&buf, 100).(*throttledWriter)
+	clock := installFakeClock(w.t)
+	n, err := w.Write(make([]byte, 250))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 250 || buf.Len() != 250 {
+		t.Errorf("throttledWriter: wrong number of bytes written. Want 250. Got %d (%d).", n, buf.Len())
+	}
+	expected := []time.Duration{time.Second, time.Second, 500 * time.Millisecond}
+	if !reflect.DeepEqual(clock.slept, expected) {
+		t.Errorf("throttledWriter: wrong sleeps. Want %v. Got %v.", expected, clock.slept)
+	}
+}
+
+func TestThrottleForgetsUnusedBandwidth(t *testing.T) {
+	th := newThrottle(100)
+	clock := installFakeClock(th)
+	th.wait(100)
+	clock.now = clock.now.Add(time.Minute)
+	// after a pause, one second worth of data goes through right away
+	th.wait(100)
+	th.wait(100)
+	expected := []time.Duration{time.Second, time.Second}
+	if !reflect.DeepEqual(clock.slept, expected) {
+		t.Errorf("throttle: wrong sleeps. Want %v. Got %v.", expected, clock.slept)
+	}
+}
+
+func TestThrottledReader(t *testing.T) {
+	r := newThrottledReader(strings.NewReader(strings.Repeat("a", 300)), 200).(*throttledReader)
+	clock := installFakeClock(r.t)
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 300 {
+		t.Errorf("throttledReader: wrong number of bytes read. Want 300. Got %d.", len(data))
+	}
+	var total time.Duration
+	for _, d := range clock.slept {
+		total += d
+	}
+	if total != 1500*time.Millisecond {
+		t.Errorf("throttledReader: wrong total sleep. Want %v. Got %v.", 1500*time.Millisecond, total)
+	}
+}
+
+func TestThrottleNoLimit(t *testing.T) {
+	var buf bytes.Buffer
+	if w := newThrottledWriter(&buf, 0); w != &buf {
+		t.Errorf("newThrottledWriter: should return the writer untouched without a limit. Got %#v.", w)
+	}
+	r := strings.NewReader("")
+	if got := newThrottledReader(r, -1); got != r {
+		t.Errorf("newThrottledReader: should return the reader untouched without a limit. Got %#v.", got)
+	}
+}
+
+func TestUploadToContainerBandwidthLimit(t *testing.T) {
+	fakeRT := &FakeRoundTripper{status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := UploadToContainerOptions{InputStream: strings.NewReader("tar content"), Path: "/tmp", BandwidthLimit: 1 << 20}
+	if err := client.UploadToContainer("a2344", opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := fakeRT.requests[0].URL.RawQuery; got != "path=%2Ftmp" {
+		t.Errorf("UploadToContainer: wrong query string. Want %q. Got %q.", "path=%2Ftmp", got)
+	}
+}
@@ -57,7 +57,13 @@ func tlsDialWithDialer(dialer *net.Dialer, network, addr string, config *tls.Con
 	if err != nil {
 		return nil, err
 	}
+	return tlsHandshake(rawConn, addr, config, errChannel)
+}
 
+// tlsHandshake starts a TLS session on a connection to the given address. When
+// errChannel is not nil, the handshake is aborted as soon as it receives an
+// error.
+func tlsHandshake(rawConn net.Conn, addr string, config *tls.Config, errChannel chan error) (net.Conn, error) {
 	colonPos := strings.LastIndex(addr, ":")
 	if colonPos == -1 {
 		colonPos = len(addr)
@@ -75,7 +81,8 @@ func tlsDialWithDialer(dialer *net.Dialer, network, addr string, config *tls.Con
 
 	conn := tls.Client(rawConn, config)
 
-	if timeout == 0 {
+	var err error
+	if errChannel == nil {
 		err = conn.Handshake()
 	} else {
 		go func() {
@@ -0,0 +1,55 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+// TrustResolver resolves a tag of an image to the digest of the manifest
+// signed for it, like the Notary server used by Docker Content Trust. It
+// returns an error when the tag has no valid signature.
+//
+// The NotaryResolver of the registry package resolves the tags from a Notary
+// server, verifying the signatures of the TUF metadata of the repository.
+type TrustResolver interface {
+	ResolveTrustedDigest(ref *Reference) (string, error)
+}
+
+// UntrustedImage is the error returned by PullImage when the TrustResolver of
+// the options fails to resolve the signed digest of a tag.
+type UntrustedImage struct {
+	Image string
+	Err   error
+}
+
+func (err *UntrustedImage) Error() string {
+	return "No trusted digest for " + err.Image + ": " + err.Err.Error()
+}
+
+// resolveTrusted pins the tag of the options to its signed digest, and
+// returns the tag, which is set on the image once it's pulled, as the docker
+// CLI does with DOCKER_CONTENT_TRUST. The references pinned to a digest
+// already are pulled as is.
+func (c *Client) resolveTrusted(opts *PullImageOptions) (string, error) {
+	tag := opts.Tag
+	if tag == "" {
+		// an empty tag would pull all the tags of the repository
+		tag = DefaultTag
+	}
+	if referenceDigestRegexp.MatchString(tag) {
+		return "", nil
+	}
+	image := opts.Repository + ":" + tag
+	ref, err := ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	digest, err := opts.Trust.ResolveTrustedDigest(ref)
+	if err != nil {
+		return "", &UntrustedImage{Image: image, Err: err}
+	}
+	if !referenceDigestRegexp.MatchString(digest) {
+		return "", ErrInvalidReference
+	}
+	opts.Tag = digest
+	return tag, nil
+}
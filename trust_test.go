@@ -0,0 +1,61 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type fakeTrustResolver map[string]string
+
+func (r fakeTrustResolver) ResolveTrustedDigest(ref *Reference) (string, error) {
+	digest, ok := r[ref.String()]
+	if !ok {
+		return "", errors.New("no signature")
+	}
+	return digest, nil
+}
+
+func TestPullImageTrusted(t *testing.T) {
+	digest := "sha256:4b82b6ae1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6"
+	fakeRT := &FakeRoundTripper{message: "Pulling 1/100", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := PullImageOptions{
+		Repository: "tsuru/python",
+		Tag:        "2.7",
+		Trust:      fakeTrustResolver{"docker.io/tsuru/python:2.7": digest},
+	}
+	if err := client.PullImage(opts, AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fakeRT.requests) != 2 {
+		t.Fatalf("PullImage: wrong number of requests. Want 2. Got %d.", len(fakeRT.requests))
+	}
+	pull := fakeRT.requests[0].URL.Query()
+	if pull.Get("fromImage") != "tsuru/python" || pull.Get("tag") != digest {
+		t.Errorf("PullImage: wrong pull query: %#v.", pull)
+	}
+	tag := fakeRT.requests[1]
+	expected := map[string][]string{"repo": {"tsuru/python"}, "tag": {"2.7"}, "force": {"1"}}
+	if tag.URL.Path != "/images/tsuru/python@"+digest+"/tag" || !reflect.DeepEqual(map[string][]string(tag.URL.Query()), expected) {
+		t.Errorf("PullImage: wrong tag request: %s.", tag.URL)
+	}
+}
+
+func TestPullImageUntrusted(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: "Pulling 1/100", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := PullImageOptions{Repository: "tsuru/python", Trust: fakeTrustResolver{}}
+	err := client.PullImage(opts, AuthConfiguration{})
+	if e, ok := err.(*UntrustedImage); !ok || e.Image != "tsuru/python:latest" {
+		t.Errorf("PullImage: wrong error. Want *UntrustedImage. Got %#v.", err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("PullImage: the untrusted image should not be pulled. Got %d requests.", len(fakeRT.requests))
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ULimit is a resource limit of the processes of a container, like the
+// number of open files. Soft and Hard are the limits, -1 meaning unlimited.
+type ULimit struct {
+	Name string `json:"Name,omitempty" yaml:"Name,omitempty"`
+	Soft int64  `json:"Soft,omitempty" yaml:"Soft,omitempty"`
+	Hard int64  `json:"Hard,omitempty" yaml:"Hard,omitempty"`
+}
+
+// ulimitNames are the names of the resource limits, as in ulimit.
+var ulimitNames = []string{
+	"core", "cpu", "data", "fsize", "locks", "memlock", "msgqueue", "nice",
+	"nofile", "nproc", "rss", "rtprio", "rttime", "sigpending", "stack",
+}
+
+// namespacedSysctls are the sysctls that the daemon accepts, since they
+// only apply to the namespaces of the container. The ones of the network
+// namespace, starting with net., are accepted too, unless the container
+// uses the network of the host.
+var namespacedSysctls = []string{
+	"kernel.msgmax", "kernel.msgmnb", "kernel.msgmni", "kernel.sem",
+	"kernel.shmall", "kernel.shmmax", "kernel.shmmni", "kernel.shm_rmid_forced",
+	"kernel.domainname", "kernel.hostname",
+}
+
+// InvalidULimit is the error returned by CreateContainer for the resource
+// limits with an unknown name or invalid values.
+type InvalidULimit struct {
+	Limit  ULimit
+	Reason string
+}
+
+func (err *InvalidULimit) Error() string {
+	return "Invalid ulimit " + err.Limit.Name + "=" + strconv.FormatInt(err.Limit.Soft, 10) + ":" +
+		strconv.FormatInt(err.Limit.Hard, 10) + ": " + err.Reason
+}
+
+// UnsupportedSysctl is the error returned by CreateContainer for the sysctls
+// that are not namespaced, and can't be set for a single container.
+type UnsupportedSysctl struct {
+	Name string
+}
+
+func (err *UnsupportedSysctl) Error() string {
+	return "Sysctl not namespaced, it can't be set for a container: " + err.Name
+}
+
+// validateLimits checks the resource limits and the sysctls of the host config.
+func (hostConfig *HostConfig) validateLimits() error {
+	for _, limit := range hostConfig.Ulimits {
+		switch {
+		case !containsString(ulimitNames, limit.Name):
+			return &InvalidULimit{Limit: limit, Reason: "unknown name"}
+		case limit.Soft < -1 || limit.Hard < -1:
+			return &InvalidULimit{Limit: limit, Reason: "negative limit"}
+		case limit.Hard != -1 && (limit.Soft == -1 || limit.Soft > limit.Hard):
+			return &InvalidULimit{Limit: limit, Reason: "soft limit above the hard limit"}
+		}
+	}
+	for name := range hostConfig.Sysctls {
+		if strings.HasPrefix(name, "fs.mqueue.") || containsString(namespacedSysctls, name) {
+			continue
+		}
+		if strings.HasPrefix(name, "net.") && hostConfig.NetworkMode != "host" {
+			continue
+		}
+		return &UnsupportedSysctl{Name: name}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHostConfigValidateLimits(t *testing.T) {
+	var tests = []struct {
+		hostConfig HostConfig
+		expected   error
+	}{
+		{HostConfig{}, nil},
+		{HostConfig{Ulimits: []ULimit{{Name: "nofile", Soft: 1024, Hard: 4096}, {Name: "core", Soft: -1, Hard: -1}}}, nil},
+		{HostConfig{Sysctls: map[string]string{"net.ipv4.ip_forward": "1", "kernel.shmmax": "1000", "fs.mqueue.msg_max": "10"}}, nil},
+		{HostConfig{Ulimits: []ULimit{{Name: "nofiles", Soft: 1024, Hard: 4096}}}, &InvalidULimit{Limit: ULimit{Name: "nofiles", Soft: 1024, Hard: 4096}, Reason: "unknown name"}},
+		{HostConfig{Ulimits: []ULimit{{Name: "nproc", Soft: -2, Hard: 10}}}, &InvalidULimit{Limit: ULimit{Name: "nproc", Soft: -2, Hard: 10}, Reason: "negative limit"}},
+		{HostConfig{Ulimits: []ULimit{{Name: "nproc", Soft: 20, Hard: 10}}}, &InvalidULimit{Limit: ULimit{Name: "nproc", Soft: 20, Hard: 10}, Reason: "soft limit above the hard limit"}},
+		{HostConfig{Sysctls: map[string]string{"vm.swappiness": "10"}}, &UnsupportedSysctl{Name: "vm.swappiness"}},
+		{HostConfig{NetworkMode: "host", Sysctls: map[string]string{"net.ipv4.ip_forward": "1"}}, &UnsupportedSysctl{Name: "net.ipv4.ip_forward"}},
+	}
+	for _, tt := range tests {
+		if err := tt.hostConfig.validateLimits(); !reflect.DeepEqual(err, tt.expected) {
+			t.Errorf("HostConfig.validateLimits(%#v): wrong error. Want %#v. Got %#v.", tt.hostConfig, tt.expected, err)
+		}
+	}
+}
+
+func TestCreateContainerInvalidULimit(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	_, err := client.CreateContainer(CreateContainerOptions{
+		Config:     &Config{Image: "base"},
+		HostConfig: &HostConfig{Ulimits: []ULimit{{Name: "nofile", Soft: 8192, Hard: 1024}}},
+	})
+	if _, ok := err.(*InvalidULimit); !ok {
+		t.Errorf("CreateContainer: wrong error. Want a *InvalidULimit. Got %#v.", err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("CreateContainer: the container should not be created. Got %d requests.", len(fakeRT.requests))
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ContainerUsage is a snapshot of the resources used by a container, for
+// reporting purposes.
+type ContainerUsage struct {
+	// CPUSeconds is the CPU time used by the container, across all the
+	// CPUs, in seconds.
+	CPUSeconds float64
+
+	// PeakMemory is the peak memory usage of the container, in bytes.
+	// Daemons that don't report the peak, like on cgroup v2 hosts, give
+	// the current usage instead.
+	PeakMemory uint64
+
+	// BlockRead and BlockWrite are the number of bytes read from and
+	// written to block devices.
+	BlockRead  uint64
+	BlockWrite uint64
+
+	// NetworkRx and NetworkTx are the number of bytes received and sent on
+	// all the network interfaces.
+	NetworkRx uint64
+	NetworkTx uint64
+
+	// Running tells whether the container is still running. When it's not,
+	// the daemon reports no statistics and only Duration and OOMKilled are
+	// set.
+	Running bool
+
+	// Duration is how long the container has been running, or ran.
+	Duration time.Duration
+
+	// OOMKilled tells whether the container was killed because it ran out
+	// of memory.
+	OOMKilled bool
+}
+
+// containerStats is the subset of the statistics of a container used by
+// ContainerUsage.
+type containerStats struct {
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"`
+		} `json:"cpu_usage"`
+	} `json:"cpu_stats"`
+	MemoryStats struct {
+		Usage    uint64 `json:"usage"`
+		MaxUsage uint64 `json:"max_usage"`
+	} `json:"memory_stats"`
+	BlkioStats struct {
+		IOServiceBytesRecursive []struct {
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes_recursive"`
+	} `json:"blkio_stats"`
+	Network  *networkStats           `json:"network"`
+	Networks map[string]networkStats `json:"networks"`
+}
+
+type networkStats struct {
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+}
+
+// ContainerUsage returns a snapshot of the resources used by the given
+// container, combining its statistics with its state. It requires Docker
+// API 1.19 or newer.
+func (c *Client) ContainerUsage(id string) (*ContainerUsage, error) {
+	container, err := c.InspectContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	usage := ContainerUsage{
+		Running:   container.State.Running,
+		OOMKilled: container.State.OOMKilled,
+	}
+	if !container.State.StartedAt.IsZero() {
+		end := container.State.FinishedAt
+		if usage.Running || end.Before(container.State.StartedAt) {
+			end = time.Now()
+		}
+		usage.Duration = end.Sub(container.State.StartedAt)
+	}
+	if !usage.Running {
+		return &usage, nil
+	}
+	stats, err := c.containerStats(id)
+	if err != nil {
+		return nil, err
+	}
+	usage.CPUSeconds = float64(stats.CPUStats.CPUUsage.TotalUsage) / float64(time.Second)
+	usage.PeakMemory = stats.MemoryStats.MaxUsage
+	if usage.PeakMemory == 0 {
+		usage.PeakMemory = stats.MemoryStats.Usage
+	}
+	for _, entry := range stats.BlkioStats.IOServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			usage.BlockRead += entry.Value
+		case "write":
+			usage.BlockWrite += entry.Value
+		}
+	}
+	if stats.Network != nil {
+		usage.NetworkRx, usage.NetworkTx = stats.Network.RxBytes, stats.Network.TxBytes
+	}
+	for _, network := range stats.Networks {
+		usage.NetworkRx += network.RxBytes
+		usage.NetworkTx += network.TxBytes
+	}
+	return &usage, nil
+}
+
+// containerStats reads a single sample of the statistics of a container.
+// Daemons that ignore the stream parameter keep sending samples, so the
+// response is closed after the first one.
+func (c *Client) containerStats(id string) (*containerStats, error) {
+	path := "/containers/" + id + "/stats?stream=false"
+	c.waitRateLimit(path)
+	resp, err := c.doRequest("GET", path, DoOptions{})
+	if e, ok := err.(*Error); ok && e.Status == http.StatusNotFound {
+		return nil, &NoSuchContainer{ID: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var stats containerStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
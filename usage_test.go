@@ -0,0 +1,100 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func usageServer(inspect string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/c1/json":
+			w.Write([]byte(inspect))
+		case "/containers/c1/stats":
+			if r.URL.Query().Get("stream") != "false" {
+				http.Error(w, "streaming", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{
+				"cpu_stats": {"cpu_usage": {"total_usage": 2500000000}},
+				"memory_stats": {"usage": 1048576, "max_usage": 4194304},
+				"blkio_stats": {"io_service_bytes_recursive": [
+					{"major": 8, "minor": 0, "op": "Read", "value": 4096},
+					{"major": 8, "minor": 0, "op": "Write", "value": 512},
+					{"major": 8, "minor": 16, "op": "read", "value": 1024},
+					{"major": 8, "minor": 0, "op": "Total", "value": 4608}
+				]},
+				"networks": {"eth0": {"rx_bytes": 100, "tx_bytes": 200}, "eth1": {"rx_bytes": 10, "tx_bytes": 20}}
+			}`))
+		default:
+			http.Error(w, "no such container", http.StatusNotFound)
+		}
+	}))
+}
+
+func TestContainerUsage(t *testing.T) {
+	server := usageServer(`{"Id":"c1","State":{"Running":true,"StartedAt":"2015-04-23T10:00:00Z"}}`)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, err := client.ContainerUsage("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Duration <= 0 {
+		t.Errorf("ContainerUsage: wrong duration. Got %s.", usage.Duration)
+	}
+	usage.Duration = 0
+	expected := &ContainerUsage{
+		CPUSeconds: 2.5,
+		PeakMemory: 4194304,
+		BlockRead:  5120,
+		BlockWrite: 512,
+		NetworkRx:  110,
+		NetworkTx:  220,
+		Running:    true,
+	}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Errorf("ContainerUsage: wrong usage. Want %#v. Got %#v.", expected, usage)
+	}
+}
+
+func TestContainerUsageExited(t *testing.T) {
+	server := usageServer(`{"Id":"c1","State":{"OOMKilled":true,"StartedAt":"2015-04-23T10:00:00Z","FinishedAt":"2015-04-23T10:01:30Z"}}`)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, err := client.ContainerUsage("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ContainerUsage{Duration: 90 * time.Second, OOMKilled: true}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Errorf("ContainerUsage: wrong usage. Want %#v. Got %#v.", expected, usage)
+	}
+}
+
+func TestContainerUsageNotFound(t *testing.T) {
+	server := usageServer("")
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ContainerUsage("c2"); err == nil {
+		t.Error("ContainerUsage: expected an error for a missing container")
+	} else if _, ok := err.(*NoSuchContainer); !ok {
+		t.Errorf("ContainerUsage: wrong error. Want a *NoSuchContainer. Got %#v.", err)
+	}
+}
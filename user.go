@@ -0,0 +1,104 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// NoSuchUser is the error returned by ResolveUser when a user or a group is
+// not defined in the image.
+type NoSuchUser struct {
+	Image string
+	Name  string
+}
+
+func (err *NoSuchUser) Error() string {
+	return "No such user or group in image " + err.Image + ": " + err.Name
+}
+
+// ResolveUser resolves a user of the given image, in one of the formats
+// "user", "user:group", "uid" or "uid:gid", to the numeric form uid:gid, as
+// the processes of a container started from the image would run. The group
+// defaults to the primary group of the user. It's useful to give the files of
+// a volume to the user of the container.
+//
+// The names are looked up in the /etc/passwd and /etc/group files of the
+// image, read from a temporary container, which is never started.
+func (c *Client) ResolveUser(image, user string) (string, error) {
+	name, group := user, ""
+	if i := strings.Index(user, ":"); i >= 0 {
+		name, group = user[:i], user[i+1:]
+	}
+	if name == "" || (group != "" && isNumeric(name) && isNumeric(group)) {
+		return user, nil
+	}
+	container, err := c.CreateContainer(CreateContainerOptions{
+		Config: &Config{Image: image, Entrypoint: []string{"/bin/true"}, NetworkDisabled: true},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer c.RemoveContainer(RemoveContainerOptions{ID: container.ID, RemoveVolumes: true, Force: true})
+	passwd, err := c.readEtcFile(container.ID, "/etc/passwd")
+	if err != nil {
+		return "", err
+	}
+	uid, gid, ok := lookupEtcEntry(passwd, name, 2, 3)
+	if !ok {
+		if !isNumeric(name) {
+			return "", &NoSuchUser{Image: image, Name: name}
+		}
+		// like the daemon, a uid missing from /etc/passwd runs with the
+		// group root
+		uid, gid = name, "0"
+	}
+	if group != "" {
+		gid = group
+		if !isNumeric(group) {
+			groups, err := c.readEtcFile(container.ID, "/etc/group")
+			if err != nil {
+				return "", err
+			}
+			if gid, _, ok = lookupEtcEntry(groups, group, 2, 2); !ok {
+				return "", &NoSuchUser{Image: image, Name: group}
+			}
+		}
+	}
+	return uid + ":" + gid, nil
+}
+
+// readEtcFile reads a file of the container, which may be missing.
+func (c *Client) readEtcFile(id, path string) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := c.CopyFileFromContainer(id, CopyFileFromContainerOptions{Path: path, OutputStream: &buf})
+	if _, ok := err.(*NoSuchPath); ok {
+		return nil, nil
+	}
+	return buf.Bytes(), err
+}
+
+// lookupEtcEntry finds the entry of the given name, or numeric ID, in a file
+// in the format of /etc/passwd or /etc/group, and returns two of its fields.
+func lookupEtcEntry(data []byte, name string, first, second int) (string, string, bool) {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) <= first || len(fields) <= second {
+			continue
+		}
+		if fields[0] == name || fields[2] == name {
+			return fields[first], fields[second], true
+		}
+	}
+	return "", "", false
+}
+
+// isNumeric tells whether s is a numeric ID.
+func isNumeric(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"archive/tar"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestResolveUser(t *testing.T) {
+	passwd := "root:x:0:0:root:/root:/bin/sh\npostgres:x:70:70::/var/lib/postgresql:/bin/sh\n"
+	group := "root:x:0:root\npostgres:x:70:\nssl-cert:x:101:postgres\n"
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.URL.Path == "/containers/create":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"tmp1"}`))
+		case r.URL.Path == "/containers/tmp1/archive" && r.URL.Query().Get("path") == "/etc/passwd":
+			w.Write([]byte(archiveWith(t, &tar.Header{Name: "passwd", Mode: 0644, Size: int64(len(passwd))}, passwd)))
+		case r.URL.Path == "/containers/tmp1/archive" && r.URL.Query().Get("path") == "/etc/group":
+			w.Write([]byte(archiveWith(t, &tar.Header{Name: "group", Mode: 0644, Size: int64(len(group))}, group)))
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		user     string
+		expected string
+	}{
+		{"postgres", "70:70"},
+		{"postgres:ssl-cert", "70:101"},
+		{"70", "70:70"},
+		{"1000", "1000:0"},
+		{"postgres:2000", "70:2000"},
+		{"1000:1000", "1000:1000"},
+	}
+	for _, tt := range tests {
+		got, err := client.ResolveUser("postgres:9.4", tt.user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.expected {
+			t.Errorf("ResolveUser(%q): wrong user. Want %q. Got %q.", tt.user, tt.expected, got)
+		}
+	}
+	requests = nil
+	_, err = client.ResolveUser("postgres:9.4", "mysql")
+	expected := &NoSuchUser{Image: "postgres:9.4", Name: "mysql"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("ResolveUser: wrong error. Want %#v. Got %#v.", expected, err)
+	}
+	if last := requests[len(requests)-1]; last != "DELETE /containers/tmp1" {
+		t.Errorf("ResolveUser: the container should be removed. Last request: %q.", last)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+// ImageVerifier verifies the images before they're pulled by PullImage, and
+// before CreateContainer creates containers from them, like a policy engine
+// checking the cosign signatures of the images. See the CosignVerifier of
+// the registry package.
+//
+// VerifyImage is given the reference of the image and the digest of its
+// manifest: the one resolved from the registry for pulls, which is then
+// pulled, and the one of the local image for containers. The digest is empty
+// for the local images which weren't pulled from the repository of the
+// reference, like the images built locally.
+type ImageVerifier interface {
+	VerifyImage(ref *Reference, digest string) error
+}
+
+// ImageVerifierFunc is an adapter allowing the use of ordinary functions as
+// an ImageVerifier.
+type ImageVerifierFunc func(ref *Reference, digest string) error
+
+// VerifyImage calls f(ref, digest).
+func (f ImageVerifierFunc) VerifyImage(ref *Reference, digest string) error {
+	return f(ref, digest)
+}
+
+// ImageRejected is the error returned by PullImage and CreateContainer when
+// the ImageVerifier of the client rejects an image.
+type ImageRejected struct {
+	Image  string
+	Digest string
+	Err    error
+}
+
+func (err *ImageRejected) Error() string {
+	return "Image " + err.Image + " rejected: " + err.Err.Error()
+}
+
+// verifyPull verifies the image pulled with the given options, pinning the
+// tag to the verified digest, which is returned.
+func (c *Client) verifyPull(opts *PullImageOptions, auth AuthConfiguration) (string, error) {
+	tag := opts.Tag
+	if tag == "" {
+		// an empty tag would pull all the tags of the repository
+		tag = DefaultTag
+	}
+	ref, err := ParseReference(opts.Repository)
+	if err != nil {
+		return "", err
+	}
+	if referenceDigestRegexp.MatchString(tag) {
+		ref.Digest, tag = tag, ""
+	} else {
+		ref.Tag = tag
+		if ref.Digest, err = c.ResolveImageDigest(opts.Repository+":"+tag, auth); err != nil {
+			return "", err
+		}
+	}
+	if err := c.ImageVerifier.VerifyImage(ref, ref.Digest); err != nil {
+		return "", &ImageRejected{Image: ref.String(), Digest: ref.Digest, Err: err}
+	}
+	opts.Tag = ref.Digest
+	return tag, nil
+}
+
+// verifyImage verifies the local image with the given name, finding its
+// digest among the RepoDigests of the image.
+func (c *Client) verifyImage(name string) error {
+	ref, err := ParseReference(name)
+	if err != nil {
+		return err
+	}
+	if ref.Digest == "" {
+		image, err := c.InspectImage(name)
+		if err != nil {
+			return err
+		}
+		for _, repoDigest := range image.RepoDigests {
+			if r, err := ParseReference(repoDigest); err == nil && r.Name() == ref.Name() {
+				ref.Digest = r.Digest
+				break
+			}
+		}
+	}
+	if err := c.ImageVerifier.VerifyImage(ref, ref.Digest); err != nil {
+		return &ImageRejected{Image: name, Digest: ref.Digest, Err: err}
+	}
+	return nil
+}
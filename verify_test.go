@@ -0,0 +1,102 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const verifiedDigest = "sha256:4b82b6ae1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6"
+
+func verifyServer(requests *[]string) *httptest.Server {
+	var mut sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		*requests = append(*requests, r.Method+" "+r.URL.RequestURI())
+		mut.Unlock()
+		switch r.URL.Path {
+		case "/distribution/tsuru/python:2.7/json":
+			w.Write([]byte(`{"Descriptor":{"digest":"` + verifiedDigest + `"}}`))
+		case "/images/tsuru/python:2.7/json":
+			w.Write([]byte(`{"Id":"sha256:abc","RepoDigests":["busybox@sha256:0000000000000000000000000000000000000000000000000000000000000000","tsuru/python@` + verifiedDigest + `"]}`))
+		case "/images/local:1.0/json":
+			w.Write([]byte(`{"Id":"sha256:def"}`))
+		case "/containers/create":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"c1"}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+}
+
+func TestPullImageVerified(t *testing.T) {
+	var requests []string
+	server := verifyServer(&requests)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var verified []string
+	client.ImageVerifier = ImageVerifierFunc(func(ref *Reference, digest string) error {
+		verified = append(verified, ref.String()+" "+digest)
+		return nil
+	})
+	if err := client.PullImage(PullImageOptions{Repository: "tsuru/python", Tag: "2.7"}, AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	expectedVerified := []string{"docker.io/tsuru/python:2.7@" + verifiedDigest + " " + verifiedDigest}
+	if !reflect.DeepEqual(verified, expectedVerified) {
+		t.Errorf("PullImage: wrong verified images. Want %#v. Got %#v.", expectedVerified, verified)
+	}
+	expected := []string{
+		"GET /distribution/tsuru/python:2.7/json",
+		"POST /images/create?fromImage=tsuru%2Fpython&tag=sha256%3A" + verifiedDigest[len("sha256:"):],
+		"POST /images/tsuru/python@" + verifiedDigest + "/tag?force=1&repo=tsuru%2Fpython&tag=2.7",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("PullImage: wrong requests. Want %#v. Got %#v.", expected, requests)
+	}
+}
+
+func TestCreateContainerVerifier(t *testing.T) {
+	var requests []string
+	server := verifyServer(&requests)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errUnsigned := errors.New("unsigned")
+	var verified []string
+	client.ImageVerifier = ImageVerifierFunc(func(ref *Reference, digest string) error {
+		verified = append(verified, ref.Name()+" "+digest)
+		if digest == "" {
+			return errUnsigned
+		}
+		return nil
+	})
+	_, err = client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "tsuru/python:2.7"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "local:1.0"}})
+	if e, ok := err.(*ImageRejected); !ok || e.Err != errUnsigned || e.Image != "local:1.0" {
+		t.Errorf("CreateContainer: wrong error. Want *ImageRejected. Got %#v.", err)
+	}
+	expectedVerified := []string{"docker.io/tsuru/python " + verifiedDigest, "docker.io/library/local "}
+	if !reflect.DeepEqual(verified, expectedVerified) {
+		t.Errorf("CreateContainer: wrong verified images. Want %#v. Got %#v.", expectedVerified, verified)
+	}
+	if last := requests[len(requests)-1]; last != "GET /images/local:1.0/json" {
+		t.Errorf("CreateContainer: the rejected container should not be created. Last request: %q.", last)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrNoSuchVolume is the error returned when the volume does not exist.
+	ErrNoSuchVolume = errors.New("no such volume")
+
+	// ErrVolumeInUse is the error returned when the volume requested to be
+	// removed is still in use.
+	ErrVolumeInUse = errors.New("volume in use and cannot be removed")
+)
+
+// Volume represents a volume.
+type Volume struct {
+	Name       string            `json:"Name" yaml:"Name"`
+	Driver     string            `json:"Driver,omitempty" yaml:"Driver,omitempty"`
+	Mountpoint string            `json:"Mountpoint,omitempty" yaml:"Mountpoint,omitempty"`
+	Labels     map[string]string `json:"Labels,omitempty" yaml:"Labels,omitempty"`
+	Options    map[string]string `json:"Options,omitempty" yaml:"Options,omitempty"`
+}
+
+// CreateVolumeOptions specify parameters to the CreateVolume function.
+type CreateVolumeOptions struct {
+	Name       string            `json:"Name,omitempty" yaml:"Name,omitempty"`
+	Driver     string            `json:"Driver,omitempty" yaml:"Driver,omitempty"`
+	DriverOpts map[string]string `json:"DriverOpts,omitempty" yaml:"DriverOpts,omitempty"`
+	Labels     map[string]string `json:"Labels,omitempty" yaml:"Labels,omitempty" apiVersion:"1.23"`
+}
+
+// ListVolumesOptions specify parameters to the ListVolumes function.
+type ListVolumesOptions struct {
+	Filters map[string][]string
+}
+
+// ListVolumes returns a list of available volumes in the server. It requires
+// Docker API 1.21 or newer.
+func (c *Client) ListVolumes(opts ListVolumesOptions) ([]Volume, error) {
+	body, _, err := c.do("GET", "/volumes?"+queryString(opts), nil, false)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Volumes []Volume
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Volumes, nil
+}
+
+// InspectVolume returns a volume by its name. It requires Docker API 1.21 or
+// newer.
+func (c *Client) InspectVolume(name string) (*Volume, error) {
+	body, status, err := c.do("GET", "/volumes/"+name, nil, false)
+	if status == http.StatusNotFound {
+		return nil, ErrNoSuchVolume
+	}
+	if err != nil {
+		return nil, err
+	}
+	var volume Volume
+	if err := json.Unmarshal(body, &volume); err != nil {
+		return nil, err
+	}
+	return &volume, nil
+}
+
+// CreateVolume creates a volume, returning it. The daemon generates a name
+// when Name is empty, and uses the local driver when Driver is empty. It
+// requires Docker API 1.21 or newer.
+func (c *Client) CreateVolume(opts CreateVolumeOptions) (*Volume, error) {
+	body, _, err := c.do("POST", "/volumes/create", opts, false)
+	if err != nil {
+		return nil, err
+	}
+	var volume Volume
+	if err := json.Unmarshal(body, &volume); err != nil {
+		return nil, err
+	}
+	return &volume, nil
+}
+
+// RemoveVolume removes a volume by its name. It requires Docker API 1.21 or
+// newer.
+func (c *Client) RemoveVolume(name string) error {
+	_, status, err := c.do("DELETE", "/volumes/"+name, nil, false)
+	if status == http.StatusNotFound {
+		return ErrNoSuchVolume
+	}
+	if status == http.StatusConflict {
+		return ErrVolumeInUse
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+// Copyright 2015 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestListVolumes(t *testing.T) {
+	body := `{"Volumes":[{"Name":"data","Driver":"local","Mountpoint":"/var/lib/docker/volumes/data","Labels":{"ci":"true"}}]}`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	volumes, err := client.ListVolumes(ListVolumesOptions{Filters: map[string][]string{"dangling": {"true"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Volume{{Name: "data", Driver: "local", Mountpoint: "/var/lib/docker/volumes/data", Labels: map[string]string{"ci": "true"}}}
+	if !reflect.DeepEqual(volumes, expected) {
+		t.Errorf("ListVolumes: wrong result. Want %#v. Got %#v.", expected, volumes)
+	}
+	req := fakeRT.requests[0]
+	if req.URL.Path != "/volumes" || req.URL.Query().Get("filters") != `{"dangling":["true"]}` {
+		t.Errorf("ListVolumes: wrong request: %s.", req.URL)
+	}
+}
+
+func TestRemoveVolume(t *testing.T) {
+	var tests = []struct {
+		status   int
+		expected error
+	}{
+		{http.StatusNoContent, nil},
+		{http.StatusNotFound, ErrNoSuchVolume},
+		{http.StatusConflict, ErrVolumeInUse},
+	}
+	for _, tt := range tests {
+		fakeRT := &FakeRoundTripper{message: "", status: tt.status}
+		client := newTestClient(fakeRT)
+		if err := client.RemoveVolume("data"); err != tt.expected {
+			t.Errorf("RemoveVolume (status %d): wrong error. Want %#v. Got %#v.", tt.status, tt.expected, err)
+		}
+		req := fakeRT.requests[0]
+		if req.Method != "DELETE" || req.URL.Path != "/volumes/data" {
+			t.Errorf("RemoveVolume: wrong request. Got %s %s.", req.Method, req.URL.Path)
+		}
+	}
+}
+
+func TestCreateVolume(t *testing.T) {
+	fakeRT := &FakeRoundTripper{message: `{"Name":"data","Driver":"local","Mountpoint":"/var/lib/docker/volumes/data/_data"}`, status: http.StatusCreated}
+	client := newTestClient(fakeRT)
+	volume, err := client.CreateVolume(CreateVolumeOptions{Name: "data", Labels: map[string]string{"app": "shop"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Volume{Name: "data", Driver: "local", Mountpoint: "/var/lib/docker/volumes/data/_data"}
+	if !reflect.DeepEqual(volume, expected) {
+		t.Errorf("CreateVolume: wrong volume. Want %#v. Got %#v.", expected, volume)
+	}
+	if req := fakeRT.requests[0]; req.Method != "POST" || req.URL.Path != "/volumes/create" {
+		t.Errorf("CreateVolume: wrong request. Got %s %s.", req.Method, req.URL.Path)
+	}
+}
+
+func TestInspectVolumeNotFound(t *testing.T) {
+	client := newTestClient(&FakeRoundTripper{message: "no such volume", status: http.StatusNotFound})
+	if _, err := client.InspectVolume("data"); err != ErrNoSuchVolume {
+		t.Errorf("InspectVolume: wrong error. Want %#v. Got %#v.", ErrNoSuchVolume, err)
+	}
+}